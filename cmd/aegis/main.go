@@ -1,82 +1,191 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"aegis-gateway/internal/adapters/files"
 	"aegis-gateway/internal/adapters/payments"
 	"aegis-gateway/internal/gateway"
 	"aegis-gateway/pkg/telemetry"
+
+	_ "github.com/lib/pq" // postgres driver for audit_sql
+)
+
+const (
+	configPath = "./aegis.yaml" // optional runtime config, reloaded on SIGHUP
+
+	// how long in-flight requests get to finish on SIGINT/SIGTERM
+	shutdownTimeout = 15 * time.Second
 )
 
 func main() {
-	err := run()
+	if len(os.Args) > 1 && os.Args[1] == "audit-schema" {
+		if err := telemetry.WriteAuditSchema(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// the selftest command takes the same flags as the server
+	name, args := "aegis", os.Args[1:]
+	if len(args) > 0 && args[0] == "selftest" {
+		name, args = "aegis selftest", args[1:]
+	}
+	opts, err := parseOptions(name, args, os.Getenv, os.Stderr)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if name == "aegis selftest" {
+		ok := runSelfTest(selfTestConfig{
+			PolicyDirs: opts.PolicyDirs,
+			LogPath:    opts.LogPath,
+			Adapters:   opts.adapters(),
+			ConfigPath: configPath,
+		}, os.Stdout)
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	err = run(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run() error {
-	// init telemetry first
-	err := telemetry.InitTelemetry("aegis-gateway", "./logs/aegis.log")
+func run(opts options) error {
+	// init telemetry first. AEGIS_AUDIT_OUTPUT picks where audit records
+	// go (both, stdout, file or none), both by default
+	err := telemetry.InitTelemetryWithConfig(telemetry.Config{
+		ServiceName: "aegis-gateway",
+		LogPath:     opts.LogPath,
+		AuditOutput: os.Getenv("AEGIS_AUDIT_OUTPUT"),
+	})
 	if err != nil {
 		return fmt.Errorf("failed to initialize telemetry: %w", err)
 	}
 	defer telemetry.Close()
 
-	// start payments adapter on port 8081
+	// start payments adapter, :8081 by default
 	paymentsAdapter := payments.NewAdapter()
 	go func() {
-		err := paymentsAdapter.Start(":8081")
+		err := paymentsAdapter.Start(opts.PaymentsAddr)
 		if err != nil {
 			fmt.Printf("ERROR: payments adapter failed: %v\n", err)
 		}
 	}()
 
-	// start files adapter on port 8082
+	// start files adapter, :8082 by default, on disk when AEGIS_FILES_DIR is set
 	filesAdapter := files.NewAdapter()
+	if dir := os.Getenv("AEGIS_FILES_DIR"); dir != "" {
+		store, err := files.NewDiskStore(dir)
+		if err != nil {
+			return err
+		}
+		filesAdapter = files.NewAdapterWithStore(store)
+	}
 	go func() {
-		err := filesAdapter.Start(":8082")
+		err := filesAdapter.Start(opts.FilesAddr)
 		if err != nil {
 			fmt.Printf("ERROR: files adapter failed: %v\n", err)
 		}
 	}()
 
-	// adapter registry
-	adapterMap := map[string]string{
-		"payments": "http://localhost:8081",
-		"files":    "http://localhost:8082",
-	}
-
 	// create gateway
-	gw, err := gateway.NewGateway("./policies", adapterMap)
+	gw, err := gateway.NewGatewayDirs(opts.PolicyDirs, opts.adapters())
 	if err != nil {
 		return fmt.Errorf("failed to create gateway: %w", err)
 	}
 	defer gw.Close()
 
-	// start gateway on port 8080
+	if opts.DeadLetterFile != "" {
+		gw.SetDeadLetterSink(gateway.NewFileDeadLetterSink(opts.DeadLetterFile))
+	}
+
+	if opts.SequenceStateFile != "" {
+		if err := gw.SetSequenceStateFile(opts.SequenceStateFile); err != nil {
+			return err
+		}
+	}
+
+	if len(opts.ShadowPolicyDirs) > 0 {
+		if err := gw.SetShadowPolicyDirs(opts.ShadowPolicyDirs...); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(configPath); err == nil {
+		if err := gw.SetConfigFile(configPath); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+
+	// admin route credentials; all optional, admin routes stay open
+	// without them
+	if err := gw.SetAdminAuth(gateway.AdminAuth{
+		Token:        os.Getenv("AEGIS_ADMIN_TOKEN"),
+		Username:     os.Getenv("AEGIS_ADMIN_USER"),
+		Password:     os.Getenv("AEGIS_ADMIN_PASSWORD"),
+		ClientCAFile: os.Getenv("AEGIS_ADMIN_CLIENT_CA"),
+	}); err != nil {
+		return err
+	}
+
+	// start gateway, :8080 by default, with TLS when a certificate is given
+	certFile, keyFile := os.Getenv("AEGIS_TLS_CERT"), os.Getenv("AEGIS_TLS_KEY")
+	if os.Getenv("AEGIS_ADMIN_CLIENT_CA") != "" && certFile == "" {
+		return fmt.Errorf("AEGIS_ADMIN_CLIENT_CA needs AEGIS_TLS_CERT and AEGIS_TLS_KEY")
+	}
 	go func() {
-		err := gw.Start(":8080")
+		var err error
+		if certFile != "" {
+			err = gw.StartTLS(opts.GatewayAddr, certFile, keyFile)
+		} else {
+			err = gw.Start(opts.GatewayAddr)
+		}
 		if err != nil {
 			fmt.Printf("ERROR: gateway failed: %v\n", err)
 		}
 	}()
 
 	fmt.Println("Aegis Gateway started successfully")
-	fmt.Println("Gateway: http://localhost:8080")
-	fmt.Println("Payments: http://localhost:8081")
-	fmt.Println("Files: http://localhost:8082")
+	fmt.Println("Gateway: " + localURL(opts.GatewayAddr))
+	fmt.Println("Payments: " + localURL(opts.PaymentsAddr))
+	fmt.Println("Files: " + localURL(opts.FilesAddr))
 
-	// wait for interrupt signal
+	// wait for interrupt signal, SIGHUP reloads the runtime config
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range sigCh {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		if err := gw.ReloadConfig(); err != nil {
+			fmt.Printf("ERROR: config reload failed, keeping current config: %v\n", err)
+		} else {
+			fmt.Println("Config reloaded successfully")
+		}
+	}
 
 	fmt.Println("\nShutting down gracefully...")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := gw.Shutdown(ctx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
 	return nil
 }
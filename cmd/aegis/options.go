@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+)
+
+// where the gateway and the built-in adapters listen and what they load.
+// each setting comes from its flag, then its environment variable, then
+// the default, so running without either behaves as before
+type options struct {
+	GatewayAddr  string
+	PaymentsAddr string
+	FilesAddr    string
+
+	// highest precedence first, see policy.NewManager
+	PolicyDirs []string
+	LogPath    string
+
+	// evaluated but not enforced, none by default. see
+	// gateway.SetShadowPolicyDirs
+	ShadowPolicyDirs []string
+
+	// monotonic_field state file, in memory only when empty. see
+	// gateway.SetSequenceStateFile
+	SequenceStateFile string
+
+	// dead-letter store, in memory when empty. see
+	// gateway.NewFileDeadLetterSink
+	DeadLetterFile string
+}
+
+const (
+	defaultGatewayAddr  = ":8080"
+	defaultPaymentsAddr = ":8081"
+	defaultFilesAddr    = ":8082"
+	defaultPolicyDir    = "./policies"
+	defaultLogPath      = "./logs/aegis.log"
+)
+
+// parse args (without the program name or subcommand) over the
+// environment read through getenv. -policy-dir and AEGIS_POLICY_DIRS take
+// several directories separated like PATH (':' on Unix), e.g.
+// ./policies/org:./policies/team
+func parseOptions(name string, args []string, getenv func(string) string, errOut io.Writer) (options, error) {
+	env := func(key, def string) string {
+		if v := getenv(key); v != "" {
+			return v
+		}
+		return def
+	}
+
+	var o options
+	var policyDirs, shadowDirs string
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.StringVar(&o.GatewayAddr, "addr", env("AEGIS_ADDR", defaultGatewayAddr), "gateway listen address (AEGIS_ADDR)")
+	fs.StringVar(&o.PaymentsAddr, "payments-addr", env("AEGIS_PAYMENTS_ADDR", defaultPaymentsAddr), "payments adapter listen address (AEGIS_PAYMENTS_ADDR)")
+	fs.StringVar(&o.FilesAddr, "files-addr", env("AEGIS_FILES_ADDR", defaultFilesAddr), "files adapter listen address (AEGIS_FILES_ADDR)")
+	fs.StringVar(&policyDirs, "policy-dir", env("AEGIS_POLICY_DIRS", defaultPolicyDir), "policy directories, highest precedence first (AEGIS_POLICY_DIRS)")
+	fs.StringVar(&o.LogPath, "log-path", env("AEGIS_LOG_PATH", defaultLogPath), "audit log file (AEGIS_LOG_PATH)")
+	fs.StringVar(&shadowDirs, "shadow-policy-dir", env("AEGIS_SHADOW_POLICY_DIRS", ""), "policy directories evaluated in shadow mode, not enforced (AEGIS_SHADOW_POLICY_DIRS)")
+	fs.StringVar(&o.SequenceStateFile, "sequence-state", env("AEGIS_SEQUENCE_STATE", ""), "file keeping monotonic_field values across restarts (AEGIS_SEQUENCE_STATE)")
+	fs.StringVar(&o.DeadLetterFile, "dead-letter-file", env("AEGIS_DEAD_LETTER_FILE", ""), "file keeping dead-lettered requests across restarts (AEGIS_DEAD_LETTER_FILE)")
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
+	}
+	if fs.NArg() > 0 {
+		return options{}, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	o.PolicyDirs = filepath.SplitList(policyDirs)
+	if len(o.PolicyDirs) == 0 {
+		return options{}, fmt.Errorf("no policy directory given")
+	}
+	if shadowDirs != "" {
+		o.ShadowPolicyDirs = filepath.SplitList(shadowDirs)
+	}
+	for _, addr := range []string{o.GatewayAddr, o.PaymentsAddr, o.FilesAddr} {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return options{}, fmt.Errorf("invalid listen address %q: %w", addr, err)
+		}
+	}
+	return o, nil
+}
+
+// adapter registry for the built-in adapters, reached on localhost when
+// they listen on every interface
+func (o options) adapters() map[string]string {
+	return map[string]string{
+		"payments": localURL(o.PaymentsAddr),
+		"files":    localURL(o.FilesAddr),
+	}
+}
+
+// http URL to reach a listen address from this host
+func localURL(addr string) string {
+	host, port, _ := net.SplitHostPort(addr)
+	switch host {
+	case "", "0.0.0.0", "::":
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
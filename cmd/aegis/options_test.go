@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want options
+	}{
+		{
+			name: "defaults",
+			want: options{
+				GatewayAddr:  ":8080",
+				PaymentsAddr: ":8081",
+				FilesAddr:    ":8082",
+				PolicyDirs:   []string{"./policies"},
+				LogPath:      "./logs/aegis.log",
+			},
+		},
+		{
+			name: "env over default",
+			env: map[string]string{
+				"AEGIS_ADDR":          ":9080",
+				"AEGIS_PAYMENTS_ADDR": "127.0.0.1:9081",
+				"AEGIS_POLICY_DIRS":   "/etc/aegis/org:/etc/aegis/team",
+				"AEGIS_LOG_PATH":      "/var/log/aegis.log",
+			},
+			want: options{
+				GatewayAddr:  ":9080",
+				PaymentsAddr: "127.0.0.1:9081",
+				FilesAddr:    ":8082",
+				PolicyDirs:   []string{"/etc/aegis/org", "/etc/aegis/team"},
+				LogPath:      "/var/log/aegis.log",
+			},
+		},
+		{
+			name: "flag over env",
+			args: []string{"-addr", ":7080", "-files-addr=:7082", "-policy-dir", "/srv/policies", "-log-path", "/tmp/a.log"},
+			env: map[string]string{
+				"AEGIS_ADDR":        ":9080",
+				"AEGIS_FILES_ADDR":  ":9082",
+				"AEGIS_POLICY_DIRS": "/etc/aegis/org:/etc/aegis/team",
+				"AEGIS_LOG_PATH":    "/var/log/aegis.log",
+			},
+			want: options{
+				GatewayAddr:  ":7080",
+				PaymentsAddr: ":8081",
+				FilesAddr:    ":7082",
+				PolicyDirs:   []string{"/srv/policies"},
+				LogPath:      "/tmp/a.log",
+			},
+		},
+		{
+			name: "shadow policies",
+			args: []string{"-shadow-policy-dir", "./candidate"},
+			env: map[string]string{
+				"AEGIS_SHADOW_POLICY_DIRS": "/etc/aegis/next",
+			},
+			want: options{
+				GatewayAddr:      ":8080",
+				PaymentsAddr:     ":8081",
+				FilesAddr:        ":8082",
+				PolicyDirs:       []string{"./policies"},
+				LogPath:          "./logs/aegis.log",
+				ShadowPolicyDirs: []string{"./candidate"},
+			},
+		},
+		{
+			name: "state files",
+			args: []string{"-dead-letter-file", "./deadletters.jsonl"},
+			env: map[string]string{
+				"AEGIS_SEQUENCE_STATE":   "/var/lib/aegis/sequences.json",
+				"AEGIS_DEAD_LETTER_FILE": "/var/lib/aegis/deadletters.jsonl",
+			},
+			want: options{
+				GatewayAddr:       ":8080",
+				PaymentsAddr:      ":8081",
+				FilesAddr:         ":8082",
+				PolicyDirs:        []string{"./policies"},
+				LogPath:           "./logs/aegis.log",
+				SequenceStateFile: "/var/lib/aegis/sequences.json",
+				DeadLetterFile:    "./deadletters.jsonl",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			got, err := parseOptions("aegis", tt.args, getenv, io.Discard)
+			if err != nil {
+				t.Fatalf("parseOptions: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseOptionsErrors(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	for _, args := range [][]string{
+		{"-addr", "8080"},
+		{"-unknown"},
+		{"extra"},
+		{"-policy-dir", ""},
+	} {
+		if _, err := parseOptions("aegis", args, noEnv, io.Discard); err == nil {
+			t.Errorf("Expected %v to be rejected", args)
+		}
+	}
+}
+
+// adapters are registered where they listen, on localhost when bound to
+// every interface
+func TestOptionsAdapters(t *testing.T) {
+	o := options{PaymentsAddr: ":9081", FilesAddr: "10.0.0.5:9082"}
+	want := map[string]string{
+		"payments": "http://localhost:9081",
+		"files":    "http://10.0.0.5:9082",
+	}
+	if got := o.adapters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := localURL("[::]:8080"); got != "http://localhost:8080" {
+		t.Errorf("Expected localhost for the IPv6 wildcard, got %s", got)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"aegis-gateway/internal/gateway"
+	"aegis-gateway/internal/policy"
+	"aegis-gateway/pkg/telemetry"
+)
+
+type selfTestConfig struct {
+	PolicyDirs []string
+	LogPath    string
+	Adapters   map[string]string // tool name -> URL
+
+	// runtime config the server would load, optional. its adapters
+	// replace Adapters, the way the server's registry is replaced
+	ConfigPath string
+}
+
+// run deployment checks, print a pass/fail line for each and return
+// whether all of them passed
+func runSelfTest(cfg selfTestConfig, out io.Writer) bool {
+	allOK := true
+	report := func(name string, err error, detail string) {
+		if err != nil {
+			allOK = false
+			fmt.Fprintf(out, "[FAIL] %s: %v\n", name, err)
+			return
+		}
+		fmt.Fprintf(out, "[PASS] %s: %s\n", name, detail)
+	}
+
+	files, err := checkPolicies(cfg.PolicyDirs)
+	report("policies", err, fmt.Sprintf("%d file(s) loaded from %s", files, strings.Join(cfg.PolicyDirs, ", ")))
+
+	err = checkLogWritable(cfg.LogPath)
+	report("audit log", err, cfg.LogPath+" is writable")
+
+	err = checkTelemetry(cfg.LogPath)
+	report("telemetry", err, "initialized")
+
+	adapters := cfg.Adapters
+	var runtime gateway.Config
+	if _, err := os.Stat(cfg.ConfigPath); cfg.ConfigPath != "" && err == nil {
+		runtime, err = gateway.LoadConfigFile(cfg.ConfigPath)
+		report("config", err, cfg.ConfigPath+" is valid")
+		if runtime.Adapters != nil {
+			adapters = runtime.Adapters
+		}
+	}
+
+	// stable order for the report
+	tools := make([]string, 0, len(adapters))
+	for tool := range adapters {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	for _, tool := range tools {
+		url := adapters[tool]
+		err := checkAdapter(url, runtime.Tools[tool].Passthrough())
+		report("adapter "+tool, err, url+" reachable")
+	}
+
+	if allOK {
+		fmt.Fprintln(out, "selftest passed")
+	} else {
+		fmt.Fprintln(out, "selftest FAILED")
+	}
+	return allOK
+}
+
+func checkPolicies(dirs []string) (int, error) {
+	m, err := policy.NewManager(dirs...)
+	if err != nil {
+		return 0, err
+	}
+	if errs := m.LoadErrors(); len(errs) > 0 {
+		return 0, policy.LoadErrorList(errs)
+	}
+	n := len(m.PolicyFiles())
+	if n == 0 {
+		return 0, fmt.Errorf("no valid policy files in %s", strings.Join(dirs, ", "))
+	}
+	return n, nil
+}
+
+func checkLogWritable(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("cannot create log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open log file: %w", err)
+	}
+	return f.Close()
+}
+
+func checkTelemetry(logPath string) error {
+	if err := telemetry.InitTelemetry("aegis-selftest", logPath); err != nil {
+		return err
+	}
+	telemetry.Close()
+	return nil
+}
+
+// adapters must answer GET /health with 200, passthrough upstreams their
+// base URL with anything below 500, like GET /health/ready checks them
+func checkAdapter(url string, passthrough bool) error {
+	probe := strings.TrimSuffix(url, "/") + "/health"
+	if passthrough {
+		probe = url
+	}
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(probe)
+	if err != nil {
+		return fmt.Errorf("unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if (passthrough && resp.StatusCode >= 500) || (!passthrough && resp.StatusCode != http.StatusOK) {
+		return fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return nil
+}
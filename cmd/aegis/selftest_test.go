@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelfTest_Healthy(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyDir := filepath.Join(tmpDir, "policies")
+	os.Mkdir(policyDir, 0755)
+	policyContent := `version: 1
+agents:
+  - id: test-agent
+    allow:
+      - tool: payments
+        actions: [create]
+`
+	if err := os.WriteFile(filepath.Join(policyDir, "test.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"healthy"}`))
+	}))
+	defer adapter.Close()
+
+	var out bytes.Buffer
+	ok := runSelfTest(selfTestConfig{
+		PolicyDirs: []string{policyDir},
+		LogPath:    filepath.Join(tmpDir, "logs", "aegis.log"),
+		Adapters:   map[string]string{"payments": adapter.URL},
+	}, &out)
+
+	if !ok {
+		t.Fatalf("Expected selftest to pass, report:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "[FAIL]") {
+		t.Errorf("Unexpected failure in report:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "[PASS] adapter payments") {
+		t.Errorf("Expected adapter check in report:\n%s", out.String())
+	}
+}
+
+func TestSelfTest_Broken(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// closed server = unreachable adapter
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	adapter.Close()
+
+	// log path under a regular file can't be created
+	blocker := filepath.Join(tmpDir, "not-a-dir")
+	os.WriteFile(blocker, []byte("x"), 0644)
+
+	var out bytes.Buffer
+	ok := runSelfTest(selfTestConfig{
+		PolicyDirs: []string{filepath.Join(tmpDir, "missing")},
+		LogPath:    filepath.Join(blocker, "aegis.log"),
+		Adapters:   map[string]string{"payments": adapter.URL},
+	}, &out)
+
+	if ok {
+		t.Fatalf("Expected selftest to fail, report:\n%s", out.String())
+	}
+	for _, check := range []string{"policies", "audit log", "telemetry", "adapter payments"} {
+		if !strings.Contains(out.String(), "[FAIL] "+check) {
+			t.Errorf("Expected %q to fail, report:\n%s", check, out.String())
+		}
+	}
+}
+
+// adapters in the runtime config are the ones probed, passthrough
+// upstreams at their base URL
+func TestSelfTest_AdaptersFromConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyDir := filepath.Join(tmpDir, "policies")
+	os.Mkdir(policyDir, 0755)
+	if err := os.WriteFile(filepath.Join(policyDir, "test.yaml"), []byte("version: 1\nagents:\n  - id: a\n    allow:\n      - tool: payments\n        actions: [create]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer adapter.Close()
+	// no /health, but up
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer upstream.Close()
+
+	configPath := filepath.Join(tmpDir, "aegis.yaml")
+	config := "adapters:\n  payments: " + adapter.URL + "\n  billing: " + upstream.URL + "\ntools:\n  billing: {type: passthrough}\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	var out bytes.Buffer
+	ok := runSelfTest(selfTestConfig{
+		PolicyDirs: []string{policyDir},
+		LogPath:    filepath.Join(tmpDir, "logs", "aegis.log"),
+		Adapters:   map[string]string{"payments": "http://127.0.0.1:1", "files": "http://127.0.0.1:1"},
+		ConfigPath: configPath,
+	}, &out)
+	if !ok {
+		t.Fatalf("Expected selftest to pass, report:\n%s", out.String())
+	}
+	for _, want := range []string{"[PASS] config", "[PASS] adapter billing: " + upstream.URL, "[PASS] adapter payments: " + adapter.URL} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in report:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "adapter files") {
+		t.Errorf("Expected the default adapters replaced by the config's:\n%s", out.String())
+	}
+
+	os.WriteFile(configPath, []byte("rate_limit: {requests_per_second: -1}\n"), 0644)
+	out.Reset()
+	if runSelfTest(selfTestConfig{PolicyDirs: []string{policyDir}, LogPath: filepath.Join(tmpDir, "logs", "aegis.log"), ConfigPath: configPath}, &out) {
+		t.Errorf("Expected an invalid config to fail, report:\n%s", out.String())
+	}
+}
@@ -0,0 +1,20 @@
+package files
+
+// write modes, WriteRequest.Mode
+const (
+	WriteModeOverwrite = "overwrite"
+	WriteModeAppend    = "append"
+)
+
+func valid_write_mode(mode string) bool {
+	return mode == "" || mode == WriteModeOverwrite || mode == WriteModeAppend
+}
+
+// what a write in mode leaves in the file. appending to a missing file
+// creates it
+func next_content(mode, current, content string) string {
+	if mode == WriteModeAppend {
+		return current + content
+	}
+	return content
+}
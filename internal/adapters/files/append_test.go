@@ -0,0 +1,58 @@
+package files
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHandleWrite_Append(t *testing.T) {
+	for name, store := range map[string]FileStore{"memory": NewMemoryStore(), "disk": must_disk_store(t)} {
+		t.Run(name, func(t *testing.T) {
+			adapter := NewAdapterWithStore(store)
+			store.Write("/logs/app.log", "line 1\n")
+
+			w, resp := write_file(adapter, WriteRequest{Path: "/logs/app.log", Content: "line 2\n", Mode: WriteModeAppend})
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected append to succeed, got %d: %s", w.Code, w.Body.String())
+			}
+			if content, _ := store.Read("/logs/app.log"); content != "line 1\nline 2\n" {
+				t.Errorf("Expected appended content, got %q", content)
+			}
+			if resp.Size != 14 || resp.Hash != content_hash("line 1\nline 2\n") {
+				t.Errorf("Expected size and hash of the whole file, got %+v", resp)
+			}
+
+			// appending to a missing file creates it
+			w, resp = write_file(adapter, WriteRequest{Path: "/logs/new.log", Content: "first\n", Mode: WriteModeAppend})
+			if w.Code != http.StatusOK || resp.Size != 6 {
+				t.Errorf("Expected append to create the file, got %d %+v", w.Code, resp)
+			}
+			if content, _ := store.Read("/logs/new.log"); content != "first\n" {
+				t.Errorf("Expected new file content, got %q", content)
+			}
+
+			// overwrite, explicit or by default, replaces as before
+			for _, mode := range []string{WriteModeOverwrite, ""} {
+				w, resp = write_file(adapter, WriteRequest{Path: "/logs/app.log", Content: "reset\n", Mode: mode})
+				if w.Code != http.StatusOK || resp.Size != 6 {
+					t.Errorf("Expected overwrite to succeed, got %d %+v", w.Code, resp)
+				}
+				if content, _ := store.Read("/logs/app.log"); content != "reset\n" {
+					t.Errorf("Expected overwritten content, got %q", content)
+				}
+			}
+
+			// appends can be conditional too
+			if w, _ := write_file(adapter, WriteRequest{Path: "/logs/app.log", Content: "x", Mode: WriteModeAppend, IfMatch: if_match(content_hash("stale"))}); w.Code != http.StatusConflict {
+				t.Errorf("Expected stale conditional append to be rejected, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestHandleWrite_InvalidMode(t *testing.T) {
+	adapter := NewAdapter()
+	if w, _ := write_file(adapter, WriteRequest{Path: "/a", Content: "x", Mode: "prepend"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,37 @@
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	// if_match names content the file no longer has
+	ErrContentChanged = errors.New("file content does not match if_match")
+
+	// if_match is empty but the file exists
+	ErrFileExists = errors.New("file already exists")
+)
+
+// hex SHA-256 of a file's content, returned by read and write and
+// compared against if_match
+func content_hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// whether a conditional write may replace current. an empty if_match only
+// creates, any other value must be the current content's hash
+func check_if_match(ifMatch string, current string, exists bool) error {
+	if ifMatch == "" {
+		if exists {
+			return ErrFileExists
+		}
+		return nil
+	}
+	if !exists || content_hash(current) != ifMatch {
+		return ErrContentChanged
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package files
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func write_file(adapter *Adapter, req WriteRequest) (*httptest.ResponseRecorder, WriteResponse) {
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	adapter.HandleWrite(w, httptest.NewRequest("POST", "/write", bytes.NewReader(body)))
+	var resp WriteResponse
+	if w.Code == http.StatusOK {
+		json.Unmarshal(w.Body.Bytes(), &resp)
+	}
+	return w, resp
+}
+
+func if_match(hash string) *string { return &hash }
+
+func TestHandleWrite_IfMatch(t *testing.T) {
+	for name, store := range map[string]FileStore{"memory": NewMemoryStore(), "disk": must_disk_store(t)} {
+		t.Run(name, func(t *testing.T) {
+			adapter := NewAdapterWithStore(store)
+
+			// create when absent
+			w, created := write_file(adapter, WriteRequest{Path: "/notes.txt", Content: "v1", IfMatch: if_match("")})
+			if w.Code != http.StatusOK || created.Hash != content_hash("v1") {
+				t.Fatalf("Expected create to succeed with the content hash, got %d %+v", w.Code, created)
+			}
+			// a second create loses
+			if w, _ := write_file(adapter, WriteRequest{Path: "/notes.txt", Content: "other", IfMatch: if_match("")}); w.Code != http.StatusConflict {
+				t.Errorf("Expected 409 creating an existing file, got %d", w.Code)
+			}
+
+			// read hands out the hash to match on
+			body, _ := json.Marshal(ReadRequest{Path: "/notes.txt"})
+			rw := httptest.NewRecorder()
+			adapter.HandleRead(rw, httptest.NewRequest("POST", "/read", bytes.NewReader(body)))
+			var read ReadResponse
+			json.NewDecoder(rw.Body).Decode(&read)
+			if read.Hash != created.Hash {
+				t.Fatalf("Expected read hash %s, got %s", created.Hash, read.Hash)
+			}
+
+			w, updated := write_file(adapter, WriteRequest{Path: "/notes.txt", Content: "v2", IfMatch: if_match(read.Hash)})
+			if w.Code != http.StatusOK || updated.Hash != content_hash("v2") {
+				t.Fatalf("Expected matching write to succeed, got %d %+v", w.Code, updated)
+			}
+
+			// the hash read before v2 is now stale
+			w, _ = write_file(adapter, WriteRequest{Path: "/notes.txt", Content: "v3", IfMatch: if_match(read.Hash)})
+			if w.Code != http.StatusConflict {
+				t.Errorf("Expected stale write to be rejected with 409, got %d", w.Code)
+			}
+			if content, _ := store.Read("/notes.txt"); content != "v2" {
+				t.Errorf("Expected rejected write to leave v2, got %q", content)
+			}
+
+			if w, _ := write_file(adapter, WriteRequest{Path: "/missing.txt", Content: "x", IfMatch: if_match(read.Hash)}); w.Code != http.StatusConflict {
+				t.Errorf("Expected 409 matching a missing file, got %d", w.Code)
+			}
+		})
+	}
+}
+
+// of several writers racing from the same hash, exactly one wins
+func TestHandleWrite_IfMatchConcurrent(t *testing.T) {
+	store := NewMemoryStore()
+	store.Write("/counter", "0")
+	adapter := NewAdapterWithStore(store)
+	base := content_hash("0")
+
+	var wins atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if w, _ := write_file(adapter, WriteRequest{Path: "/counter", Content: fmt.Sprint(i + 1), IfMatch: if_match(base)}); w.Code == http.StatusOK {
+				wins.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if wins.Load() != 1 {
+		t.Errorf("Expected exactly one write to win, got %d", wins.Load())
+	}
+}
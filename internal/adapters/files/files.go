@@ -2,10 +2,13 @@ package files
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"sync"
 	"time"
+
+	"aegis-gateway/pkg/apierror"
+	"aegis-gateway/pkg/telemetry"
 )
 
 type ReadRequest struct {
@@ -15,57 +18,82 @@ type ReadRequest struct {
 type ReadResponse struct {
 	Path    string `json:"path"`
 	Content string `json:"content"`
+	// pass as if_match to write only if the file is unchanged
+	Hash string `json:"hash"`
 }
 
 type WriteRequest struct {
 	Path    string `json:"path"`
 	Content string `json:"content"`
+	// write only if the file's content still has this hash; "" writes
+	// only if the file doesn't exist. omitted writes unconditionally
+	IfMatch *string `json:"if_match,omitempty"`
+	// "overwrite" (default) or "append"
+	Mode string `json:"mode,omitempty"`
 }
 
 type WriteResponse struct {
 	Path   string `json:"path"`
 	Status string `json:"status"`
+	// hash of the content now stored
+	Hash string `json:"hash"`
+	// length in bytes of the content now stored
+	Size int `json:"size"`
+}
+
+type DeleteRequest struct {
+	Path string `json:"path"`
+}
+
+type DeleteResponse struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
 }
 
 type Adapter struct {
-	mu    sync.RWMutex
-	files map[string]string
+	store FileStore
 }
 
+// in-memory adapter seeded with demo files
 func NewAdapter() *Adapter {
-	a := &Adapter{
-		files: make(map[string]string),
-	}
-	a.files["/hr-docs/employee-handbook.pdf"] = "Employee handbook content..."
-	a.files["/hr-docs/benefits.pdf"] = "Benefits information..."
-	a.files["/legal/contract.docx"] = "Legal contract content..."
-	return a
+	store := NewMemoryStore()
+	store.Write("/hr-docs/employee-handbook.pdf", "Employee handbook content...")
+	store.Write("/hr-docs/benefits.pdf", "Benefits information...")
+	store.Write("/legal/contract.docx", "Legal contract content...")
+	return NewAdapterWithStore(store)
+}
+
+// adapter over a given store, e.g. a DiskStore so writes survive restarts
+func NewAdapterWithStore(store FileStore) *Adapter {
+	return &Adapter{store: store}
 }
 
 func (a *Adapter) HandleRead(w http.ResponseWriter, r *http.Request) {
 	var req ReadRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf(`{"error":"InvalidRequest","message":"%s"}`, err.Error()), http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, err.Error())
 		return
 	}
 
 	if req.Path == "" {
-		http.Error(w, `{"error":"InvalidRequest","message":"Path is required"}`, http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, "Path is required")
 		return
 	}
 
-	a.mu.RLock()
-	content, exists := a.files[req.Path]
-	a.mu.RUnlock()
-
-	if !exists {
-		http.Error(w, `{"error":"NotFound","message":"File not found"}`, http.StatusNotFound)
+	content, err := a.store.Read(req.Path)
+	if errors.Is(err, ErrNotFound) {
+		apierror.Write(w, apierror.NotFound, "File not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(w, apierror.StorageError, "Failed to read file")
 		return
 	}
 
 	resp := ReadResponse{
 		Path:    req.Path,
 		Content: content,
+		Hash:    content_hash(content),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -75,27 +103,87 @@ func (a *Adapter) HandleRead(w http.ResponseWriter, r *http.Request) {
 func (a *Adapter) HandleWrite(w http.ResponseWriter, r *http.Request) {
 	var req WriteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf(`{"error":"InvalidRequest","message":"%s"}`, err.Error()), http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, err.Error())
 		return
 	}
 
 	if req.Path == "" {
-		http.Error(w, `{"error":"InvalidRequest","message":"Path is required"}`, http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, "Path is required")
 		return
 	}
 
 	if req.Content == "" {
-		http.Error(w, `{"error":"InvalidRequest","message":"Content is required"}`, http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, "Content is required")
+		return
+	}
+
+	if !valid_write_mode(req.Mode) {
+		apierror.Write(w, apierror.InvalidRequest, "Mode must be overwrite or append")
 		return
 	}
 
-	a.mu.Lock()
-	a.files[req.Path] = req.Content
-	a.mu.Unlock()
+	stored := req.Content
+	var err error
+	if req.IfMatch == nil && req.Mode != WriteModeAppend {
+		err = a.store.Write(req.Path, req.Content)
+	} else {
+		// read and write under the store's lock so a concurrent write
+		// can't slip in between
+		err = a.store.Update(req.Path, func(current string, exists bool) (string, error) {
+			if req.IfMatch != nil {
+				if err := check_if_match(*req.IfMatch, current, exists); err != nil {
+					return "", err
+				}
+			}
+			stored = next_content(req.Mode, current, req.Content)
+			return stored, nil
+		})
+	}
+	if errors.Is(err, ErrContentChanged) || errors.Is(err, ErrFileExists) {
+		apierror.Write(w, apierror.Conflict, fmt.Sprintf("Write rejected: %v", err))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, apierror.StorageError, "Failed to write file")
+		return
+	}
 
 	resp := WriteResponse{
 		Path:   req.Path,
 		Status: "written",
+		Hash:   content_hash(stored),
+		Size:   len(stored),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (a *Adapter) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	var req DeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	if req.Path == "" {
+		apierror.Write(w, apierror.InvalidRequest, "Path is required")
+		return
+	}
+
+	err := a.store.Delete(req.Path)
+	if errors.Is(err, ErrNotFound) {
+		apierror.Write(w, apierror.NotFound, "File not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(w, apierror.StorageError, "Failed to delete file")
+		return
+	}
+
+	resp := DeleteResponse{
+		Path:   req.Path,
+		Status: "deleted",
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -111,11 +199,13 @@ func (a *Adapter) Start(addr string) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/read", a.HandleRead)
 	mux.HandleFunc("/write", a.HandleWrite)
+	mux.HandleFunc("/delete", a.HandleDelete)
+	mux.HandleFunc("/list", a.HandleList)
 	mux.HandleFunc("/health", a.HandleHealth)
 
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      mux,
+		Handler:      telemetry.TraceHandler("files-adapter", mux),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"aegis-gateway/pkg/apierror"
 )
 
 func TestHandleRead_Success(t *testing.T) {
@@ -230,3 +234,108 @@ func TestHealthEndpoint(t *testing.T) {
 		t.Errorf("Expected status healthy, got %s", resp["status"])
 	}
 }
+
+func TestHandleDelete_Existing(t *testing.T) {
+	adapter := NewAdapter()
+
+	bodyBytes, _ := json.Marshal(DeleteRequest{Path: "/legal/contract.docx"})
+	req := httptest.NewRequest("POST", "/delete", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+	adapter.HandleDelete(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var resp DeleteResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Path != "/legal/contract.docx" || resp.Status != "deleted" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+
+	// gone afterwards
+	readBytes, _ := json.Marshal(ReadRequest{Path: "/legal/contract.docx"})
+	readW := httptest.NewRecorder()
+	adapter.HandleRead(readW, httptest.NewRequest("POST", "/read", bytes.NewReader(readBytes)))
+	if readW.Code != http.StatusNotFound {
+		t.Errorf("Expected deleted file to read as 404, got %d", readW.Code)
+	}
+}
+
+func TestHandleDelete_Missing(t *testing.T) {
+	adapter := NewAdapter()
+
+	bodyBytes, _ := json.Marshal(DeleteRequest{Path: "/nonexistent.txt"})
+	req := httptest.NewRequest("POST", "/delete", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+	adapter.HandleDelete(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func TestDiskStore_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatalf("NewDiskStore() error = %v", err)
+	}
+	adapter := NewAdapterWithStore(store)
+
+	writeBytes, _ := json.Marshal(WriteRequest{Path: "/reports/q1.txt", Content: "Q1 numbers"})
+	writeW := httptest.NewRecorder()
+	adapter.HandleWrite(writeW, httptest.NewRequest("POST", "/write", bytes.NewReader(writeBytes)))
+	if writeW.Code != http.StatusOK {
+		t.Fatalf("Expected write status 200, got %d", writeW.Code)
+	}
+
+	// a new adapter over the same directory, as after a restart
+	store, _ = NewDiskStore(dir)
+	adapter = NewAdapterWithStore(store)
+
+	readBytes, _ := json.Marshal(ReadRequest{Path: "/reports/q1.txt"})
+	readW := httptest.NewRecorder()
+	adapter.HandleRead(readW, httptest.NewRequest("POST", "/read", bytes.NewReader(readBytes)))
+	var resp ReadResponse
+	json.NewDecoder(readW.Body).Decode(&resp)
+	if readW.Code != http.StatusOK || resp.Content != "Q1 numbers" {
+		t.Errorf("Expected persisted content, got %d %+v", readW.Code, resp)
+	}
+
+	// paths can't escape the store directory
+	if err := store.Write("/../../escape.txt", "x"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.txt")); err != nil {
+		t.Errorf("Expected traversal path to stay under root: %v", err)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	adapter := NewAdapter()
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		status  int
+		code    string
+	}{
+		{"invalid json", adapter.HandleRead, "{", http.StatusBadRequest, "AEGIS-400-REQUEST"},
+		{"missing content", adapter.HandleWrite, `{"path":"/a.txt"}`, http.StatusBadRequest, "AEGIS-400-REQUEST"},
+		{"missing file", adapter.HandleRead, `{"path":"/nope.txt"}`, http.StatusNotFound, "AEGIS-404-NOT-FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, httptest.NewRequest("POST", "/", bytes.NewReader([]byte(tt.body))))
+			if w.Code != tt.status || w.Header().Get("Content-Type") != "application/json" {
+				t.Fatalf("Expected %d with a JSON body, got %d %q", tt.status, w.Code, w.Header().Get("Content-Type"))
+			}
+			var resp apierror.Response
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.Code != tt.code || resp.Error == "" || resp.Reason == "" {
+				t.Errorf("Expected code %s with error and reason, got %+v", tt.code, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package files
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"aegis-gateway/pkg/apierror"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+type ListRequest struct {
+	// directory to list, recursively
+	Path  string `json:"path"`
+	Limit int    `json:"limit,omitempty"`
+	// next_cursor of the previous page
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type ListResponse struct {
+	Path  string   `json:"path"`
+	Files []string `json:"files"`
+	// set while there are more files after this page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// list the files under a directory, sorted by path, a page at a time. the
+// cursor carries the last path returned and the next page starts after
+// it, so files written or deleted between pages don't shift the pages:
+// nothing that exists throughout is skipped or listed twice
+func (a *Adapter) HandleList(w http.ResponseWriter, r *http.Request) {
+	var req ListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	if req.Path == "" {
+		apierror.Write(w, apierror.InvalidRequest, "Path is required")
+		return
+	}
+	if req.Limit < 0 || req.Limit > maxListLimit {
+		apierror.Write(w, apierror.InvalidRequest, "Limit must be between 1 and 1000")
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultListLimit
+	}
+	after, err := decode_cursor(req.Cursor)
+	if err != nil {
+		apierror.Write(w, apierror.InvalidRequest, "Invalid cursor")
+		return
+	}
+
+	paths, err := a.store.List(req.Path)
+	if err != nil {
+		apierror.Write(w, apierror.StorageError, "Failed to list files")
+		return
+	}
+
+	start := 0
+	if req.Cursor != "" {
+		start = sort.SearchStrings(paths, after)
+		if start < len(paths) && paths[start] == after {
+			start++
+		}
+	}
+	resp := ListResponse{Path: req.Path, Files: []string{}}
+	if end := start + req.Limit; end < len(paths) {
+		resp.Files = paths[start:end]
+		resp.NextCursor = encode_cursor(paths[end-1])
+	} else if start < len(paths) {
+		resp.Files = paths[start:]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func encode_cursor(last string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(last))
+}
+
+func decode_cursor(cursor string) (string, error) {
+	last, err := base64.RawURLEncoding.DecodeString(cursor)
+	return string(last), err
+}
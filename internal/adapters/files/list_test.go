@@ -0,0 +1,122 @@
+package files
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func list_page(t *testing.T, adapter *Adapter, req ListRequest) ListResponse {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	adapter.HandleList(w, httptest.NewRequest("POST", "/list", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ListResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return resp
+}
+
+// every page of a listing, calling between after each page
+func list_all(t *testing.T, adapter *Adapter, dir string, limit int, between func(page int)) []string {
+	t.Helper()
+	var all []string
+	cursor := ""
+	for page := 0; page < 100; page++ {
+		resp := list_page(t, adapter, ListRequest{Path: dir, Limit: limit, Cursor: cursor})
+		if len(resp.Files) > limit {
+			t.Fatalf("Page %d has %d files, limit %d", page, len(resp.Files), limit)
+		}
+		all = append(all, resp.Files...)
+		if resp.NextCursor == "" {
+			return all
+		}
+		cursor = resp.NextCursor
+		if between != nil {
+			between(page)
+		}
+	}
+	t.Fatal("Listing did not end")
+	return nil
+}
+
+func TestHandleList_Pages(t *testing.T) {
+	for name, store := range map[string]FileStore{"memory": NewMemoryStore(), "disk": must_disk_store(t)} {
+		t.Run(name, func(t *testing.T) {
+			adapter := NewAdapterWithStore(store)
+			var want []string
+			for i := 0; i < 7; i++ {
+				p := fmt.Sprintf("/logs/%02d.log", i)
+				store.Write(p, "x")
+				want = append(want, p)
+			}
+			store.Write("/logs-archive/old.log", "x")
+			store.Write("/other/a.txt", "x")
+
+			if got := list_all(t, adapter, "/logs", 3, nil); !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+			if got := list_page(t, adapter, ListRequest{Path: "/"}); len(got.Files) != 9 || got.NextCursor != "" {
+				t.Errorf("Expected all 9 files on one page, got %+v", got)
+			}
+			if got := list_page(t, adapter, ListRequest{Path: "/missing"}); len(got.Files) != 0 {
+				t.Errorf("Expected an empty listing, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestHandleList_ConcurrentWrites(t *testing.T) {
+	store := NewMemoryStore()
+	adapter := NewAdapterWithStore(store)
+	for i := 0; i < 10; i++ {
+		store.Write(fmt.Sprintf("/data/%02d", i), "x")
+	}
+
+	got := list_all(t, adapter, "/data", 3, func(page int) {
+		// changes both behind and ahead of the cursor between pages
+		store.Write(fmt.Sprintf("/data/%02d-new", page), "x")
+		store.Delete(fmt.Sprintf("/data/%02d", 9-page))
+	})
+
+	seen := make(map[string]bool)
+	for _, p := range got {
+		if seen[p] {
+			t.Errorf("%s listed twice", p)
+		}
+		seen[p] = true
+	}
+	// files present for the whole listing must all be there
+	for i := 0; i < 6; i++ {
+		if p := fmt.Sprintf("/data/%02d", i); !seen[p] {
+			t.Errorf("%s was skipped", p)
+		}
+	}
+}
+
+func TestHandleList_InvalidRequest(t *testing.T) {
+	adapter := NewAdapter()
+	for _, req := range []ListRequest{{}, {Path: "/", Limit: -1}, {Path: "/", Limit: 1001}, {Path: "/", Cursor: "not base64!"}} {
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		adapter.HandleList(w, httptest.NewRequest("POST", "/list", bytes.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %+v, got %d", req, w.Code)
+		}
+	}
+}
+
+func must_disk_store(t *testing.T) *DiskStore {
+	t.Helper()
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskStore() error = %v", err)
+	}
+	return store
+}
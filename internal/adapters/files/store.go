@@ -0,0 +1,205 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrNotFound = errors.New("file not found")
+
+// where the adapter keeps file contents, keyed by request path
+type FileStore interface {
+	Read(path string) (string, error)
+	Write(path, content string) error
+	Delete(path string) error
+	// paths of the files under dir, recursively, sorted
+	List(dir string) ([]string, error)
+	// atomically replace path's content with what fn returns for the
+	// current one (exists is false for a missing file). nothing is
+	// written if fn fails, and its error is returned
+	Update(path string, fn func(current string, exists bool) (string, error)) error
+}
+
+// whether file p is inside directory dir, comparing whole segments
+func under_dir(p, dir string) bool {
+	p, dir = path.Clean("/"+p), path.Clean("/"+dir)
+	return dir == "/" || strings.HasPrefix(p, dir+"/")
+}
+
+// in-memory store, contents are lost on restart
+type MemoryStore struct {
+	mu    sync.RWMutex
+	files map[string]string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{files: make(map[string]string)}
+}
+
+func (s *MemoryStore) Read(p string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	content, ok := s.files[p]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return content, nil
+}
+
+func (s *MemoryStore) Write(p, content string) error {
+	s.mu.Lock()
+	s.files[p] = content
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Delete(p string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[p]; !ok {
+		return ErrNotFound
+	}
+	delete(s.files, p)
+	return nil
+}
+
+func (s *MemoryStore) Update(p string, fn func(string, bool) (string, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, exists := s.files[p]
+	content, err := fn(current, exists)
+	if err != nil {
+		return err
+	}
+	s.files[p] = content
+	return nil
+}
+
+func (s *MemoryStore) List(dir string) ([]string, error) {
+	s.mu.RLock()
+	out := []string{}
+	for p := range s.files {
+		if under_dir(p, dir) {
+			out = append(out, p)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Strings(out)
+	return out, nil
+}
+
+// store backed by a directory, request paths map to files under root
+type DiskStore struct {
+	root string
+
+	// serializes changes so Update's read and write can't interleave
+	// with another change
+	mu sync.Mutex
+}
+
+func NewDiskStore(root string) (*DiskStore, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create files dir: %w", err)
+	}
+	return &DiskStore{root: root}, nil
+}
+
+// file for a request path. cleaning against "/" first keeps ".." from
+// escaping root
+func (s *DiskStore) file(p string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+p)))
+}
+
+func (s *DiskStore) Read(p string) (string, error) {
+	data, err := os.ReadFile(s.file(p))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	return string(data), err
+}
+
+func (s *DiskStore) Write(p, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(p, content)
+}
+
+func (s *DiskStore) Update(p string, fn func(string, bool) (string, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.file(p))
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	content, err := fn(string(data), exists)
+	if err != nil {
+		return err
+	}
+	return s.write(p, content)
+}
+
+// write to a temp file and rename so readers never see a partial file.
+// caller must hold s.mu
+func (s *DiskStore) write(p, content string) error {
+	name := s.file(p)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *DiskStore) Delete(p string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.file(p))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// temp files of writes in progress aren't listed
+func (s *DiskStore) List(dir string) ([]string, error) {
+	base := s.file(dir)
+	out := []string{}
+	err := filepath.WalkDir(base, func(name string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// dir itself being a file lists nothing, like MemoryStore
+		if d.IsDir() || name == base || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, name)
+		if err != nil {
+			return err
+		}
+		out = append(out, "/"+filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(out)
+	return out, err
+}
@@ -0,0 +1,24 @@
+package payments
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"aegis-gateway/pkg/currency"
+)
+
+// reject amounts finer than the currency's minor unit, e.g. 100.5 JPY or
+// 10.001 USD
+func check_minor_units(amount float64, code string) error {
+	exp := currency.Exponent(code)
+	scaled := amount * currency.Scale(code)
+	// tolerance for binary floats like 19.99*100 = 1998.9999999999998
+	if math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+		if exp == 0 {
+			return fmt.Errorf("Amount %v has decimals, but %s has no minor unit", amount, strings.ToUpper(code))
+		}
+		return fmt.Errorf("Amount %v has more than %d decimal places allowed for %s", amount, exp, strings.ToUpper(code))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package payments
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aegis-gateway/pkg/apierror"
+)
+
+func TestHandleCreate_MinorUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		currency string
+		reason   string
+	}{
+		{name: "USD cents", amount: 19.99, currency: "USD"},
+		{name: "USD whole", amount: 20, currency: "USD"},
+		{name: "USD fractional cent", amount: 10.001, currency: "USD", reason: "Amount 10.001 has more than 2 decimal places allowed for USD"},
+		{name: "JPY whole", amount: 100, currency: "JPY"},
+		{name: "JPY fractional", amount: 100.5, currency: "JPY", reason: "Amount 100.5 has decimals, but JPY has no minor unit"},
+		{name: "lowercase code", amount: 100.5, currency: "jpy", reason: "Amount 100.5 has decimals, but JPY has no minor unit"},
+		{name: "KWD fils", amount: 1.234, currency: "KWD"},
+		{name: "unknown currency", amount: 5.25, currency: "XYZ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter := NewAdapter()
+			bodyBytes, _ := json.Marshal(CreateRequest{Amount: tt.amount, Currency: tt.currency, VendorID: "V123"})
+			w := httptest.NewRecorder()
+			adapter.HandleCreate(w, httptest.NewRequest("POST", "/create", bytes.NewReader(bodyBytes)))
+
+			if tt.reason == "" {
+				if w.Code != http.StatusOK {
+					t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+				}
+				return
+			}
+			var resp apierror.Response
+			json.NewDecoder(w.Body).Decode(&resp)
+			if w.Code != http.StatusBadRequest || resp.Reason != tt.reason {
+				t.Errorf("Expected 400 %q, got %d %+v", tt.reason, w.Code, resp)
+			}
+		})
+	}
+}
+
+func createPaymentIn(t *testing.T, adapter *Adapter, amount float64, code string) string {
+	t.Helper()
+	bodyBytes, _ := json.Marshal(CreateRequest{Amount: amount, Currency: code, VendorID: "V123"})
+	w := httptest.NewRecorder()
+	adapter.HandleCreate(w, httptest.NewRequest("POST", "/create", bytes.NewReader(bodyBytes)))
+	var resp CreateResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to create %v %s payment: %s", amount, code, w.Body.String())
+	}
+	return resp.PaymentID
+}
+
+// refund balances are kept in the currency's own minor units
+func TestHandleRefund_MinorUnits(t *testing.T) {
+	adapter := NewAdapter()
+
+	// a fils left over is still a balance
+	kwd := createPaymentIn(t, adapter, 1.005, "KWD")
+	if w, resp := refund(adapter, kwd, 1.004); w.Code != http.StatusOK || adapter.payments[kwd].Status != "partially_refunded" {
+		t.Errorf("Expected 0.001 KWD to remain, got %d %+v status %s", w.Code, resp, adapter.payments[kwd].Status)
+	}
+	w, _ := refund(adapter, kwd, 0.002)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "exceeds remaining balance 0.001") {
+		t.Errorf("Expected a refund beyond 0.001 KWD to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+
+	// no fractional yen, however they'd round
+	jpy := createPaymentIn(t, adapter, 100, "JPY")
+	if w, _ := refund(adapter, jpy, 100.4); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a fractional JPY refund to be rejected, got %d", w.Code)
+	}
+	if w, resp := refund(adapter, jpy, 0); w.Code != http.StatusOK || resp.Amount != 100 {
+		t.Errorf("Expected the full 100 JPY to be refundable, got %d %+v", w.Code, resp)
+	}
+}
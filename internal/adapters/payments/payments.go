@@ -3,13 +3,21 @@ package payments
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"sync"
 	"time"
 
+	"aegis-gateway/pkg/apierror"
+	"aegis-gateway/pkg/currency"
+	"aegis-gateway/pkg/telemetry"
+
 	"github.com/google/uuid"
 )
 
+// header the gateway forwards the calling agent's ID in
+const AgentIDHeader = "X-Agent-ID"
+
 type CreateRequest struct {
 	Amount    float64 `json:"amount"`
 	Currency  string  `json:"currency"`
@@ -26,11 +34,36 @@ type CreateResponse struct {
 
 type RefundRequest struct {
 	PaymentID string `json:"payment_id"`
-	Reason    string `json:"reason,omitempty"`
+	// partial refund amount, omitted refunds the remaining balance
+	Amount float64 `json:"amount,omitempty"`
+	Reason string  `json:"reason,omitempty"`
 }
 
 type RefundResponse struct {
-	RefundID  string `json:"refund_id"`
+	RefundID  string  `json:"refund_id"`
+	PaymentID string  `json:"payment_id"`
+	Amount    float64 `json:"amount"`
+	Remaining float64 `json:"remaining"`
+	Status    string  `json:"status"`
+}
+
+// what GET /payments/{id} reports, e.g. for the gateway's owns_payment
+// condition
+type PaymentResponse struct {
+	PaymentID string  `json:"payment_id"`
+	AgentID   string  `json:"agent_id"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
+	Refunded  float64 `json:"refunded"`
+	Status    string  `json:"status"`
+}
+
+type VoidRequest struct {
+	PaymentID string `json:"payment_id"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type VoidResponse struct {
 	PaymentID string `json:"payment_id"`
 	Status    string `json:"status"`
 }
@@ -39,32 +72,40 @@ type Adapter struct {
 	mu       sync.RWMutex
 	payments map[string]CreateResponse
 	refunds  map[string]RefundResponse
+	refunded map[string]float64 // payment id -> total refunded
+	owners   map[string]string  // payment id -> creating agent
 }
 
 func NewAdapter() *Adapter {
 	return &Adapter{
 		payments: make(map[string]CreateResponse),
 		refunds:  make(map[string]RefundResponse),
+		refunded: make(map[string]float64),
+		owners:   make(map[string]string),
 	}
 }
 
 func (a *Adapter) HandleCreate(w http.ResponseWriter, r *http.Request) {
 	var req CreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf(`{"error":"InvalidRequest","message":"%s"}`, err.Error()), http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, err.Error())
 		return
 	}
 
 	if req.Amount <= 0 {
-		http.Error(w, `{"error":"InvalidRequest","message":"Amount must be positive"}`, http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, "Amount must be positive")
 		return
 	}
 	if req.Currency == "" {
-		http.Error(w, `{"error":"InvalidRequest","message":"Currency is required"}`, http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, "Currency is required")
 		return
 	}
 	if req.VendorID == "" {
-		http.Error(w, `{"error":"InvalidRequest","message":"VendorID is required"}`, http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, "VendorID is required")
+		return
+	}
+	if err := check_minor_units(req.Amount, req.Currency); err != nil {
+		apierror.Write(w, apierror.InvalidRequest, err.Error())
 		return
 	}
 
@@ -77,6 +118,7 @@ func (a *Adapter) HandleCreate(w http.ResponseWriter, r *http.Request) {
 
 	a.mu.Lock()
 	a.payments[resp.PaymentID] = resp
+	a.owners[resp.PaymentID] = r.Header.Get(AgentIDHeader)
 	a.mu.Unlock()
 
 	w.Header().Set("Content-Type", "application/json")
@@ -86,34 +128,126 @@ func (a *Adapter) HandleCreate(w http.ResponseWriter, r *http.Request) {
 func (a *Adapter) HandleRefund(w http.ResponseWriter, r *http.Request) {
 	var req RefundRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf(`{"error":"InvalidRequest","message":"%s"}`, err.Error()), http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, err.Error())
 		return
 	}
 
 	if req.PaymentID == "" {
-		http.Error(w, `{"error":"InvalidRequest","message":"PaymentID is required"}`, http.StatusBadRequest)
+		apierror.Write(w, apierror.InvalidRequest, "PaymentID is required")
+		return
+	}
+	if req.Amount < 0 {
+		apierror.Write(w, apierror.InvalidRequest, "Amount must be positive")
 		return
 	}
 
-	a.mu.RLock()
-	_, exists := a.payments[req.PaymentID]
-	a.mu.RUnlock()
-
+	a.mu.Lock()
+	payment, exists := a.payments[req.PaymentID]
 	if !exists {
-		http.Error(w, `{"error":"NotFound","message":"Payment not found"}`, http.StatusNotFound)
+		a.mu.Unlock()
+		apierror.Write(w, apierror.NotFound, "Payment not found")
+		return
+	}
+	if payment.Status == "voided" {
+		a.mu.Unlock()
+		apierror.Write(w, apierror.Conflict, "Payment has been voided")
+		return
+	}
+
+	if err := check_minor_units(req.Amount, payment.Currency); err != nil {
+		a.mu.Unlock()
+		apierror.Write(w, apierror.InvalidRequest, err.Error())
 		return
 	}
 
+	remaining := payment.Amount - a.refunded[req.PaymentID]
+	amount := req.Amount
+	if amount == 0 {
+		amount = remaining
+	}
+	// compare in the currency's minor units so float sums like 0.1+0.2
+	// don't overshoot
+	scale, exp := currency.Scale(payment.Currency), currency.Exponent(payment.Currency)
+	if amount <= 0 || math.Round(amount*scale) > math.Round(remaining*scale) {
+		a.mu.Unlock()
+		apierror.Write(w, apierror.InvalidRequest, fmt.Sprintf("Refund of %.*f exceeds remaining balance %.*f", exp, amount, exp, remaining))
+		return
+	}
+	remaining -= amount
+
 	resp := RefundResponse{
 		RefundID:  uuid.New().String(),
 		PaymentID: req.PaymentID,
+		Amount:    amount,
+		Remaining: remaining,
 		Status:    "refunded",
 	}
+	payment.Status = "refunded"
+	if math.Round(remaining*scale) > 0 {
+		payment.Status = "partially_refunded"
+	}
+	a.refunded[req.PaymentID] += amount
+	a.payments[req.PaymentID] = payment
+	a.refunds[resp.RefundID] = resp
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// void cancels a payment that hasn't been captured or refunded yet
+func (a *Adapter) HandleVoid(w http.ResponseWriter, r *http.Request) {
+	var req VoidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	if req.PaymentID == "" {
+		apierror.Write(w, apierror.InvalidRequest, "PaymentID is required")
+		return
+	}
 
 	a.mu.Lock()
-	a.refunds[resp.RefundID] = resp
+	payment, exists := a.payments[req.PaymentID]
+	if !exists {
+		a.mu.Unlock()
+		apierror.Write(w, apierror.NotFound, "Payment not found")
+		return
+	}
+	if payment.Status != "created" {
+		a.mu.Unlock()
+		apierror.Write(w, apierror.Conflict, fmt.Sprintf("Cannot void a %s payment", payment.Status))
+		return
+	}
+	payment.Status = "voided"
+	a.payments[req.PaymentID] = payment
 	a.mu.Unlock()
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(VoidResponse{PaymentID: req.PaymentID, Status: "voided"})
+}
+
+// look up one payment, including the agent that created it
+func (a *Adapter) HandleGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	a.mu.RLock()
+	payment, exists := a.payments[id]
+	resp := PaymentResponse{
+		PaymentID: id,
+		AgentID:   a.owners[id],
+		Amount:    payment.Amount,
+		Currency:  payment.Currency,
+		Refunded:  a.refunded[id],
+		Status:    payment.Status,
+	}
+	a.mu.RUnlock()
+	if !exists {
+		apierror.Write(w, apierror.NotFound, "Payment not found")
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
@@ -127,11 +261,13 @@ func (a *Adapter) Start(addr string) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/create", a.HandleCreate)
 	mux.HandleFunc("/refund", a.HandleRefund)
+	mux.HandleFunc("/void", a.HandleVoid)
+	mux.HandleFunc("GET /payments/{id}", a.HandleGet)
 	mux.HandleFunc("/health", a.HandleHealth)
 
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      mux,
+		Handler:      telemetry.TraceHandler("payments-adapter", mux),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"aegis-gateway/pkg/apierror"
 )
 
 func TestHandleCreate_Success(t *testing.T) {
@@ -199,3 +202,210 @@ func TestHealthEndpoint(t *testing.T) {
 		t.Errorf("Expected status healthy, got %s", resp["status"])
 	}
 }
+
+// creates a payment and returns its ID
+func createPayment(adapter *Adapter) string {
+	bodyBytes, _ := json.Marshal(CreateRequest{Amount: 1000.0, Currency: "USD", VendorID: "V123"})
+	w := httptest.NewRecorder()
+	adapter.HandleCreate(w, httptest.NewRequest("POST", "/create", bytes.NewReader(bodyBytes)))
+
+	var resp CreateResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return resp.PaymentID
+}
+
+func TestHandleVoid_Success(t *testing.T) {
+	adapter := NewAdapter()
+	paymentID := createPayment(adapter)
+
+	bodyBytes, _ := json.Marshal(VoidRequest{PaymentID: paymentID})
+	w := httptest.NewRecorder()
+	adapter.HandleVoid(w, httptest.NewRequest("POST", "/void", bytes.NewReader(bodyBytes)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var resp VoidResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.PaymentID != paymentID || resp.Status != "voided" {
+		t.Errorf("Unexpected void response: %+v", resp)
+	}
+
+	// voided payments can't be refunded or voided again
+	refundBytes, _ := json.Marshal(RefundRequest{PaymentID: paymentID})
+	w = httptest.NewRecorder()
+	adapter.HandleRefund(w, httptest.NewRequest("POST", "/refund", bytes.NewReader(refundBytes)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 refunding a voided payment, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	adapter.HandleVoid(w, httptest.NewRequest("POST", "/void", bytes.NewReader(bodyBytes)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 voiding twice, got %d", w.Code)
+	}
+}
+
+func TestHandleVoid_RefundedPayment(t *testing.T) {
+	adapter := NewAdapter()
+	paymentID := createPayment(adapter)
+
+	refundBytes, _ := json.Marshal(RefundRequest{PaymentID: paymentID})
+	w := httptest.NewRecorder()
+	adapter.HandleRefund(w, httptest.NewRequest("POST", "/refund", bytes.NewReader(refundBytes)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected refund to succeed, got %d", w.Code)
+	}
+
+	bodyBytes, _ := json.Marshal(VoidRequest{PaymentID: paymentID})
+	w = httptest.NewRecorder()
+	adapter.HandleVoid(w, httptest.NewRequest("POST", "/void", bytes.NewReader(bodyBytes)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
+}
+
+func TestHandleVoid_NotFound(t *testing.T) {
+	adapter := NewAdapter()
+
+	bodyBytes, _ := json.Marshal(VoidRequest{PaymentID: "missing"})
+	w := httptest.NewRecorder()
+	adapter.HandleVoid(w, httptest.NewRequest("POST", "/void", bytes.NewReader(bodyBytes)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func refund(adapter *Adapter, paymentID string, amount float64) (*httptest.ResponseRecorder, RefundResponse) {
+	bodyBytes, _ := json.Marshal(RefundRequest{PaymentID: paymentID, Amount: amount})
+	w := httptest.NewRecorder()
+	adapter.HandleRefund(w, httptest.NewRequest("POST", "/refund", bytes.NewReader(bodyBytes)))
+
+	var resp RefundResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestHandleRefund_Full(t *testing.T) {
+	adapter := NewAdapter()
+	paymentID := createPayment(adapter)
+
+	w, resp := refund(adapter, paymentID, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if resp.Amount != 1000 || resp.Remaining != 0 {
+		t.Errorf("Expected full refund of 1000, got %+v", resp)
+	}
+	if status := adapter.payments[paymentID].Status; status != "refunded" {
+		t.Errorf("Expected payment status refunded, got %s", status)
+	}
+
+	// nothing left to refund
+	if w, _ := refund(adapter, paymentID, 0); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 refunding twice, got %d", w.Code)
+	}
+}
+
+func TestHandleRefund_Partial(t *testing.T) {
+	adapter := NewAdapter()
+	paymentID := createPayment(adapter)
+
+	w, resp := refund(adapter, paymentID, 300)
+	if w.Code != http.StatusOK || resp.Remaining != 700 {
+		t.Fatalf("Expected first partial refund to leave 700, got %d %+v", w.Code, resp)
+	}
+	if status := adapter.payments[paymentID].Status; status != "partially_refunded" {
+		t.Errorf("Expected payment status partially_refunded, got %s", status)
+	}
+
+	w, resp = refund(adapter, paymentID, 700)
+	if w.Code != http.StatusOK || resp.Remaining != 0 {
+		t.Fatalf("Expected second partial refund to clear the balance, got %d %+v", w.Code, resp)
+	}
+	if status := adapter.payments[paymentID].Status; status != "refunded" {
+		t.Errorf("Expected payment status refunded, got %s", status)
+	}
+}
+
+func TestHandleRefund_ExceedsRemaining(t *testing.T) {
+	adapter := NewAdapter()
+	paymentID := createPayment(adapter)
+
+	if w, _ := refund(adapter, paymentID, 600); w.Code != http.StatusOK {
+		t.Fatalf("Expected first refund to succeed, got %d", w.Code)
+	}
+	w, _ := refund(adapter, paymentID, 400.01)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "exceeds remaining balance 400.00") {
+		t.Errorf("Unexpected error body: %s", w.Body.String())
+	}
+
+	// the rejected refund didn't count
+	if w, resp := refund(adapter, paymentID, 0); w.Code != http.StatusOK || resp.Amount != 400 {
+		t.Errorf("Expected remaining 400 to be refundable, got %d %+v", w.Code, resp)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	adapter := NewAdapter()
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		status  int
+		code    string
+	}{
+		{"invalid json", adapter.HandleCreate, "invalid json", http.StatusBadRequest, "AEGIS-400-REQUEST"},
+		{"missing currency", adapter.HandleCreate, `{"amount":10,"vendor_id":"V1"}`, http.StatusBadRequest, "AEGIS-400-REQUEST"},
+		{"unknown payment", adapter.HandleVoid, `{"payment_id":"nope"}`, http.StatusNotFound, "AEGIS-404-NOT-FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, httptest.NewRequest("POST", "/", strings.NewReader(tt.body)))
+			if w.Code != tt.status || w.Header().Get("Content-Type") != "application/json" {
+				t.Fatalf("Expected %d with a JSON body, got %d %q", tt.status, w.Code, w.Header().Get("Content-Type"))
+			}
+			var resp apierror.Response
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.Code != tt.code || resp.Error == "" || resp.Reason == "" {
+				t.Errorf("Expected code %s with error and reason, got %+v", tt.code, resp)
+			}
+		})
+	}
+}
+
+func TestHandleGet_RecordsOwner(t *testing.T) {
+	adapter := NewAdapter()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /payments/{id}", adapter.HandleGet)
+
+	createBodyBytes, _ := json.Marshal(CreateRequest{Amount: 500.0, Currency: "USD", VendorID: "V123"})
+	createReq := httptest.NewRequest("POST", "/create", bytes.NewReader(createBodyBytes))
+	createReq.Header.Set(AgentIDHeader, "finance-agent")
+	createW := httptest.NewRecorder()
+	adapter.HandleCreate(createW, createReq)
+
+	var createResp CreateResponse
+	json.NewDecoder(createW.Body).Decode(&createResp)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/payments/"+createResp.PaymentID, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var resp PaymentResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.AgentID != "finance-agent" || resp.Amount != 500.0 || resp.Status != "created" {
+		t.Errorf("Unexpected payment %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/payments/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
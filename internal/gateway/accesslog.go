@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// one line per HTTP request, written for every request the gateway
+// answers, including 404s and requests rejected before policy evaluation.
+// unlike the audit log it records traffic, not decisions
+type AccessLogEntry struct {
+	Time       string  `json:"time"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	DurationMs float64 `json:"duration_ms"`
+	RemoteAddr string  `json:"remote_addr"`
+	RequestID  string  `json:"request_id,omitempty"`
+	AgentID    string  `json:"agent_id,omitempty"`
+}
+
+// where access log lines go, see Config.AccessLog
+type accessLog struct {
+	dest string
+	mu   sync.Mutex
+	w    io.Writer
+	c    io.Closer
+}
+
+// open the access_log destination: "stdout", "stderr" or a file path,
+// appended to. "" turns the access log off
+func open_access_log(dest string) (*accessLog, error) {
+	switch dest {
+	case "":
+		return nil, nil
+	case "stdout":
+		return &accessLog{dest: dest, w: os.Stdout}, nil
+	case "stderr":
+		return &accessLog{dest: dest, w: os.Stderr}, nil
+	}
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("access_log: %w", err)
+	}
+	return &accessLog{dest: dest, w: f, c: f}, nil
+}
+
+// whole lines only, so concurrent requests never interleave
+func (l *accessLog) write(e AccessLogEntry) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(line); err != nil {
+		fmt.Printf("ERROR: access log: %v\n", err)
+	}
+}
+
+func (l *accessLog) close() {
+	if l == nil || l.c == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.c.Close()
+}
+
+// whether dest differs from the current access log's
+func (g *Gateway) access_log_changed(dest string) bool {
+	prev := g.accessLog.Load()
+	return !(prev != nil && prev.dest == dest || prev == nil && dest == "")
+}
+
+// swap in l, closing the old access log after it's been replaced
+func (g *Gateway) swap_access_log(l *accessLog) {
+	g.accessLog.Swap(l).close()
+}
+
+// wraps the whole router rather than being a mux middleware, so requests
+// no route matches are logged too
+func (g *Gateway) access_log_middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := g.accessLog.Load()
+		if l == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rec := &accessRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		l.write(AccessLogEntry{
+			Time:       start.UTC().Format(time.RFC3339Nano),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     rec.status,
+			Bytes:      rec.bytes,
+			DurationMs: float64(time.Since(start).Microseconds()) / 1000.0,
+			RemoteAddr: r.RemoteAddr,
+			RequestID:  w.Header().Get(requestIDHeader),
+			AgentID:    r.Header.Get("X-Agent-ID"),
+		})
+	})
+}
+
+// status and body size of a response on its way out
+type accessRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (a *accessRecorder) WriteHeader(status int) {
+	if a.status == 0 {
+		a.status = status
+	}
+	a.ResponseWriter.WriteHeader(status)
+}
+
+func (a *accessRecorder) Write(p []byte) (int, error) {
+	if a.status == 0 {
+		a.status = http.StatusOK
+	}
+	n, err := a.ResponseWriter.Write(p)
+	a.bytes += int64(n)
+	return n, err
+}
+
+// for http.ResponseController
+func (a *accessRecorder) Unwrap() http.ResponseWriter {
+	return a.ResponseWriter
+}
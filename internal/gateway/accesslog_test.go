@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"aegis-gateway/pkg/telemetry"
+
+	_ "github.com/lib/pq"
+)
+
+func read_access_log(t *testing.T, path string) []AccessLogEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open access log: %v", err)
+	}
+	defer f.Close()
+	var out []AccessLogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e AccessLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid access log line %q: %v", scanner.Text(), err)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func TestAccessLog(t *testing.T) {
+	gw, mockURL := setupTestGateway(t)
+	defer gw.Close()
+	logPath := filepath.Join(t.TempDir(), "access.log")
+	if err := gw.SetConfig(Config{Adapters: map[string]string{"payments": mockURL}, AccessLog: logPath}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(requestIDHeader, "req-"+method)
+		req.RemoteAddr = "10.0.0.7:51234"
+		w := httptest.NewRecorder()
+		gw.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+	ok := send("POST", "/tools/payments/create", `{"amount": 100}`)
+	if ok.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", ok.Code)
+	}
+	// never reaches a handler, let alone policy evaluation
+	if w := send("PUT", "/nowhere", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status 405, got %d", w.Code)
+	}
+
+	entries := read_access_log(t, logPath)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 access log lines, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Method != "POST" || e.Path != "/tools/payments/create" || e.Status != http.StatusOK {
+		t.Errorf("Unexpected request fields: %+v", e)
+	}
+	if e.Bytes != int64(ok.Body.Len()) || e.Bytes == 0 {
+		t.Errorf("Expected %d bytes, got %d", ok.Body.Len(), e.Bytes)
+	}
+	if e.RemoteAddr != "10.0.0.7:51234" || e.RequestID != "req-POST" || e.AgentID != "test-agent" {
+		t.Errorf("Unexpected client fields: %+v", e)
+	}
+	if e.Time == "" || e.DurationMs < 0 {
+		t.Errorf("Expected a timestamp and duration, got %+v", e)
+	}
+	if e := entries[1]; e.Method != "PUT" || e.Path != "/nowhere" || e.Status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected the 405 to be logged, got %+v", e)
+	}
+
+	// turned off by a config without access_log
+	if err := gw.SetConfig(Config{}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+	send("POST", "/tools/payments/create", `{"amount": 100}`)
+	if got := len(read_access_log(t, logPath)); got != 2 {
+		t.Errorf("Expected no more lines once off, got %d", got)
+	}
+}
+
+func TestAccessLogInvalidDestination(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	dest := filepath.Join(t.TempDir(), "missing", "access.log")
+	if err := gw.SetConfig(Config{AccessLog: dest}); err == nil {
+		t.Fatal("Expected an error for an unwritable access_log")
+	}
+	if gw.cfg().AccessLog != "" || gw.accessLog.Load() != nil {
+		t.Error("Expected the current config to stay active")
+	}
+}
+
+type stubAuditSink struct{}
+
+func (stubAuditSink) Write(telemetry.AuditLog) error { return nil }
+func (stubAuditSink) Close() error                   { return nil }
+
+func TestAccessLogInvalidDestination_KeepsAuditSink(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	current := stubAuditSink{}
+	telemetry.SetAuditSink(current)
+	defer telemetry.SetAuditSink(nil)
+
+	// the audit_sql settings are fine, but the access log can't be opened:
+	// the whole config is rejected before the audit sink is swapped
+	err := gw.SetConfig(Config{
+		AuditSQL:  &telemetry.SQLSinkConfig{Driver: "postgres", DSN: "postgres://aegis@localhost/audit", Table: "audit_log"},
+		AccessLog: filepath.Join(t.TempDir(), "missing", "access.log"),
+	})
+	if err == nil {
+		t.Fatal("Expected an error for an unwritable access_log")
+	}
+	if got := telemetry.SetAuditSink(current); got != current {
+		t.Errorf("Expected the current audit sink to stay installed, got %T", got)
+	}
+	if gw.cfg().AuditSQL != nil {
+		t.Error("Expected the current config to stay active")
+	}
+}
@@ -0,0 +1,149 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"aegis-gateway/pkg/apierror"
+)
+
+// credentials for the admin routes (/policies*, /deadletters*,
+// /config/reload, /stats, /metrics). tool routes keep their per-agent API
+// keys and /health stays open for probes. with nothing set the admin
+// routes are open, as before. with several methods set, passing any one
+// of them is enough
+type AdminAuth struct {
+	// static bearer token: Authorization: Bearer <token>
+	Token string
+
+	// HTTP basic credentials, both required
+	Username string
+	Password string
+
+	// PEM bundle of CAs for client certificates. admin requests may then
+	// authenticate with a certificate signed by one of them; the gateway
+	// must be served with StartTLS/ServeTLS for this
+	ClientCAFile string
+}
+
+// admin credentials as checked per request; hashes so comparisons don't
+// leak the length
+type adminAuth struct {
+	token    []byte
+	username []byte
+	password []byte
+	mtls     bool
+}
+
+func (a *adminAuth) enabled() bool {
+	return a != nil && (a.token != nil || a.username != nil || a.mtls)
+}
+
+func secret_hash(s string) []byte {
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
+
+// protect the admin routes. call before Start; not safe to change while
+// serving
+func (g *Gateway) SetAdminAuth(cfg AdminAuth) error {
+	if (cfg.Username == "") != (cfg.Password == "") {
+		return fmt.Errorf("admin auth: username and password must be set together")
+	}
+	a := &adminAuth{}
+	if cfg.Token != "" {
+		a.token = secret_hash(cfg.Token)
+	}
+	if cfg.Username != "" {
+		a.username, a.password = secret_hash(cfg.Username), secret_hash(cfg.Password)
+	}
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("admin auth: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("admin auth: no certificates in %s", cfg.ClientCAFile)
+		}
+		// tool clients don't need a certificate, so only verify one if sent
+		g.server.TLSConfig = &tls.Config{
+			ClientAuth: tls.VerifyClientCertIfGiven,
+			ClientCAs:  pool,
+			MinVersion: tls.VersionTLS12,
+		}
+		a.mtls = true
+	}
+	g.adminAuth = a
+	return nil
+}
+
+// serve with TLS, see ServeTLS
+func (g *Gateway) StartTLS(addr, certFile, keyFile string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Gateway listening on %s (TLS)\n", addr)
+	return g.ServeTLS(l, certFile, keyFile)
+}
+
+// serve TLS on l until Shutdown. client certificates are verified when
+// AdminAuth.ClientCAFile is set
+func (g *Gateway) ServeTLS(l net.Listener, certFile, keyFile string) error {
+	if err := g.server.ServeTLS(l, certFile, keyFile); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (g *Gateway) admin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := g.adminAuth
+		if !a.enabled() || a.allows(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch {
+		case a.token != nil:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		case a.username != nil:
+			w.Header().Set("WWW-Authenticate", `Basic realm="aegis-admin"`)
+		}
+		write_error(w, apierror.Unauthorized, "Admin credentials required")
+	})
+}
+
+func (g *Gateway) admin_func(next http.HandlerFunc) http.Handler {
+	return g.admin(next)
+}
+
+func (a *adminAuth) allows(r *http.Request) bool {
+	if a.mtls && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return true
+	}
+	auth := r.Header.Get("Authorization")
+	if a.token != nil {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok && subtle.ConstantTimeCompare(secret_hash(token), a.token) == 1 {
+			return true
+		}
+	}
+	if a.username != nil {
+		if user, pass, ok := r.BasicAuth(); ok {
+			userOK := subtle.ConstantTimeCompare(secret_hash(user), a.username)
+			passOK := subtle.ConstantTimeCompare(secret_hash(pass), a.password)
+			if userOK&passOK == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
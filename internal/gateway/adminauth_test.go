@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func adminStatus(gw *Gateway, method, path string, setAuth func(*http.Request)) int {
+	req := httptest.NewRequest(method, path, nil)
+	if setAuth != nil {
+		setAuth(req)
+	}
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAdminAuthToken(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	// open until configured
+	if code := adminStatus(gw, "GET", "/policies", nil); code != http.StatusOK {
+		t.Fatalf("Expected admin routes open by default, got %d", code)
+	}
+	if err := gw.SetAdminAuth(AdminAuth{Token: "admin-s3cret"}); err != nil {
+		t.Fatalf("SetAdminAuth() error = %v", err)
+	}
+
+	bearer := func(token string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+	routes := []struct{ method, path string }{
+		{"GET", "/policies"},
+		{"POST", "/policies/reload"},
+		{"GET", "/policies/status"},
+		{"GET", "/deadletters"},
+		{"GET", "/stats"},
+		{"GET", "/metrics"},
+	}
+	for _, rt := range routes {
+		if code := adminStatus(gw, rt.method, rt.path, nil); code != http.StatusUnauthorized {
+			t.Errorf("%s %s without a token: expected 401, got %d", rt.method, rt.path, code)
+		}
+		if code := adminStatus(gw, rt.method, rt.path, bearer("wrong")); code != http.StatusUnauthorized {
+			t.Errorf("%s %s with a wrong token: expected 401, got %d", rt.method, rt.path, code)
+		}
+		if code := adminStatus(gw, rt.method, rt.path, bearer("admin-s3cret")); code != http.StatusOK {
+			t.Errorf("%s %s with the token: expected 200, got %d", rt.method, rt.path, code)
+		}
+	}
+
+	// probes and tool routes don't need admin credentials
+	if code := adminStatus(gw, "GET", "/health", nil); code != http.StatusOK {
+		t.Errorf("Expected /health to stay open, got %d", code)
+	}
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{"amount":100}`)))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected tool route unaffected by admin auth, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAdminAuthBasic(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	if err := gw.SetAdminAuth(AdminAuth{Username: "ops"}); err == nil {
+		t.Error("Expected a username without a password to be rejected")
+	}
+	if err := gw.SetAdminAuth(AdminAuth{Username: "ops", Password: "pa55"}); err != nil {
+		t.Fatalf("SetAdminAuth() error = %v", err)
+	}
+
+	basic := func(user, pass string) func(*http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(user, pass) }
+	}
+	if code := adminStatus(gw, "GET", "/policies", basic("ops", "pa55")); code != http.StatusOK {
+		t.Errorf("Expected valid basic credentials to pass, got %d", code)
+	}
+	req := httptest.NewRequest("GET", "/policies", nil)
+	req.SetBasicAuth("ops", "wrong")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("Expected 401 with a challenge, got %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+// a CA and a client certificate it signed, PEM encoded
+func testClientCert(t *testing.T, dir string) (caFile string, client tls.Certificate) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "aegis-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+	caFile = filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0644); err != nil {
+		t.Fatalf("Failed to write CA: %v", err)
+	}
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "ops"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caTmpl, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create client certificate: %v", err)
+	}
+	return caFile, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestAdminAuthClientCert(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	caFile, clientCert := testClientCert(t, t.TempDir())
+	if err := gw.SetAdminAuth(AdminAuth{ClientCAFile: caFile}); err != nil {
+		t.Fatalf("SetAdminAuth() error = %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(gw.router)
+	srv.TLS = gw.server.TLSConfig
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(path string, certs ...tls.Certificate) int {
+		// a fresh transport per call so connections aren't reused across certs
+		tr := srv.Client().Transport.(*http.Transport).Clone()
+		tr.TLSClientConfig.Certificates = certs
+		defer tr.CloseIdleConnections()
+		resp, err := (&http.Client{Transport: tr}).Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := get("/policies", clientCert); code != http.StatusOK {
+		t.Errorf("Expected a trusted client certificate to pass, got %d", code)
+	}
+	if code := get("/policies"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a client certificate, got %d", code)
+	}
+	if code := get("/health"); code != http.StatusOK {
+		t.Errorf("Expected /health reachable without a certificate, got %d", code)
+	}
+}
@@ -0,0 +1,29 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+)
+
+// approver HMAC keys from approver_keys, read from the environment so
+// they stay out of the config file
+//
+//	approver_keys:
+//	  cfo-agent: AEGIS_APPROVER_KEY_CFO
+func (c Config) approver_keys() (map[string][]byte, error) {
+	if c.ApproverKeys == nil {
+		return nil, nil
+	}
+	keys := make(map[string][]byte, len(c.ApproverKeys))
+	for id, env := range c.ApproverKeys {
+		if id == "" || env == "" {
+			return nil, fmt.Errorf("approver_keys: approver and environment variable are required")
+		}
+		key := os.Getenv(env)
+		if key == "" {
+			return nil, fmt.Errorf("approver_keys: %s is not set", env)
+		}
+		keys[id] = []byte(key)
+	}
+	return keys, nil
+}
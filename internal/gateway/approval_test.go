@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"aegis-gateway/internal/policy"
+)
+
+const dualApprovalPolicy = `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          require_dual_approval:
+            threshold: 10000
+`
+
+func TestApproverKeysFromConfig(t *testing.T) {
+	var received map[string]interface{}
+	gw := setupGatewayWithPolicy(t, dualApprovalPolicy, map[string]string{"payments": recordingAdapter(t, &received).URL})
+
+	if err := gw.SetConfig(Config{ApproverKeys: map[string]string{"cfo-agent": "AEGIS_TEST_UNSET_KEY"}}); err == nil {
+		t.Error("Expected approver_keys naming an unset variable to be rejected")
+	}
+
+	t.Setenv("AEGIS_TEST_CFO_KEY", "cfo-secret")
+	if err := gw.SetConfig(Config{ApproverKeys: map[string]string{"cfo-agent": "AEGIS_TEST_CFO_KEY"}}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	params := map[string]interface{}{"amount": 50000.0}
+	token := policy.SignApproval("cfo-agent", []byte("cfo-secret"), time.Now().Add(time.Hour), policy.NewApprovalNonce(), "finance-agent", "payments", "create", params)
+	send := func() int {
+		body, _ := json.Marshal(params)
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(body))
+		req.Header.Set("X-Agent-ID", "finance-agent")
+		req.Header.Set(policy.ApproverHeader, token)
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("Expected an approval signed with the configured key to pass, got %d", code)
+	}
+	if code := send(); code != http.StatusForbidden {
+		t.Errorf("Expected the same approval to be refused the second time, got %d", code)
+	}
+}
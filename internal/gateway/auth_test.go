@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aegis-gateway/internal/policy"
+	"aegis-gateway/pkg/telemetry"
+)
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	policyContent := `version: 1
+agents:
+  - id: keyed-agent
+    api_key_sha256: "` + policy.HashAPIKey("s3cret-key") + `"
+    allow:
+      - tool: payments
+        actions: [create]
+  - id: open-agent
+    allow:
+      - tool: payments
+        actions: [create]
+`
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"payment_id":"p-1"}`))
+	}))
+	defer adapter.Close()
+	gw := setupGatewayWithPolicy(t, policyContent, map[string]string{"payments": adapter.URL})
+
+	send := func(agentID, authorization string) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": 100.0})
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+		req.Header.Set("X-Agent-ID", agentID)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name          string
+		agentID       string
+		authorization string
+		requireKeys   bool
+		wantCode      int
+	}{
+		{"valid key", "keyed-agent", "Bearer s3cret-key", false, http.StatusOK},
+		{"wrong key", "keyed-agent", "Bearer guess", false, http.StatusUnauthorized},
+		{"missing key", "keyed-agent", "", false, http.StatusUnauthorized},
+		{"key without bearer scheme", "keyed-agent", "s3cret-key", false, http.StatusUnauthorized},
+		{"another agent's key", "open-agent", "Bearer s3cret-key", true, http.StatusUnauthorized},
+		{"no key configured, optional", "open-agent", "", false, http.StatusOK},
+		{"no key configured, required", "open-agent", "", true, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw.SetConfig(Config{RequireAPIKeys: tt.requireKeys})
+			w := send(tt.agentID, tt.authorization)
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized {
+				var resp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Error != "Unauthorized" {
+					t.Errorf("Expected Unauthorized error, got %+v", resp)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyNeverLogged(t *testing.T) {
+	policyContent := `version: 1
+agents:
+  - id: keyed-agent
+    api_key_sha256: "` + policy.HashAPIKey("s3cret-key") + `"
+    allow:
+      - tool: payments
+        actions: [create]
+`
+	gw := setupGatewayWithPolicy(t, policyContent, map[string]string{})
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	if err := telemetry.InitTelemetry("aegis-test", logPath); err != nil {
+		t.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+
+	bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": 100.0})
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "keyed-agent")
+	req.Header.Set("Authorization", "Bearer s3cret-key")
+	gw.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	logs, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(logs), "keyed-agent") {
+		t.Fatalf("Expected the audit log to record the agent, got %q", logs)
+	}
+	if strings.Contains(string(logs), "s3cret-key") {
+		t.Error("API key leaked into the audit log")
+	}
+}
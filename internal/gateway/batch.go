@@ -0,0 +1,182 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"aegis-gateway/pkg/apierror"
+
+	"github.com/gorilla/mux"
+)
+
+// items per POST /tools/batch
+const maxBatchItems = 100
+
+type BatchRequest struct {
+	Items []BatchItem `json:"items"`
+
+	// skip the remaining items once one is denied by policy
+	StopOnFirstDenial bool `json:"stop_on_first_denial"`
+}
+
+type BatchItem struct {
+	Tool   string                 `json:"tool"`
+	Action string                 `json:"action"`
+	Params map[string]interface{} `json:"params"`
+}
+
+type BatchItemResult struct {
+	Index  int    `json:"index"`
+	Tool   string `json:"tool"`
+	Action string `json:"action"`
+
+	// what /tools/{tool}/{action} would have answered for the item alone
+	Status   int             `json:"status,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+
+	// X-Aegis-Origin of that answer, gateway or adapter
+	Origin string `json:"origin,omitempty"`
+
+	// not run because an earlier item was denied
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+
+	// stop_on_first_denial cut the batch short
+	Stopped bool `json:"stopped,omitempty"`
+}
+
+// run several tool requests in order. every item goes through the same
+// path as a single request (auth, rate limit, policy, audit record,
+// adapter call) with the batch's headers, so a batch grants nothing its
+// items wouldn't get one by one. items are not rolled back: one failing
+// doesn't undo the ones before it
+func (g *Gateway) handle_batch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Agent-ID") == "" {
+		write_error(w, apierror.MissingHeader, "X-Agent-ID header is required")
+		return
+	}
+	if !json_content_type(r.Header.Get("Content-Type"), g.cfg().RequireContentType) {
+		write_error(w, apierror.UnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, g.cfg().max_request_bytes(""))
+	var req BatchRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		write_error(w, apierror.RequestTooLarge, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+		return
+	}
+	if err != nil {
+		write_error(w, apierror.InvalidRequest, "Request body must be valid JSON")
+		return
+	}
+	if len(req.Items) == 0 {
+		write_error(w, apierror.InvalidRequest, "Batch needs at least one item")
+		return
+	}
+	if len(req.Items) > maxBatchItems {
+		write_error(w, apierror.InvalidRequest, fmt.Sprintf("Batch has %d items, at most %d are allowed", len(req.Items), maxBatchItems))
+		return
+	}
+
+	batchID := request_id(r)
+	w.Header().Set(requestIDHeader, batchID)
+
+	resp := BatchResponse{Results: make([]BatchItemResult, len(req.Items))}
+	for i, item := range req.Items {
+		res := &resp.Results[i]
+		res.Index, res.Tool, res.Action = i, item.Tool, item.Action
+		if resp.Stopped {
+			res.Skipped = true
+			continue
+		}
+		rec := g.run_batch_item(r, fmt.Sprintf("%s-%d", batchID, i), item)
+		res.Status, res.Response = rec.result()
+		res.Origin = response_origin(rec.header)
+		if req.StopOnFirstDenial && res.Status == apierror.PolicyViolation.Status {
+			resp.Stopped = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// one item through handleToolRequest, buffered
+func (g *Gateway) run_batch_item(batch *http.Request, requestID string, item BatchItem) *batchRecorder {
+	rec := &batchRecorder{header: make(http.Header)}
+	if item.Tool == "" || item.Action == "" {
+		write_error(rec, apierror.InvalidRequest, "Batch items need a tool and an action")
+		return rec
+	}
+
+	params := item.Params
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		write_error(rec, apierror.InvalidRequest, "Failed to encode params")
+		return rec
+	}
+	path := "/tools/" + url.PathEscape(item.Tool) + "/" + url.PathEscape(item.Action)
+	r, err := http.NewRequestWithContext(batch.Context(), http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		write_error(rec, apierror.InvalidRequest, err.Error())
+		return rec
+	}
+	r.Header = batch.Header.Clone()
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(requestIDHeader, requestID)
+	r.RemoteAddr = batch.RemoteAddr
+	r.TLS = batch.TLS
+	r = mux.SetURLVars(r, map[string]string{"tool": item.Tool, "action": item.Action})
+
+	g.handleToolRequest(rec, r)
+	return rec
+}
+
+// in-memory ResponseWriter for batch items
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *batchRecorder) Header() http.Header { return b.header }
+
+func (b *batchRecorder) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *batchRecorder) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// status and body; a body that isn't JSON is passed on as a string
+func (b *batchRecorder) result() (int, json.RawMessage) {
+	status := b.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	data := bytes.TrimSpace(b.body.Bytes())
+	if len(data) == 0 {
+		return status, nil
+	}
+	if !json.Valid(data) {
+		data, _ = json.Marshal(string(data))
+	}
+	return status, json.RawMessage(data)
+}
@@ -0,0 +1,185 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"aegis-gateway/pkg/telemetry"
+)
+
+// audit sink keeping every record, to check batch items are audited one by one
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	records []telemetry.AuditLog
+}
+
+func (s *memoryAuditSink) Write(log telemetry.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, log)
+	return nil
+}
+
+func (s *memoryAuditSink) Close() error { return nil }
+
+func (s *memoryAuditSink) all() []telemetry.AuditLog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]telemetry.AuditLog(nil), s.records...)
+}
+
+func post_batch(t *testing.T, gw *Gateway, body string) BatchResponse {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/tools/batch", bytes.NewBufferString(body))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp BatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode batch response: %v", err)
+	}
+	return resp
+}
+
+func item_statuses(resp BatchResponse) []int {
+	out := make([]int, len(resp.Results))
+	for i, r := range resp.Results {
+		out[i] = r.Status
+	}
+	return out
+}
+
+func TestBatchAllAllowed(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	sink := &memoryAuditSink{}
+	telemetry.SetAuditSink(sink)
+	defer telemetry.SetAuditSink(nil)
+
+	resp := post_batch(t, gw, `{"items": [
+		{"tool": "payments", "action": "create", "params": {"amount": 100}},
+		{"tool": "payments", "action": "create", "params": {"amount": 200}}
+	]}`)
+
+	if len(resp.Results) != 2 || resp.Stopped {
+		t.Fatalf("Expected 2 results, got %+v", resp)
+	}
+	for i, r := range resp.Results {
+		if r.Index != i || r.Status != http.StatusOK || r.Tool != "payments" || r.Action != "create" {
+			t.Errorf("Unexpected result %d: %+v", i, r)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(r.Response, &body); err != nil || body["payment_id"] != "test-123" {
+			t.Errorf("Expected the adapter response for item %d, got %s", i, r.Response)
+		}
+	}
+
+	records := sink.all()
+	if len(records) != 2 {
+		t.Fatalf("Expected one audit record per item, got %d", len(records))
+	}
+	if records[0].RequestID == records[1].RequestID {
+		t.Errorf("Expected each item to get its own request ID, got %s twice", records[0].RequestID)
+	}
+}
+
+func TestBatchMixed(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	sink := &memoryAuditSink{}
+	telemetry.SetAuditSink(sink)
+	defer telemetry.SetAuditSink(nil)
+
+	resp := post_batch(t, gw, `{"items": [
+		{"tool": "payments", "action": "create", "params": {"amount": 100}},
+		{"tool": "payments", "action": "create", "params": {"amount": 9000}},
+		{"tool": "files", "action": "read", "params": {"path": "/x"}},
+		{"tool": "payments", "action": "create", "params": {"amount": 300}},
+		{"tool": "", "action": "create"}
+	]}`)
+
+	want := []int{200, 403, 403, 200, 400}
+	got := item_statuses(resp)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected statuses %v, got %v", want, got)
+		}
+	}
+	var denied ErrorResponse
+	if err := json.Unmarshal(resp.Results[1].Response, &denied); err != nil || denied.Code != "AEGIS-403-POLICY" {
+		t.Errorf("Expected the policy error envelope for the denied item, got %s", resp.Results[1].Response)
+	}
+
+	// the malformed item never reaches policy, so isn't audited
+	records := sink.all()
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 audit records, got %d", len(records))
+	}
+	for i, allow := range []bool{true, false, false, true} {
+		if records[i].Decision != allow {
+			t.Errorf("Expected audit record %d allow=%v, got %+v", i, allow, records[i])
+		}
+	}
+}
+
+func TestBatchStopOnFirstDenial(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	sink := &memoryAuditSink{}
+	telemetry.SetAuditSink(sink)
+	defer telemetry.SetAuditSink(nil)
+
+	resp := post_batch(t, gw, `{"stop_on_first_denial": true, "items": [
+		{"tool": "payments", "action": "create", "params": {"amount": 100}},
+		{"tool": "payments", "action": "create", "params": {"amount": 9000}},
+		{"tool": "payments", "action": "create", "params": {"amount": 300}}
+	]}`)
+
+	if !resp.Stopped {
+		t.Error("Expected the batch to be reported as stopped")
+	}
+	if got := item_statuses(resp); got[0] != 200 || got[1] != 403 || got[2] != 0 {
+		t.Errorf("Expected statuses [200 403 0], got %v", got)
+	}
+	if !resp.Results[2].Skipped || resp.Results[2].Tool != "payments" {
+		t.Errorf("Expected the last item skipped, got %+v", resp.Results[2])
+	}
+	if n := len(sink.all()); n != 2 {
+		t.Errorf("Expected only the items that ran to be audited, got %d records", n)
+	}
+}
+
+func TestBatchInvalid(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	tooMany := `{"items": [` + string(bytes.Repeat([]byte(`{"tool":"payments","action":"create"},`), maxBatchItems)) + `{"tool":"payments","action":"create"}]}`
+	for name, body := range map[string]string{
+		"empty":    `{"items": []}`,
+		"not json": `[`,
+		"too many": tooMany,
+	} {
+		req := httptest.NewRequest("POST", "/tools/batch", bytes.NewBufferString(body))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/tools/batch", bytes.NewBufferString(`{"items": [{"tool": "payments", "action": "create"}]}`))
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without X-Agent-ID, got %d", w.Code)
+	}
+}
@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// per-tool circuit breaker settings; zero FailureThreshold disables it
+type BreakerConfig struct {
+	// consecutive adapter failures that open the circuit
+	FailureThreshold int `yaml:"failure_threshold" json:"failure_threshold"`
+
+	// how long the circuit stays open before a probe is let through
+	Cooldown time.Duration `yaml:"cooldown" json:"cooldown"`
+}
+
+func (b BreakerConfig) validate() error {
+	if b.FailureThreshold < 0 || b.Cooldown < 0 {
+		return fmt.Errorf("circuit_breaker values cannot be negative")
+	}
+	if b.FailureThreshold > 0 && b.Cooldown == 0 {
+		return fmt.Errorf("circuit_breaker: cooldown is required")
+	}
+	return nil
+}
+
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+type breaker struct {
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool // half-open probe in flight
+}
+
+// circuit breakers keyed by tool, kept across config reloads
+type breakerSet struct {
+	mu       sync.Mutex
+	breakers map[string]*breaker
+	now      func() time.Time
+}
+
+func newBreakerSet() *breakerSet {
+	return &breakerSet{breakers: make(map[string]*breaker), now: time.Now}
+}
+
+// whether a call to tool may go ahead. once the cooldown has passed a
+// single probe is let through; everything else fails fast until it's done.
+// a rejected call also gets how long until the circuit half-opens, 0 while
+// a probe is in flight
+func (s *breakerSet) allow(tool string, cfg BreakerConfig) (bool, time.Duration) {
+	if cfg.FailureThreshold == 0 {
+		return true, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.breakers[tool]
+	if b == nil || b.state == breakerClosed {
+		return true, 0
+	}
+	open := s.now().Sub(b.openedAt)
+	if b.state == breakerOpen && open >= cfg.Cooldown {
+		b.state = breakerHalfOpen
+	}
+	if b.state == breakerHalfOpen {
+		if !b.probing {
+			b.probing = true
+			return true, 0
+		}
+		return false, 0
+	}
+	return false, cfg.Cooldown - open
+}
+
+// record the outcome of a call to tool
+func (s *breakerSet) record(tool string, cfg BreakerConfig, ok bool) {
+	if cfg.FailureThreshold == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.breakers[tool]
+	if b == nil {
+		b = &breaker{state: breakerClosed}
+		s.breakers[tool] = b
+	}
+	b.probing = false
+	if ok {
+		b.state, b.failures = breakerClosed, 0
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= cfg.FailureThreshold {
+		b.state, b.openedAt = breakerOpen, s.now()
+	}
+}
+
+// tool -> breaker state, for /health
+func (s *breakerSet) states() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]string, len(s.breakers))
+	for tool, b := range s.breakers {
+		out[tool] = b.state
+	}
+	return out
+}
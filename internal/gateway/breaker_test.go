@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var hits atomic.Int32
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"content":"hello"}`))
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	defer gw.Close()
+	gw.SetConfig(Config{CircuitBreaker: BreakerConfig{FailureThreshold: 2, Cooldown: time.Minute}})
+
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	gw.breakers.now = func() time.Time { return now }
+
+	// two failures trip the breaker
+	for i := 0; i < 2; i++ {
+		if w := sendRead(gw); w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected adapter 503, got %d", w.Code)
+		}
+	}
+
+	// open: fail fast without calling the adapter
+	w := sendRead(gw)
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusServiceUnavailable || resp.Error != "AdapterUnavailable" {
+		t.Fatalf("Expected AdapterUnavailable, got %d %+v", w.Code, resp)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("Expected adapter not to be called while open, got %d hits", got)
+	}
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, req)
+	var health struct {
+		Circuits map[string]string `json:"circuits"`
+	}
+	json.NewDecoder(rec.Body).Decode(&health)
+	if health.Circuits["files"] != "open" {
+		t.Errorf("Expected files circuit open in /health, got %v", health.Circuits)
+	}
+
+	// after the cooldown a probe goes through and closes the circuit
+	healthy.Store(true)
+	now = now.Add(time.Minute)
+	if w := sendRead(gw); w.Code != http.StatusOK {
+		t.Fatalf("Expected probe to succeed, got %d", w.Code)
+	}
+	if w := sendRead(gw); w.Code != http.StatusOK {
+		t.Errorf("Expected circuit closed after recovery, got %d", w.Code)
+	}
+	if got := gw.breakers.states()["files"]; got != "closed" {
+		t.Errorf("Expected closed, got %s", got)
+	}
+}
+
+func TestCircuitBreaker_FailedProbeReopens(t *testing.T) {
+	s := newBreakerSet()
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	cfg := BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute}
+
+	s.record("payments", cfg, false)
+	if ok, _ := s.allow("payments", cfg); ok {
+		t.Fatal("Expected open circuit to reject")
+	}
+
+	now = now.Add(time.Minute)
+	if ok, _ := s.allow("payments", cfg); !ok {
+		t.Fatal("Expected a probe after the cooldown")
+	}
+	if ok, _ := s.allow("payments", cfg); ok {
+		t.Error("Expected only one probe while half-open")
+	}
+	s.record("payments", cfg, false)
+	if ok, _ := s.allow("payments", cfg); ok {
+		t.Error("Expected failed probe to reopen the circuit")
+	}
+}
+
+// a request turned away by the concurrency limit never takes the half-open
+// probe, so the circuit can still close
+func TestCircuitBreaker_ConcurrencyLimitKeepsProbe(t *testing.T) {
+	var healthy atomic.Bool
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"content":"hello"}`))
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	defer gw.Close()
+	gw.SetConfig(Config{
+		CircuitBreaker:  BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute},
+		ConcurrencyWait: 10 * time.Millisecond,
+		Tools:           map[string]ToolConfig{"files": {MaxConcurrency: 1}},
+	})
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	gw.breakers.now = func() time.Time { return now }
+
+	sendRead(gw)
+	now = now.Add(time.Minute)
+
+	// the circuit is due a probe, but the tool is saturated
+	release, ok := gw.acquire_concurrency(context.Background(), gw.cfg(), "other-agent", "files")
+	if !ok {
+		t.Fatal("Expected to take the only concurrency slot")
+	}
+	w := sendRead(gw)
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Error != "ConcurrencyLimit" {
+		t.Fatalf("Expected ConcurrencyLimit, got %d %+v", w.Code, resp)
+	}
+	release()
+
+	healthy.Store(true)
+	if w := sendRead(gw); w.Code != http.StatusOK {
+		t.Fatalf("Expected the probe to go through once the slot is free, got %d", w.Code)
+	}
+	if got := gw.breakers.states()["files"]; got != "closed" {
+		t.Errorf("Expected closed, got %s", got)
+	}
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var errReadAdapterResponse = errors.New("failed to read adapter response")
+
+// adapter response, fully read so it can be shared between callers
+type adapterResult struct {
+	status int
+	body   []byte
+}
+
+// forward a request and read the whole response, retrying connection
+// failures and 502/503 responses per retry. the last failure is returned
+// as-is once attempts run out.
+func (g *Gateway) call_adapter(ctx context.Context, method, url string, body []byte, timeout time.Duration, retry RetryConfig, up upstreamOptions) (adapterResult, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := g.call_adapter_once(ctx, method, url, body, timeout, up)
+		// an oversized response would only be oversized again
+		if attempt >= retry.MaxAttempts || (err == nil && !retryable_status(res.status)) || errors.Is(err, errAdapterResponseTooLarge) {
+			return res, err
+		}
+		if !retry.wait(ctx, attempt) {
+			return res, err
+		}
+	}
+}
+
+func (g *Gateway) call_adapter_once(ctx context.Context, method, url string, body []byte, timeout time.Duration, up upstreamOptions) (adapterResult, error) {
+	resp, err := g.forward_to_adapter(ctx, method, url, body, timeout, up)
+	if err != nil {
+		return adapterResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var src io.Reader = resp.Body
+	if up.maxResponseBytes > 0 {
+		if resp.ContentLength > up.maxResponseBytes {
+			return adapterResult{}, errAdapterResponseTooLarge
+		}
+		src = io.LimitReader(resp.Body, up.maxResponseBytes+1)
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return adapterResult{}, errReadAdapterResponse
+	}
+	if up.maxResponseBytes > 0 && int64(len(data)) > up.maxResponseBytes {
+		return adapterResult{}, errAdapterResponseTooLarge
+	}
+	return adapterResult{status: resp.StatusCode, body: data}, nil
+}
+
+// single-flight group: concurrent calls with the same key share one
+// adapter call and its result
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	dups int // callers waiting on this call, for tests
+	res  adapterResult
+	err  error
+}
+
+// run fn for key, or join the in-flight call with the same key. fn runs
+// on its own so that every caller, the one that started it included, can
+// give up when its ctx ends while the call carries on for the rest
+func (f *flightGroup) do(ctx context.Context, key string, fn func() (adapterResult, error)) (adapterResult, error) {
+	f.mu.Lock()
+	c, ok := f.calls[key]
+	if ok {
+		c.dups++
+	} else {
+		if f.calls == nil {
+			f.calls = make(map[string]*flightCall)
+		}
+		c = &flightCall{done: make(chan struct{})}
+		f.calls[key] = c
+		go func() {
+			c.res, c.err = fn()
+			f.mu.Lock()
+			delete(f.calls, key)
+			f.mu.Unlock()
+			close(c.done)
+		}()
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.res, c.err
+	case <-ctx.Done():
+		return adapterResult{}, ctx.Err()
+	}
+}
@@ -0,0 +1,209 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aegis-gateway/internal/policy"
+)
+
+const filesReadPolicy = `version: 1
+agents:
+  - id: reader-agent
+    allow:
+      - tool: files
+        actions: [read]
+`
+
+// wait until n callers are queued behind the in-flight call for key
+func waitForDups(t *testing.T, f *flightGroup, key string, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		f.mu.Lock()
+		c := f.calls[key]
+		dups := 0
+		if c != nil {
+			dups = c.dups
+		}
+		f.mu.Unlock()
+		if dups == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d coalesced callers", n)
+}
+
+func TestCoalesceIdenticalReads(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":"hello"}`))
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	defer gw.Close()
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"files": {Actions: map[string]ActionConfig{"read": {Coalesce: true}}},
+	}})
+
+	params := map[string]interface{}{"path": "/shared/report.txt"}
+	bodyBytes, _ := json.Marshal(params)
+
+	const n = 10
+	codes := make([]int, n)
+	bodies := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/tools/files/read", bytes.NewReader(bodyBytes))
+			req.Header.Set("X-Agent-ID", "reader-agent")
+			w := httptest.NewRecorder()
+			gw.router.ServeHTTP(w, req)
+			codes[i], bodies[i] = w.Code, w.Body.String()
+		}(i)
+	}
+
+	waitForDups(t, &gw.flights, "files/read/"+policy.HashParams(params)+"/reader-agent", n-1)
+	close(release)
+	wg.Wait()
+
+	if got := hits.Load(); got != 1 {
+		t.Errorf("Expected adapter to be hit once, got %d", got)
+	}
+	for i := range codes {
+		if codes[i] != http.StatusOK || bodies[i] != `{"content":"hello"}` {
+			t.Errorf("Request %d: got %d %q", i, codes[i], bodies[i])
+		}
+	}
+}
+
+func TestCoalesceDisabledByDefault(t *testing.T) {
+	var hits atomic.Int32
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(`{}`))
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	defer gw.Close()
+
+	bodyBytes, _ := json.Marshal(map[string]interface{}{"path": "/shared/report.txt"})
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/tools/files/read", bytes.NewReader(bodyBytes))
+		req.Header.Set("X-Agent-ID", "reader-agent")
+		gw.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("Expected 2 adapter calls without coalescing, got %d", got)
+	}
+}
+
+func TestCoalescePerAgent(t *testing.T) {
+	var hits atomic.Int32
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"agent":"` + r.Header.Get(agentIDHeader) + `"}`))
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, `version: 1
+agents:
+  - id: agent-a
+    allow:
+      - tool: files
+        actions: [read]
+  - id: agent-b
+    allow:
+      - tool: files
+        actions: [read]
+`, map[string]string{"files": adapter.URL})
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"files": {Actions: map[string]ActionConfig{"read": {Coalesce: true}}},
+	}})
+
+	// same params, different agents: each gets its own call and answer
+	bodies := make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, agent := range []string{"agent-a", "agent-b"} {
+		wg.Add(1)
+		go func(agent string) {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/tools/files/read", bytes.NewReader([]byte(`{"path":"/shared/report.txt"}`)))
+			req.Header.Set("X-Agent-ID", agent)
+			w := httptest.NewRecorder()
+			gw.router.ServeHTTP(w, req)
+			mu.Lock()
+			bodies[agent] = w.Body.String()
+			mu.Unlock()
+		}(agent)
+	}
+	wg.Wait()
+
+	if got := hits.Load(); got != 2 {
+		t.Errorf("Expected one adapter call per agent, got %d", got)
+	}
+	for agent, body := range bodies {
+		if body != `{"agent":"`+agent+`"}` {
+			t.Errorf("Expected %s to get its own response, got %s", agent, body)
+		}
+	}
+}
+
+func TestFlightGroupCallerCancel(t *testing.T) {
+	var f flightGroup
+	release := make(chan struct{})
+	fn := func() (adapterResult, error) {
+		<-release
+		return adapterResult{status: http.StatusOK}, nil
+	}
+
+	started := make(chan struct{})
+	var leader adapterResult
+	var leaderErr error
+	go func() {
+		defer close(started)
+		leader, leaderErr = f.do(context.Background(), "k", fn)
+	}()
+
+	// a waiter whose request ends doesn't wait for the shared call
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := f.do(ctx, "k", fn)
+		done <- err
+	}()
+	waitForDups(t, &f, "k", 1)
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the cancelled caller to return before the call finished")
+	}
+
+	close(release)
+	<-started
+	if leaderErr != nil || leader.status != http.StatusOK {
+		t.Errorf("Expected the call to carry on for the others, got %+v, %v", leader, leaderErr)
+	}
+}
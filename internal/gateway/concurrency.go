@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// how long a request waits for a free slot before 503 ConcurrencyLimit,
+// unless concurrency_wait is set
+const defaultConcurrencyWait = 100 * time.Millisecond
+
+// caps on simultaneous adapter calls, one semaphore per key ("tool:x" or
+// "agent:y"), kept across config reloads. a changed limit starts a fresh
+// semaphore; calls holding a slot in the old one release it there
+type concurrencyLimiter struct {
+	mu       sync.Mutex
+	sems     map[string]chan struct{}
+	inflight map[string]int
+}
+
+func newConcurrencyLimiter() *concurrencyLimiter {
+	return &concurrencyLimiter{sems: make(map[string]chan struct{}), inflight: make(map[string]int)}
+}
+
+func (l *concurrencyLimiter) semaphore(key string, limit int) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sem := l.sems[key]
+	if cap(sem) != limit {
+		sem = make(chan struct{}, limit)
+		l.sems[key] = sem
+	}
+	return sem
+}
+
+// take a slot for key, waiting up to wait. limit 0 means unlimited, but
+// the call is still counted for /stats. release must be called once the
+// call is done
+func (l *concurrencyLimiter) acquire(ctx context.Context, key string, limit int, wait time.Duration) (release func(), ok bool) {
+	var sem chan struct{}
+	if limit > 0 {
+		sem = l.semaphore(key, limit)
+		select {
+		case sem <- struct{}{}:
+		default:
+			t := time.NewTimer(wait)
+			defer t.Stop()
+			select {
+			case sem <- struct{}{}:
+			case <-t.C:
+				return nil, false
+			case <-ctx.Done():
+				return nil, false
+			}
+		}
+	}
+
+	l.mu.Lock()
+	l.inflight[key]++
+	l.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			if l.inflight[key]--; l.inflight[key] == 0 {
+				delete(l.inflight, key)
+			}
+			l.mu.Unlock()
+			if sem != nil {
+				<-sem
+			}
+		})
+	}, true
+}
+
+// calls currently holding a slot, by key
+func (l *concurrencyLimiter) snapshot(prefix string) map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]int)
+	for key, n := range l.inflight {
+		if name, ok := strings.CutPrefix(key, prefix); ok {
+			out[name] = n
+		}
+	}
+	return out
+}
+
+// slots for one adapter call: the tool's, then the agent's. ok is false
+// when either is full past the wait
+func (g *Gateway) acquire_concurrency(ctx context.Context, cfg *Config, agentID, tool string) (release func(), ok bool) {
+	wait := cfg.ConcurrencyWait
+	if wait == 0 {
+		wait = defaultConcurrencyWait
+	}
+	releaseTool, ok := g.concurrency.acquire(ctx, "tool:"+tool, cfg.tool(tool).MaxConcurrency, wait)
+	if !ok {
+		return nil, false
+	}
+	releaseAgent, ok := g.concurrency.acquire(ctx, "agent:"+agentID, cfg.AgentMaxConcurrency, wait)
+	if !ok {
+		releaseTool()
+		return nil, false
+	}
+	return func() {
+		releaseAgent()
+		releaseTool()
+	}, true
+}
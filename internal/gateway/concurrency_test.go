@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	release := make(chan struct{})
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer adapter.Close()
+
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	if err := gw.SetConfig(Config{
+		Adapters:        map[string]string{"payments": adapter.URL},
+		ConcurrencyWait: 20 * time.Millisecond,
+		Tools:           map[string]ToolConfig{"payments": {MaxConcurrency: 2}},
+	}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{"amount":100}`)))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w
+	}
+	inFlight := func() int {
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, httptest.NewRequest("GET", "/stats", nil))
+		var stats statsResponse
+		json.NewDecoder(w.Body).Decode(&stats)
+		return stats.InFlight.Tools["payments"]
+	}
+	waitInFlight := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for inFlight() != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d calls in flight, got %d", n, inFlight())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// saturate the semaphore
+	var wg sync.WaitGroup
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- send().Code
+		}()
+	}
+	waitInFlight(2)
+
+	// overflow is turned away after the short wait
+	w := send()
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 while saturated, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", got)
+	}
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Error != "ConcurrencyLimit" || resp.Code != "AEGIS-503-CONCURRENCY" {
+		t.Errorf("Expected ConcurrencyLimit, got %+v", resp)
+	}
+
+	// freeing the slots lets requests through again
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected the in-flight requests to succeed, got %d", code)
+		}
+	}
+	waitInFlight(0)
+	if w := send(); w.Code != http.StatusOK {
+		t.Errorf("Expected capacity back after release, got %d", w.Code)
+	}
+}
+
+func TestConcurrencyLimiterWaits(t *testing.T) {
+	l := newConcurrencyLimiter()
+	release, ok := l.acquire(t.Context(), "agent:a", 1, time.Second)
+	if !ok {
+		t.Fatal("Expected the first slot")
+	}
+	if _, ok := l.acquire(t.Context(), "agent:a", 1, 10*time.Millisecond); ok {
+		t.Fatal("Expected the second acquire to time out")
+	}
+
+	// a slot freed within the wait is taken
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		release()
+	}()
+	release2, ok := l.acquire(t.Context(), "agent:a", 1, time.Second)
+	if !ok {
+		t.Fatal("Expected the freed slot to be handed over")
+	}
+	release2()
+	release2() // idempotent
+	if got := l.snapshot("agent:"); len(got) != 0 {
+		t.Errorf("Expected nothing in flight, got %v", got)
+	}
+}
@@ -0,0 +1,353 @@
+package gateway
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"aegis-gateway/internal/policy"
+	"aegis-gateway/pkg/telemetry"
+
+	"gopkg.in/yaml.v3"
+)
+
+// adapter call timeout when nothing more specific is configured
+const defaultAdapterTimeout = 10 * time.Second
+
+// tool request body limit when nothing more specific is configured
+const defaultMaxRequestBytes = 4 << 20
+
+// runtime settings for the gateway, reloadable without a restart
+type Config struct {
+	// tool name -> adapter URL. left nil, the current registry is kept
+	Adapters map[string]string `yaml:"adapters" json:"adapters,omitempty"`
+
+	// adapter call timeout for tools/actions without their own
+	AdapterTimeout time.Duration `yaml:"adapter_timeout" json:"adapter_timeout"`
+
+	// overall deadline for a request, policy evaluation and adapter calls
+	// (with retries) included; 504 when it passes. 0 means none
+	RequestTimeout time.Duration `yaml:"request_timeout" json:"request_timeout,omitempty"`
+
+	// largest tool request body accepted, 413 beyond it. 0 uses the default
+	MaxRequestBytes int64 `yaml:"max_request_bytes" json:"max_request_bytes,omitempty"`
+
+	// reject tool requests without a Content-Type header. requests with a
+	// non-JSON Content-Type are always rejected
+	RequireContentType bool `yaml:"require_content_type" json:"require_content_type"`
+
+	// per-agent request rate limit on tool requests
+	RateLimit RateLimitConfig `yaml:"rate_limit" json:"rate_limit"`
+
+	CORS CORSConfig `yaml:"cors" json:"cors"`
+
+	// only accept JSON numbers for amount in policy conditions; by default
+	// numeric strings like "1000" are parsed
+	StrictAmounts bool `yaml:"strict_amounts" json:"strict_amounts"`
+
+	// reject agents with no api_key_sha256 in the policy. agents that have
+	// a key must always present it
+	RequireAPIKeys bool `yaml:"require_api_keys" json:"require_api_keys"`
+
+	// approver ID -> environment variable holding its HMAC key, for
+	// require_dual_approval tokens. left nil, keys set with SetApproverKeys
+	// are kept
+	ApproverKeys map[string]string `yaml:"approver_keys" json:"approver_keys,omitempty"`
+
+	// change ID -> approved window, for require_change_window. left nil,
+	// windows set with SetChangeWindows are kept
+	ChangeWindows policy.StaticChangeWindows `yaml:"change_windows" json:"change_windows,omitempty"`
+
+	// simultaneous adapter calls per agent, across tools. 0 is unlimited
+	AgentMaxConcurrency int `yaml:"agent_max_concurrency" json:"agent_max_concurrency,omitempty"`
+
+	// how long a request waits for a concurrency slot before 503
+	// ConcurrencyLimit. 0 uses the default
+	ConcurrencyWait time.Duration `yaml:"concurrency_wait" json:"concurrency_wait,omitempty"`
+
+	// fail fast with 503 for tools whose adapter keeps failing
+	CircuitBreaker BreakerConfig `yaml:"circuit_breaker" json:"circuit_breaker"`
+
+	// honor X-Debug-Conditions and return condition traces to callers.
+	// exposes policy internals, keep off in production
+	DebugTrace bool `yaml:"debug_trace" json:"debug_trace"`
+
+	// add X-Aegis-Decision, X-Aegis-Policy-Version and, on denials,
+	// X-Aegis-Reason to tool responses. exposes policy internals, keep off
+	// in production
+	DecisionHeaders bool `yaml:"decision_headers" json:"decision_headers"`
+
+	// sample low-value allows in the audit log, nil logs every decision
+	AuditSampling *telemetry.AuditSampling `yaml:"audit_sampling" json:"audit_sampling,omitempty"`
+
+	// also write audit records to a database table
+	AuditSQL *telemetry.SQLSinkConfig `yaml:"audit_sql" json:"audit_sql,omitempty"`
+
+	// reject allowed requests with 503 when the audit sink can't record
+	// them; by default the gateway fails open and keeps serving
+	AuditFailClosed bool `yaml:"audit_fail_closed" json:"audit_fail_closed"`
+
+	// request fields left out of the params hash in audit records and
+	// spans, dotted paths reach into nested objects (e.g. "card.cvv")
+	SensitiveParams []string `yaml:"sensitive_params" json:"sensitive_params,omitempty"`
+
+	// one JSON line per HTTP request: "stdout", "stderr" or a file path.
+	// "" (default) is off
+	AccessLog string `yaml:"access_log" json:"access_log,omitempty"`
+
+	// targets notified of matching policy denials, see WebhookConfig
+	DenialWebhooks []WebhookConfig `yaml:"denial_webhooks" json:"denial_webhooks,omitempty"`
+
+	Tools map[string]ToolConfig `yaml:"tools" json:"tools"`
+}
+
+// token bucket per agent; zero RequestsPerSecond disables limiting
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second" json:"requests_per_second"`
+	Burst             int     `yaml:"burst" json:"burst"`
+}
+
+// origins allowed to call the gateway from a browser, "*" for any
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" json:"allowed_origins,omitempty"`
+}
+
+// per-tool forwarding settings
+type ToolConfig struct {
+	// "adapter" (default) or "passthrough" for a plain REST API, which is
+	// probed for reachability rather than /health in readiness checks
+	Type string `yaml:"type" json:"type,omitempty"`
+
+	// headers added to every call to the tool's adapter
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"`
+
+	// credentials added to every call to the tool's adapter
+	Auth *UpstreamAuth `yaml:"auth" json:"auth,omitempty"`
+
+	// largest adapter response passed back, 502 beyond it. 0 is unlimited
+	MaxResponseBytes int64 `yaml:"max_response_bytes" json:"max_response_bytes,omitempty"`
+
+	// write failed forwards to the dead-letter sink for later replay
+	DeadLetter bool `yaml:"dead_letter" json:"dead_letter"`
+
+	// request body field renames applied to every action of the tool
+	RequestMap FieldMap `yaml:"request_map" json:"request_map,omitempty"`
+
+	// response fields returned to the agent; empty means all fields
+	ResponseAllow []string `yaml:"response_allow" json:"response_allow,omitempty"`
+
+	// response fields stripped before returning to the agent, dotted paths
+	// reach into nested objects (e.g. "card.number")
+	ResponseDeny []string `yaml:"response_deny" json:"response_deny,omitempty"`
+
+	// adapter call timeout for this tool, overrides the global one
+	Timeout time.Duration `yaml:"timeout" json:"timeout,omitempty"`
+
+	// request body limit for this tool, overrides the global one
+	MaxRequestBytes int64 `yaml:"max_request_bytes" json:"max_request_bytes,omitempty"`
+
+	// simultaneous calls to the tool's adapter, 0 is unlimited
+	MaxConcurrency int `yaml:"max_concurrency" json:"max_concurrency,omitempty"`
+
+	// retry transient adapter failures for every action of the tool
+	Retry *RetryConfig `yaml:"retry" json:"retry,omitempty"`
+
+	// currency injected when a request omits one
+	DefaultCurrency string `yaml:"default_currency" json:"default_currency,omitempty"`
+
+	// unit agents send amounts in: "major" (default, e.g. dollars) or
+	// "minor" (cents); minor amounts are converted to major before policy
+	AmountUnit string `yaml:"amount_unit" json:"amount_unit,omitempty"`
+
+	// per-action overrides
+	Actions map[string]ActionConfig `yaml:"actions" json:"actions,omitempty"`
+}
+
+// per-action forwarding settings, take precedence over the tool-level ones
+type ActionConfig struct {
+	RequestMap FieldMap      `yaml:"request_map" json:"request_map,omitempty"`
+	Timeout    time.Duration `yaml:"timeout" json:"timeout,omitempty"`
+
+	// adapter body built from the agent's params, replaces request_map.
+	// see render_template
+	RequestTemplate map[string]interface{} `yaml:"request_template" json:"request_template,omitempty"`
+
+	// adapter path when it differs from the action name, e.g. "charge" or
+	// "/payments/{vendor_id}/charge". placeholders take the agent's params
+	Path string `yaml:"path" json:"path,omitempty"`
+
+	// HTTP method for the adapter call, POST by default. GET and DELETE
+	// send the params as a query string
+	Method string `yaml:"method" json:"method,omitempty"`
+
+	// share one adapter call between concurrent identical requests. only
+	// for read-like actions without side effects
+	Coalesce bool `yaml:"coalesce" json:"coalesce,omitempty"`
+
+	Retry *RetryConfig `yaml:"retry" json:"retry,omitempty"`
+}
+
+// look up settings for a tool, zero value if not configured
+func (c Config) tool(name string) ToolConfig {
+	return c.Tools[name]
+}
+
+// adapter timeout for an action: action, then tool, then global, then default
+func (c Config) timeout(tool, action string) time.Duration {
+	tc := c.tool(tool)
+	if a, ok := tc.Actions[action]; ok && a.Timeout > 0 {
+		return a.Timeout
+	}
+	if tc.Timeout > 0 {
+		return tc.Timeout
+	}
+	if c.AdapterTimeout > 0 {
+		return c.AdapterTimeout
+	}
+	return defaultAdapterTimeout
+}
+
+// request body limit for a tool: tool, then global, then default
+func (c Config) max_request_bytes(tool string) int64 {
+	if n := c.tool(tool).MaxRequestBytes; n > 0 {
+		return n
+	}
+	if c.MaxRequestBytes > 0 {
+		return c.MaxRequestBytes
+	}
+	return defaultMaxRequestBytes
+}
+
+// retry policy for an action: action, then tool; none by default
+func (c Config) retry(tool, action string) RetryConfig {
+	tc := c.tool(tool)
+	if a, ok := tc.Actions[action]; ok && a.Retry != nil {
+		return *a.Retry
+	}
+	if tc.Retry != nil {
+		return *tc.Retry
+	}
+	return RetryConfig{}
+}
+
+// read and validate a config file
+func LoadConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c Config) validate() error {
+	for tool, raw := range c.Adapters {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("adapter %s: invalid URL %q", tool, raw)
+		}
+	}
+	if c.AdapterTimeout < 0 {
+		return fmt.Errorf("adapter_timeout cannot be negative")
+	}
+	if c.RequestTimeout < 0 {
+		return fmt.Errorf("request_timeout cannot be negative")
+	}
+	if c.MaxRequestBytes < 0 {
+		return fmt.Errorf("max_request_bytes cannot be negative")
+	}
+	if c.RateLimit.RequestsPerSecond < 0 || c.RateLimit.Burst < 0 {
+		return fmt.Errorf("rate_limit values cannot be negative")
+	}
+	if c.AgentMaxConcurrency < 0 || c.ConcurrencyWait < 0 {
+		return fmt.Errorf("agent_max_concurrency and concurrency_wait cannot be negative")
+	}
+	if err := c.CircuitBreaker.validate(); err != nil {
+		return err
+	}
+	if s := c.AuditSampling; s != nil && (s.AllowRate < 0 || s.AllowRate > 1) {
+		return fmt.Errorf("audit_sampling.allow_rate must be between 0 and 1")
+	}
+	if _, err := c.approver_keys(); err != nil {
+		return err
+	}
+	for id, w := range c.ChangeWindows {
+		if id == "" || w.Start.IsZero() {
+			return fmt.Errorf("change_windows: change ID and start are required")
+		}
+		if !w.End.IsZero() && !w.End.After(w.Start) {
+			return fmt.Errorf("change_windows: %s ends before it starts", id)
+		}
+	}
+	if c.AuditSQL != nil {
+		if err := c.AuditSQL.Validate(); err != nil {
+			return fmt.Errorf("audit_sql: %w", err)
+		}
+	}
+	for _, p := range c.SensitiveParams {
+		if p == "" || strings.HasPrefix(p, ".") || strings.HasSuffix(p, ".") {
+			return fmt.Errorf("sensitive_params: invalid field %q", p)
+		}
+	}
+	for _, o := range c.CORS.AllowedOrigins {
+		if o == "" {
+			return fmt.Errorf("cors: empty origin")
+		}
+	}
+	for i, wc := range c.DenialWebhooks {
+		if err := wc.validate(); err != nil {
+			return fmt.Errorf("denial_webhooks[%d]: %w", i, err)
+		}
+	}
+	for tool, tc := range c.Tools {
+		if tc.Timeout < 0 {
+			return fmt.Errorf("tool %s: timeout cannot be negative", tool)
+		}
+		if tc.MaxRequestBytes < 0 {
+			return fmt.Errorf("tool %s: max_request_bytes cannot be negative", tool)
+		}
+		if tc.MaxConcurrency < 0 {
+			return fmt.Errorf("tool %s: max_concurrency cannot be negative", tool)
+		}
+		if err := tc.validate_upstream(); err != nil {
+			return fmt.Errorf("tool %s: %w", tool, err)
+		}
+		if tc.Retry != nil {
+			if err := tc.Retry.validate(); err != nil {
+				return fmt.Errorf("tool %s: %w", tool, err)
+			}
+		}
+		if tc.AmountUnit != "" && tc.AmountUnit != "major" && tc.AmountUnit != "minor" {
+			return fmt.Errorf("tool %s: amount_unit must be major or minor", tool)
+		}
+		for action, ac := range tc.Actions {
+			if ac.Timeout < 0 {
+				return fmt.Errorf("tool %s, action %s: timeout cannot be negative", tool, action)
+			}
+			if strings.Count(ac.Path, "{") != strings.Count(ac.Path, "}") || strings.ContainsAny(ac.Path, "?#") {
+				return fmt.Errorf("tool %s, action %s: invalid path %q", tool, action, ac.Path)
+			}
+			if err := validate_template(ac.RequestTemplate); err != nil {
+				return fmt.Errorf("tool %s, action %s: request_template: %w", tool, action, err)
+			}
+			if !valid_method(ac.Method) {
+				return fmt.Errorf("tool %s, action %s: unsupported method %q", tool, action, ac.Method)
+			}
+			if ac.Retry != nil {
+				if err := ac.Retry.validate(); err != nil {
+					return fmt.Errorf("tool %s, action %s: %w", tool, action, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConfigTimeoutPrecedence(t *testing.T) {
+	cfg := Config{
+		AdapterTimeout: 5 * time.Second,
+		Tools: map[string]ToolConfig{
+			"files": {
+				Timeout: 2 * time.Second,
+				Actions: map[string]ActionConfig{
+					"write": {Timeout: 30 * time.Second},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		tool, action string
+		want         time.Duration
+	}{
+		{"files", "write", 30 * time.Second},
+		{"files", "read", 2 * time.Second},
+		{"payments", "create", 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := cfg.timeout(tt.tool, tt.action); got != tt.want {
+			t.Errorf("timeout(%s, %s) = %v, want %v", tt.tool, tt.action, got, tt.want)
+		}
+	}
+
+	if got := (Config{}).timeout("files", "read"); got != defaultAdapterTimeout {
+		t.Errorf("Expected default timeout %v, got %v", defaultAdapterTimeout, got)
+	}
+}
+
+func TestActionTimeoutAgainstSlowAdapter(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer slow.Close()
+
+	gw := setupGatewayWithPolicy(t, `version: 1
+agents:
+  - id: files-agent
+    allow:
+      - tool: files
+        actions: [read, write]
+`, map[string]string{"files": slow.URL})
+
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"files": {
+			Timeout: 50 * time.Millisecond,
+			Actions: map[string]ActionConfig{
+				"write": {Timeout: 2 * time.Second},
+			},
+		},
+	}})
+
+	send := func(action string) int {
+		req := httptest.NewRequest("POST", "/tools/files/"+action, bytes.NewReader([]byte(`{"path":"/a","content":"x"}`)))
+		req.Header.Set("X-Agent-ID", "files-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send("write"); code != http.StatusOK {
+		t.Errorf("Expected long-timeout write to succeed, got %d", code)
+	}
+	if code := send("read"); code != http.StatusBadGateway {
+		t.Errorf("Expected short-timeout read to time out with 502, got %d", code)
+	}
+}
@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContentTypeValidation(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	send := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		required    bool
+		wantCode    int
+	}{
+		{"json", "application/json", `{"amount":100}`, false, http.StatusOK},
+		{"json with charset", "application/json; charset=utf-8", `{"amount":100}`, false, http.StatusOK},
+		{"form post", "application/x-www-form-urlencoded", "amount=100", false, http.StatusUnsupportedMediaType},
+		{"text", "text/plain", `{"amount":100}`, false, http.StatusUnsupportedMediaType},
+		{"missing allowed", "", `{"amount":100}`, false, http.StatusOK},
+		{"missing required", "", `{"amount":100}`, true, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw.SetConfig(Config{RequireContentType: tt.required})
+			w := send(tt.contentType, tt.body)
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode == http.StatusUnsupportedMediaType {
+				var resp ErrorResponse
+				json.NewDecoder(w.Body).Decode(&resp)
+				if resp.Error != "UnsupportedMediaType" {
+					t.Errorf("Expected UnsupportedMediaType error, got %s", resp.Error)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"aegis-gateway/internal/policy"
+)
+
+// request headers the gateway reads
+const (
+	parentAgentHeader     = "X-Parent-Agent"
+	debugConditionsHeader = "X-Debug-Conditions"
+)
+
+// headers a browser may send, for preflight responses. built from the
+// constants the gateway reads them by, so a new one can't be left out
+var corsAllowHeaders = strings.Join([]string{
+	"Content-Type", "Authorization",
+	agentIDHeader, parentAgentHeader, requestIDHeader, debugConditionsHeader,
+	policy.ApproverHeader, policy.ChangeIDHeader,
+}, ", ")
+
+// add CORS headers for allowed origins
+func (g *Gateway) cors_middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && origin_allowed(g.cfg().CORS.AllowedOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Vary", "Origin")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// answer preflight requests for any route
+func (g *Gateway) handle_preflight(w http.ResponseWriter, r *http.Request) {
+	if !origin_allowed(g.cfg().CORS.AllowedOrigins, r.Header.Get("Origin")) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func origin_allowed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"aegis-gateway/internal/policy"
+	"aegis-gateway/pkg/apierror"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// a forward that failed and was parked for later replay. sensitive_params
+// are never stored; Redacted lists the ones the request carried
+type DeadLetter struct {
+	ID        string                 `json:"id"`
+	AgentID   string                 `json:"agent_id"`
+	Tool      string                 `json:"tool"`
+	Action    string                 `json:"action"`
+	Params    map[string]interface{} `json:"params"`
+	Redacted  []string               `json:"redacted,omitempty"`
+	Error     string                 `json:"error"`
+	Timestamp string                 `json:"timestamp"`
+}
+
+// whether a forward failed: no response at all, or a 5xx. failed
+// forwards are dead-lettered, and a replay that fails keeps its entry
+func adapter_failed(status int, err error) bool {
+	return err != nil || status >= 500
+}
+
+// returned by Remove for an id that isn't stored
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// storage for dead-lettered requests
+type DeadLetterSink interface {
+	Add(dl DeadLetter) error
+	List() ([]DeadLetter, error)
+	// remove one entry, ErrDeadLetterNotFound if it isn't there. replays
+	// claim their entry this way, so it must be atomic
+	Remove(id string) error
+}
+
+// in-memory sink, used by default
+type MemoryDeadLetterSink struct {
+	mu      sync.Mutex
+	entries []DeadLetter
+}
+
+func NewMemoryDeadLetterSink() *MemoryDeadLetterSink {
+	return &MemoryDeadLetterSink{}
+}
+
+func (s *MemoryDeadLetterSink) Add(dl DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, dl)
+	return nil
+}
+
+func (s *MemoryDeadLetterSink) List() ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]DeadLetter, len(s.entries))
+	copy(out, s.entries)
+	return out, nil
+}
+
+func (s *MemoryDeadLetterSink) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, dl := range s.entries {
+		if dl.ID == id {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+}
+
+// file-backed sink, one JSON record per line so entries survive restarts
+type FileDeadLetterSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileDeadLetterSink(path string) *FileDeadLetterSink {
+	return &FileDeadLetterSink{path: path}
+}
+
+func (s *FileDeadLetterSink) Add(dl DeadLetter) error {
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func (s *FileDeadLetterSink) List() ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read_entries()
+}
+
+func (s *FileDeadLetterSink) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read_entries()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	var kept []byte
+	for _, dl := range entries {
+		if dl.ID == id {
+			found = true
+			continue
+		}
+		data, err := json.Marshal(dl)
+		if err != nil {
+			return err
+		}
+		kept = append(kept, data...)
+		kept = append(kept, '\n')
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrDeadLetterNotFound, id)
+	}
+
+	return os.WriteFile(s.path, kept, 0644)
+}
+
+// caller must hold s.mu
+func (s *FileDeadLetterSink) read_entries() ([]DeadLetter, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []DeadLetter
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var dl DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &dl); err != nil {
+			return nil, fmt.Errorf("corrupt dead-letter record: %w", err)
+		}
+		entries = append(entries, dl)
+	}
+	return entries, scanner.Err()
+}
+
+// park a failed forward in the dead-letter sink, see adapter_failed
+func (g *Gateway) dead_letter(agentID, tool, action string, params map[string]interface{}, status int, cause error) {
+	if cause == nil {
+		cause = fmt.Errorf("adapter returned status %d", status)
+	}
+	cfg := g.cfg()
+	dl := DeadLetter{
+		ID:        uuid.New().String(),
+		AgentID:   agentID,
+		Tool:      tool,
+		Action:    action,
+		Params:    cfg.redact_params(params),
+		Redacted:  cfg.sensitive_paths(params),
+		Error:     cause.Error(),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := g.deadLetters.Add(dl); err != nil {
+		fmt.Printf("ERROR: failed to write dead letter for %s/%s: %v\n", tool, action, err)
+	}
+}
+
+func (g *Gateway) handle_list_deadletters(w http.ResponseWriter, r *http.Request) {
+	entries, err := g.deadLetters.List()
+	if err != nil {
+		write_error(w, apierror.DeadLetterError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []DeadLetter{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// re-send a dead-lettered request to its adapter, removing it on success.
+// the entry is taken out while it's replayed, so a second replay of it
+// at the same time gets 404 instead of sending it again.
+// the replay is checked against the current policies first, as a dry run
+// since the original request was already counted against stateful
+// conditions and quotas, and goes through the tool's circuit breaker.
+// conditions reading headers, e.g. require_dual_approval, see the replay
+// request's. rate and concurrency limits are for agents and don't apply
+// to an admin replaying
+func (g *Gateway) handle_replay_deadletter(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	entries, err := g.deadLetters.List()
+	if err != nil {
+		write_error(w, apierror.DeadLetterError, err.Error())
+		return
+	}
+
+	var dl *DeadLetter
+	for i := range entries {
+		if entries[i].ID == id {
+			dl = &entries[i]
+			break
+		}
+	}
+	if dl == nil {
+		write_error(w, apierror.NotFound, fmt.Sprintf("No dead letter with id: %s", id))
+		return
+	}
+
+	// what was stripped can't be sent again, the request has to be redone
+	if len(dl.Redacted) > 0 {
+		write_error(w, apierror.DeadLetterInvalid, fmt.Sprintf("Dead letter %s was stored without its sensitive params %v and can't be replayed", dl.ID, dl.Redacted))
+		return
+	}
+
+	cfg := g.cfg()
+	decision := g.policyManager.EvaluateRequest(policy.Request{
+		AgentID: dl.AgentID,
+		Tool:    dl.Tool,
+		Action:  dl.Action,
+		Params:  dl.Params,
+		Headers: policy_headers(r),
+		DryRun:  true,
+		Context: r.Context(),
+
+		StrictAmounts: cfg.StrictAmounts,
+	})
+	if !decision.Allow {
+		apierror.WriteBody(w, apierror.PolicyViolation.Status, ErrorResponse{
+			Response:   apierror.PolicyViolation.Response(decision.Reason),
+			ReasonCode: decision.ReasonCode,
+		})
+		return
+	}
+
+	adapterURL, ok := cfg.Adapters[dl.Tool]
+	if !ok {
+		write_error(w, apierror.AdapterNotFound, fmt.Sprintf("No adapter configured for tool: %s", dl.Tool))
+		return
+	}
+
+	body, err := json.Marshal(dl.Params)
+	if err == nil {
+		body, err = g.adapter_body(dl.Tool, dl.Action, dl.Params, body)
+	}
+	if err != nil {
+		write_error(w, apierror.DeadLetterError, "Failed to encode dead-lettered params")
+		return
+	}
+
+	method := cfg.method(dl.Tool, dl.Action)
+	targetURL, err := cfg.target_url(adapterURL, dl.Tool, dl.Action, dl.Params)
+	if err == nil {
+		targetURL, body, err = adapter_request(method, targetURL, body)
+	}
+	if err != nil {
+		write_error(w, apierror.DeadLetterInvalid, err.Error())
+		return
+	}
+
+	if err := g.deadLetters.Remove(dl.ID); errors.Is(err, ErrDeadLetterNotFound) {
+		write_error(w, apierror.NotFound, fmt.Sprintf("No dead letter with id: %s", id))
+		return
+	} else if err != nil {
+		write_error(w, apierror.DeadLetterError, err.Error())
+		return
+	}
+	// put back unless the adapter takes it
+	replayed := false
+	defer func() {
+		if replayed {
+			return
+		}
+		if err := g.deadLetters.Add(*dl); err != nil {
+			fmt.Printf("ERROR: failed to restore dead letter %s: %v\n", dl.ID, err)
+		}
+	}()
+
+	if ok, wait := g.breakers.allow(dl.Tool, cfg.CircuitBreaker); !ok {
+		set_retry_after(w, wait)
+		write_error(w, apierror.AdapterUnavailable, fmt.Sprintf("Circuit open for tool: %s", dl.Tool))
+		return
+	}
+	adapterResp, err := g.forward_to_adapter(with_agent_id(r.Context(), dl.AgentID), method, targetURL, body, cfg.timeout(dl.Tool, dl.Action), cfg.upstream(dl.Tool))
+	status := 0
+	if err == nil {
+		status = adapterResp.StatusCode
+	}
+	g.breakers.record(dl.Tool, cfg.CircuitBreaker, !adapter_failed(status, err))
+	if err != nil {
+		write_adapter_failure(w, r.Context(), err)
+		return
+	}
+	defer adapterResp.Body.Close()
+
+	responseBody, err := io.ReadAll(adapterResp.Body)
+	if err != nil {
+		write_error(w, apierror.AdapterError, "Failed to read adapter response")
+		return
+	}
+
+	// adapter accepted it, so it no longer needs replaying
+	replayed = !adapter_failed(adapterResp.StatusCode, nil)
+
+	write_adapter_response(w, adapterResp.StatusCode, g.filter_response(dl.Tool, responseBody))
+}
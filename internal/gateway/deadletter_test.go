@@ -0,0 +1,310 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// adapter URL that refuses connections
+func deadAdapterURL() string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+	return url
+}
+
+func TestDeadLetter_FailedForwardIsParked(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	gw.SetAdapter("payments", deadAdapterURL())
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {DeadLetter: true},
+	}})
+
+	body := map[string]interface{}{
+		"amount":   1000.0,
+		"currency": "USD",
+	}
+	bodyBytes, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("Expected status 502, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/deadletters", nil)
+	w = httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	var entries []DeadLetter
+	json.NewDecoder(w.Body).Decode(&entries)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d", len(entries))
+	}
+	dl := entries[0]
+	if dl.Tool != "payments" || dl.Action != "create" || dl.AgentID != "test-agent" {
+		t.Errorf("Unexpected dead letter: %+v", dl)
+	}
+	if dl.Params["amount"] != 1000.0 {
+		t.Errorf("Expected amount 1000 in params, got %v", dl.Params["amount"])
+	}
+	if dl.Error == "" || dl.Timestamp == "" {
+		t.Errorf("Expected error and timestamp to be recorded, got %+v", dl)
+	}
+}
+
+func TestDeadLetter_DisabledToolNotParked(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	gw.SetAdapter("payments", deadAdapterURL())
+
+	bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": 1000.0})
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	entries, _ := gw.deadLetters.List()
+	if len(entries) != 0 {
+		t.Errorf("Expected no dead letters for unconfigured tool, got %d", len(entries))
+	}
+}
+
+func TestDeadLetter_Replay(t *testing.T) {
+	gw, mockURL := setupTestGateway(t)
+	defer gw.Close()
+
+	gw.SetDeadLetterSink(NewFileDeadLetterSink(filepath.Join(t.TempDir(), "deadletters.jsonl")))
+	gw.SetAdapter("payments", deadAdapterURL())
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {DeadLetter: true},
+	}})
+
+	bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": 1000.0, "currency": "USD"})
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	entries, err := gw.deadLetters.List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d (err=%v)", len(entries), err)
+	}
+
+	// replay while the adapter is still down keeps the entry
+	req = httptest.NewRequest("POST", "/deadletters/"+entries[0].ID+"/replay", nil)
+	w = httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502 while adapter down, got %d", w.Code)
+	}
+	if remaining, _ := gw.deadLetters.List(); len(remaining) != 1 {
+		t.Errorf("Expected dead letter to be kept after failed replay, got %d", len(remaining))
+	}
+
+	// adapter recovers
+	gw.SetAdapter("payments", mockURL)
+	req = httptest.NewRequest("POST", "/deadletters/"+entries[0].ID+"/replay", nil)
+	w = httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on replay, got %d", w.Code)
+	}
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp["payment_id"] != "test-123" {
+		t.Errorf("Expected payment_id test-123, got %v", resp["payment_id"])
+	}
+	if remaining, _ := gw.deadLetters.List(); len(remaining) != 0 {
+		t.Errorf("Expected dead letter removed after replay, got %d", len(remaining))
+	}
+}
+
+func TestDeadLetter_ReplayUnknownID(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	req := httptest.NewRequest("POST", "/deadletters/nope/replay", nil)
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+func replay(gw *Gateway, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/deadletters/"+id+"/replay", nil)
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	return w
+}
+
+// a 5xx is as much a failed forward as no response, both when parking and
+// when replaying
+func TestDeadLetter_ServerErrorIsParked(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"payment_id":"p-1"}`))
+	}))
+	defer adapter.Close()
+
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	gw.SetAdapter("payments", adapter.URL)
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{"payments": {DeadLetter: true}}})
+
+	if w := call_payments(gw, "test-agent", "create", `{"amount":100}`); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected the adapter's 503, got %d", w.Code)
+	}
+	entries, _ := gw.deadLetters.List()
+	if len(entries) != 1 || entries[0].Error != "adapter returned status 503" {
+		t.Fatalf("Expected the 503 to be dead-lettered, got %+v", entries)
+	}
+
+	if w := replay(gw, entries[0].ID); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 from the replay, got %d", w.Code)
+	}
+	if remaining, _ := gw.deadLetters.List(); len(remaining) != 1 {
+		t.Fatalf("Expected the entry kept after a 503 replay, got %d", len(remaining))
+	}
+
+	failing.Store(false)
+	if w := replay(gw, entries[0].ID); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 once the adapter recovers, got %d", w.Code)
+	}
+	if remaining, _ := gw.deadLetters.List(); len(remaining) != 0 {
+		t.Errorf("Expected the entry removed, got %d", len(remaining))
+	}
+}
+
+func TestDeadLetter_SensitiveParamsRedacted(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	sink := NewFileDeadLetterSink(filepath.Join(t.TempDir(), "deadletters.jsonl"))
+	gw.SetDeadLetterSink(sink)
+	gw.SetAdapter("payments", deadAdapterURL())
+	gw.SetConfig(Config{
+		SensitiveParams: []string{"card.number", "memo"},
+		Tools:           map[string]ToolConfig{"payments": {DeadLetter: true}},
+	})
+
+	call_payments(gw, "test-agent", "create", `{"amount":100,"card":{"number":"4111111111111111","exp":"12/30"}}`)
+
+	data, _ := os.ReadFile(sink.path)
+	if bytes.Contains(data, []byte("4111111111111111")) {
+		t.Fatalf("Expected the card number not to be stored, got %s", data)
+	}
+	entries, _ := gw.deadLetters.List()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d", len(entries))
+	}
+	dl := entries[0]
+	if card, _ := dl.Params["card"].(map[string]interface{}); card["exp"] != "12/30" || len(dl.Redacted) != 1 || dl.Redacted[0] != "card.number" {
+		t.Errorf("Expected only card.number removed and listed, got %+v", dl)
+	}
+
+	// it can't be sent as it was, so it isn't sent at all
+	w := replay(gw, dl.ID)
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusBadRequest || resp.Code != "AEGIS-400-DEAD-LETTER" {
+		t.Errorf("Expected the replay to be refused, got %d %+v", w.Code, resp)
+	}
+}
+
+func TestDeadLetter_ReplayChecksPolicyAndBreaker(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	gw.SetConfig(Config{CircuitBreaker: BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute}})
+
+	// no longer allowed by the current policy
+	gw.deadLetters.Add(DeadLetter{ID: "over", AgentID: "test-agent", Tool: "payments", Action: "create", Params: map[string]interface{}{"amount": 10000.0}})
+	w := replay(gw, "over")
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusForbidden || resp.ReasonCode != "AMOUNT_EXCEEDED" {
+		t.Errorf("Expected the replay denied by policy, got %d %+v", w.Code, resp)
+	}
+	if remaining, _ := gw.deadLetters.List(); len(remaining) != 1 {
+		t.Errorf("Expected a denied replay to keep its entry, got %d", len(remaining))
+	}
+
+	// the circuit for payments is open
+	gw.deadLetters.Add(DeadLetter{ID: "ok", AgentID: "test-agent", Tool: "payments", Action: "create", Params: map[string]interface{}{"amount": 100.0}})
+	gw.breakers.record("payments", gw.cfg().CircuitBreaker, false)
+	w = replay(gw, "ok")
+	resp = ErrorResponse{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusServiceUnavailable || resp.Error != "AdapterUnavailable" {
+		t.Errorf("Expected the open circuit to reject the replay, got %d %+v", w.Code, resp)
+	}
+}
+
+// a replay claims its entry, so a second one at the same time isn't sent
+func TestDeadLetter_ConcurrentReplay(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	var forwarded atomic.Int32
+	arrived, release := make(chan struct{}), make(chan struct{})
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		forwarded.Add(1)
+		arrived <- struct{}{}
+		<-release
+		w.Write([]byte(`{"payment_id":"p-1"}`))
+	}))
+	defer adapter.Close()
+
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	gw.SetAdapter("payments", adapter.URL)
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{"payments": {DeadLetter: true}}})
+
+	call_payments(gw, "test-agent", "create", `{"amount":100}`)
+	entries, _ := gw.deadLetters.List()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d", len(entries))
+	}
+	failing.Store(false)
+
+	first := make(chan int)
+	go func() { first <- replay(gw, entries[0].ID).Code }()
+	<-arrived
+
+	if w := replay(gw, entries[0].ID); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 while the entry is being replayed, got %d", w.Code)
+	}
+	close(release)
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("Expected the first replay to succeed, got %d", code)
+	}
+	if n := forwarded.Load(); n != 1 {
+		t.Errorf("Expected the entry forwarded once, got %d", n)
+	}
+	if remaining, _ := gw.deadLetters.List(); len(remaining) != 0 {
+		t.Errorf("Expected the entry removed, got %d", len(remaining))
+	}
+}
@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aegis-gateway/internal/policy"
+)
+
+// longest X-Aegis-Reason sent, in bytes
+const maxReasonHeader = 256
+
+// with decision_headers on, say how the policy decided on the response
+// itself: X-Aegis-Decision (allow or deny), X-Aegis-Policy-Version and,
+// for denials, X-Aegis-Reason
+func set_decision_headers(w http.ResponseWriter, d policy.Decision) {
+	h := w.Header()
+	if d.Allow {
+		h.Set("X-Aegis-Decision", "allow")
+	} else {
+		h.Set("X-Aegis-Decision", "deny")
+		h.Set("X-Aegis-Reason", header_safe(d.Reason, maxReasonHeader))
+	}
+	h.Set("X-Aegis-Policy-Version", strconv.Itoa(d.Version))
+}
+
+// printable ASCII only, anything else becomes '?', cut to max bytes.
+// reasons echo request params, which mustn't be able to inject headers
+func header_safe(s string, max int) string {
+	var b strings.Builder
+	for _, r := range s {
+		if b.Len() >= max {
+			break
+		}
+		if r < 0x20 || r > 0x7e {
+			r = '?'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
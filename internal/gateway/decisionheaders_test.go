@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDecisionHeaders(t *testing.T) {
+	gw, mockURL := setupTestGateway(t)
+	defer gw.Close()
+	gw.SetConfig(Config{Adapters: map[string]string{"payments": mockURL}, DecisionHeaders: true})
+
+	w := post_payment(gw, `{"amount": 100}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("X-Aegis-Decision"); got != "allow" {
+		t.Errorf("Expected X-Aegis-Decision allow, got %q", got)
+	}
+	if got := w.Header().Get("X-Aegis-Policy-Version"); got != "1" {
+		t.Errorf("Expected X-Aegis-Policy-Version 1, got %q", got)
+	}
+	if got := w.Header().Get("X-Aegis-Reason"); got != "" {
+		t.Errorf("Expected no reason on an allow, got %q", got)
+	}
+
+	w = post_payment(gw, `{"amount": 9000}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, got %d", w.Code)
+	}
+	if got := w.Header().Get("X-Aegis-Decision"); got != "deny" {
+		t.Errorf("Expected X-Aegis-Decision deny, got %q", got)
+	}
+	if got := w.Header().Get("X-Aegis-Reason"); got != "Amount 9000.00 exceeds max_amount=5000.00" {
+		t.Errorf("Unexpected X-Aegis-Reason %q", got)
+	}
+}
+
+func TestDecisionHeadersOffByDefault(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	for _, body := range []string{`{"amount": 100}`, `{"amount": 9000}`} {
+		w := post_payment(gw, body)
+		for _, h := range []string{"X-Aegis-Decision", "X-Aegis-Policy-Version", "X-Aegis-Reason"} {
+			if got := w.Header().Get(h); got != "" {
+				t.Errorf("Expected no %s without decision_headers, got %q", h, got)
+			}
+		}
+	}
+}
+
+func TestHeaderSafe(t *testing.T) {
+	if got := header_safe("Path /a\r\nX-Evil: 1 café", 256); got != "Path /a??X-Evil: 1 caf?" {
+		t.Errorf("Expected control and non-ASCII characters replaced, got %q", got)
+	}
+	if got := header_safe(strings.Repeat("x", 300), 256); len(got) != 256 {
+		t.Errorf("Expected the reason cut to 256 bytes, got %d", len(got))
+	}
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aegis-gateway/pkg/apierror"
+)
+
+// every error path answers with the shared envelope and its documented code
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		method   string
+		path     string
+		agent    string
+		ctype    string
+		body     string
+		repeat   int
+		wantCode int
+		want     string
+	}{
+		{name: "missing agent header", path: "/tools/payments/create", body: `{"amount":100}`,
+			wantCode: 400, want: "AEGIS-400-HEADER"},
+		{name: "invalid json", path: "/tools/payments/create", agent: "test-agent", body: `{`,
+			wantCode: 400, want: "AEGIS-400-REQUEST"},
+		{name: "api key required", config: Config{RequireAPIKeys: true}, path: "/tools/payments/create", agent: "test-agent", body: `{"amount":100}`,
+			wantCode: 401, want: "AEGIS-401-AUTH"},
+		{name: "policy violation", path: "/tools/payments/create", agent: "test-agent", body: `{"amount":10000}`,
+			wantCode: 403, want: "AEGIS-403-POLICY"},
+		{name: "body too large", config: Config{MaxRequestBytes: 8}, path: "/tools/payments/create", agent: "test-agent", body: `{"amount":100,"currency":"USD"}`,
+			wantCode: 413, want: "AEGIS-413-REQUEST-SIZE"},
+		{name: "wrong content type", path: "/tools/payments/create", agent: "test-agent", ctype: "text/plain", body: `{"amount":100}`,
+			wantCode: 415, want: "AEGIS-415-MEDIA-TYPE"},
+		{name: "rate limited", config: Config{RateLimit: RateLimitConfig{RequestsPerSecond: 0.001, Burst: 1}}, path: "/tools/payments/create", agent: "test-agent", body: `{"amount":100}`, repeat: 1,
+			wantCode: 429, want: "AEGIS-429-RATE-LIMIT"},
+		{name: "adapter down", config: Config{Adapters: map[string]string{"payments": deadAdapterURL()}}, path: "/tools/payments/create", agent: "test-agent", body: `{"amount":100}`,
+			wantCode: 502, want: "AEGIS-502-ADAPTER"},
+		{name: "evaluate without fields", method: "POST", path: "/policies/evaluate", body: `{}`,
+			wantCode: 400, want: "AEGIS-400-REQUEST"},
+		{name: "unknown dead letter", method: "POST", path: "/deadletters/nope/replay",
+			wantCode: 404, want: "AEGIS-404-NOT-FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw, _ := setupTestGateway(t)
+			defer gw.Close()
+			if err := gw.SetConfig(tt.config); err != nil {
+				t.Fatalf("SetConfig() error = %v", err)
+			}
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i <= tt.repeat; i++ {
+				method := tt.method
+				if method == "" {
+					method = "POST"
+				}
+				req := httptest.NewRequest(method, tt.path, strings.NewReader(tt.body))
+				if tt.agent != "" {
+					req.Header.Set("X-Agent-ID", tt.agent)
+				}
+				if tt.ctype != "" {
+					req.Header.Set("Content-Type", tt.ctype)
+				}
+				w = httptest.NewRecorder()
+				gw.router.ServeHTTP(w, req)
+			}
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected a JSON error body, got Content-Type %q", ct)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode error body: %v", err)
+			}
+			if resp.Code != tt.want || resp.Error == "" || resp.Reason == "" {
+				t.Errorf("Expected code %s with error and reason set, got %+v", tt.want, resp)
+			}
+		})
+	}
+}
+
+// adapter errors reach the client unchanged, in the same envelope
+func TestAdapterErrorEnvelope(t *testing.T) {
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, apierror.InvalidRequest, "Amount must be positive")
+	}))
+	defer adapter.Close()
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	gw.SetAdapter("payments", adapter.URL)
+
+	req := httptest.NewRequest("POST", "/tools/payments/create", strings.NewReader(`{"amount":-5}`))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the adapter's 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Code != "AEGIS-400-REQUEST" || resp.Reason != "Amount must be positive" {
+		t.Errorf("Unexpected adapter error: %+v", resp)
+	}
+}
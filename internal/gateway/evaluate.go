@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"aegis-gateway/internal/policy"
+	"aegis-gateway/pkg/apierror"
+)
+
+// body of POST /policies/evaluate
+type EvaluateRequest struct {
+	AgentID string                 `json:"agent_id"`
+	Tool    string                 `json:"tool"`
+	Action  string                 `json:"action"`
+	Params  map[string]interface{} `json:"params"`
+	Headers map[string]string      `json:"headers,omitempty"`
+}
+
+// dry-run a request against the loaded policies. nothing is forwarded and
+// stateful conditions don't record anything
+func (g *Gateway) handle_evaluate(w http.ResponseWriter, r *http.Request) {
+	var req EvaluateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AgentID == "" || req.Tool == "" || req.Action == "" {
+		write_error(w, apierror.InvalidRequest, "Body must be JSON with agent_id, tool and action")
+		return
+	}
+	if req.Params == nil {
+		req.Params = map[string]interface{}{}
+	}
+
+	// same header form the tool endpoint passes to conditions
+	headers := make(map[string]string, len(req.Headers))
+	for name, value := range req.Headers {
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+
+	decision := g.policyManager.EvaluateRequest(policy.Request{
+		AgentID: req.AgentID,
+		Tool:    req.Tool,
+		Action:  req.Action,
+		Params:  req.Params,
+		Headers: headers,
+		DryRun:  true,
+		Context: r.Context(),
+
+		StrictAmounts: g.cfg().StrictAmounts,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(decision)
+}
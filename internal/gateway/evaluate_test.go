@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"aegis-gateway/internal/policy"
+)
+
+func evaluate(gw *Gateway, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/policies/evaluate", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestEvaluateEndpoint(t *testing.T) {
+	var calls atomic.Int32
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, `version: 1
+agents:
+  - id: test-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+`, map[string]string{"payments": adapter.URL})
+
+	tests := []struct {
+		name  string
+		body  string
+		allow bool
+	}{
+		{"allowed", `{"agent_id":"test-agent","tool":"payments","action":"create","params":{"amount":1000}}`, true},
+		{"denied by condition", `{"agent_id":"test-agent","tool":"payments","action":"create","params":{"amount":10000}}`, false},
+		{"unknown agent", `{"agent_id":"nobody","tool":"payments","action":"create","params":{"amount":1}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := evaluate(gw, tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var d policy.Decision
+			json.NewDecoder(w.Body).Decode(&d)
+			if d.Allow != tt.allow {
+				t.Errorf("Expected allow=%v, got %+v", tt.allow, d)
+			}
+			if d.Reason == "" {
+				t.Errorf("Expected a reason in decision, got %+v", d)
+			}
+		})
+	}
+
+	if n := calls.Load(); n != 0 {
+		t.Errorf("Expected no adapter calls, got %d", n)
+	}
+}
+
+func TestEvaluateEndpoint_InvalidRequest(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	for _, body := range []string{`not json`, `{"agent_id":"test-agent","tool":"payments"}`} {
+		if w := evaluate(gw, body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"aegis-gateway/internal/policy"
+	"aegis-gateway/pkg/apierror"
 	"aegis-gateway/pkg/telemetry"
 
 	"github.com/fsnotify/fsnotify"
@@ -21,17 +27,64 @@ import (
 type Gateway struct {
 	policyManager *policy.Manager
 	router        *mux.Router
-	adapters      map[string]string  // tool name -> URL
+	server        *http.Server
 	watcher       *fsnotify.Watcher
+	deadLetters   DeadLetterSink
+	flights       flightGroup
+	breakers      *breakerSet
+	concurrency   *concurrencyLimiter
+	limiter       *rateLimiter
+	metrics       *gatewayMetrics
+	stats         *gatewayStats
+
+	// current config, swapped atomically on reload. configMu serializes
+	// the swaps, so changes made at the same time don't undo each other
+	state      atomic.Pointer[runtimeState]
+	configMu   sync.Mutex
+	configPath string
+	policyDirs []string
+
+	// credentials for the admin routes, nil leaves them open
+	adminAuth *adminAuth
+
+	// per-request HTTP log, nil when access_log is off
+	accessLog atomic.Pointer[accessLog]
+
+	// nil when maintenance mode is off
+	maintenance atomic.Pointer[MaintenanceMode]
+
+	// delivers denial events to Config.DenialWebhooks
+	webhooks *webhookNotifier
 }
 
+// the shared error envelope, see apierror, plus gateway-only details
 type ErrorResponse struct {
-	Error  string `json:"error"`
-	Reason string `json:"reason,omitempty"`
+	apierror.Response
+
+	// stable code for a policy denial's reason, see policy.ReasonCode
+	ReasonCode policy.ReasonCode `json:"reason_code,omitempty"`
+
+	// condition trace for denied debug requests
+	Trace []policy.ConditionResult `json:"trace,omitempty"`
+
+	// files that failed a rejected policy reload
+	Errors []policy.PolicyLoadError `json:"errors,omitempty"`
+}
+
+// every gateway error goes through here or apierror.WriteBody, so clients
+// always get the same envelope
+func write_error(w http.ResponseWriter, k apierror.Kind, reason string) {
+	apierror.Write(w, k, reason)
 }
 
 func NewGateway(policyDir string, adapters map[string]string) (*Gateway, error) {
-	pm, err := policy.NewManager(policyDir)
+	return NewGatewayDirs([]string{policyDir}, adapters)
+}
+
+// gateway over several policy directories, highest precedence first,
+// see policy.NewManager
+func NewGatewayDirs(policyDirs []string, adapters map[string]string) (*Gateway, error) {
+	pm, err := policy.NewManager(policyDirs...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create policy manager: %w", err)
 	}
@@ -41,16 +94,26 @@ func NewGateway(policyDir string, adapters map[string]string) (*Gateway, error)
 		return nil, fmt.Errorf("failed to create file watcher: %w", err)
 	}
 
-	if err := watcher.Add(policyDir); err != nil {
+	if err := watch_policy_dirs(watcher, policyDirs); err != nil {
 		return nil, fmt.Errorf("failed to watch policy directory: %w", err)
 	}
 
 	g := &Gateway{
 		policyManager: pm,
 		router:        mux.NewRouter(),
-		adapters:      adapters,
 		watcher:       watcher,
+		policyDirs:    policyDirs,
+		deadLetters:   NewMemoryDeadLetterSink(),
+		breakers:      newBreakerSet(),
+		concurrency:   newConcurrencyLimiter(),
+		limiter:       newRateLimiter(),
+		metrics:       newGatewayMetrics(),
+		stats:         newGatewayStats(),
+		webhooks:      newWebhookNotifier(),
 	}
+	g.state.Store(newRuntimeState(Config{Adapters: adapters}))
+	pm.SetPaymentOwners(adapterPaymentOwners{g})
+	g.server = &http.Server{Handler: g.access_log_middleware(g.router)}
 
 	g.setupRoutes()
 	go g.watchPolicies()
@@ -60,53 +123,120 @@ func NewGateway(policyDir string, adapters map[string]string) (*Gateway, error)
 
 func (g *Gateway) setupRoutes() {
 	// main tool execution endpoint
-	g.router.HandleFunc("/tools/{tool}/{action}", g.handleToolRequest).Methods("POST")
+	g.router.HandleFunc("/tools/{tool}/{action}", g.handleToolRequest).Methods("POST", "GET")
+	g.router.HandleFunc("/tools/batch", g.handle_batch).Methods("POST")
 	
 	// admin endpoints
 	g.router.HandleFunc("/health", g.handle_health).Methods("GET")
-	g.router.HandleFunc("/policies/reload", g.handle_reload).Methods("POST")
+	g.router.HandleFunc("/health/ready", g.handle_ready).Methods("GET")
+	g.router.Handle("/policies", g.admin_func(g.handle_list_policies)).Methods("GET")
+	g.router.Handle("/policies/reload", g.admin_func(g.handle_reload)).Methods("POST")
+	g.router.Handle("/policies/status", g.admin_func(g.handle_policy_status)).Methods("GET")
+	g.router.Handle("/policies/evaluate", g.admin_func(g.handle_evaluate)).Methods("POST")
+	g.router.Handle("/policies/validate", g.admin_func(g.handle_validate)).Methods("POST")
+	g.router.Handle("/deadletters", g.admin_func(g.handle_list_deadletters)).Methods("GET")
+	g.router.Handle("/deadletters/{id}/replay", g.admin_func(g.handle_replay_deadletter)).Methods("POST")
+	g.router.Handle("/config/reload", g.admin_func(g.handle_config_reload)).Methods("POST")
+	g.router.Handle("/stats", g.admin_func(g.handle_stats)).Methods("GET")
+	g.router.Handle("/version", g.admin_func(g.handle_version)).Methods("GET")
+	g.router.Handle("/maintenance", g.admin_func(g.handle_get_maintenance)).Methods("GET")
+	g.router.Handle("/maintenance", g.admin_func(g.handle_set_maintenance)).Methods("POST")
+	g.router.Handle("/openapi.json", g.admin_func(g.handle_openapi)).Methods("GET")
+	g.setup_metrics_route()
+
+	// CORS preflight for any route
+	g.router.PathPrefix("/").Methods("OPTIONS").HandlerFunc(g.handle_preflight)
+	g.router.Use(origin_middleware)
+	g.router.Use(g.cors_middleware)
+	g.router.Use(g.timeout_middleware)
+}
+
+// HMAC keys for require_dual_approval tokens, keyed by approver ID. the
+// shadow policies get them too
+func (g *Gateway) SetApproverKeys(keys map[string][]byte) {
+	g.policyManager.SetApproverKeys(keys)
+	if sm := g.policyManager.Shadow(); sm != nil {
+		sm.SetApproverKeys(keys)
+	}
+}
+
+// approved change windows for require_change_window. the shadow policies
+// get them too
+func (g *Gateway) SetChangeWindows(cw policy.ChangeWindows) {
+	g.policyManager.SetChangeWindows(cw)
+	if sm := g.policyManager.Shadow(); sm != nil {
+		sm.SetChangeWindows(cw)
+	}
+}
+
+// persist monotonic_field state to path, so a restart can't be used to
+// replay old values. saved values are loaded first
+func (g *Gateway) SetSequenceStateFile(path string) error {
+	return g.policyManager.SetSequenceStateFile(path)
+}
+
+// where owns_payment looks up who created a payment (defaults to the
+// payments adapter)
+func (g *Gateway) SetPaymentOwners(po policy.PaymentOwners) {
+	g.policyManager.SetPaymentOwners(po)
+}
+
+// replace the dead-letter sink (defaults to in-memory)
+func (g *Gateway) SetDeadLetterSink(sink DeadLetterSink) {
+	g.deadLetters = sink
 }
 
 func (g *Gateway) handle_health(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]interface{}{"status": "healthy"}
+	if circuits := g.breakers.states(); len(circuits) > 0 {
+		resp["circuits"] = circuits
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (g *Gateway) handle_reload(w http.ResponseWriter, r *http.Request) {
+	g.reload_shadow_policies(false)
 	err := g.policyManager.Reload()
+
+	// nothing was applied, the previous policies are still active
+	var loadErrs policy.LoadErrorList
+	if errors.As(err, &loadErrs) {
+		apierror.WriteBody(w, apierror.PolicyReloadRejected.Status, ErrorResponse{
+			Response: apierror.PolicyReloadRejected.Response(fmt.Sprintf("%d policy file(s) failed to load, previous policies kept", len(loadErrs))),
+			Errors:   loadErrs,
+		})
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error":"ReloadFailed","message":"%s"}`, err.Error()), http.StatusInternalServerError)
+		write_error(w, apierror.ReloadFailed, err.Error())
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "reloaded"})
 }
 
-// watch for policy file changes and auto-reload
+// loaded files, any that failed on the last load and migrations applied
+func (g *Gateway) handle_policy_status(w http.ResponseWriter, r *http.Request) {
+	errs := g.policyManager.LoadErrors()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     len(errs) == 0,
+		"files":  g.policyManager.PolicyFiles(),
+		"errors": errs,
+		"notes":  g.policyManager.LoadNotes(),
+	})
+}
+
+// currently loaded policies; API key hashes and condition values are left out
+func (g *Gateway) handle_list_policies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.policyManager.Summaries())
+}
+
+// watch for policy file changes and auto-reload, once per burst of events
 func (g *Gateway) watchPolicies() {
-	for {
-		select {
-		case event, ok := <-g.watcher.Events:
-			if !ok {
-				return
-			}
-			// reload on write or create
-			if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create {
-				fmt.Printf("Policy file changed: %s, reloading...\n", event.Name)
-				err := g.policyManager.Reload()
-				if err != nil {
-					fmt.Printf("ERROR: failed to reload policies: %v\n", err)
-				} else {
-					fmt.Println("Policies reloaded successfully")
-				}
-			}
-		case err, ok := <-g.watcher.Errors:
-			if !ok {
-				return
-			}
-			fmt.Printf("ERROR: watcher error: %v\n", err)
-		}
-	}
+	watch_policy_events(g.watcher.Events, g.watcher.Errors, policyReloadDebounce, g.reload_watched_policies)
 }
 
 // main handler for tool execution requests
@@ -117,51 +247,102 @@ func (g *Gateway) handleToolRequest(w http.ResponseWriter, r *http.Request) {
 	ctx, span := telemetry.StartSpan(ctx, "gateway.handleToolRequest")
 	defer span.End()
 
+	// echoed on every response, including errors, and sent to the adapter
+	requestID := request_id(r)
+	ctx = with_request_id(ctx, requestID)
+	w.Header().Set(requestIDHeader, requestID)
+
 	// extract tool and action from URL
 	vars := mux.Vars(r)
 	toolName := vars["tool"]
 	actionName := vars["action"]
 
-	agentID := r.Header.Get("X-Agent-ID")
-	parentAgent := r.Header.Get("X-Parent-Agent")
+	// GET is for reading, and only offered where the adapter reads too
+	if r.Method == http.MethodGet && g.cfg().method(toolName, actionName) != http.MethodGet {
+		w.Header().Set("Allow", http.MethodPost)
+		write_error(w, apierror.MethodNotAllowed, fmt.Sprintf("%s/%s is not configured for GET", toolName, actionName))
+		return
+	}
+
+	if g.maintenance.Load().blocks(toolName, actionName) {
+		write_error(w, apierror.Maintenance, fmt.Sprintf("Gateway is in maintenance mode for %s/%s", toolName, actionName))
+		return
+	}
+
+	agentID := r.Header.Get(agentIDHeader)
+	parentAgent := r.Header.Get(parentAgentHeader)
 
 	// agent ID is required
 	if agentID == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:  "MissingHeader",
-			Reason: "X-Agent-ID header is required",
-		})
+		write_error(w, apierror.MissingHeader, "X-Agent-ID header is required")
 		return
 	}
 
+	// authenticate before rate limiting so a caller can't drain another
+	// agent's budget
+	if !g.authenticate(agentID, r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		write_error(w, apierror.Unauthorized, fmt.Sprintf("Invalid or missing API key for agent: %s", agentID))
+		return
+	}
+	ctx = with_agent_id(ctx, agentID)
+
+	if ok, wait := g.limiter.allow(agentID, time.Now(), g.cfg().RateLimit); !ok {
+		set_retry_after(w, wait)
+		write_error(w, apierror.RateLimited, fmt.Sprintf("Rate limit exceeded for agent: %s", agentID))
+		return
+	}
+
+	if r.Method != http.MethodGet && !json_content_type(r.Header.Get("Content-Type"), g.cfg().RequireContentType) {
+		write_error(w, apierror.UnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, g.cfg().max_request_bytes(toolName))
 	requestBody, err := io.ReadAll(r.Body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		write_error(w, apierror.RequestTooLarge, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+		return
+	}
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:  "InvalidRequest",
-			Reason: "Failed to read request body",
-		})
+		write_error(w, apierror.InvalidRequest, "Failed to read request body")
 		return
 	}
+	requestBytes := int64(len(requestBody))
 
+	// GET tool requests carry their params in the query string
 	var requestParams map[string]interface{}
-	if err := json.Unmarshal(requestBody, &requestParams); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:  "InvalidRequest",
-			Reason: "Request body must be valid JSON",
-		})
+	if r.Method == http.MethodGet {
+		requestParams = query_params(r.URL.Query())
+		requestBody, _ = json.Marshal(requestParams)
+	} else if err := json.Unmarshal(requestBody, &requestParams); err != nil {
+		write_error(w, apierror.InvalidRequest, "Request body must be valid JSON")
 		return
 	}
 
-	paramsHash := policy.HashParams(requestParams)
+	// defaults/normalization apply to both policy and the forwarded body
+	if g.normalize_params(toolName, requestParams) {
+		requestBody, _ = json.Marshal(requestParams)
+	}
+
+	// sensitive fields don't contribute to the hash that's logged and traced
+	paramsHash := policy.HashParams(g.cfg().redact_params(requestParams))
 
 	// evaluate policy
-	decision := g.policyManager.Evaluate(agentID, toolName, actionName, requestParams)
+	evalStart := time.Now()
+	decision := g.policyManager.EvaluateRequest(policy.Request{
+		AgentID: agentID,
+		Tool:    toolName,
+		Action:  actionName,
+		Params:  requestParams,
+		Headers: policy_headers(r),
+		Debug:   g.cfg().DebugTrace && r.Header.Get(debugConditionsHeader) == "true",
+		Context: ctx,
+
+		StrictAmounts: g.cfg().StrictAmounts,
+	})
+	g.stats.policy.observe(time.Since(evalStart))
 	latencyMs := float64(time.Since(startTime).Microseconds()) / 1000.0
 
 	// add telemetry attributes
@@ -174,85 +355,330 @@ func (g *Gateway) handleToolRequest(w http.ResponseWriter, r *http.Request) {
 		"params.hash":     paramsHash,
 		"latency.ms":      latencyMs,
 		"parent.agent":    parentAgent,
+		"request.id":      requestID,
 	})
 
-	telemetry.LogDecision(ctx, agentID, toolName, actionName, decision.Reason, paramsHash, parentAgent, decision.Allow, decision.Version, latencyMs)
+	metricTool, metricAction := g.metric_labels(toolName, actionName)
+	g.metrics.decision(metricTool, metricAction, decision.Allow)
+	telemetry.RecordDecision(ctx, agentID, toolName, actionName, policy.ReasonCategory(decision.Reason), decision.Allow)
+	audit := telemetry.AuditLog{
+		RequestID:    requestID,
+		AgentID:      agentID,
+		Tool:         toolName,
+		Action:       actionName,
+		Decision:     decision.Allow,
+		Reason:       decision.Reason,
+		Version:      decision.Version,
+		ParamsHash:   paramsHash,
+		LatencyMs:    latencyMs,
+		ParentAgent:  parentAgent,
+		RequestBytes: requestBytes,
+	}
+	shadow_audit(&audit, decision)
+	if g.cfg().DecisionHeaders {
+		set_decision_headers(w, decision)
+	}
+
+	// denials are audited now. allows are audited once the adapter has
+	// responded so the response size is known, unless failing closed, where
+	// the record has to be written before the action runs
+	var auditErr error
+	var responseBytes int64
+	if !decision.Allow {
+		auditErr = telemetry.LogAudit(ctx, audit)
+	} else if g.cfg().AuditFailClosed {
+		auditErr = telemetry.LogAuditSync(ctx, audit)
+	} else {
+		defer func() {
+			audit.ResponseBytes = responseBytes
+			telemetry.LogAudit(ctx, audit)
+		}()
+	}
 
 	// check if policy allows this
 	if !decision.Allow {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:  "PolicyViolation",
-			Reason: decision.Reason,
+		g.webhooks.notify(g.cfg().DenialWebhooks, DenialEvent{
+			Event:         denialEventType,
+			Timestamp:     time.Now().UTC().Format(time.RFC3339),
+			RequestID:     requestID,
+			AgentID:       agentID,
+			ParentAgent:   parentAgent,
+			Tool:          toolName,
+			Action:        actionName,
+			Reason:        decision.Reason,
+			ReasonCode:    decision.ReasonCode,
+			PolicyVersion: decision.Version,
+			ParamsHash:    paramsHash,
+		})
+		apierror.WriteBody(w, apierror.PolicyViolation.Status, ErrorResponse{
+			Response:   apierror.PolicyViolation.Response(decision.Reason),
+			ReasonCode: decision.ReasonCode,
+			Trace:      decision.Trace,
 		})
 		return
 	}
 
+	// an action that can't be audited doesn't run when failing closed
+	if auditErr != nil && g.cfg().AuditFailClosed {
+		write_error(w, apierror.AuditUnavailable, "Audit log is unavailable")
+		return
+	}
+
+	// adapter owns the body on success, so the trace travels in a header
+	if decision.Trace != nil {
+		if data, err := json.Marshal(decision.Trace); err == nil {
+			w.Header().Set("X-Aegis-Condition-Trace", string(data))
+		}
+	}
+
 	// find the adapter for this tool
-	adapterURL, ok := g.adapters[toolName]
+	cfg := g.cfg()
+	adapterURL, ok := cfg.Adapters[toolName]
 	if !ok {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:  "AdapterNotFound",
-			Reason: fmt.Sprintf("No adapter configured for tool: %s", toolName),
-		})
+		write_error(w, apierror.AdapterNotFound, fmt.Sprintf("No adapter configured for tool: %s", toolName))
 		return
 	}
 
-	// forward request to adapter
-	targetURL := fmt.Sprintf("%s/%s", strings.TrimSuffix(adapterURL, "/"), actionName)
-	adapterResp, err := g.forward_to_adapter(ctx, targetURL, requestBody)
+	// reshape the body for the adapter if the tool has a mapping configured
+	adapterBody, err := g.adapter_body(toolName, actionName, requestParams, requestBody)
+	if errors.Is(err, errMappedField) {
+		write_error(w, apierror.InvalidRequest, err.Error())
+		return
+	}
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadGateway)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:  "AdapterError",
-			Reason: err.Error(),
-		})
+		write_error(w, apierror.TransformError, "Failed to transform request body")
 		return
 	}
-	defer adapterResp.Body.Close()
 
-	responseBody, err := io.ReadAll(adapterResp.Body)
+	// adapter path, from the action's template when one is configured, and
+	// the params as a query string for GET/DELETE actions
+	method := cfg.method(toolName, actionName)
+	targetURL, err := cfg.target_url(adapterURL, toolName, actionName, requestParams)
+	if err == nil {
+		targetURL, adapterBody, err = adapter_request(method, targetURL, adapterBody)
+	}
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadGateway)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:  "AdapterError",
-			Reason: "Failed to read adapter response",
+		write_error(w, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	// forward request to adapter. responses are streamed unless the action
+	// coalesces or the tool filters responses; identical in-flight calls to
+	// coalescing actions share one adapter call
+	timeout, retry := cfg.timeout(toolName, actionName), cfg.retry(toolName, actionName)
+	upstream := cfg.upstream(toolName)
+	forward := func(ctx context.Context) (adapterResult, error) {
+		start := time.Now()
+		res, err := g.call_adapter(ctx, method, targetURL, adapterBody, timeout, retry, upstream)
+		elapsed := time.Since(start)
+		g.metrics.adapter_call(metricTool, metricAction, elapsed, err)
+		g.stats.adapter.observe(elapsed)
+		// an oversized response still means the adapter is up
+		g.breakers.record(toolName, cfg.CircuitBreaker, (err == nil && res.status < 500) || errors.Is(err, errAdapterResponseTooLarge))
+		return res, err
+	}
+
+	// held until the response is written, streamed ones included. taken
+	// before the breaker check, which may hand out the half-open probe
+	// that only an adapter call gives back
+	release, ok := g.acquire_concurrency(ctx, cfg, agentID, toolName)
+	if !ok {
+		set_retry_after(w, 0)
+		write_error(w, apierror.ConcurrencyLimit, fmt.Sprintf("Too many concurrent requests for tool %s or agent %s", toolName, agentID))
+		return
+	}
+	defer release()
+	// fail fast while the adapter is known to be down
+	if ok, wait := g.breakers.allow(toolName, cfg.CircuitBreaker); !ok {
+		set_retry_after(w, wait)
+		write_error(w, apierror.AdapterUnavailable, fmt.Sprintf("Circuit open for tool: %s", toolName))
+		return
+	}
+	if cfg.streams(toolName, actionName) {
+		start := time.Now()
+		resp, err := g.call_adapter_stream(ctx, method, targetURL, adapterBody, timeout, retry, upstream)
+		elapsed := time.Since(start)
+		g.metrics.adapter_call(metricTool, metricAction, elapsed, err)
+		g.stats.adapter.observe(elapsed)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		g.breakers.record(toolName, cfg.CircuitBreaker, !adapter_failed(status, err))
+		if adapter_failed(status, err) && cfg.tool(toolName).DeadLetter {
+			g.dead_letter(agentID, toolName, actionName, requestParams, status, err)
+		}
+		if err != nil {
+			write_adapter_failure(w, ctx, err)
+			return
+		}
+		responseBytes, err = stream_adapter_response(w, resp)
+		if err != nil {
+			fmt.Printf("ERROR: %s/%s: %v\n", toolName, actionName, err)
+		}
+		return
+	}
+
+	var result adapterResult
+	if cfg.tool(toolName).Actions[actionName].Coalesce {
+		// the shared call must outlive any one caller's cancellation
+		shared := context.WithoutCancel(ctx)
+		// keyed on every field, requests differing only in a sensitive one
+		// mustn't share a call, and on the agent, whose ID the adapter gets
+		// and may answer differently for
+		key := toolName + "/" + actionName + "/" + policy.HashParams(requestParams) + "/" + agentID
+		result, err = g.flights.do(ctx, key, func() (adapterResult, error) {
+			return forward(shared)
 		})
+	} else {
+		result, err = forward(ctx)
+	}
+	if errors.Is(err, errReadAdapterResponse) {
+		write_error(w, apierror.AdapterError, "Failed to read adapter response")
+		return
+	}
+	if errors.Is(err, errAdapterResponseTooLarge) {
+		write_error(w, apierror.ResponseTooLarge, fmt.Sprintf("Adapter response exceeds %d bytes", upstream.maxResponseBytes))
+		return
+	}
+	if adapter_failed(result.status, err) && cfg.tool(toolName).DeadLetter {
+		g.dead_letter(agentID, toolName, actionName, requestParams, result.status, err)
+	}
+	if err != nil {
+		write_adapter_failure(w, ctx, err)
 		return
 	}
 
+	// strip fields the agent shouldn't see
+	responseBytes = int64(len(result.body))
+	responseBody := g.filter_response(toolName, result.body)
+
 	// return adapter response
+	write_adapter_response(w, result.status, responseBody)
+}
+
+// relay an adapter response. empty bodies (204 or otherwise) are passed
+// through with their status and no forced JSON content type.
+func write_adapter_response(w http.ResponseWriter, status int, body []byte) {
+	mark_adapter_origin(w)
+	if status == http.StatusNoContent || len(body) == 0 {
+		w.WriteHeader(status)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(adapterResp.StatusCode)
-	w.Write(responseBody)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// whether a tool request body is declared as JSON. a missing header is
+// taken as JSON unless required
+func json_content_type(header string, required bool) bool {
+	if header == "" {
+		return !required
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	return err == nil && mediaType == "application/json"
+}
+
+// check the bearer key for the claimed agent. agents without a configured
+// key pass unless require_api_keys is set
+func (g *Gateway) authenticate(agentID string, r *http.Request) bool {
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		key = ""
+	}
+	err := g.policyManager.CheckAPIKey(agentID, key)
+	if errors.Is(err, policy.ErrNoAPIKey) {
+		return !g.cfg().RequireAPIKeys
+	}
+	return err == nil
+}
+
+// headers visible to policy conditions; credentials are never passed on
+func policy_headers(r *http.Request) map[string]string {
+	headers := make(map[string]string, len(r.Header))
+	for name, values := range r.Header {
+		if name == "Authorization" || name == "Cookie" || len(values) == 0 {
+			continue
+		}
+		headers[name] = values[0]
+	}
+	return headers
+}
+
+// one adapter call. timeout bounds all of it, reading the body included;
+// see forward_to_adapter_stream for responses relayed as they arrive
+func (g *Gateway) forward_to_adapter(ctx context.Context, method, url string, body []byte, timeout time.Duration, up upstreamOptions) (*http.Response, error) {
+	return g.send_to_adapter(ctx, method, url, body, up, &http.Client{Timeout: timeout})
 }
 
-func (g *Gateway) forward_to_adapter(ctx context.Context, url string, body []byte) (*http.Response, error) {
+func (g *Gateway) send_to_adapter(ctx context.Context, method, url string, body []byte, up upstreamOptions, httpClient *http.Client) (*http.Response, error) {
 	ctx, span := telemetry.StartSpan(ctx, "gateway.forward_to_adapter")
 	defer span.End()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, values := range up.headers {
+		req.Header[name] = values
+	}
+	if id := request_id_from(ctx); id != "" {
+		req.Header.Set(requestIDHeader, id)
+	}
+	if id := agent_id_from(ctx); id != "" {
+		req.Header.Set(agentIDHeader, id)
+	}
+	telemetry.InjectHeaders(ctx, req.Header)
 
-	// 10 second timeout for adapter calls
-	httpClient := &http.Client{Timeout: 10 * time.Second}
 	return httpClient.Do(req)
 }
 
 func (g *Gateway) Start(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
 	fmt.Printf("Gateway listening on %s\n", addr)
-	return http.ListenAndServe(addr, g.router)
+	return g.Serve(l)
+}
+
+// serve on l until Shutdown
+func (g *Gateway) Serve(l net.Listener) error {
+	if err := g.server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// stop accepting requests, wait for in-flight ones to finish (or ctx to
+// expire), then stop watching policies and flush telemetry
+func (g *Gateway) Shutdown(ctx context.Context) error {
+	err := g.server.Shutdown(ctx)
+	if cerr := g.Close(); err == nil {
+		err = cerr
+	}
+	if ferr := telemetry.Flush(ctx); err == nil {
+		err = ferr
+	}
+	return err
 }
 
 func (g *Gateway) Close() error {
+	if l := g.accessLog.Swap(nil); l != nil {
+		l.close()
+	}
+	g.webhooks.close()
+	if err := g.policyManager.FlushSequenceState(); err != nil {
+		fmt.Printf("ERROR: failed to persist sequence state: %v\n", err)
+	}
 	return g.watcher.Close()
 }
@@ -3,13 +3,16 @@ package gateway
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"aegis-gateway/internal/policy"
 	"aegis-gateway/pkg/telemetry"
 )
 
@@ -59,6 +62,28 @@ agents:
 	return gw, mockServer.URL
 }
 
+// gateway over a custom policy, for tests that need more than setupTestGateway's
+func setupGatewayWithPolicy(t *testing.T, policyContent string, adapters map[string]string) *Gateway {
+	tmpDir := t.TempDir()
+
+	logPath := filepath.Join(tmpDir, "test-audit.log")
+	if err := telemetry.InitTelemetry("aegis-test", logPath); err != nil {
+		t.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+
+	policyPath := filepath.Join(tmpDir, "test-policy.yaml")
+	if err := os.WriteFile(policyPath, []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+
+	gw, err := NewGateway(tmpDir, adapters)
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	t.Cleanup(func() { gw.Close() })
+	return gw
+}
+
 func TestHandleToolRequest_MissingAgentID(t *testing.T) {
 	gw, _ := setupTestGateway(t)
 	defer gw.Close()
@@ -131,6 +156,9 @@ func TestHandleToolRequest_PolicyViolation(t *testing.T) {
 	if resp.Error != "PolicyViolation" {
 		t.Errorf("Expected PolicyViolation error, got %s", resp.Error)
 	}
+	if resp.ReasonCode != policy.ReasonAmountExceeded {
+		t.Errorf("Expected reason_code %s, got %s", policy.ReasonAmountExceeded, resp.ReasonCode)
+	}
 }
 
 func TestHandleToolRequest_Success(t *testing.T) {
@@ -338,3 +366,299 @@ func TestParentAgentHeader(t *testing.T) {
 	}
 	// Parent agent header is captured in telemetry
 }
+
+func TestAuditRecordsSizes(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	if err := telemetry.InitTelemetry("aegis-test", logPath); err != nil {
+		t.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+
+	send := func(amount float64) (int, int) {
+		bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": amount, "currency": "USD"})
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return len(bodyBytes), w.Body.Len()
+	}
+	allowedReq, allowedResp := send(1000)
+	deniedReq, _ := send(10000)
+
+	data, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 audit records, got %d: %s", len(lines), data)
+	}
+	var allowed, denied telemetry.AuditLog
+	json.Unmarshal([]byte(lines[0]), &allowed)
+	json.Unmarshal([]byte(lines[1]), &denied)
+
+	if !allowed.Decision || allowed.RequestBytes != int64(allowedReq) || allowed.ResponseBytes != int64(allowedResp) {
+		t.Errorf("Expected allow with %d/%d bytes, got %+v", allowedReq, allowedResp, allowed)
+	}
+	if allowedResp == 0 {
+		t.Error("Expected a non-empty adapter response")
+	}
+	if denied.Decision || denied.RequestBytes != int64(deniedReq) || denied.ResponseBytes != 0 {
+		t.Errorf("Expected deny with %d/0 bytes, got %+v", deniedReq, denied)
+	}
+}
+
+func TestHandleToolRequest_AdapterNoContent(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer adapter.Close()
+	gw.SetAdapter("payments", adapter.URL)
+
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{"amount":100}`)))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected empty body, got %q", w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "" {
+		t.Errorf("Expected no Content-Type on empty response, got %q", ct)
+	}
+}
+
+func TestHandleToolRequest_AdapterEmptyBody(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer adapter.Close()
+	gw.SetAdapter("payments", adapter.URL)
+
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{"amount":100}`)))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "" {
+		t.Errorf("Expected no Content-Type on empty response, got %q", ct)
+	}
+}
+
+func TestDebugConditionTrace(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	send := func(amount float64) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": amount})
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		req.Header.Set("X-Debug-Conditions", "true")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// ignored unless enabled in config
+	if h := send(1000).Header().Get("X-Aegis-Condition-Trace"); h != "" {
+		t.Errorf("Expected no trace header with debug_trace off, got %q", h)
+	}
+
+	gw.SetConfig(Config{DebugTrace: true})
+
+	w := send(1000)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var trace []policy.ConditionResult
+	if err := json.Unmarshal([]byte(w.Header().Get("X-Aegis-Condition-Trace")), &trace); err != nil {
+		t.Fatalf("Invalid trace header: %v", err)
+	}
+	if len(trace) != 1 || trace[0].Condition != "max_amount" || !trace[0].Passed {
+		t.Errorf("Unexpected trace: %+v", trace)
+	}
+
+	w = send(10000)
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Trace) != 1 || resp.Trace[0].Passed {
+		t.Errorf("Expected failed max_amount in denial trace, got %+v", resp.Trace)
+	}
+}
+
+// audit sink that always reports an outage
+type failingAuditSink struct{}
+
+func (failingAuditSink) Write(telemetry.AuditLog) error { return fmt.Errorf("database down") }
+func (failingAuditSink) Close() error                   { return nil }
+
+func TestAuditFailClosed(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	telemetry.SetAuditSink(failingAuditSink{})
+	defer telemetry.SetAuditSink(nil)
+
+	send := func() int {
+		bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": 1000.0})
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// fail open by default
+	if code := send(); code != http.StatusOK {
+		t.Errorf("Expected status 200 when failing open, got %d", code)
+	}
+
+	gw.SetConfig(Config{AuditFailClosed: true})
+	if code := send(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 when failing closed, got %d", code)
+	}
+}
+
+// audit sink that accepts records into a queue but can't persist them yet
+type queueingAuditSink struct{}
+
+func (queueingAuditSink) Write(telemetry.AuditLog) error     { return nil }
+func (queueingAuditSink) WriteSync(telemetry.AuditLog) error { return fmt.Errorf("database down") }
+func (queueingAuditSink) Close() error                       { return nil }
+
+func TestAuditFailClosed_WaitsForSink(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	telemetry.SetAuditSink(queueingAuditSink{})
+	defer telemetry.SetAuditSink(nil)
+
+	gw.SetConfig(Config{AuditFailClosed: true})
+	bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": 1000.0})
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	// queued isn't written: the action mustn't run before its record exists
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 until the record is persisted, got %d", w.Code)
+	}
+}
+
+func TestListPolicies(t *testing.T) {
+	firstPolicy := `version: 1
+agents:
+  - id: test-agent
+    allow:
+      - tool: payments
+        actions: [create]
+`
+	secondPolicy := `version: 2
+agents:
+  - id: hr-agent
+    api_key_sha256: "` + policy.HashAPIKey("hr-key") + `"
+    allow:
+      - tool: files
+        actions: [read]
+        conditions:
+          folder_prefix: "/hr-docs/"
+  - id: audit-agent
+    allow:
+      - tool: files
+        actions: [read]
+`
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{"test-policy.yaml": firstPolicy, "second.yaml": secondPolicy} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write policy: %v", err)
+		}
+	}
+	gw, err := NewGateway(tmpDir, map[string]string{})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	defer gw.Close()
+
+	req := httptest.NewRequest("GET", "/policies", nil)
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), policy.HashAPIKey("hr-key")) {
+		t.Error("API key hash leaked from /policies")
+	}
+
+	var summaries []policy.PolicySummary
+	json.NewDecoder(w.Body).Decode(&summaries)
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 policy files, got %+v", summaries)
+	}
+	if summaries[0].File != "second.yaml" || summaries[0].Version != 2 || len(summaries[0].Agents) != 2 {
+		t.Errorf("Unexpected summary for second.yaml: %+v", summaries[0])
+	}
+	if summaries[1].File != "test-policy.yaml" || len(summaries[1].Agents) != 1 {
+		t.Errorf("Unexpected summary for test-policy.yaml: %+v", summaries[1])
+	}
+	hr := summaries[0].Agents[0]
+	if !hr.HasAPIKey || hr.Permissions[0].Tool != "files" || hr.Permissions[0].Conditions[0] != "path_prefix" {
+		t.Errorf("Unexpected hr-agent summary: %+v", hr)
+	}
+}
+
+func TestPolicyLoadErrorsSurfaced(t *testing.T) {
+	tmpDir := t.TempDir()
+	valid := `version: 1
+agents:
+  - id: test-agent
+    allow:
+      - tool: payments
+        actions: [create]
+`
+	for name, content := range map[string]string{"valid.yaml": valid, "broken.yaml": "version: 0\nagents: []\n"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write policy: %v", err)
+		}
+	}
+	gw, err := NewGateway(tmpDir, map[string]string{})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	defer gw.Close()
+
+	req := httptest.NewRequest("POST", "/policies/reload", nil)
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", w.Code)
+	}
+	var reload ErrorResponse
+	json.NewDecoder(w.Body).Decode(&reload)
+	if reload.Code != "AEGIS-422-POLICY-RELOAD" || len(reload.Errors) != 1 || reload.Errors[0].File != "broken.yaml" {
+		t.Errorf("Unexpected reload response: %+v", reload)
+	}
+
+	req = httptest.NewRequest("GET", "/policies/status", nil)
+	w = httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	var status struct {
+		OK     bool                     `json:"ok"`
+		Files  []string                 `json:"files"`
+		Errors []policy.PolicyLoadError `json:"errors"`
+	}
+	json.NewDecoder(w.Body).Decode(&status)
+	if status.OK || len(status.Files) != 1 || status.Files[0] != "valid.yaml" || len(status.Errors) != 1 {
+		t.Errorf("Unexpected status response: %+v", status)
+	}
+}
@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// per-adapter budget for the readiness check
+const adapterHealthTimeout = 2 * time.Second
+
+type adapterHealth struct {
+	Status    string  `json:"status"` // "up" or "down"
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// readiness: ping every registered adapter's /health concurrently.
+// "ready" with 200 when all are up, "degraded" with 503 otherwise so
+// load balancers stop routing here. /health stays a cheap liveness check
+func (g *Gateway) handle_ready(w http.ResponseWriter, r *http.Request) {
+	cfg := g.cfg()
+	adapters := cfg.Adapters
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]adapterHealth, len(adapters))
+	for tool, url := range adapters {
+		wg.Add(1)
+		go func(tool, url string) {
+			defer wg.Done()
+			h := check_adapter_health(r.Context(), url, cfg.tool(tool).Passthrough())
+			mu.Lock()
+			results[tool] = h
+			mu.Unlock()
+		}(tool, url)
+	}
+	wg.Wait()
+
+	status, code := "ready", http.StatusOK
+	for _, h := range results {
+		if h.Status != "up" {
+			status, code = "degraded", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   status,
+		"adapters": results,
+	})
+}
+
+// adapters answer GET /health with 200. passthrough upstreams have no
+// such endpoint, so their base URL answering below 500 counts as up
+func check_adapter_health(ctx context.Context, url string, passthrough bool) adapterHealth {
+	ctx, cancel := context.WithTimeout(ctx, adapterHealthTimeout)
+	defer cancel()
+
+	start := time.Now()
+	down := func(err error) adapterHealth {
+		return adapterHealth{
+			Status:    "down",
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000.0,
+			Error:     err.Error(),
+		}
+	}
+
+	probe := strings.TrimSuffix(url, "/") + "/health"
+	if passthrough {
+		probe = url
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", probe, nil)
+	if err != nil {
+		return down(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return down(fmt.Errorf("unreachable: %w", err))
+	}
+	resp.Body.Close()
+	if (passthrough && resp.StatusCode >= 500) || (!passthrough && resp.StatusCode != http.StatusOK) {
+		return down(fmt.Errorf("health check returned %d", resp.StatusCode))
+	}
+	return adapterHealth{
+		Status:    "up",
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000.0,
+	}
+}
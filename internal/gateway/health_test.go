@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadinessAggregatesAdapters(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			t.Errorf("Expected /health probe, got %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"status":"healthy"}`))
+	}))
+	defer healthy.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{
+		"files":    healthy.URL,
+		"payments": deadAdapterURL(),
+	})
+
+	readiness := func() (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, httptest.NewRequest("GET", "/health/ready", nil))
+		var resp map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	code, resp := readiness()
+	if code != http.StatusServiceUnavailable || resp["status"] != "degraded" {
+		t.Fatalf("Expected 503 degraded, got %d %v", code, resp)
+	}
+	adapters := resp["adapters"].(map[string]interface{})
+	if files := adapters["files"].(map[string]interface{}); files["status"] != "up" {
+		t.Errorf("Expected files up, got %v", files)
+	}
+	payments := adapters["payments"].(map[string]interface{})
+	if payments["status"] != "down" || payments["error"] == nil {
+		t.Errorf("Expected payments down with an error, got %v", payments)
+	}
+
+	// liveness doesn't depend on adapters
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected /health 200, got %d", w.Code)
+	}
+
+	gw.SetAdapter("payments", healthy.URL)
+	if code, resp := readiness(); code != http.StatusOK || resp["status"] != "ready" {
+		t.Errorf("Expected 200 ready once every adapter is up, got %d %v", code, resp)
+	}
+}
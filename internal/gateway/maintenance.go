@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"aegis-gateway/internal/policy"
+	"aegis-gateway/pkg/apierror"
+)
+
+// maintenance mode, toggled with POST /maintenance:
+//
+//	{"enabled": true, "actions": ["payments/create", "files/write"]}
+//
+// while on, matching tool requests get 503 Maintenance before anything
+// else runs. entries are "tool/action", "tool" or "tool/*" for every
+// action of a tool; no actions blocks every tool request. health and
+// admin routes keep working. the mode isn't persisted, a restart clears it
+type MaintenanceMode struct {
+	Enabled bool     `json:"enabled"`
+	Actions []string `json:"actions,omitempty"`
+
+	// when it was turned on
+	Since *time.Time `json:"since,omitempty"`
+}
+
+// whether a request for tool/action is blocked
+func (m *MaintenanceMode) blocks(tool, action string) bool {
+	if m == nil || !m.Enabled {
+		return false
+	}
+	if len(m.Actions) == 0 {
+		return true
+	}
+	for _, entry := range m.Actions {
+		if entry == tool || entry == tool+"/"+policy.Wildcard || entry == tool+"/"+action {
+			return true
+		}
+	}
+	return false
+}
+
+func validate_maintenance_actions(actions []string) error {
+	for _, entry := range actions {
+		tool, action, _ := strings.Cut(entry, "/")
+		if tool == "" || strings.Contains(action, "/") || strings.HasSuffix(entry, "/") {
+			return fmt.Errorf("invalid maintenance action %q, expected tool, tool/action or tool/*", entry)
+		}
+	}
+	return nil
+}
+
+// turn maintenance mode on or off
+func (g *Gateway) SetMaintenance(enabled bool, actions []string) error {
+	if err := validate_maintenance_actions(actions); err != nil {
+		return err
+	}
+	if !enabled {
+		g.maintenance.Store(nil)
+		return nil
+	}
+	now := time.Now().UTC()
+	g.maintenance.Store(&MaintenanceMode{Enabled: true, Actions: actions, Since: &now})
+	return nil
+}
+
+// the current mode, Enabled false when off
+func (g *Gateway) Maintenance() MaintenanceMode {
+	if m := g.maintenance.Load(); m != nil {
+		return *m
+	}
+	return MaintenanceMode{}
+}
+
+func (g *Gateway) handle_get_maintenance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.Maintenance())
+}
+
+func (g *Gateway) handle_set_maintenance(w http.ResponseWriter, r *http.Request) {
+	var req MaintenanceMode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		write_error(w, apierror.InvalidRequest, "Request body must be valid JSON")
+		return
+	}
+	if err := g.SetMaintenance(req.Enabled, req.Actions); err != nil {
+		write_error(w, apierror.InvalidRequest, err.Error())
+		return
+	}
+	state := g.Maintenance()
+	if state.Enabled {
+		fmt.Printf("Maintenance mode on (actions: %v)\n", state.Actions)
+	} else {
+		fmt.Println("Maintenance mode off")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func set_maintenance(t *testing.T, gw *Gateway, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/maintenance", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	if w := set_maintenance(t, gw, `{"enabled": true}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected maintenance to be turned on, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := post_payment(gw, `{"amount": 100}`)
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusServiceUnavailable || resp.Error != "Maintenance" || resp.Code != "AEGIS-503-MAINTENANCE" {
+		t.Fatalf("Expected 503 Maintenance, got %d %+v", w.Code, resp)
+	}
+
+	// health and admin routes keep working
+	for _, path := range []string{"/health", "/policies", "/maintenance"} {
+		rec := httptest.NewRecorder()
+		gw.router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected %s to be served during maintenance, got %d", path, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	gw.router.ServeHTTP(rec, httptest.NewRequest("GET", "/maintenance", nil))
+	var state MaintenanceMode
+	json.NewDecoder(rec.Body).Decode(&state)
+	if !state.Enabled || state.Since == nil {
+		t.Errorf("Expected maintenance reported as on, got %+v", state)
+	}
+
+	if w := set_maintenance(t, gw, `{"enabled": false}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected maintenance to be turned off, got %d", w.Code)
+	}
+	if w := post_payment(gw, `{"amount": 100}`); w.Code != http.StatusOK {
+		t.Errorf("Expected tool requests to be restored, got %d", w.Code)
+	}
+	if gw.Maintenance().Enabled {
+		t.Error("Expected maintenance reported as off")
+	}
+}
+
+func TestMaintenanceModeActions(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	if err := gw.SetMaintenance(true, []string{"payments/refund", "files"}); err != nil {
+		t.Fatalf("SetMaintenance() error = %v", err)
+	}
+	tests := []struct {
+		tool, action string
+		blocked      bool
+	}{
+		{"payments", "refund", true},
+		{"payments", "create", false},
+		{"files", "write", true},
+		{"files", "read", true},
+	}
+	m := gw.maintenance.Load()
+	for _, tt := range tests {
+		if got := m.blocks(tt.tool, tt.action); got != tt.blocked {
+			t.Errorf("blocks(%s, %s) = %v, want %v", tt.tool, tt.action, got, tt.blocked)
+		}
+	}
+	if w := post_payment(gw, `{"amount": 100}`); w.Code != http.StatusOK {
+		t.Errorf("Expected payments/create to stay available, got %d", w.Code)
+	}
+
+	gw.SetMaintenance(true, []string{"payments/*"})
+	if w := post_payment(gw, `{"amount": 100}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected payments/* to block payments/create, got %d", w.Code)
+	}
+}
+
+func TestMaintenanceModeInvalid(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	for _, body := range []string{`not json`, `{"enabled": true, "actions": ["payments/"]}`, `{"enabled": true, "actions": ["/create"]}`, `{"enabled": true, "actions": ["a/b/c"]}`} {
+		if w := set_maintenance(t, gw, body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+	if gw.Maintenance().Enabled {
+		t.Error("Expected maintenance to stay off")
+	}
+}
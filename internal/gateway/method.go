@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// HTTP method the adapter is called with for an action, POST by default
+func (c Config) method(tool, action string) string {
+	if m := c.tool(tool).Actions[action].Method; m != "" {
+		return strings.ToUpper(m)
+	}
+	return http.MethodPost
+}
+
+func valid_method(m string) bool {
+	switch strings.ToUpper(m) {
+	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// GET and DELETE carry params in the query string instead of a body
+func method_has_body(m string) bool {
+	return m != http.MethodGet && m != http.MethodDelete
+}
+
+// url and body for an adapter call. for bodyless methods the JSON body is
+// moved into the query string
+func adapter_request(method, target string, body []byte) (string, []byte, error) {
+	if method_has_body(method) {
+		return target, body, nil
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(body, &params); err != nil {
+		return "", nil, fmt.Errorf("failed to decode params for %s: %w", method, err)
+	}
+	if q := encode_query(params); q != "" {
+		target += "?" + q
+	}
+	return target, nil, nil
+}
+
+// strings and numbers as-is, lists as repeated keys, objects as JSON
+func encode_query(params map[string]interface{}) string {
+	q := url.Values{}
+	for k, v := range params {
+		if list, ok := v.([]interface{}); ok {
+			for _, item := range list {
+				q.Add(k, query_value(item))
+			}
+			continue
+		}
+		q.Set(k, query_value(v))
+	}
+	return q.Encode()
+}
+
+func query_value(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	case nil:
+		return ""
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
+
+// params of a GET tool request, typed like a JSON body would be (see
+// parse_query_value). repeated keys are lists
+func query_params(q url.Values) map[string]interface{} {
+	params := make(map[string]interface{}, len(q))
+	for k, vals := range q {
+		if len(vals) == 1 {
+			params[k] = parse_query_value(vals[0])
+			continue
+		}
+		list := make([]interface{}, len(vals))
+		for i, v := range vals {
+			list[i] = parse_query_value(v)
+		}
+		params[k] = list
+	}
+	return params
+}
+
+// the inverse of query_value: numbers, bools and JSON objects or lists
+// come back as what they were. only the forms query_value writes count,
+// so "007" or "1e3" stay strings and ids keep their digits
+func parse_query_value(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == s {
+		return f
+	}
+	if strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") {
+		var v interface{}
+		if json.Unmarshal([]byte(s), &v) == nil {
+			return v
+		}
+	}
+	return s
+}
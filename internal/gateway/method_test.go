@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type adapterCall struct {
+	method string
+	query  url.Values
+	body   string
+}
+
+func TestActionMethod(t *testing.T) {
+	var got adapterCall
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = adapterCall{r.Method, r.URL.Query(), string(body)}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":"ok"}`))
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	send := func(req *http.Request) {
+		t.Helper()
+		req.Header.Set("X-Agent-ID", "reader-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// GET only where the action is configured for it
+	req := httptest.NewRequest("GET", "/tools/files/read?path=/doc.txt", nil)
+	req.Header.Set("X-Agent-ID", "reader-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "POST" {
+		t.Errorf("Expected 405 for GET on a POST action, got %d", w.Code)
+	}
+
+	// POST stays the default
+	send(httptest.NewRequest("POST", "/tools/files/read", bytes.NewReader([]byte(`{"path":"/doc.txt"}`))))
+	if got.method != "POST" || got.body != `{"path":"/doc.txt"}` || len(got.query) != 0 {
+		t.Errorf("Expected POST with JSON body, got %+v", got)
+	}
+
+	if err := gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"files": {Actions: map[string]ActionConfig{"read": {Method: "get"}}},
+	}}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	// a GET tool request reaches the adapter as GET with params in the query
+	send(httptest.NewRequest("GET", "/tools/files/read?path=/doc.txt&tag=a&tag=b", nil))
+	if got.method != "GET" || got.body != "" {
+		t.Errorf("Expected GET without a body, got %+v", got)
+	}
+	if got.query.Get("path") != "/doc.txt" || len(got.query["tag"]) != 2 {
+		t.Errorf("Expected params in the query, got %v", got.query)
+	}
+
+	// JSON bodies are moved to the query too
+	send(httptest.NewRequest("POST", "/tools/files/read", bytes.NewReader([]byte(`{"path":"/doc.txt","limit":10}`))))
+	if got.method != "GET" || got.query.Get("path") != "/doc.txt" || got.query.Get("limit") != "10" {
+		t.Errorf("Expected POST body forwarded as GET query, got %+v", got)
+	}
+}
+
+func TestActionMethodValidation(t *testing.T) {
+	cfg := Config{Tools: map[string]ToolConfig{
+		"files": {Actions: map[string]ActionConfig{"read": {Method: "TRACE"}}},
+	}}
+	if err := cfg.validate(); err == nil {
+		t.Error("Expected an unsupported method to be rejected")
+	}
+}
+
+func TestQueryParamsTyped(t *testing.T) {
+	got := query_params(url.Values{
+		"amount":    {"100"},
+		"rate":      {"2.5"},
+		"urgent":    {"true"},
+		"vendor_id": {"007"},
+		"big":       {"1e3"},
+		"meta":      {`{"k":"v"}`},
+		"tag":       {"a", "2"},
+	})
+	want := map[string]interface{}{
+		"amount":    100.0,
+		"rate":      2.5,
+		"urgent":    true,
+		"vendor_id": "007",
+		"big":       "1e3",
+		"meta":      map[string]interface{}{"k": "v"},
+		"tag":       []interface{}{"a", 2.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("query_params() = %v, want %v", got, want)
+	}
+}
+
+func TestGetRequestStrictAmounts(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	if err := gw.SetConfig(Config{
+		StrictAmounts: true,
+		Tools:         map[string]ToolConfig{"payments": {Actions: map[string]ActionConfig{"create": {Method: "GET"}}}},
+	}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	send := func(amount string) int {
+		req := httptest.NewRequest("GET", "/tools/payments/create?amount="+amount, nil)
+		req.Header.Set("X-Agent-ID", "test-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// query amounts are numbers to max_amount, as in a JSON body
+	if code := send("1000"); code != http.StatusOK {
+		t.Errorf("Expected a query amount within max_amount to pass, got %d", code)
+	}
+	if code := send("6000"); code != http.StatusForbidden {
+		t.Errorf("Expected a query amount over max_amount to be denied, got %d", code)
+	}
+}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// prometheus collectors, on a per-gateway registry
+type gatewayMetrics struct {
+	registry *prometheus.Registry
+
+	requests       *prometheus.CounterVec
+	denied         *prometheus.CounterVec
+	adapterLatency *prometheus.HistogramVec
+	adapterErrors  *prometheus.CounterVec
+}
+
+func newGatewayMetrics() *gatewayMetrics {
+	m := &gatewayMetrics{
+		registry: prometheus.NewRegistry(),
+		// decision_allow mirrors the audit log field
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_requests_total",
+			Help: "Tool requests evaluated, by tool, action and decision.",
+		}, []string{"tool", "action", "decision_allow"}),
+		denied: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_policy_denied_total",
+			Help: "Tool requests denied by policy.",
+		}, []string{"tool", "action"}),
+		adapterLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aegis_adapter_latency_seconds",
+			Help:    "Adapter call latency, including retries.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"tool", "action"}),
+		adapterErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_adapter_errors_total",
+			Help: "Adapter calls that failed without a response.",
+		}, []string{"tool", "action"}),
+	}
+	m.registry.MustRegister(m.requests, m.denied, m.adapterLatency, m.adapterErrors)
+	return m
+}
+
+// label for tools and actions neither the config nor the policies know
+const unknownLabel = "unknown"
+
+// tool and action labels for a request. both come from the URL, so only
+// tools in the config and actions the config or a policy rule names are
+// used as they are; anything else counts as "unknown", which keeps the
+// number of series bounded
+func (g *Gateway) metric_labels(tool, action string) (string, string) {
+	cfg := g.cfg()
+	_, adapter := cfg.Adapters[tool]
+	tc, configured := cfg.Tools[tool]
+	if !adapter && !configured {
+		return unknownLabel, unknownLabel
+	}
+	if _, ok := tc.Actions[action]; ok || g.policyManager.Names(tool, action) {
+		return tool, action
+	}
+	return tool, unknownLabel
+}
+
+func (m *gatewayMetrics) decision(tool, action string, allowed bool) {
+	m.requests.WithLabelValues(tool, action, strconv.FormatBool(allowed)).Inc()
+	if !allowed {
+		m.denied.WithLabelValues(tool, action).Inc()
+	}
+}
+
+func (m *gatewayMetrics) adapter_call(tool, action string, elapsed time.Duration, err error) {
+	m.adapterLatency.WithLabelValues(tool, action).Observe(elapsed.Seconds())
+	if err != nil {
+		m.adapterErrors.WithLabelValues(tool, action).Inc()
+	}
+}
+
+func (g *Gateway) setup_metrics_route() {
+	g.router.Handle("/metrics", g.admin(promhttp.HandlerFor(g.metrics.registry, promhttp.HandlerOpts{}))).Methods("GET")
+}
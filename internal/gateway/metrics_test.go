@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	for _, amount := range []float64{100, 200, 10000} {
+		bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": amount})
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		gw.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	body, _ := io.ReadAll(w.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		`aegis_requests_total{action="create",decision_allow="true",tool="payments"} 2`,
+		`aegis_requests_total{action="create",decision_allow="false",tool="payments"} 1`,
+		`aegis_policy_denied_total{action="create",tool="payments"} 1`,
+		`aegis_adapter_latency_seconds_count{action="create",tool="payments"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in /metrics output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `aegis_adapter_errors_total{`) {
+		t.Errorf("Expected no adapter errors, got:\n%s", out)
+	}
+}
+
+// names from the URL that nothing configures don't get their own series
+func TestMetricsUnknownLabels(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	for _, path := range []string{"/tools/payments/create", "/tools/payments/refund-x1", "/tools/bogus-1/create", "/tools/bogus-2/anything"} {
+		req := httptest.NewRequest("POST", path, bytes.NewReader([]byte(`{"amount":100}`)))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		gw.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	out := w.Body.String()
+	for _, want := range []string{
+		`aegis_requests_total{action="create",decision_allow="true",tool="payments"} 1`,
+		`aegis_requests_total{action="unknown",decision_allow="false",tool="payments"} 1`,
+		`aegis_requests_total{action="unknown",decision_allow="false",tool="unknown"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in /metrics output:\n%s", want, out)
+		}
+	}
+	for _, name := range []string{"refund-x1", "bogus-1", "bogus-2", "anything"} {
+		if strings.Contains(out, name) {
+			t.Errorf("Expected %s not to be used as a label:\n%s", name, out)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"aegis-gateway/internal/policy"
+	"aegis-gateway/pkg/currency"
+)
+
+// fill in defaults and normalize amounts before policy evaluation so
+// conditions and adapters see the same values. explicit values sent by
+// the agent always win. returns true if params were changed.
+func (g *Gateway) normalize_params(tool string, params map[string]interface{}) bool {
+	if params == nil {
+		return false
+	}
+	tc := g.cfg().tool(tool)
+	changed := false
+
+	if tc.DefaultCurrency != "" {
+		if cur, ok := params["currency"]; !ok || cur == "" {
+			params["currency"] = tc.DefaultCurrency
+			changed = true
+		}
+	}
+
+	// a string amount the policy accepts is forwarded as the number it was
+	// checked as; adapters decode amount as a JSON number
+	if s, ok := params["amount"].(string); ok {
+		if amt, ok := policy.ParseAmount(s, g.cfg().StrictAmounts); ok {
+			params["amount"] = amt
+			changed = true
+		}
+	}
+
+	// minor units are per currency: cents for USD, yen for JPY, fils for
+	// KWD. without a currency they're taken as cents
+	if tc.AmountUnit == "minor" {
+		if amt, ok := policy.ParseAmount(params["amount"], g.cfg().StrictAmounts); ok {
+			code, _ := params["currency"].(string)
+			params["amount"] = amt / currency.Scale(code)
+			changed = true
+		}
+	}
+
+	return changed
+}
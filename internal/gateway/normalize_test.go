@@ -0,0 +1,185 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aegis-gateway/internal/adapters/payments"
+)
+
+const currencyPolicy = `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+          currencies: [USD, EUR]
+`
+
+func TestDefaultCurrency(t *testing.T) {
+	var received map[string]interface{}
+	adapter := recordingAdapter(t, &received)
+	gw := setupGatewayWithPolicy(t, currencyPolicy, map[string]string{"payments": adapter.URL})
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {DefaultCurrency: "USD"},
+	}})
+
+	send := func(body string) int {
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Agent-ID", "finance-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// omitted currency gets the default, which passes the currencies condition
+	if code := send(`{"amount":100,"vendor_id":"V1"}`); code != http.StatusOK {
+		t.Fatalf("Expected request without currency to be allowed, got %d", code)
+	}
+	if received["currency"] != "USD" {
+		t.Errorf("Expected adapter to receive default currency USD, got %v", received["currency"])
+	}
+
+	// explicit currency is authoritative
+	if code := send(`{"amount":100,"currency":"EUR","vendor_id":"V1"}`); code != http.StatusOK {
+		t.Fatalf("Expected EUR request to be allowed, got %d", code)
+	}
+	if received["currency"] != "EUR" {
+		t.Errorf("Expected explicit currency EUR to be kept, got %v", received["currency"])
+	}
+
+	// explicit disallowed currency is not overridden by the default
+	if code := send(`{"amount":100,"currency":"GBP"}`); code != http.StatusForbidden {
+		t.Errorf("Expected explicit GBP to be denied, got %d", code)
+	}
+}
+
+func TestAmountUnitMinor(t *testing.T) {
+	var received map[string]interface{}
+	adapter := recordingAdapter(t, &received)
+	gw := setupGatewayWithPolicy(t, currencyPolicy, map[string]string{"payments": adapter.URL})
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {AmountUnit: "minor"},
+	}})
+
+	// 450000 cents = 4500.00, within max_amount=5000
+	bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": 450000, "currency": "USD"})
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "finance-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if received["amount"] != 4500.0 {
+		t.Errorf("Expected adapter to receive 4500 major units, got %v", received["amount"])
+	}
+}
+
+func TestAmountUnitMinorPerCurrency(t *testing.T) {
+	var received map[string]interface{}
+	adapter := recordingAdapter(t, &received)
+	gw := setupGatewayWithPolicy(t, `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+`, map[string]string{"payments": adapter.URL})
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{"payments": {AmountUnit: "minor"}}})
+
+	tests := []struct {
+		body string
+		want float64
+	}{
+		{`{"amount":450000,"currency":"USD"}`, 4500},
+		{`{"amount":4500,"currency":"JPY"}`, 4500},
+		{`{"amount":4500000,"currency":"kwd"}`, 4500},
+		{`{"amount":450000}`, 4500},
+	}
+	for _, tt := range tests {
+		w := call_payments(gw, "finance-agent", "create", tt.body)
+		if w.Code != http.StatusOK || received["amount"] != tt.want {
+			t.Errorf("%s: expected %v major units, got %d with adapter amount %v", tt.body, tt.want, w.Code, received["amount"])
+		}
+	}
+
+	// 4500 yen is not 45 yen, so it's checked against max_amount as 4500
+	if w := call_payments(gw, "finance-agent", "create", `{"amount":6000,"currency":"JPY"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 6000 JPY over max_amount to be denied, got %d", w.Code)
+	}
+}
+
+func TestAmountUnitValidation(t *testing.T) {
+	cfg := Config{Tools: map[string]ToolConfig{"payments": {AmountUnit: "cents"}}}
+	if err := cfg.validate(); err == nil {
+		t.Error("Expected unknown amount_unit to be rejected")
+	}
+}
+
+func TestStringAmounts(t *testing.T) {
+	var received map[string]interface{}
+	adapter := recordingAdapter(t, &received)
+	gw := setupGatewayWithPolicy(t, currencyPolicy, map[string]string{"payments": adapter.URL})
+
+	send := func(body string) int {
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Agent-ID", "finance-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for body, want := range map[string]int{
+		`{"amount":1000,"currency":"USD"}`:     http.StatusOK,
+		`{"amount":1000.5,"currency":"USD"}`:   http.StatusOK,
+		`{"amount":"1000","currency":"USD"}`:   http.StatusOK,
+		`{"amount":"9000","currency":"USD"}`:   http.StatusForbidden,
+		`{"amount":"plenty","currency":"USD"}`: http.StatusForbidden,
+	} {
+		if code := send(body); code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, code)
+		}
+	}
+
+	// minor units convert string amounts too, before policy sees them
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{"payments": {AmountUnit: "minor"}}})
+	if code := send(`{"amount":"450000","currency":"USD"}`); code != http.StatusOK || received["amount"] != 4500.0 {
+		t.Errorf("Expected 450000 cents as 4500.00, got %d with adapter amount %v", code, received["amount"])
+	}
+
+	gw.SetConfig(Config{StrictAmounts: true})
+	if code := send(`{"amount":"1000","currency":"USD"}`); code != http.StatusForbidden {
+		t.Errorf("Expected a string amount rejected in strict mode, got %d", code)
+	}
+	if code := send(`{"amount":1000,"currency":"USD"}`); code != http.StatusOK {
+		t.Errorf("Expected a JSON number allowed in strict mode, got %d", code)
+	}
+}
+
+// string amounts reach the adapter as numbers, which the real payments
+// handler needs to decode them
+func TestStringAmountsForwarded(t *testing.T) {
+	server := payments_adapter_server(t)
+	gw := setupGatewayWithPolicy(t, currencyPolicy, map[string]string{"payments": server.URL})
+
+	for _, body := range []string{`{"amount":"1000","currency":"USD","vendor_id":"V1"}`, `{"amount":" 12.50 ","currency":"EUR","vendor_id":"V1"}`} {
+		w := call_payments(gw, "finance-agent", "create", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected the adapter to accept the amount, got %d: %s", body, w.Code, w.Body.String())
+		}
+		var created payments.CreateResponse
+		json.NewDecoder(w.Body).Decode(&created)
+		if created.PaymentID == "" {
+			t.Errorf("%s: expected a payment, got %s", body, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"aegis-gateway/internal/policy"
+
+	"github.com/gorilla/mux"
+)
+
+// OpenAPI 3 description of the gateway, built per request from the
+// registered routes, the configured adapters and the loaded policies so
+// it never drifts from what the gateway actually serves
+type openAPIDoc struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       openAPIInfo                `json:"info"`
+	Paths      map[string]openAPIPathItem `json:"paths"`
+	Components openAPIComponents          `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// lowercase HTTP method -> operation
+type openAPIPathItem map[string]*openAPIOperation
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+// either a full parameter or a $ref to one in components
+type openAPIParameter struct {
+	Ref         string         `json:"$ref,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	In          string         `json:"in,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema,omitempty"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema,omitempty"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref,omitempty"`
+	Type       string                    `json:"type,omitempty"`
+	Properties map[string]*openAPISchema `json:"properties,omitempty"`
+	Items      *openAPISchema            `json:"items,omitempty"`
+	Required   []string                  `json:"required,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema        `json:"schemas"`
+	Parameters      map[string]openAPIParameter      `json:"parameters"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+// summaries for the fixed routes, keyed "METHOD path"
+var openAPISummaries = map[string]string{
+	"POST /tools/{tool}/{action}":   "Call a tool action",
+	"GET /tools/{tool}/{action}":    "Call a tool action with params in the query string",
+	"POST /tools/batch":             "Run several tool requests in order",
+	"GET /health":                   "Liveness",
+	"GET /health/ready":             "Readiness, including adapter health",
+	"GET /policies":                 "List loaded policies",
+	"POST /policies/reload":         "Reload policies",
+	"GET /policies/status":          "Policy load status",
+	"POST /policies/evaluate":       "Dry-run a policy decision",
+	"POST /policies/validate":       "Check a candidate policy file",
+	"GET /deadletters":              "List dead letters",
+	"POST /deadletters/{id}/replay": "Replay a dead letter",
+	"POST /config/reload":           "Reload runtime configuration",
+	"GET /stats":                    "Latency stats",
+	"GET /version":                  "Build and policy set version",
+	"GET /maintenance":              "Maintenance mode",
+	"POST /maintenance":             "Set maintenance mode",
+	"GET /metrics":                  "Prometheus metrics",
+	"GET /openapi.json":             "This document",
+}
+
+// {name} or {name:pattern} in a route template
+var routeVarPattern = regexp.MustCompile(`\{([^{}:]+)(:[^{}]*)?\}`)
+
+func (g *Gateway) handle_openapi(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.openapi_doc())
+}
+
+func (g *Gateway) openapi_doc() openAPIDoc {
+	doc := openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "Aegis Gateway",
+			Version:     Version,
+			Description: "Policy-enforcing gateway between agents and their tools. Tool calls need the X-Agent-ID header.",
+		},
+		Paths:      make(map[string]openAPIPathItem),
+		Components: openapi_components(),
+	}
+
+	g.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			// the catch-all CORS preflight isn't an API route
+			if method == http.MethodOptions {
+				continue
+			}
+			doc.add(method, openapi_path(tmpl), route_operation(method, tmpl))
+		}
+		return nil
+	})
+
+	for tool, actions := range g.tool_actions() {
+		for _, action := range actions {
+			doc.add(http.MethodPost, "/tools/"+tool+"/"+action, tool_operation(http.MethodPost, tool, action))
+			if g.cfg().method(tool, action) == http.MethodGet {
+				doc.add(http.MethodGet, "/tools/"+tool+"/"+action, tool_operation(http.MethodGet, tool, action))
+			}
+		}
+	}
+	return doc
+}
+
+func (d *openAPIDoc) add(method, path string, op *openAPIOperation) {
+	item := d.Paths[path]
+	if item == nil {
+		item = make(openAPIPathItem)
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// tools with an adapter and the actions the loaded policies allow on
+// them, plus actions configured per tool. wildcard actions can't be
+// listed; a "*" tool rule counts for every tool
+func (g *Gateway) tool_actions() map[string][]string {
+	cfg := g.cfg()
+	sets := make(map[string]map[string]bool, len(cfg.Adapters))
+	for tool := range cfg.Adapters {
+		sets[tool] = make(map[string]bool)
+		for action := range cfg.tool(tool).Actions {
+			sets[tool][action] = true
+		}
+	}
+	for _, ps := range g.policyManager.Summaries() {
+		for _, agent := range ps.Agents {
+			for _, perm := range agent.Permissions {
+				for tool, set := range sets {
+					if perm.Tool != tool && perm.Tool != policy.Wildcard {
+						continue
+					}
+					for _, action := range perm.Actions {
+						if action != policy.Wildcard {
+							set[action] = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	out := make(map[string][]string, len(sets))
+	for tool, set := range sets {
+		actions := make([]string, 0, len(set))
+		for action := range set {
+			actions = append(actions, action)
+		}
+		sort.Strings(actions)
+		out[tool] = actions
+	}
+	return out
+}
+
+// mux templates may carry patterns, {id:[0-9]+}; OpenAPI wants {id}
+func openapi_path(tmpl string) string {
+	return routeVarPattern.ReplaceAllString(tmpl, "{$1}")
+}
+
+func operation_id(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func route_operation(method, tmpl string) *openAPIOperation {
+	path := openapi_path(tmpl)
+	op := &openAPIOperation{
+		OperationID: operation_id(method, path),
+		Summary:     openAPISummaries[method+" "+path],
+		Responses: map[string]openAPIResponse{
+			"200":     {Description: "OK"},
+			"default": error_response("Error"),
+		},
+	}
+	for _, m := range routeVarPattern.FindAllStringSubmatch(tmpl, -1) {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name: m[1], In: "path", Required: true, Schema: &openAPISchema{Type: "string"},
+		})
+	}
+
+	switch {
+	case strings.HasPrefix(path, "/tools/"):
+		op.Tags = []string{"tools"}
+		op.Parameters = append(op.Parameters, agent_parameters()...)
+		op.Security = []map[string][]string{{"agentKey": {}}, {}}
+		op.Responses["403"] = error_response("Denied by policy")
+		if method != http.MethodGet {
+			op.RequestBody = json_body()
+		}
+	case strings.HasPrefix(path, "/health"):
+		op.Tags = []string{"health"}
+	default:
+		op.Tags = []string{"admin"}
+		op.Security = []map[string][]string{{"adminToken": {}}, {}}
+	}
+	return op
+}
+
+// GET operations take their params from the query string instead
+func tool_operation(method, tool, action string) *openAPIOperation {
+	path := "/tools/" + tool + "/" + action
+	var body *openAPIRequestBody
+	if method != http.MethodGet {
+		body = json_body()
+	}
+	return &openAPIOperation{
+		OperationID: operation_id(method, path),
+		Summary:     tool + " " + action,
+		Tags:        []string{tool},
+		Parameters:  agent_parameters(),
+		RequestBody: body,
+		Responses: map[string]openAPIResponse{
+			"200":     {Description: "Adapter response", Content: json_content(&openAPISchema{Type: "object"})},
+			"403":     error_response("Denied by policy"),
+			"default": error_response("Error"),
+		},
+		Security: []map[string][]string{{"agentKey": {}}, {}},
+	}
+}
+
+func agent_parameters() []openAPIParameter {
+	return []openAPIParameter{
+		{Ref: "#/components/parameters/AgentID"},
+		{Ref: "#/components/parameters/ParentAgent"},
+	}
+}
+
+func json_body() *openAPIRequestBody {
+	return &openAPIRequestBody{Required: true, Content: json_content(&openAPISchema{Type: "object"})}
+}
+
+func json_content(s *openAPISchema) map[string]openAPIMediaType {
+	return map[string]openAPIMediaType{"application/json": {Schema: s}}
+}
+
+func error_response(description string) openAPIResponse {
+	return openAPIResponse{Description: description, Content: json_content(&openAPISchema{Ref: "#/components/schemas/Error"})}
+}
+
+func openapi_components() openAPIComponents {
+	str := func() *openAPISchema { return &openAPISchema{Type: "string"} }
+	return openAPIComponents{
+		Schemas: map[string]*openAPISchema{
+			// see ErrorResponse
+			"Error": {
+				Type:     "object",
+				Required: []string{"error", "code", "reason"},
+				Properties: map[string]*openAPISchema{
+					"error":       str(),
+					"code":        str(),
+					"reason":      str(),
+					"reason_code": str(),
+					"trace":       {Type: "array", Items: &openAPISchema{Type: "object"}},
+					"errors":      {Type: "array", Items: &openAPISchema{Type: "object"}},
+				},
+			},
+		},
+		Parameters: map[string]openAPIParameter{
+			"AgentID": {
+				Name: "X-Agent-ID", In: "header", Required: true,
+				Description: "Agent making the request; policies are looked up by it",
+				Schema:      str(),
+			},
+			"ParentAgent": {
+				Name: "X-Parent-Agent", In: "header",
+				Description: "Agent that delegated the request, recorded in the audit log",
+				Schema:      str(),
+			},
+		},
+		SecuritySchemes: map[string]openAPISecurityScheme{
+			"agentKey":   {Type: "http", Scheme: "bearer", Description: "Per-agent API key, when the agent has one"},
+			"adminToken": {Type: "http", Scheme: "bearer", Description: "Admin token, when admin auth is configured"},
+		},
+	}
+}
@@ -0,0 +1,220 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const openAPIPolicy = `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create, refund]
+      - tool: files
+        actions: ["*"]
+  - id: ops-agent
+    allow:
+      - tool: "*"
+        actions: [health-check]
+      - tool: crm
+        actions: [lookup]
+`
+
+func get_openapi(t *testing.T, gw *Gateway) map[string]interface{} {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var doc map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+	check_openapi(t, doc)
+	return doc
+}
+
+var openAPIMethods = map[string]bool{"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true}
+
+// the structural rules of OpenAPI 3 the document relies on
+func check_openapi(t *testing.T, doc map[string]interface{}) {
+	t.Helper()
+	if v, _ := doc["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		t.Errorf("Expected an OpenAPI 3 version, got %v", doc["openapi"])
+	}
+	info, _ := doc["info"].(map[string]interface{})
+	if info["title"] == "" || info["title"] == nil || info["version"] == "" || info["version"] == nil {
+		t.Errorf("Expected info.title and info.version, got %v", info)
+	}
+
+	// every $ref points at something in the document
+	raw, _ := json.Marshal(doc)
+	for _, m := range regexp.MustCompile(`"\$ref":"#/([^"]+)"`).FindAllStringSubmatch(string(raw), -1) {
+		var node interface{} = doc
+		for _, key := range strings.Split(m[1], "/") {
+			obj, _ := node.(map[string]interface{})
+			node = obj[key]
+		}
+		if node == nil {
+			t.Errorf("Unresolved $ref #/%s", m[1])
+		}
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	if len(paths) == 0 {
+		t.Fatal("Expected paths")
+	}
+	ids := make(map[string]string)
+	for path, item := range paths {
+		if !strings.HasPrefix(path, "/") {
+			t.Errorf("Path %s must start with /", path)
+		}
+		var templated []string
+		for _, m := range regexp.MustCompile(`\{([^}]+)\}`).FindAllStringSubmatch(path, -1) {
+			templated = append(templated, m[1])
+		}
+		for method, rawOp := range item.(map[string]interface{}) {
+			if !openAPIMethods[method] {
+				t.Errorf("%s: unknown method %s", path, method)
+				continue
+			}
+			op := rawOp.(map[string]interface{})
+			where := method + " " + path
+			id, _ := op["operationId"].(string)
+			if prev, dup := ids[id]; id == "" || dup {
+				t.Errorf("%s: operationId %q missing or shared with %s", where, id, prev)
+			}
+			ids[id] = where
+			if responses, _ := op["responses"].(map[string]interface{}); len(responses) == 0 {
+				t.Errorf("%s: no responses", where)
+			}
+
+			declared := make(map[string]bool)
+			params, _ := op["parameters"].([]interface{})
+			for _, p := range params {
+				param := p.(map[string]interface{})
+				if param["in"] == "path" {
+					if param["required"] != true {
+						t.Errorf("%s: path parameter %v must be required", where, param["name"])
+					}
+					declared[param["name"].(string)] = true
+				}
+			}
+			if len(declared) != len(templated) {
+				t.Errorf("%s: path parameters %v, declared %v", where, templated, declared)
+			}
+			for _, name := range templated {
+				if !declared[name] {
+					t.Errorf("%s: path parameter %s not declared", where, name)
+				}
+			}
+		}
+	}
+}
+
+func operation(t *testing.T, doc map[string]interface{}, method, path string) map[string]interface{} {
+	t.Helper()
+	item, _ := doc["paths"].(map[string]interface{})[path].(map[string]interface{})
+	op, _ := item[method].(map[string]interface{})
+	if op == nil {
+		t.Fatalf("Expected %s %s in the document", method, path)
+	}
+	return op
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	gw := setupGatewayWithPolicy(t, openAPIPolicy, map[string]string{
+		"payments": "http://payments.invalid",
+		"files":    "http://files.invalid",
+	})
+
+	doc := get_openapi(t, gw)
+
+	// routes registered on the router
+	for _, route := range []struct{ method, path string }{
+		{"post", "/tools/{tool}/{action}"},
+		{"get", "/tools/{tool}/{action}"},
+		{"post", "/tools/batch"},
+		{"get", "/health"},
+		{"post", "/policies/evaluate"},
+		{"post", "/deadletters/{id}/replay"},
+		{"get", "/openapi.json"},
+	} {
+		operation(t, doc, route.method, route.path)
+	}
+
+	// tool actions from the policies, for tools with an adapter
+	for _, path := range []string{
+		"/tools/payments/create",
+		"/tools/payments/refund",
+		"/tools/payments/health-check",
+		"/tools/files/health-check",
+	} {
+		op := operation(t, doc, "post", path)
+		params, _ := json.Marshal(op["parameters"])
+		if !strings.Contains(string(params), "#/components/parameters/AgentID") {
+			t.Errorf("Expected %s to take X-Agent-ID, got %s", path, params)
+		}
+	}
+	paths := doc["paths"].(map[string]interface{})
+	for _, path := range []string{"/tools/files/*", "/tools/crm/lookup", "/tools/*/health-check"} {
+		if _, ok := paths[path]; ok {
+			t.Errorf("Expected no %s: wildcards and tools without an adapter can't be called", path)
+		}
+	}
+
+	agentID := doc["components"].(map[string]interface{})["parameters"].(map[string]interface{})["AgentID"].(map[string]interface{})
+	if agentID["name"] != "X-Agent-ID" || agentID["in"] != "header" || agentID["required"] != true {
+		t.Errorf("Expected a required X-Agent-ID header parameter, got %v", agentID)
+	}
+}
+
+// generated per request, so configuration changes show up right away
+func TestOpenAPIDocumentIsLive(t *testing.T) {
+	gw := setupGatewayWithPolicy(t, openAPIPolicy, map[string]string{"payments": "http://payments.invalid"})
+
+	paths := get_openapi(t, gw)["paths"].(map[string]interface{})
+	if _, ok := paths["/tools/crm/lookup"]; ok {
+		t.Fatal("Expected crm to be missing before it has an adapter")
+	}
+
+	gw.SetAdapter("crm", "http://crm.invalid")
+	paths = get_openapi(t, gw)["paths"].(map[string]interface{})
+	if _, ok := paths["/tools/crm/lookup"]; !ok {
+		t.Error("Expected crm/lookup once crm has an adapter")
+	}
+
+	// GET only for actions configured as GET, without a body
+	if _, ok := paths["/tools/crm/lookup"].(map[string]interface{})["get"]; ok {
+		t.Error("Expected no GET for crm/lookup while it's a POST action")
+	}
+	cfg := *gw.cfg()
+	cfg.Tools = map[string]ToolConfig{"crm": {Actions: map[string]ActionConfig{"lookup": {Method: "GET"}}}}
+	if err := gw.SetConfig(cfg); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+	op := operation(t, get_openapi(t, gw), "get", "/tools/crm/lookup")
+	if op["requestBody"] != nil {
+		t.Errorf("Expected GET crm/lookup without a request body, got %v", op["requestBody"])
+	}
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	tests := map[string]string{
+		"/tools/{tool}/{action}":   "/tools/{tool}/{action}",
+		"/deadletters/{id:[0-9]+}": "/deadletters/{id}",
+		"/health":                  "/health",
+	}
+	for in, want := range tests {
+		if got := openapi_path(in); got != want {
+			t.Errorf("openapi_path(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package gateway
+
+import "net/http"
+
+// X-Aegis-Origin says who produced a response, so clients can tell a
+// transport failure (AdapterError, GatewayTimeout) from an adapter's own
+// 4xx/5xx, which is relayed unwrapped
+const (
+	originHeader  = "X-Aegis-Origin"
+	originGateway = "gateway"
+	originAdapter = "adapter"
+)
+
+// responses are the gateway's own unless an adapter response is relayed
+func origin_middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(originHeader, originGateway)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// call before writing the status of a relayed adapter response
+func mark_adapter_origin(w http.ResponseWriter) {
+	w.Header().Set(originHeader, originAdapter)
+}
+
+// origin recorded in h, the gateway when unmarked
+func response_origin(h http.Header) string {
+	if o := h.Get(originHeader); o != "" {
+		return o
+	}
+	return originGateway
+}
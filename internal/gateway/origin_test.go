@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// an adapter's business error is relayed as sent and marked as the adapter's
+func TestAdapterErrorPassthrough(t *testing.T) {
+	const body = `{"field":"vendor_id","problem":"vendor is blocked"}`
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(body))
+	}))
+	defer adapter.Close()
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	gw.SetAdapter("payments", adapter.URL)
+
+	req := httptest.NewRequest("POST", "/tools/payments/create", strings.NewReader(`{"amount":100}`))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected the adapter's 422, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Body.String(); got != body {
+		t.Errorf("Expected the adapter body unwrapped, got %s", got)
+	}
+	if got := w.Header().Get(originHeader); got != originAdapter {
+		t.Errorf("Expected %s: %s, got %q", originHeader, originAdapter, got)
+	}
+}
+
+// a transport failure gets the gateway's AdapterError envelope
+func TestAdapterTransportError(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	gw.SetAdapter("payments", deadAdapterURL())
+
+	req := httptest.NewRequest("POST", "/tools/payments/create", strings.NewReader(`{"amount":100}`))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("Expected 502, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(originHeader); got != originGateway {
+		t.Errorf("Expected %s: %s, got %q", originHeader, originGateway, got)
+	}
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Error != "AdapterError" || resp.Code != "AEGIS-502-ADAPTER" {
+		t.Errorf("Expected the AdapterError envelope, got %+v", resp)
+	}
+}
+
+// gateway answers that never reach an adapter are the gateway's too
+func TestPolicyDenialOrigin(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	req := httptest.NewRequest("POST", "/tools/payments/create", strings.NewReader(`{"amount":10000}`))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403, got %d", w.Code)
+	}
+	if got := w.Header().Get(originHeader); got != originGateway {
+		t.Errorf("Expected %s: %s, got %q", originHeader, originGateway, got)
+	}
+}
+
+func TestBatchItemOrigin(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	resp := post_batch(t, gw, `{"items": [
+		{"tool": "payments", "action": "create", "params": {"amount": 100}},
+		{"tool": "payments", "action": "create", "params": {"amount": 10000}}
+	]}`)
+
+	want := []string{originAdapter, originGateway}
+	for i, r := range resp.Results {
+		if r.Origin != want[i] {
+			t.Errorf("Expected item %d origin %s, got %q", i, want[i], r.Origin)
+		}
+	}
+}
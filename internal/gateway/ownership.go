@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"aegis-gateway/internal/policy"
+)
+
+// header adapters receive the authenticated agent's ID in, so they can
+// record who created what
+const agentIDHeader = "X-Agent-ID"
+
+// tool whose adapter owns_payment looks payments up in
+const paymentsTool = "payments"
+
+type agentIDKey struct{}
+
+func with_agent_id(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, agentIDKey{}, id)
+}
+
+func agent_id_from(ctx context.Context) string {
+	id, _ := ctx.Value(agentIDKey{}).(string)
+	return id
+}
+
+// default owns_payment lookup: GET /payments/{id} on the payments adapter,
+// which records the agent that created each payment
+type adapterPaymentOwners struct {
+	g *Gateway
+}
+
+func (a adapterPaymentOwners) Owner(ctx context.Context, id string) (string, error) {
+	cfg := a.g.cfg()
+	adapterURL, ok := cfg.Adapters[paymentsTool]
+	if !ok {
+		return "", fmt.Errorf("no adapter configured for tool: %s", paymentsTool)
+	}
+	seg, ok := path_segment(id)
+	if !ok {
+		return "", fmt.Errorf("invalid payment id %q", id)
+	}
+	target := strings.TrimSuffix(adapterURL, "/") + "/payments/" + seg
+	resp, err := a.g.forward_to_adapter(ctx, http.MethodGet, target, nil, cfg.timeout(paymentsTool, ""), cfg.upstream(paymentsTool))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", policy.ErrPaymentNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("payment lookup returned %d", resp.StatusCode)
+	}
+	var payment struct {
+		AgentID string `json:"agent_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payment); err != nil {
+		return "", fmt.Errorf("payment lookup: %w", err)
+	}
+	return payment.AgentID, nil
+}
@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aegis-gateway/internal/adapters/payments"
+)
+
+// owns_payment only guards refunds; creates carry no payment_id
+const ownsPaymentPolicy = `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+      - tool: payments
+        actions: [refund]
+        conditions:
+          owns_payment: true
+  - id: other-agent
+    allow:
+      - tool: payments
+        actions: [refund]
+        conditions:
+          owns_payment: true
+`
+
+func payments_adapter_server(t *testing.T) *httptest.Server {
+	t.Helper()
+	a := payments.NewAdapter()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/create", a.HandleCreate)
+	mux.HandleFunc("/refund", a.HandleRefund)
+	mux.HandleFunc("GET /payments/{id}", a.HandleGet)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func call_payments(gw *Gateway, agentID, action, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/tools/payments/"+action, bytes.NewBufferString(body))
+	req.Header.Set("X-Agent-ID", agentID)
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOwnsPayment(t *testing.T) {
+	server := payments_adapter_server(t)
+	gw := setupGatewayWithPolicy(t, ownsPaymentPolicy, map[string]string{"payments": server.URL})
+
+	w := call_payments(gw, "finance-agent", "create", `{"amount": 100, "currency": "USD", "vendor_id": "V1"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected create to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	var created payments.CreateResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	refund := `{"payment_id": "` + created.PaymentID + `", "amount": 10}`
+
+	w = call_payments(gw, "other-agent", "refund", refund)
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusForbidden || resp.Reason != "Payment "+created.PaymentID+" belongs to another agent" {
+		t.Errorf("Expected cross-agent refund to be denied, got %d %+v", w.Code, resp)
+	}
+
+	if w := call_payments(gw, "finance-agent", "refund", refund); w.Code != http.StatusOK {
+		t.Errorf("Expected same-agent refund to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = call_payments(gw, "finance-agent", "refund", `{"payment_id": "missing"}`)
+	resp = ErrorResponse{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusForbidden || resp.Reason != "Payment missing not found" {
+		t.Errorf("Expected unknown payment to be denied, got %d %+v", w.Code, resp)
+	}
+}
+
+func TestForwardsAgentID(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(agentIDHeader)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	gw := setupGatewayWithPolicy(t, ownsPaymentPolicy, map[string]string{"payments": server.URL})
+
+	if w := call_payments(gw, "finance-agent", "create", `{"amount": 1}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected create to succeed, got %d", w.Code)
+	}
+	if got != "finance-agent" {
+		t.Errorf("Expected adapter to receive X-Agent-ID finance-agent, got %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// tool types. a passthrough tool fronts an existing REST API rather than
+// an Aegis adapter: its adapter URL is the API's base URL, usually with
+// headers or auth to inject and per-action paths and methods
+const (
+	toolTypeAdapter     = "adapter"
+	toolTypePassthrough = "passthrough"
+)
+
+var errAdapterResponseTooLarge = errors.New("adapter response too large")
+
+// whether the tool fronts a plain REST API instead of an adapter
+func (t ToolConfig) Passthrough() bool {
+	return t.Type == toolTypePassthrough
+}
+
+// credentials added to every call to the tool's upstream. the secret is
+// read from the environment so it stays out of the config file
+//
+//	auth:
+//	  type: bearer
+//	  secret_env: BILLING_API_TOKEN
+type UpstreamAuth struct {
+	// "bearer" or "basic"
+	Type string `yaml:"type" json:"type"`
+
+	// basic auth user name
+	Username string `yaml:"username" json:"username,omitempty"`
+
+	// environment variable holding the bearer token or basic password
+	SecretEnv string `yaml:"secret_env" json:"secret_env"`
+}
+
+// settings for one call to a tool's upstream
+type upstreamOptions struct {
+	// set on the outgoing request, after the gateway's own headers
+	headers http.Header
+
+	// 0 means unlimited
+	maxResponseBytes int64
+}
+
+func (c Config) upstream(tool string) upstreamOptions {
+	tc := c.tool(tool)
+	up := upstreamOptions{maxResponseBytes: tc.MaxResponseBytes}
+	if len(tc.Headers) == 0 && tc.Auth == nil {
+		return up
+	}
+	up.headers = make(http.Header, len(tc.Headers)+1)
+	for name, value := range tc.Headers {
+		up.headers.Set(name, value)
+	}
+	if a := tc.Auth; a != nil {
+		secret := os.Getenv(a.SecretEnv)
+		req := http.Request{Header: up.headers}
+		switch a.Type {
+		case "bearer":
+			up.headers.Set("Authorization", "Bearer "+secret)
+		case "basic":
+			req.SetBasicAuth(a.Username, secret)
+		}
+	}
+	return up
+}
+
+func (tc ToolConfig) validate_upstream() error {
+	switch tc.Type {
+	case "", toolTypeAdapter, toolTypePassthrough:
+	default:
+		return fmt.Errorf("type must be %s or %s", toolTypeAdapter, toolTypePassthrough)
+	}
+	if tc.MaxResponseBytes < 0 {
+		return fmt.Errorf("max_response_bytes cannot be negative")
+	}
+	for name := range tc.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Type", "Content-Length", "Host", "X-Request-Id":
+			return fmt.Errorf("header %s is set by the gateway", name)
+		}
+	}
+	if a := tc.Auth; a != nil {
+		if a.Type != "bearer" && a.Type != "basic" {
+			return fmt.Errorf("auth.type must be bearer or basic")
+		}
+		if a.Type == "basic" && a.Username == "" {
+			return fmt.Errorf("auth.username is required for basic auth")
+		}
+		if a.SecretEnv == "" {
+			return fmt.Errorf("auth.secret_env is required")
+		}
+		if os.Getenv(a.SecretEnv) == "" {
+			return fmt.Errorf("auth.secret_env: %s is not set", a.SecretEnv)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPassthroughTool(t *testing.T) {
+	type seen struct {
+		method, path, auth, version, requestID, contentType string
+	}
+	var got seen
+	var body map[string]interface{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = seen{
+			method:      r.Method,
+			path:        r.URL.Path,
+			auth:        r.Header.Get("Authorization"),
+			version:     r.Header.Get("X-Api-Version"),
+			requestID:   r.Header.Get("X-Request-ID"),
+			contentType: r.Header.Get("Content-Type"),
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"ch_1","amount":100}`))
+	}))
+	defer upstream.Close()
+
+	t.Setenv("AEGIS_TEST_UPSTREAM_TOKEN", "s3cret")
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	if err := gw.SetConfig(Config{
+		Adapters: map[string]string{"payments": upstream.URL + "/api"},
+		Tools: map[string]ToolConfig{"payments": {
+			Type:    toolTypePassthrough,
+			Headers: map[string]string{"X-Api-Version": "2024-01"},
+			Auth:    &UpstreamAuth{Type: "bearer", SecretEnv: "AEGIS_TEST_UPSTREAM_TOKEN"},
+			Actions: map[string]ActionConfig{
+				"create": {Path: "/v1/charges", Method: "PUT"},
+				"refund": {Path: "/v1/refunds"},
+			},
+		}},
+	}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	send := func(action string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/tools/payments/"+action, bytes.NewReader([]byte(`{"amount":100,"currency":"USD"}`)))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		req.Header.Set("X-Request-ID", "req-passthrough")
+		req.Header.Set("Authorization", "Bearer agent-key")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("create")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected upstream status 201 passed through, got %d: %s", w.Code, w.Body.String())
+	}
+	if body, _ := io.ReadAll(w.Body); string(body) != `{"id":"ch_1","amount":100}` {
+		t.Errorf("Expected upstream body passed through, got %s", body)
+	}
+	want := seen{
+		method:      "PUT",
+		path:        "/api/v1/charges",
+		auth:        "Bearer s3cret",
+		version:     "2024-01",
+		requestID:   "req-passthrough",
+		contentType: "application/json",
+	}
+	if got != want {
+		t.Errorf("Upstream saw %+v, want %+v", got, want)
+	}
+	if body["amount"] != 100.0 || body["currency"] != "USD" {
+		t.Errorf("Expected the request body forwarded, got %v", body)
+	}
+
+	// refund isn't in the policy, so nothing reaches the upstream
+	got = seen{}
+	if w := send("refund"); w.Code != http.StatusForbidden || got.path != "" {
+		t.Errorf("Expected policy to still apply, got %d with upstream call %+v", w.Code, got)
+	}
+
+	// readiness probes the base URL instead of /health
+	w = httptest.NewRecorder()
+	gw.router.ServeHTTP(w, httptest.NewRequest("GET", "/health/ready", nil))
+	if w.Code != http.StatusOK || got.path != "/api" {
+		t.Errorf("Expected the passthrough upstream probed at its base URL, got %d, path %q", w.Code, got.path)
+	}
+}
+
+func TestAdapterResponseLimit(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":"` + strings.Repeat("x", 256) + `"}`))
+	}))
+	defer upstream.Close()
+
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	if err := gw.SetConfig(Config{
+		Adapters: map[string]string{"payments": upstream.URL},
+		Tools:    map[string]ToolConfig{"payments": {MaxResponseBytes: 128}},
+	}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{"amount":100}`)))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("Expected status 502, got %d", w.Code)
+	}
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Error != "ResponseTooLarge" {
+		t.Errorf("Expected ResponseTooLarge error, got %+v", resp)
+	}
+}
+
+func TestPassthroughConfigValidation(t *testing.T) {
+	t.Setenv("AEGIS_TEST_UPSTREAM_TOKEN", "s3cret")
+	bad := map[string]ToolConfig{
+		"unknown type":       {Type: "grpc"},
+		"negative limit":     {MaxResponseBytes: -1},
+		"gateway header":     {Headers: map[string]string{"content-type": "text/plain"}},
+		"bad header name":    {Headers: map[string]string{"X Bad": "v"}},
+		"unknown auth":       {Auth: &UpstreamAuth{Type: "digest", SecretEnv: "AEGIS_TEST_UPSTREAM_TOKEN"}},
+		"basic without user": {Auth: &UpstreamAuth{Type: "basic", SecretEnv: "AEGIS_TEST_UPSTREAM_TOKEN"}},
+		"missing secret env": {Auth: &UpstreamAuth{Type: "bearer"}},
+		"secret env not set": {Auth: &UpstreamAuth{Type: "bearer", SecretEnv: "AEGIS_TEST_UNSET_TOKEN"}},
+	}
+	for name, tc := range bad {
+		if err := (Config{Tools: map[string]ToolConfig{"api": tc}}).validate(); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+
+	basic := Config{Tools: map[string]ToolConfig{"api": {
+		Type: toolTypePassthrough,
+		Auth: &UpstreamAuth{Type: "basic", Username: "svc", SecretEnv: "AEGIS_TEST_UPSTREAM_TOKEN"},
+	}}}
+	if err := basic.validate(); err != nil {
+		t.Fatalf("Expected basic auth config to validate, got %v", err)
+	}
+	req := http.Request{Header: basic.upstream("api").headers}
+	if user, pass, ok := req.BasicAuth(); !ok || user != "svc" || pass != "s3cret" {
+		t.Errorf("Expected basic credentials svc/s3cret, got %q %q %v", user, pass, ok)
+	}
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// {param} placeholders in an action's adapter path
+var pathParamPattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// adapter URL for an action. the action's path template is used if one is
+// configured, with {param} placeholders filled from the agent's params
+// (path-escaped, see path_segment); otherwise the action name is appended
+// as before
+func (c Config) target_url(adapterURL, tool, action string, params map[string]interface{}) (string, error) {
+	base := strings.TrimSuffix(adapterURL, "/")
+	tmpl := c.tool(tool).Actions[action].Path
+	if tmpl == "" {
+		return base + "/" + action, nil
+	}
+
+	var missing, invalid []string
+	path := pathParamPattern.ReplaceAllStringFunc(tmpl, func(m string) string {
+		name := m[1 : len(m)-1]
+		val, ok := path_value(params[name])
+		if !ok {
+			missing = append(missing, name)
+			return m
+		}
+		seg, ok := path_segment(val)
+		if !ok {
+			invalid = append(invalid, name)
+			return m
+		}
+		return seg
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("path %s needs params: %s", tmpl, strings.Join(missing, ", "))
+	}
+	if len(invalid) > 0 {
+		return "", fmt.Errorf("path %s: params %s can't be empty, \".\" or \"..\"", tmpl, strings.Join(invalid, ", "))
+	}
+	return base + "/" + strings.TrimPrefix(path, "/"), nil
+}
+
+// val escaped as a single path segment. escaping leaves "." and ".."
+// alone, and those would walk out of the adapter's route
+func path_segment(val string) (string, bool) {
+	if val == "" || val == "." || val == ".." {
+		return "", false
+	}
+	return url.PathEscape(val), true
+}
+
+// string form of a param used in a path; only strings and numbers qualify
+func path_value(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, val != ""
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestActionPathMapping(t *testing.T) {
+	var gotPath string
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer adapter.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		wantCode int
+		wantPath string
+	}{
+		{"plain action", "", `{"amount":100}`, http.StatusOK, "/create"},
+		{"renamed action", "charge", `{"amount":100}`, http.StatusOK, "/charge"},
+		{"template", "/payments/{vendor_id}/charge", `{"amount":100,"vendor_id":"V 42/x"}`, http.StatusOK, "/payments/V%2042%2Fx/charge"},
+		{"numeric template param", "/payments/{amount}", `{"amount":100}`, http.StatusOK, "/payments/100"},
+		{"missing template param", "/payments/{vendor_id}/charge", `{"amount":100}`, http.StatusBadRequest, ""},
+		{"empty template param", "/payments/{vendor_id}/charge", `{"amount":100,"vendor_id":""}`, http.StatusBadRequest, ""},
+		{"dot template param", "/payments/{vendor_id}/charge", `{"amount":100,"vendor_id":"."}`, http.StatusBadRequest, ""},
+		{"dot-dot template param", "/payments/{vendor_id}/charge", `{"amount":100,"vendor_id":".."}`, http.StatusBadRequest, ""},
+		{"dots within a template param", "/payments/{vendor_id}/charge", `{"amount":100,"vendor_id":"..x"}`, http.StatusOK, "/payments/..x/charge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw, _ := setupTestGateway(t)
+			defer gw.Close()
+			gw.SetAdapter("payments", adapter.URL)
+			err := gw.SetConfig(Config{Tools: map[string]ToolConfig{
+				"payments": {Actions: map[string]ActionConfig{"create": {Path: tt.path}}},
+			}})
+			if err != nil {
+				t.Fatalf("SetConfig() error = %v", err)
+			}
+			gotPath = ""
+
+			req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("X-Agent-ID", "test-agent")
+			w := httptest.NewRecorder()
+			gw.router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("Expected adapter path %q, got %q", tt.wantPath, gotPath)
+			}
+		})
+	}
+}
+
+func TestActionPathValidation(t *testing.T) {
+	for _, path := range []string{"/payments/{vendor_id/charge", "/charge?x=1"} {
+		cfg := Config{Tools: map[string]ToolConfig{
+			"payments": {Actions: map[string]ActionConfig{"create": {Path: path}}},
+		}}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected path %q to be rejected", path)
+		}
+	}
+}
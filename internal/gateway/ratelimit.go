@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// how often buckets that have refilled are dropped
+const rateSweepInterval = time.Minute
+
+// buckets kept at most. X-Agent-ID is up to the caller, so past this the
+// least recently seen tenth is dropped
+const maxRateBuckets = 100000
+
+// per-agent token buckets, kept across config reloads so a reload doesn't
+// hand every agent a fresh burst. limits are passed with each call, so
+// new ones apply right away; a lower burst caps what an agent has saved
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*bucket)}
+}
+
+func rate_burst(cfg RateLimitConfig) float64 {
+	if cfg.Burst < 1 {
+		return math.Max(1, math.Ceil(cfg.RequestsPerSecond))
+	}
+	return float64(cfg.Burst)
+}
+
+// take a token for agentID. when the agent is over its limit, false and
+// how long until the bucket holds a token again
+func (l *rateLimiter) allow(agentID string, now time.Time, cfg RateLimitConfig) (bool, time.Duration) {
+	if l == nil || cfg.RequestsPerSecond <= 0 {
+		return true, 0
+	}
+	rate, burst := cfg.RequestsPerSecond, rate_burst(cfg)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateSweepInterval {
+		l.sweep(now, rate, burst)
+	}
+	b, ok := l.buckets[agentID]
+	if !ok {
+		if len(l.buckets) >= maxRateBuckets {
+			l.sweep(now, rate, burst)
+			l.evict(len(l.buckets) - maxRateBuckets + maxRateBuckets/10)
+		}
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[agentID] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// drop buckets that have refilled to burst, a new one would be the same.
+// caller must hold l.mu
+func (l *rateLimiter) sweep(now time.Time, rate, burst float64) {
+	for id, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= burst {
+			delete(l.buckets, id)
+		}
+	}
+	l.lastSweep = now
+}
+
+// drop the n least recently seen buckets, caller must hold l.mu
+func (l *rateLimiter) evict(n int) {
+	if n <= 0 {
+		return
+	}
+	ids := make([]string, 0, len(l.buckets))
+	for id := range l.buckets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return l.buckets[ids[i]].last.Before(l.buckets[ids[j]].last) })
+	if n > len(ids) {
+		n = len(ids)
+	}
+	for _, id := range ids[:n] {
+		delete(l.buckets, id)
+	}
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSweepsIdleBuckets(t *testing.T) {
+	l := newRateLimiter()
+	cfg := RateLimitConfig{RequestsPerSecond: 1, Burst: 2}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 50; i++ {
+		l.allow(fmt.Sprintf("agent-%d", i), now, cfg)
+	}
+	if len(l.buckets) != 50 {
+		t.Fatalf("Expected 50 buckets, got %d", len(l.buckets))
+	}
+
+	// a bucket refilled to burst is dropped, one still refilling isn't
+	now = now.Add(rateSweepInterval)
+	l.allow("busy", now, cfg)
+	l.allow("busy", now, cfg)
+	now = now.Add(rateSweepInterval)
+	l.allow("late", now.Add(-time.Second), cfg)
+	l.allow("late", now.Add(-time.Second), cfg)
+	l.allow("new", now, cfg)
+	if _, ok := l.buckets["late"]; !ok || len(l.buckets) != 2 {
+		t.Errorf("Expected only late and new to be kept, got %d buckets", len(l.buckets))
+	}
+}
+
+func TestRateLimiterCapsBuckets(t *testing.T) {
+	l := newRateLimiter()
+	// refills slowly enough that no bucket is swept
+	cfg := RateLimitConfig{RequestsPerSecond: 0.0001, Burst: 1}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	for i := 0; i <= maxRateBuckets; i++ {
+		l.allow(fmt.Sprintf("agent-%d", i), now.Add(time.Duration(i)), cfg)
+	}
+	if len(l.buckets) > maxRateBuckets {
+		t.Fatalf("Expected at most %d buckets, got %d", maxRateBuckets, len(l.buckets))
+	}
+	if _, ok := l.buckets["agent-0"]; ok {
+		t.Error("Expected the least recently seen bucket to be dropped")
+	}
+	if _, ok := l.buckets[fmt.Sprintf("agent-%d", maxRateBuckets)]; !ok {
+		t.Error("Expected the newest bucket to be kept")
+	}
+}
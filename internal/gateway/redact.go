@@ -0,0 +1,50 @@
+package gateway
+
+import "strings"
+
+// copy of params without the configured sensitive fields, for the hash
+// that ends up in audit records and spans. the original is left intact
+// for policy evaluation and forwarding
+func (c Config) redact_params(params map[string]interface{}) map[string]interface{} {
+	if len(c.SensitiveParams) == 0 {
+		return params
+	}
+	out := copy_object(params)
+	for _, p := range c.SensitiveParams {
+		delete_path(out, strings.Split(p, "."))
+	}
+	return out
+}
+
+// copy nested objects so deleting from the copy can't reach the original;
+// other values are shared
+func copy_object(obj map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		if child, ok := v.(map[string]interface{}); ok {
+			v = copy_object(child)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// the configured sensitive fields params actually carries
+func (c Config) sensitive_paths(params map[string]interface{}) []string {
+	var present []string
+	for _, p := range c.SensitiveParams {
+		if has_path(params, strings.Split(p, ".")) {
+			present = append(present, p)
+		}
+	}
+	return present
+}
+
+func has_path(obj map[string]interface{}, path []string) bool {
+	v, ok := obj[path[0]]
+	if !ok || len(path) == 1 {
+		return ok
+	}
+	child, ok := v.(map[string]interface{})
+	return ok && has_path(child, path[1:])
+}
@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aegis-gateway/pkg/telemetry"
+)
+
+func TestSensitiveParamsExcludedFromHash(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	if err := telemetry.InitTelemetry("aegis-test", logPath); err != nil {
+		t.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+
+	var received map[string]interface{}
+	gw.SetAdapter("payments", recordingAdapter(t, &received).URL)
+	if err := gw.SetConfig(Config{SensitiveParams: []string{"memo", "card.cvv"}}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	send := func(params map[string]interface{}) {
+		bodyBytes, _ := json.Marshal(params)
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+	}
+	send(map[string]interface{}{"amount": 100.0, "memo": "salary for alice", "card": map[string]interface{}{"last4": "4242", "cvv": "123"}})
+	send(map[string]interface{}{"amount": 100.0, "memo": "bonus for bob", "card": map[string]interface{}{"last4": "4242", "cvv": "999"}})
+	send(map[string]interface{}{"amount": 200.0, "memo": "salary for alice", "card": map[string]interface{}{"last4": "4242", "cvv": "123"}})
+
+	// the adapter still gets the whole request
+	if received["memo"] != "salary for alice" {
+		t.Errorf("Expected memo forwarded unchanged, got %v", received["memo"])
+	}
+
+	data, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 audit records, got %d", len(lines))
+	}
+	hashes := make([]string, len(lines))
+	for i, line := range lines {
+		var rec telemetry.AuditLog
+		json.Unmarshal([]byte(line), &rec)
+		hashes[i] = rec.ParamsHash
+	}
+	if hashes[0] != hashes[1] {
+		t.Errorf("Expected requests differing only in sensitive fields to hash the same: %s != %s", hashes[0], hashes[1])
+	}
+	if hashes[0] == hashes[2] {
+		t.Error("Expected a different amount to change the hash")
+	}
+}
+
+func TestRedactParamsLeavesOriginal(t *testing.T) {
+	cfg := Config{SensitiveParams: []string{"memo", "card.cvv"}}
+	params := map[string]interface{}{
+		"amount": 100.0,
+		"memo":   "x",
+		"card":   map[string]interface{}{"cvv": "123", "last4": "4242"},
+	}
+
+	redacted := cfg.redact_params(params)
+	if _, ok := redacted["memo"]; ok {
+		t.Error("Expected memo removed")
+	}
+	if card := redacted["card"].(map[string]interface{}); card["cvv"] != nil || card["last4"] != "4242" {
+		t.Errorf("Expected only card.cvv removed, got %v", card)
+	}
+	if params["memo"] != "x" || params["card"].(map[string]interface{})["cvv"] != "123" {
+		t.Errorf("Original params were modified: %v", params)
+	}
+
+	if err := (Config{SensitiveParams: []string{""}}).validate(); err == nil {
+		t.Error("Expected an empty sensitive field to be rejected")
+	}
+}
@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// correlation ID shared by the client, the gateway's audit record and the
+// adapter request
+const requestIDHeader = "X-Request-ID"
+
+// longest client-supplied ID that's reused as-is
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// the client's X-Request-ID if it's usable, otherwise a new UUID. IDs
+// end up in logs, so anything long or non-printable is replaced
+func request_id(r *http.Request) string {
+	id := r.Header.Get(requestIDHeader)
+	if id == "" || len(id) > maxRequestIDLen {
+		return uuid.New().String()
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return uuid.New().String()
+		}
+	}
+	return id
+}
+
+func with_request_id(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func request_id_from(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
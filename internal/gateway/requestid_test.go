@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"aegis-gateway/pkg/telemetry"
+
+	"github.com/google/uuid"
+)
+
+func TestRequestIDPropagated(t *testing.T) {
+	var adapterID string
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		adapterID = r.Header.Get("X-Request-ID")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":"ok"}`))
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+
+	tests := []struct {
+		name     string
+		clientID string
+	}{
+		{"client supplied", "client-req-42"},
+		{"generated", ""},
+		{"unusable replaced", "bad id\nwith newline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "audit.log")
+			if err := telemetry.InitTelemetry("aegis-test", logPath); err != nil {
+				t.Fatalf("Failed to initialize telemetry: %v", err)
+			}
+			adapterID = ""
+
+			req := httptest.NewRequest("POST", "/tools/files/read", bytes.NewReader([]byte(`{"path":"/doc.txt"}`)))
+			req.Header.Set("X-Agent-ID", "reader-agent")
+			if tt.clientID != "" {
+				req.Header.Set("X-Request-ID", tt.clientID)
+			}
+			w := httptest.NewRecorder()
+			gw.router.ServeHTTP(w, req)
+
+			got := w.Header().Get("X-Request-ID")
+			if tt.name == "client supplied" {
+				if got != tt.clientID {
+					t.Errorf("Expected client ID %q echoed, got %q", tt.clientID, got)
+				}
+			} else if _, err := uuid.Parse(got); err != nil {
+				t.Errorf("Expected a generated UUID, got %q", got)
+			}
+			if adapterID != got {
+				t.Errorf("Expected adapter to receive %q, got %q", got, adapterID)
+			}
+
+			data, _ := os.ReadFile(logPath)
+			var rec telemetry.AuditLog
+			if err := json.Unmarshal(bytes.TrimSpace(data), &rec); err != nil {
+				t.Fatalf("Invalid audit record %q: %v", data, err)
+			}
+			if rec.RequestID != got {
+				t.Errorf("Expected audit request_id %q, got %q", got, rec.RequestID)
+			}
+		})
+	}
+}
+
+func TestRequestIDOnErrorResponse(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("X-Request-ID", "missing-agent-req")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	if got := w.Header().Get("X-Request-ID"); got != "missing-agent-req" {
+		t.Errorf("Expected request ID on the error response, got %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// cap on a single backoff when max_delay isn't set
+const defaultRetryMaxDelay = 10 * time.Second
+
+// retry policy for adapter calls. only enable it for actions that are safe
+// to repeat: a retried payment create can charge twice
+type RetryConfig struct {
+	// total attempts including the first; 0 or 1 disables retries
+	MaxAttempts int `yaml:"max_attempts" json:"max_attempts"`
+
+	// delay before the first retry, doubled on each further one
+	BaseDelay time.Duration `yaml:"base_delay" json:"base_delay"`
+
+	// fraction of each delay that is randomized, 0..1
+	Jitter float64 `yaml:"jitter" json:"jitter,omitempty"`
+
+	// longest any one delay gets, 10s when 0
+	MaxDelay time.Duration `yaml:"max_delay" json:"max_delay,omitempty"`
+}
+
+func (r RetryConfig) validate() error {
+	if r.MaxAttempts < 0 || r.BaseDelay < 0 || r.MaxDelay < 0 {
+		return fmt.Errorf("retry values cannot be negative")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// backoff before retry n (1 = first retry), doubled up to the cap
+func (r RetryConfig) delay(n int) time.Duration {
+	limit := r.MaxDelay
+	if limit == 0 {
+		limit = defaultRetryMaxDelay
+	}
+	d := r.BaseDelay
+	for i := 1; i < n && d < limit; i++ {
+		d *= 2
+	}
+	d = min(d, limit)
+	if r.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * r.Jitter * float64(d))
+	}
+	return d
+}
+
+// wait out the backoff before retry n, false if ctx ends first. a retry
+// that couldn't start before ctx's deadline would only end in a timeout,
+// so then there's no wait and the caller keeps the last failure
+func (r RetryConfig) wait(ctx context.Context, n int) bool {
+	d := r.delay(n)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return false
+	}
+	return sleep_ctx(ctx, d) == nil
+}
+
+// adapter responses worth another attempt
+func retryable_status(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable
+}
+
+// wait d or until ctx is done
+func sleep_ctx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
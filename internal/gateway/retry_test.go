@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// adapter that answers 503 for the first failures calls, then 200
+func flakyAdapter(t *testing.T, failures int32, hits *atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["path"] != "/shared/report.txt" {
+			t.Errorf("Attempt %d got body %v", hits.Load()+1, body)
+		}
+		if hits.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":"hello"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sendRead(gw *Gateway) *httptest.ResponseRecorder {
+	bodyBytes, _ := json.Marshal(map[string]interface{}{"path": "/shared/report.txt"})
+	req := httptest.NewRequest("POST", "/tools/files/read", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "reader-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRetry_RecoversFromTransientFailures(t *testing.T) {
+	var hits atomic.Int32
+	adapter := flakyAdapter(t, 2, &hits)
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	defer gw.Close()
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"files": {Actions: map[string]ActionConfig{
+			"read": {Retry: &RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}},
+		}},
+	}})
+
+	w := sendRead(gw)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 after retries, got %d", w.Code)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRetry_ExhaustsAttempts(t *testing.T) {
+	var hits atomic.Int32
+	adapter := flakyAdapter(t, 100, &hits)
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	defer gw.Close()
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"files": {Retry: &RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, Jitter: 0.5}},
+	}})
+
+	w := sendRead(gw)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the last 503 to be relayed, got %d", w.Code)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRetry_OffByDefault(t *testing.T) {
+	var hits atomic.Int32
+	adapter := flakyAdapter(t, 1, &hits)
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	defer gw.Close()
+
+	if w := sendRead(gw); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without retries, got %d", w.Code)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("Expected a single attempt, got %d", got)
+	}
+}
+
+func TestRetryDelayBackoff(t *testing.T) {
+	r := RetryConfig{MaxAttempts: 4, BaseDelay: 10 * time.Millisecond}
+	for n, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond} {
+		if got := r.delay(n); got != want {
+			t.Errorf("delay(%d) = %v, want %v", n, got, want)
+		}
+	}
+
+	// capped, by default and by max_delay, and never overflowing
+	if got := r.delay(64); got != defaultRetryMaxDelay {
+		t.Errorf("delay(64) = %v, want the default cap %v", got, defaultRetryMaxDelay)
+	}
+	r.MaxDelay = 25 * time.Millisecond
+	if got := r.delay(3); got != 25*time.Millisecond {
+		t.Errorf("delay(3) = %v, want max_delay 25ms", got)
+	}
+	if err := (Config{Tools: map[string]ToolConfig{"files": {Retry: &RetryConfig{MaxDelay: -time.Second}}}}).validate(); err == nil {
+		t.Error("Expected a negative max_delay to be rejected")
+	}
+	if err := (Config{Tools: map[string]ToolConfig{"files": {Retry: &RetryConfig{Jitter: 2}}}}).validate(); err == nil {
+		t.Error("Expected jitter above 1 to be rejected")
+	}
+}
+
+// a backoff that would outlast request_timeout isn't waited out: the last
+// failure comes back right away instead of a timeout
+func TestRetry_StopsAtDeadline(t *testing.T) {
+	var hits atomic.Int32
+	adapter := flakyAdapter(t, 100, &hits)
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	defer gw.Close()
+	gw.SetConfig(Config{
+		RequestTimeout: 500 * time.Millisecond,
+		Tools: map[string]ToolConfig{
+			"files": {Retry: &RetryConfig{MaxAttempts: 3, BaseDelay: time.Second}},
+		},
+	})
+
+	start := time.Now()
+	w := sendRead(gw)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the adapter's 503, got %d", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("Expected no wait for a retry past the deadline, took %v", elapsed)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("Expected a single attempt, got %d", got)
+	}
+}
@@ -0,0 +1,18 @@
+package gateway
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// tell a throttled client when to try again, in whole seconds rounded up.
+// never less than 1: a client reading 0 would retry straight away
+func set_retry_after(w http.ResponseWriter, wait time.Duration) {
+	secs := int64(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+}
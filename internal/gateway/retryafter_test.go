@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterRateLimited(t *testing.T) {
+	gw, mockURL := setupTestGateway(t)
+	defer gw.Close()
+	// one token, refilled every 4s
+	gw.SetConfig(Config{
+		Adapters:  map[string]string{"payments": mockURL},
+		RateLimit: RateLimitConfig{RequestsPerSecond: 0.25, Burst: 1},
+	})
+
+	if w := post_payment(gw, `{"amount": 100}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected first request allowed, got %d", w.Code)
+	}
+	w := post_payment(gw, `{"amount": 100}`)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected second request rate limited, got %d", w.Code)
+	}
+	// the bucket is empty, a token is 4s away
+	if got := w.Header().Get("Retry-After"); got != "4" {
+		t.Errorf("Expected Retry-After 4, got %q", got)
+	}
+}
+
+func TestRetryAfterCircuitOpen(t *testing.T) {
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	defer gw.Close()
+	gw.SetConfig(Config{CircuitBreaker: BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute}})
+
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	gw.breakers.now = func() time.Time { return now }
+
+	// the adapter's own 503 passes through untouched
+	if w := sendRead(gw); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "" {
+		t.Fatalf("Expected the adapter's 503 without Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	now = now.Add(20 * time.Second)
+	w := sendRead(gw)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected the open circuit to reject, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "40" {
+		t.Errorf("Expected Retry-After 40 until the circuit half-opens, got %q", got)
+	}
+
+	// past the cooldown with the probe still in flight: no estimate, retry soon
+	now = now.Add(time.Minute)
+	if ok, _ := gw.breakers.allow("files", gw.cfg().CircuitBreaker); !ok {
+		t.Fatal("Expected a probe after the cooldown")
+	}
+	if got := sendRead(gw).Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Expected Retry-After 1 while probing, got %q", got)
+	}
+}
+
+func TestSetRetryAfter(t *testing.T) {
+	for _, tt := range []struct {
+		wait time.Duration
+		want int
+	}{
+		{0, 1},
+		{-time.Second, 1},
+		{300 * time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	} {
+		w := httptest.NewRecorder()
+		set_retry_after(w, tt.wait)
+		if got := w.Header().Get("Retry-After"); got != strconv.Itoa(tt.want) {
+			t.Errorf("set_retry_after(%v) = %q, want %d", tt.wait, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"aegis-gateway/pkg/apierror"
+	"aegis-gateway/pkg/telemetry"
+)
+
+// config, swapped as one unit. state built from it that must outlive a
+// reload, such as rate limit buckets, lives on the Gateway
+type runtimeState struct {
+	config Config
+}
+
+func newRuntimeState(cfg Config) *runtimeState {
+	if cfg.Adapters == nil {
+		cfg.Adapters = map[string]string{}
+	}
+	return &runtimeState{config: cfg}
+}
+
+// current config, treat as read-only
+func (g *Gateway) cfg() *Config {
+	if s := g.state.Load(); s != nil {
+		return &s.config
+	}
+	return &Config{}
+}
+
+// validate and atomically swap in new runtime settings. a config without
+// adapters keeps the current registry. invalid configs are rejected and the
+// current one stays active.
+func (g *Gateway) SetConfig(cfg Config) error {
+	g.configMu.Lock()
+	defer g.configMu.Unlock()
+	return g.set_config(cfg)
+}
+
+// caller must hold g.configMu
+func (g *Gateway) set_config(cfg Config) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	prev := g.cfg()
+	if cfg.Adapters == nil {
+		cfg.Adapters = prev.Adapters
+	}
+	// open whatever the new config needs before swapping anything in, so
+	// a rejected config leaves the current settings fully in place. only
+	// reconnect when the database settings actually changed
+	swapSink := !reflect.DeepEqual(cfg.AuditSQL, prev.AuditSQL)
+	var sink telemetry.AuditSink
+	if swapSink && cfg.AuditSQL != nil {
+		s, err := telemetry.OpenSQLAuditSink(*cfg.AuditSQL)
+		if err != nil {
+			return fmt.Errorf("audit_sql: %w", err)
+		}
+		sink = s
+	}
+	swapLog := g.access_log_changed(cfg.AccessLog)
+	var accessLog *accessLog
+	if swapLog {
+		l, err := open_access_log(cfg.AccessLog)
+		if err != nil {
+			if sink != nil {
+				sink.Close()
+			}
+			return err
+		}
+		accessLog = l
+	}
+
+	if swapSink {
+		if old := telemetry.SetAuditSink(sink); old != nil {
+			old.Close()
+		}
+	}
+	if swapLog {
+		g.swap_access_log(accessLog)
+	}
+	if cfg.ApproverKeys != nil || prev.ApproverKeys != nil {
+		keys, _ := cfg.approver_keys()
+		g.SetApproverKeys(keys)
+	}
+	if cfg.ChangeWindows != nil || prev.ChangeWindows != nil {
+		g.SetChangeWindows(cfg.ChangeWindows)
+	}
+	g.state.Store(newRuntimeState(cfg))
+	telemetry.SetAuditSampling(cfg.AuditSampling)
+	return nil
+}
+
+// register or replace the adapter URL for a tool
+func (g *Gateway) SetAdapter(tool, url string) error {
+	g.configMu.Lock()
+	defer g.configMu.Unlock()
+	cfg := *g.cfg()
+	adapters := make(map[string]string, len(cfg.Adapters)+1)
+	for k, v := range cfg.Adapters {
+		adapters[k] = v
+	}
+	adapters[tool] = url
+	cfg.Adapters = adapters
+	return g.set_config(cfg)
+}
+
+// load runtime settings from path and remember it for ReloadConfig
+func (g *Gateway) SetConfigFile(path string) error {
+	g.configPath = path
+	return g.ReloadConfig()
+}
+
+// re-read the config file set with SetConfigFile
+func (g *Gateway) ReloadConfig() error {
+	if g.configPath == "" {
+		return fmt.Errorf("no config file configured")
+	}
+	cfg, err := LoadConfigFile(g.configPath)
+	if err != nil {
+		return err
+	}
+	return g.SetConfig(cfg)
+}
+
+func (g *Gateway) handle_config_reload(w http.ResponseWriter, r *http.Request) {
+	if err := g.ReloadConfig(); err != nil {
+		write_error(w, apierror.ConfigReloadFailed, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "reloaded"})
+}
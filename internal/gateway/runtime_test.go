@@ -0,0 +1,213 @@
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aegis-gateway/internal/policy"
+)
+
+func sendPayment(gw *Gateway) int {
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{"amount":100,"currency":"USD"}`)))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestConfigReload_RateLimit(t *testing.T) {
+	gw, mockURL := setupTestGateway(t)
+	defer gw.Close()
+
+	configPath := filepath.Join(t.TempDir(), "aegis.yaml")
+	writeConfig := func(content string) {
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+
+	// very slow refill so the burst is all an agent gets during the test
+	writeConfig(`adapters:
+  payments: ` + mockURL + `
+rate_limit:
+  requests_per_second: 0.001
+  burst: 1
+`)
+	if err := gw.SetConfigFile(configPath); err != nil {
+		t.Fatalf("SetConfigFile() error = %v", err)
+	}
+
+	if code := sendPayment(gw); code != http.StatusOK {
+		t.Fatalf("Expected first request allowed, got %d", code)
+	}
+	if code := sendPayment(gw); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected second request rate limited, got %d", code)
+	}
+
+	// raise the burst and reload via the admin endpoint
+	writeConfig(`adapters:
+  payments: ` + mockURL + `
+rate_limit:
+  requests_per_second: 0.001
+  burst: 3
+`)
+	req := httptest.NewRequest("POST", "/config/reload", nil)
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected reload to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// buckets outlive the reload, so test-agent's spent burst isn't refilled
+	if code := sendPayment(gw); code != http.StatusTooManyRequests {
+		t.Errorf("Expected test-agent to stay limited across the reload, got %d", code)
+	}
+
+	// while the new burst applies to a fresh bucket. other-agent has no
+	// policy, so what gets through the limiter is denied
+	send := func() int {
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{"amount":100}`)))
+		req.Header.Set("X-Agent-ID", "other-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	for i := 0; i < 3; i++ {
+		if code := send(); code != http.StatusForbidden {
+			t.Fatalf("Request %d after reload: expected it past the limiter, got %d", i+1, code)
+		}
+	}
+	if code := send(); code != http.StatusTooManyRequests {
+		t.Errorf("Expected request beyond new burst to be limited, got %d", code)
+	}
+}
+
+func TestConfigReload_InvalidKeepsCurrent(t *testing.T) {
+	gw, mockURL := setupTestGateway(t)
+	defer gw.Close()
+
+	configPath := filepath.Join(t.TempDir(), "aegis.yaml")
+	os.WriteFile(configPath, []byte("adapter_timeout: 3s\n"), 0644)
+	if err := gw.SetConfigFile(configPath); err != nil {
+		t.Fatalf("SetConfigFile() error = %v", err)
+	}
+
+	os.WriteFile(configPath, []byte("adapters:\n  payments: not-a-url\n"), 0644)
+	if err := gw.ReloadConfig(); err == nil {
+		t.Fatal("Expected invalid config to be rejected")
+	}
+
+	if got := gw.cfg().AdapterTimeout; got != 3*time.Second {
+		t.Errorf("Expected previous adapter_timeout to stay active, got %v", got)
+	}
+	if got := gw.cfg().Adapters["payments"]; got != mockURL {
+		t.Errorf("Expected previous adapter registry to stay active, got %q", got)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	gw.SetConfig(Config{CORS: CORSConfig{AllowedOrigins: []string{"https://console.example.com"}}})
+
+	req := httptest.NewRequest("OPTIONS", "/tools/payments/create", nil)
+	req.Header.Set("Origin", "https://console.example.com")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected preflight 204, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://console.example.com" {
+		t.Errorf("Expected allowed origin header, got %q", got)
+	}
+	allowed := w.Header().Get("Access-Control-Allow-Headers")
+	for _, h := range []string{"X-Agent-ID", "X-Parent-Agent", "X-Request-ID", "X-Approver", "X-Change-ID", "X-Debug-Conditions", "Authorization"} {
+		if !strings.Contains(allowed, h) {
+			t.Errorf("Expected %s in Access-Control-Allow-Headers, got %q", h, allowed)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	w = httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS header for unknown origin, got %q", got)
+	}
+}
+
+// adapters registered at the same time all end up in the registry
+func TestSetAdapterConcurrent(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			gw.SetAdapter(fmt.Sprintf("tool-%d", i), "http://localhost:9000")
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 20; i++ {
+		if _, ok := gw.cfg().Adapters[fmt.Sprintf("tool-%d", i)]; !ok {
+			t.Errorf("Expected tool-%d registered", i)
+		}
+	}
+}
+
+func TestChangeWindowsFromConfig(t *testing.T) {
+	var received map[string]interface{}
+	gw := setupGatewayWithPolicy(t, `version: 1
+agents:
+  - id: deploy-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          require_change_window: true
+`, map[string]string{"payments": recordingAdapter(t, &received).URL})
+
+	send := func(changeID string) int {
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{"amount":100}`)))
+		req.Header.Set("X-Agent-ID", "deploy-agent")
+		req.Header.Set("X-Change-ID", changeID)
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	configPath := filepath.Join(t.TempDir(), "aegis.yaml")
+	open := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	content := "change_windows:\n" +
+		"  CHG-1: {start: " + open + "}\n" +
+		"  CHG-2: {start: " + open + ", end: " + time.Now().Add(-time.Minute).UTC().Format(time.RFC3339) + "}\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := gw.SetConfigFile(configPath); err != nil {
+		t.Fatalf("SetConfigFile() error = %v", err)
+	}
+	if code := send("CHG-1"); code != http.StatusOK {
+		t.Errorf("Expected the open change window to allow, got %d", code)
+	}
+	if code := send("CHG-2"); code != http.StatusForbidden {
+		t.Errorf("Expected the closed change window to deny, got %d", code)
+	}
+
+	bad := Config{ChangeWindows: policy.StaticChangeWindows{"CHG-3": {Start: time.Now(), End: time.Now().Add(-time.Hour)}}}
+	if err := gw.SetConfig(bad); err == nil {
+		t.Error("Expected a window ending before it starts to be rejected")
+	}
+}
@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"fmt"
+
+	"aegis-gateway/internal/policy"
+	"aegis-gateway/pkg/telemetry"
+)
+
+// evaluate the policies in dirs alongside the live ones without enforcing
+// them. every request where they decide differently gets the shadow
+// decision in its audit record (shadow_decision_allow, shadow_reason), so
+// a policy change can be tried against real traffic before it goes live.
+// the directories are watched and reloaded like the live ones
+func (g *Gateway) SetShadowPolicyDirs(dirs ...string) error {
+	sm, err := policy.NewManager(dirs...)
+	if err != nil {
+		return fmt.Errorf("failed to create shadow policy manager: %w", err)
+	}
+	if err := watch_policy_dirs(g.watcher, dirs); err != nil {
+		return fmt.Errorf("failed to watch shadow policy directory: %w", err)
+	}
+	sm.SetPaymentOwners(adapterPaymentOwners{g})
+	if keys, _ := g.cfg().approver_keys(); keys != nil {
+		sm.SetApproverKeys(keys)
+	}
+	if cw := g.cfg().ChangeWindows; cw != nil {
+		sm.SetChangeWindows(cw)
+	}
+	g.policyManager.SetShadow(sm)
+	return nil
+}
+
+// reload the shadow policies, if any. they're never enforced, so a bad
+// shadow file is logged and otherwise ignored
+func (g *Gateway) reload_shadow_policies(rewatch bool) {
+	sm := g.policyManager.Shadow()
+	if sm == nil {
+		return
+	}
+	if rewatch {
+		if err := watch_policy_dirs(g.watcher, sm.Dirs()); err != nil {
+			fmt.Printf("ERROR: failed to re-watch shadow policy directory: %v\n", err)
+		}
+	}
+	if err := sm.Reload(); err != nil {
+		fmt.Printf("ERROR: failed to reload shadow policies: %v\n", err)
+	}
+}
+
+// record the shadow decision when it differs from the enforced one
+func shadow_audit(audit *telemetry.AuditLog, d policy.Decision) {
+	if !d.Diverged() {
+		return
+	}
+	allow := d.Shadow.Allow
+	audit.ShadowDecision = &allow
+	audit.ShadowReason = d.Shadow.Reason
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"aegis-gateway/pkg/telemetry"
+)
+
+const shadowPolicy = `version: 2
+agents:
+  - id: test-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: %s
+`
+
+func write_shadow_policy(t *testing.T, dir, maxAmount string) {
+	t.Helper()
+	content := []byte(fmt.Sprintf(shadowPolicy, maxAmount))
+	if err := os.WriteFile(filepath.Join(dir, "next.yaml"), content, 0644); err != nil {
+		t.Fatalf("Failed to write shadow policy: %v", err)
+	}
+}
+
+func post_payment(gw *Gateway, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewBufferString(body))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestShadowPolicyDivergence(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	sink := &memoryAuditSink{}
+	telemetry.SetAuditSink(sink)
+	defer telemetry.SetAuditSink(nil)
+
+	// the candidate policy lowers max_amount from 5000 to 1000
+	shadowDir := t.TempDir()
+	write_shadow_policy(t, shadowDir, "1000")
+	if err := gw.SetShadowPolicyDirs(shadowDir); err != nil {
+		t.Fatalf("Failed to set shadow policies: %v", err)
+	}
+
+	// allowed by both
+	if w := post_payment(gw, `{"amount": 500}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	// the live policy enforces; the shadow denial is only recorded
+	if w := post_payment(gw, `{"amount": 3000}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected the live policy to allow 3000, got %d: %s", w.Code, w.Body.String())
+	}
+	// denied by both
+	if w := post_payment(gw, `{"amount": 9000}`); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, got %d: %s", w.Code, w.Body.String())
+	}
+
+	records := sink.all()
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 audit records, got %d", len(records))
+	}
+	for _, i := range []int{0, 2} {
+		if records[i].ShadowDecision != nil || records[i].ShadowReason != "" {
+			t.Errorf("Expected no shadow fields where the decisions agree, got %+v", records[i])
+		}
+	}
+	rec := records[1]
+	if !rec.Decision || rec.Version != 1 {
+		t.Errorf("Expected the live decision in the record, got %+v", rec)
+	}
+	if rec.ShadowDecision == nil || *rec.ShadowDecision || rec.ShadowReason != "Amount 3000.00 exceeds max_amount=1000.00" {
+		t.Errorf("Expected the shadow denial in the record, got %+v", rec)
+	}
+
+	// shadow policies reload with the live ones
+	write_shadow_policy(t, shadowDir, "5000")
+	req := httptest.NewRequest("POST", "/policies/reload", nil)
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected reload to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	post_payment(gw, `{"amount": 3000}`)
+	if rec := sink.all()[3]; rec.ShadowDecision != nil {
+		t.Errorf("Expected the reloaded shadow policy to agree, got %+v", rec)
+	}
+}
+
+func TestShadowPolicyDirsInvalid(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	if err := gw.SetShadowPolicyDirs(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("Expected an error for a missing shadow policy directory")
+	}
+	if gw.policyManager.Shadow() != nil {
+		t.Error("Expected shadow mode to stay off")
+	}
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte(`{"payment_id":"slow-1"}`))
+	}))
+	defer adapter.Close()
+
+	gw, _ := setupTestGateway(t)
+	gw.SetAdapter("payments", adapter.URL)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- gw.Serve(l) }()
+	url := "http://" + l.Addr().String() + "/tools/payments/create"
+
+	// slow in-flight request
+	type result struct {
+		code int
+		err  error
+	}
+	inflight := make(chan result, 1)
+	go func() {
+		bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": 100.0})
+		req, _ := http.NewRequest("POST", url, bytes.NewReader(bodyBytes))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			inflight <- result{err: err}
+			return
+		}
+		resp.Body.Close()
+		inflight <- result{code: resp.StatusCode}
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- gw.Shutdown(ctx)
+	}()
+
+	// new connections are refused once shutdown has begun
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", l.Addr().String(), 100*time.Millisecond)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("Expected new connections to be rejected during shutdown")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	close(release)
+	if r := <-inflight; r.err != nil || r.code != http.StatusOK {
+		t.Errorf("Expected in-flight request to complete with 200, got %d (err=%v)", r.code, r.err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// log-scale buckets, 4 per doubling starting at 1µs, so each bucket is
+// ~19% wide. 128 buckets reach ~70 minutes; anything slower lands in
+// the last one
+const (
+	latencyBucketsPerDoubling = 4
+	latencyBuckets            = 128
+)
+
+// lock-free latency histogram. observe is a few atomic adds, so the hot
+// path never contends on a mutex; percentiles are read from a snapshot
+// that may be a request or two behind under load
+type latencyHistogram struct {
+	buckets [latencyBuckets]atomic.Uint64
+	count   atomic.Uint64
+	sumNs   atomic.Uint64
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.buckets[latency_bucket(d)].Add(1)
+	h.count.Add(1)
+	h.sumNs.Add(uint64(d))
+}
+
+// smallest bucket whose upper bound is >= d
+func latency_bucket(d time.Duration) int {
+	us := float64(d) / float64(time.Microsecond)
+	if us <= 1 {
+		return 0
+	}
+	i := int(math.Ceil(math.Log2(us) * latencyBucketsPerDoubling))
+	if i >= latencyBuckets {
+		return latencyBuckets - 1
+	}
+	return i
+}
+
+// upper bound of bucket i in milliseconds
+func latency_bucket_bound(i int) float64 {
+	return math.Exp2(float64(i)/latencyBucketsPerDoubling) / 1000
+}
+
+type latencySummary struct {
+	Count  uint64  `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+}
+
+// percentiles are bucket upper bounds, so they overstate by at most one
+// bucket width
+func (h *latencyHistogram) summary() latencySummary {
+	var counts [latencyBuckets]uint64
+	var total uint64
+	for i := range h.buckets {
+		counts[i] = h.buckets[i].Load()
+		total += counts[i]
+	}
+	s := latencySummary{Count: total}
+	if total == 0 {
+		return s
+	}
+	s.MeanMs = float64(h.sumNs.Load()) / float64(h.count.Load()) / float64(time.Millisecond)
+	s.P50Ms = percentile(counts[:], total, 0.50)
+	s.P95Ms = percentile(counts[:], total, 0.95)
+	s.P99Ms = percentile(counts[:], total, 0.99)
+	return s
+}
+
+func percentile(counts []uint64, total uint64, q float64) float64 {
+	rank := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			return latency_bucket_bound(i)
+		}
+	}
+	return latency_bucket_bound(len(counts) - 1)
+}
+
+// in-memory latency stats since start. policy evaluation and adapter
+// calls are tracked separately so a slow adapter doesn't hide policy cost
+type gatewayStats struct {
+	started time.Time
+	policy  latencyHistogram
+	adapter latencyHistogram
+}
+
+func newGatewayStats() *gatewayStats {
+	return &gatewayStats{started: time.Now()}
+}
+
+type statsResponse struct {
+	UptimeSeconds float64        `json:"uptime_seconds"`
+	PolicyEval    latencySummary `json:"policy_eval"`
+	Adapter       latencySummary `json:"adapter"`
+	InFlight      inFlightStats  `json:"in_flight"`
+}
+
+// adapter calls holding a concurrency slot right now
+type inFlightStats struct {
+	Tools  map[string]int `json:"tools"`
+	Agents map[string]int `json:"agents"`
+}
+
+func (g *Gateway) handle_stats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statsResponse{
+		UptimeSeconds: time.Since(g.stats.started).Seconds(),
+		PolicyEval:    g.stats.policy.summary(),
+		Adapter:       g.stats.adapter.summary(),
+		InFlight: inFlightStats{
+			Tools:  g.concurrency.snapshot("tool:"),
+			Agents: g.concurrency.snapshot("agent:"),
+		},
+	})
+}
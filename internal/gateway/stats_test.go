@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStatsEndpoint(t *testing.T) {
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte(`{"status":"created"}`))
+	}))
+	defer adapter.Close()
+
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	gw.SetAdapter("payments", adapter.URL)
+
+	// 8 allowed, 2 denied before reaching the adapter
+	for _, amount := range []float64{100, 200, 300, 400, 500, 600, 700, 800, 9000, 10000} {
+		bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": amount})
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		gw.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, httptest.NewRequest("GET", "/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var stats statsResponse
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+
+	if stats.PolicyEval.Count != 10 {
+		t.Errorf("Expected 10 policy evaluations, got %d", stats.PolicyEval.Count)
+	}
+	if stats.Adapter.Count != 8 {
+		t.Errorf("Expected 8 adapter calls, got %d", stats.Adapter.Count)
+	}
+	for name, s := range map[string]latencySummary{"policy_eval": stats.PolicyEval, "adapter": stats.Adapter} {
+		if s.P50Ms <= 0 || s.P50Ms > s.P95Ms || s.P95Ms > s.P99Ms {
+			t.Errorf("Expected 0 < p50 <= p95 <= p99 for %s, got %+v", name, s)
+		}
+	}
+	// every adapter call sleeps 5ms, so even the median can't be below it
+	if stats.Adapter.P50Ms < 5 || stats.Adapter.MeanMs < 5 {
+		t.Errorf("Expected adapter latency of at least 5ms, got %+v", stats.Adapter)
+	}
+	if stats.PolicyEval.P99Ms >= stats.Adapter.P50Ms {
+		t.Errorf("Expected policy evaluation to be tracked apart from adapter time, got %+v vs %+v", stats.PolicyEval, stats.Adapter)
+	}
+	if stats.UptimeSeconds <= 0 {
+		t.Errorf("Expected positive uptime, got %v", stats.UptimeSeconds)
+	}
+}
+
+func TestLatencyHistogramPercentiles(t *testing.T) {
+	var h latencyHistogram
+	if s := h.summary(); s.Count != 0 || s.P99Ms != 0 {
+		t.Errorf("Expected an empty summary, got %+v", s)
+	}
+	for i := 1; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	s := h.summary()
+	if s.Count != 100 {
+		t.Fatalf("Expected count 100, got %d", s.Count)
+	}
+	// bucket bounds overstate by at most ~19%
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"p50", s.P50Ms, 50},
+		{"p95", s.P95Ms, 95},
+		{"p99", s.P99Ms, 99},
+		{"mean", s.MeanMs, 50.5},
+	} {
+		if c.got < c.want || c.got > c.want*1.2 {
+			t.Errorf("Expected %s near %v, got %v", c.name, c.want, c.got)
+		}
+	}
+}
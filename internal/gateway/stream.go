@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// like call_adapter, but the final response is returned unread so its
+// body can be streamed. bodies of retried attempts are closed
+func (g *Gateway) call_adapter_stream(ctx context.Context, method, url string, body []byte, timeout time.Duration, retry RetryConfig, up upstreamOptions) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := g.forward_to_adapter_stream(ctx, method, url, body, timeout, up)
+		if attempt >= retry.MaxAttempts || (err == nil && !retryable_status(resp.StatusCode)) {
+			return resp, err
+		}
+		if !retry.wait(ctx, attempt) {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+// like forward_to_adapter, but timeout only bounds the wait for the
+// response headers. a streamed body takes as long as it takes, within the
+// request's own deadline, so large responses aren't cut off mid-copy
+func (g *Gateway) forward_to_adapter_stream(ctx context.Context, method, url string, body []byte, timeout time.Duration, up upstreamOptions) (*http.Response, error) {
+	if timeout <= 0 {
+		return g.send_to_adapter(ctx, method, url, body, up, &http.Client{})
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(timeout, cancel)
+	resp, err := g.send_to_adapter(ctx, method, url, body, up, &http.Client{})
+	if !timer.Stop() {
+		// fired, whatever came back is cancelled along with ctx
+		if err == nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("%s %q: no response headers from adapter within %v", method, url, timeout)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelOnClose{resp.Body, cancel}
+	return resp, nil
+}
+
+// releases the context of a streamed call once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// responses can only be streamed when nothing needs the whole body:
+// coalesced calls share a buffered result, filtering rewrites the JSON and
+// a size limit has to be checked before anything is sent
+func (c *Config) streams(tool, action string) bool {
+	tc := c.tool(tool)
+	return !tc.Actions[action].Coalesce && len(tc.ResponseAllow) == 0 && len(tc.ResponseDeny) == 0 && tc.MaxResponseBytes == 0
+}
+
+// copy an adapter response to the client without holding it in memory.
+// empty bodies are passed through like write_adapter_response does.
+// returns the number of body bytes copied
+func stream_adapter_response(w http.ResponseWriter, resp *http.Response) (int64, error) {
+	defer resp.Body.Close()
+
+	mark_adapter_origin(w)
+	body := bufio.NewReader(resp.Body)
+	if resp.StatusCode == http.StatusNoContent || resp.ContentLength == 0 {
+		w.WriteHeader(resp.StatusCode)
+		return 0, nil
+	}
+	if _, err := body.Peek(1); errors.Is(err, io.EOF) {
+		w.WriteHeader(resp.StatusCode)
+		return 0, nil
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if resp.ContentLength > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	// headers are out, a failure from here on can only cut the body short
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return n, fmt.Errorf("streaming adapter response: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamLargeAdapterResponse(t *testing.T) {
+	const size = 16 << 20
+
+	// deterministic content, generated in chunks so the adapter doesn't
+	// hold it all either
+	chunk := bytes.Repeat([]byte("0123456789abcdef"), 2048)
+	want := sha256.New()
+	for n := 0; n < size; n += len(chunk) {
+		want.Write(chunk)
+	}
+
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(size))
+		for n := 0; n < size; n += len(chunk) {
+			w.Write(chunk)
+		}
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+	server := httptest.NewServer(gw.router)
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/tools/files/read", bytes.NewReader([]byte(`{"path":"/big.bin"}`)))
+	req.Header.Set("X-Agent-ID", "reader-agent")
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	got := sha256.New()
+	n, err := io.Copy(got, resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	runtime.ReadMemStats(&after)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if n != size || !bytes.Equal(got.Sum(nil), want.Sum(nil)) {
+		t.Errorf("Response didn't round-trip: got %d bytes", n)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("Expected adapter Content-Type, got %q", ct)
+	}
+	if resp.ContentLength != size {
+		t.Errorf("Expected Content-Length %d, got %d", size, resp.ContentLength)
+	}
+
+	// buffering would allocate at least the whole body
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > size/4 {
+		t.Errorf("Expected bounded memory, allocated %d bytes for a %d byte response", alloc, size)
+	}
+}
+
+func TestStreamOutlastsAdapterTimeout(t *testing.T) {
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		for i := 0; i < 5; i++ {
+			w.Write([]byte("chunk\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, nil)
+	if err := gw.SetConfig(Config{Adapters: map[string]string{"files": adapter.URL}, AdapterTimeout: 100 * time.Millisecond}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	rr := sendRead(gw)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Body.String(); got != strings.Repeat("chunk\n", 5) {
+		t.Errorf("Expected the whole body past adapter_timeout, got %q", got)
+	}
+}
+
+func TestStreamAdapterTimeoutBoundsHeaders(t *testing.T) {
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("late"))
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, nil)
+	if err := gw.SetConfig(Config{Adapters: map[string]string{"files": adapter.URL}, AdapterTimeout: 100 * time.Millisecond}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	start := time.Now()
+	rr := sendRead(gw)
+	if rr.Code == http.StatusOK {
+		t.Fatalf("Expected adapter_timeout to fail the call, got 200: %s", rr.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("Expected the call to give up at adapter_timeout, took %v", elapsed)
+	}
+}
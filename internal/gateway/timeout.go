@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"aegis-gateway/pkg/apierror"
+)
+
+// bound every request by request_timeout. the deadline is on the request
+// context, so adapter calls and retry waits made with it are cancelled
+// when it passes
+func (g *Gateway) timeout_middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := g.cfg().RequestTimeout
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// answer a failed adapter call: 504 if the request's own deadline cut it
+// short, 502 otherwise
+func write_adapter_failure(w http.ResponseWriter, ctx context.Context, err error) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		write_error(w, apierror.GatewayTimeout, "Request exceeded the gateway's request_timeout")
+		return
+	}
+	write_error(w, apierror.AdapterError, err.Error())
+}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	if err := gw.SetConfig(Config{
+		Adapters:       map[string]string{"payments": slow.URL},
+		RequestTimeout: 200 * time.Millisecond,
+	}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{"amount":100}`)))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	start := time.Now()
+	gw.router.ServeHTTP(w, req)
+	elapsed := time.Since(start)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected status 504, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Error != "GatewayTimeout" || resp.Code != "AEGIS-504-TIMEOUT" {
+		t.Errorf("Expected GatewayTimeout, got %+v", resp)
+	}
+	// the 10s adapter timeout didn't apply, the request deadline did
+	if elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected the 504 at the 200ms deadline, took %v", elapsed)
+	}
+}
+
+// a slow adapter within the deadline, and an adapter_timeout shorter than
+// it, still behave as before
+func TestRequestTimeoutNotReached(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer slow.Close()
+
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	send := func() int {
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(`{"amount":100}`)))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	gw.SetConfig(Config{Adapters: map[string]string{"payments": slow.URL}, RequestTimeout: time.Second})
+	if code := send(); code != http.StatusOK {
+		t.Errorf("Expected 200 within the deadline, got %d", code)
+	}
+	gw.SetConfig(Config{Adapters: map[string]string{"payments": slow.URL}, RequestTimeout: time.Second, AdapterTimeout: 10 * time.Millisecond})
+	if code := send(); code != http.StatusBadGateway {
+		t.Errorf("Expected the adapter timeout to stay a 502, got %d", code)
+	}
+	if err := (Config{RequestTimeout: -time.Second}).validate(); err == nil {
+		t.Error("Expected a negative request_timeout to be rejected")
+	}
+}
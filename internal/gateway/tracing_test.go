@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"aegis-gateway/pkg/telemetry"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var traceparentPattern = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+func TestTraceparentPropagatedToAdapter(t *testing.T) {
+	var traceparent string
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":"ok"}`))
+	}))
+	defer adapter.Close()
+
+	gw := setupGatewayWithPolicy(t, filesReadPolicy, map[string]string{"files": adapter.URL})
+
+	// the request's own span, gateway spans are its children
+	ctx, span := telemetry.StartSpan(t.Context(), "test")
+	defer span.End()
+	parent := trace.SpanContextFromContext(ctx)
+
+	req := httptest.NewRequest("POST", "/tools/files/read", bytes.NewReader([]byte(`{"path":"/doc.txt"}`))).WithContext(ctx)
+	req.Header.Set("X-Agent-ID", "reader-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	m := traceparentPattern.FindStringSubmatch(traceparent)
+	if m == nil {
+		t.Fatalf("Expected a valid traceparent on the adapter request, got %q", traceparent)
+	}
+	if m[1] != parent.TraceID().String() {
+		t.Errorf("Expected trace ID %s, got %s", parent.TraceID(), m[1])
+	}
+	if m[2] == parent.SpanID().String() || m[2] == (trace.SpanID{}).String() {
+		t.Errorf("Expected the forwarding span's ID in traceparent, got %s", m[2])
+	}
+}
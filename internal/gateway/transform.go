@@ -0,0 +1,238 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// renames request fields before forwarding: agent field -> adapter field
+type FieldMap map[string]string
+
+// pick the field map for an action, falling back to the tool-level one
+func (t ToolConfig) request_map(action string) FieldMap {
+	if a, ok := t.Actions[action]; ok && a.RequestMap != nil {
+		return a.RequestMap
+	}
+	return t.RequestMap
+}
+
+// a request sets a field the mapping writes, see FieldMap.apply
+var errMappedField = errors.New("field is set by request_map")
+
+// apply the mapping to a copy of params; unmapped fields pass through
+// unchanged. a field that's also a mapping target is refused: with
+// amount->value, {"amount":1,"value":1000000} would pass policy on amount
+// while the adapter could get either value
+func (fm FieldMap) apply(params map[string]interface{}) (map[string]interface{}, error) {
+	targets := make(map[string]bool, len(fm))
+	for _, target := range fm {
+		targets[target] = true
+	}
+	out := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		target, mapped := fm[k]
+		if !mapped {
+			if targets[k] {
+				return nil, fmt.Errorf("%w and can't be sent directly: %s", errMappedField, k)
+			}
+			target = k
+		}
+		if _, dup := out[target]; dup {
+			return nil, fmt.Errorf("%w more than once: %s", errMappedField, target)
+		}
+		out[target] = v
+	}
+	return out, nil
+}
+
+// {{param}} placeholders in a request template, dotted paths allowed
+var templateParamPattern = regexp.MustCompile(`\{\{\s*([^{}\s]*)\s*\}\}`)
+
+// build the adapter body from a request template. a string that is only a
+// placeholder takes the param's value as-is (numbers stay numbers, objects
+// stay objects) and is dropped when the param is missing; placeholders
+// inside longer strings are interpolated. everything else is a constant
+//
+//	request_template:
+//	  money: {value: "{{amount}}", iso: "{{currency}}"}
+//	  reference: "aegis-{{vendor_id}}"
+//	  source: agent
+func render_template(tmpl interface{}, params map[string]interface{}) (interface{}, bool) {
+	switch t := tmpl.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			if rendered, ok := render_template(v, params); ok {
+				out[k] = rendered
+			}
+		}
+		return out, true
+	case []interface{}:
+		out := make([]interface{}, 0, len(t))
+		for _, v := range t {
+			if rendered, ok := render_template(v, params); ok {
+				out = append(out, rendered)
+			}
+		}
+		return out, true
+	case string:
+		if m := templateParamPattern.FindStringSubmatch(t); m != nil && m[0] == t {
+			v, ok := param_path(params, m[1])
+			return v, ok
+		}
+		return templateParamPattern.ReplaceAllStringFunc(t, func(p string) string {
+			v, ok := param_path(params, templateParamPattern.FindStringSubmatch(p)[1])
+			if !ok {
+				return ""
+			}
+			return fmt.Sprint(v)
+		}), true
+	}
+	return tmpl, true
+}
+
+// check placeholders in a request template at config load
+func validate_template(tmpl interface{}) error {
+	switch t := tmpl.(type) {
+	case map[string]interface{}:
+		for _, v := range t {
+			if err := validate_template(v); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, v := range t {
+			if err := validate_template(v); err != nil {
+				return err
+			}
+		}
+	case string:
+		for _, m := range templateParamPattern.FindAllStringSubmatch(t, -1) {
+			if m[1] == "" || strings.HasPrefix(m[1], ".") || strings.HasSuffix(m[1], ".") {
+				return fmt.Errorf("invalid placeholder %q", m[0])
+			}
+		}
+	}
+	return nil
+}
+
+// value at a dotted path in nested params
+func param_path(params map[string]interface{}, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	cur := params
+	for i, part := range parts {
+		v, ok := cur[part]
+		if !ok || v == nil {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return v, true
+		}
+		if cur, ok = v.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// build the body sent to the adapter: the action's request_template if it
+// has one, else the field map. the original body is reused when neither is
+// configured so adapters see exactly what the agent sent
+func (g *Gateway) adapter_body(tool, action string, params map[string]interface{}, original []byte) ([]byte, error) {
+	tc := g.cfg().tool(tool)
+	if tmpl := tc.Actions[action].RequestTemplate; tmpl != nil {
+		body, _ := render_template(tmpl, params)
+		return json.Marshal(body)
+	}
+	fm := tc.request_map(action)
+	if len(fm) == 0 {
+		return original, nil
+	}
+	mapped, err := fm.apply(params)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mapped)
+}
+
+// strip fields from a JSON adapter response according to the tool's
+// allow/deny lists; non-JSON bodies are returned untouched. numbers are
+// kept as the adapter wrote them, so large IDs and amounts don't lose
+// precision going through float64
+func (g *Gateway) filter_response(tool string, body []byte) []byte {
+	tc := g.cfg().tool(tool)
+	if len(tc.ResponseAllow) == 0 && len(tc.ResponseDeny) == 0 {
+		return body
+	}
+
+	var decoded interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return body
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return body
+	}
+
+	switch v := decoded.(type) {
+	case map[string]interface{}:
+		filter_object(v, tc.ResponseAllow, tc.ResponseDeny)
+	case []interface{}:
+		// list responses: filter each object element
+		for _, item := range v {
+			if obj, ok := item.(map[string]interface{}); ok {
+				filter_object(obj, tc.ResponseAllow, tc.ResponseDeny)
+			}
+		}
+	default:
+		return body
+	}
+
+	filtered, err := json.Marshal(decoded)
+	if err != nil {
+		return body
+	}
+	return filtered
+}
+
+func filter_object(obj map[string]interface{}, allow, deny []string) {
+	if len(allow) > 0 {
+		keep := make(map[string]bool, len(allow))
+		for _, k := range allow {
+			keep[k] = true
+		}
+		for k := range obj {
+			if !keep[k] {
+				delete(obj, k)
+			}
+		}
+	}
+
+	for _, p := range deny {
+		delete_path(obj, strings.Split(p, "."))
+	}
+}
+
+// remove a dotted path from nested objects, missing segments are ignored.
+// an array on the way gets the rest of the path removed from each element,
+// so items.card.number covers every item
+func delete_path(v interface{}, path []string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(t, path[0])
+			return
+		}
+		delete_path(t[path[0]], path[1:])
+	case []interface{}:
+		for _, item := range t {
+			delete_path(item, path)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// mock adapter that records the last body it received
+func recordingAdapter(t *testing.T, got *map[string]interface{}) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, got)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRequestTransform_RenamesField(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	var received map[string]interface{}
+	gw.SetAdapter("payments", recordingAdapter(t, &received).URL)
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {
+			Actions: map[string]ActionConfig{
+				"create": {RequestMap: FieldMap{"amount": "value"}},
+			},
+		},
+	}})
+
+	bodyBytes, _ := json.Marshal(map[string]interface{}{
+		"amount":   1000.0,
+		"currency": "USD",
+	})
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if received["value"] != 1000.0 {
+		t.Errorf("Expected adapter to receive value=1000, got %v", received["value"])
+	}
+	if _, ok := received["amount"]; ok {
+		t.Errorf("Expected amount to be renamed, adapter still got it: %v", received)
+	}
+	if received["currency"] != "USD" {
+		t.Errorf("Expected unmapped currency to pass through, got %v", received["currency"])
+	}
+}
+
+func TestRequestTransform_TargetCollision(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	var received map[string]interface{}
+	gw.SetAdapter("payments", recordingAdapter(t, &received).URL)
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {RequestMap: FieldMap{"amount": "value"}},
+	}})
+
+	// policy checks amount=1, the adapter must never see value=1000000.
+	// a collision is refused even when both agree
+	for _, body := range []map[string]interface{}{
+		{"amount": 1.0, "value": 1000000.0},
+		{"amount": 1.0, "value": 1.0},
+		{"value": 1000000.0},
+	} {
+		received = nil
+		bodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+		req.Header.Set("X-Agent-ID", "test-agent")
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, req)
+
+		if w.Code == http.StatusOK {
+			t.Errorf("Expected %v to be refused, got 200", body)
+		}
+		if received != nil {
+			t.Errorf("Expected nothing forwarded for %v, adapter got %v", body, received)
+		}
+	}
+}
+
+func TestRequestTransform_PolicySeesOriginalFields(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	var received map[string]interface{}
+	gw.SetAdapter("payments", recordingAdapter(t, &received).URL)
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {RequestMap: FieldMap{"amount": "value"}},
+	}})
+
+	// max_amount is still enforced against the agent's amount field
+	bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": 10000.0})
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+	if received != nil {
+		t.Errorf("Expected no adapter call on denial, got %v", received)
+	}
+}
+
+func TestRequestTemplate(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	var received map[string]interface{}
+	gw.SetAdapter("payments", recordingAdapter(t, &received).URL)
+	if err := gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {
+			RequestMap: FieldMap{"amount": "ignored"},
+			Actions: map[string]ActionConfig{
+				"create": {RequestTemplate: map[string]interface{}{
+					"money":     map[string]interface{}{"value": "{{amount}}", "iso": "{{ currency }}"},
+					"reference": "aegis-{{vendor.id}}",
+					"memo":      "{{memo}}",
+					"source":    "agent",
+				}},
+			},
+		},
+	}}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	bodyBytes, _ := json.Marshal(map[string]interface{}{
+		"amount":   1000.0,
+		"currency": "USD",
+		"vendor":   map[string]interface{}{"id": "V42"},
+	})
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	want := map[string]interface{}{
+		"money":     map[string]interface{}{"value": 1000.0, "iso": "USD"},
+		"reference": "aegis-V42",
+		"source":    "agent",
+	}
+	gotJSON, _ := json.Marshal(received)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("Adapter received %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+func TestRequestTransform_NoOpPassthrough(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	var raw []byte
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ = io.ReadAll(r.Body)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer adapter.Close()
+	gw.SetAdapter("payments", adapter.URL)
+
+	// key order and spacing an encoder wouldn't produce survive untouched
+	body := `{"currency": "USD",  "amount": 100}`
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader([]byte(body)))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if string(raw) != body {
+		t.Errorf("Expected the agent's body forwarded byte for byte, got %s", raw)
+	}
+}
+
+func TestRequestTemplateValidation(t *testing.T) {
+	for _, tmpl := range []map[string]interface{}{
+		{"value": "{{}}"},
+		{"nested": map[string]interface{}{"value": "{{.amount}}"}},
+		{"list": []interface{}{"{{amount.}}"}},
+	} {
+		cfg := Config{Tools: map[string]ToolConfig{"payments": {
+			Actions: map[string]ActionConfig{"create": {RequestTemplate: tmpl}},
+		}}}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected validation error for %v", tmpl)
+		}
+	}
+}
+
+func TestFieldMapActionOverridesTool(t *testing.T) {
+	tc := ToolConfig{
+		RequestMap: FieldMap{"amount": "value"},
+		Actions: map[string]ActionConfig{
+			"refund": {RequestMap: FieldMap{"amount": "refund_value"}},
+		},
+	}
+
+	if got := tc.request_map("create")["amount"]; got != "value" {
+		t.Errorf("Expected tool-level mapping for create, got %q", got)
+	}
+	if got := tc.request_map("refund")["amount"]; got != "refund_value" {
+		t.Errorf("Expected action mapping for refund, got %q", got)
+	}
+}
+
+func TestResponseFilter_DenylistStripsFields(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	adapter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"payment_id":"p-1","status":"created","internal_id":"db-42","card":{"number":"4111111111111111","brand":"visa"}}`))
+	}))
+	defer adapter.Close()
+
+	gw.SetAdapter("payments", adapter.URL)
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {ResponseDeny: []string{"internal_id", "card.number"}},
+	}})
+
+	bodyBytes, _ := json.Marshal(map[string]interface{}{"amount": 100.0})
+	req := httptest.NewRequest("POST", "/tools/payments/create", bytes.NewReader(bodyBytes))
+	req.Header.Set("X-Agent-ID", "test-agent")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if _, ok := resp["internal_id"]; ok {
+		t.Errorf("Expected internal_id to be stripped, got %v", resp)
+	}
+	card, _ := resp["card"].(map[string]interface{})
+	if _, ok := card["number"]; ok {
+		t.Errorf("Expected card.number to be stripped, got %v", card)
+	}
+	if card["brand"] != "visa" {
+		t.Errorf("Expected card.brand to pass through, got %v", card["brand"])
+	}
+	if resp["payment_id"] != "p-1" || resp["status"] != "created" {
+		t.Errorf("Expected remaining fields intact, got %v", resp)
+	}
+}
+
+func TestResponseFilter_Allowlist(t *testing.T) {
+	gw := &Gateway{}
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {ResponseAllow: []string{"payment_id", "status"}},
+	}})
+
+	out := gw.filter_response("payments", []byte(`{"payment_id":"p-1","status":"created","internal_id":"db-42"}`))
+
+	var resp map[string]interface{}
+	json.Unmarshal(out, &resp)
+	if len(resp) != 2 || resp["payment_id"] != "p-1" || resp["status"] != "created" {
+		t.Errorf("Expected only allowlisted fields, got %v", resp)
+	}
+}
+
+func TestResponseFilter_NonJSONUntouched(t *testing.T) {
+	gw := &Gateway{}
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"files": {ResponseDeny: []string{"content"}},
+	}})
+
+	body := []byte("plain text response")
+	if out := gw.filter_response("files", body); string(out) != string(body) {
+		t.Errorf("Expected non-JSON body untouched, got %q", out)
+	}
+}
+
+func TestResponseFilter_KeepsNumbers(t *testing.T) {
+	gw := &Gateway{}
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {ResponseDeny: []string{"internal_id"}},
+	}})
+
+	out := gw.filter_response("payments", []byte(`{"payment_id":9007199254740993,"amount":0.1000000000000000055511151231257827,"internal_id":"db-42"}`))
+	if want := `{"amount":0.1000000000000000055511151231257827,"payment_id":9007199254740993}`; string(out) != want {
+		t.Errorf("Expected numbers kept as sent, got %s", out)
+	}
+}
+
+func TestResponseFilter_DenyInsideArrays(t *testing.T) {
+	gw := &Gateway{}
+	gw.SetConfig(Config{Tools: map[string]ToolConfig{
+		"payments": {ResponseDeny: []string{"items.card.number", "batches.secret"}},
+	}})
+
+	out := gw.filter_response("payments", []byte(`{"items":[{"id":1,"card":{"number":"4111","last4":"1111"}},{"id":2,"card":{"number":"5500"}}],"batches":[[{"secret":"s","id":3}]]}`))
+	want := `{"batches":[[{"id":3}]],"items":[{"card":{"last4":"1111"},"id":1},{"card":{},"id":2}]}`
+	if string(out) != want {
+		t.Errorf("Expected the path removed from every element\ngot  %s\nwant %s", out, want)
+	}
+}
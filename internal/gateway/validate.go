@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+
+	"aegis-gateway/pkg/apierror"
+)
+
+// largest candidate file POST /policies/validate accepts
+const maxValidateBytes = 1 << 20
+
+// check a candidate policy file without loading it, e.g. from CI before a
+// merge. the body is the raw file; ?file= names it (and so picks YAML or
+// JSON by extension) and decides which active file it would replace.
+// without it the Content-Type decides. the answer is always 200 with
+// "valid" and the problems found
+func (g *Gateway) handle_validate(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("file")
+	if name == "" {
+		name = "candidate.yaml"
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+			name = "candidate.json"
+		}
+	}
+	if ext := path.Ext(name); ext != ".yaml" && ext != ".json" {
+		write_error(w, apierror.InvalidRequest, "file must end in .yaml or .json")
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValidateBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		write_error(w, apierror.RequestTooLarge, fmt.Sprintf("Policy file exceeds %d bytes", tooLarge.Limit))
+		return
+	}
+	if err != nil || len(data) == 0 {
+		write_error(w, apierror.InvalidRequest, "Body must be the policy file contents")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.policyManager.ValidatePolicy(name, data))
+}
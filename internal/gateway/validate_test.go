@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"aegis-gateway/internal/policy"
+)
+
+func post_validate(gw *Gateway, target, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", target, bytes.NewBufferString(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestValidatePolicyEndpoint(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	valid := "version: 3\nagents:\n  - id: ci-agent\n    allow:\n      - tool: files\n        actions: [read]\n"
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		valid       bool
+		stage       string
+	}{
+		{name: "valid yaml", target: "/policies/validate", body: valid, valid: true},
+		{name: "valid json", target: "/policies/validate", contentType: "application/json", body: `{"version": 1, "agents": [{"id": "ci-agent"}]}`, valid: true},
+		{name: "json by file name", target: "/policies/validate?file=team/ci.json", body: `{"version": 1, "agents": [`, stage: policy.StageParse},
+		{name: "bad regex", target: "/policies/validate", body: "version: 1\nagents:\n  - id: a\n    allow:\n      - tool: files\n        actions: [read]\n        conditions:\n          path_regex: \"(\"\n", stage: policy.StageValidate},
+		{name: "duplicate agent", target: "/policies/validate", body: "version: 1\nagents:\n  - id: a\n  - id: a\n", stage: policy.StageSet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post_validate(gw, tt.target, tt.contentType, tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var res policy.ValidationResult
+			json.NewDecoder(w.Body).Decode(&res)
+			if res.Valid != tt.valid {
+				t.Fatalf("Expected valid=%v, got %+v", tt.valid, res)
+			}
+			if !tt.valid && (len(res.Errors) != 1 || res.Errors[0].Stage != tt.stage) {
+				t.Errorf("Expected one %s error, got %+v", tt.stage, res.Errors)
+			}
+		})
+	}
+
+	if w := post_validate(gw, "/policies/validate?file=ci.txt", "", valid); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a non-policy file name, got %d", w.Code)
+	}
+	if w := post_validate(gw, "/policies/validate", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty body, got %d", w.Code)
+	}
+	if w := post_validate(gw, "/policies/validate", "", strings.Repeat("#", maxValidateBytes+1)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for an oversized file, got %d", w.Code)
+	}
+
+	// validating never loads anything
+	if files := gw.policyManager.PolicyFiles(); len(files) != 1 {
+		t.Errorf("Expected the live policies to be unchanged, got %v", files)
+	}
+}
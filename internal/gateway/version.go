@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// build info, set at link time:
+//
+//	go build -ldflags "-X aegis-gateway/internal/gateway.Version=1.4.0 \
+//	  -X aegis-gateway/internal/gateway.Commit=$(git rev-parse HEAD) \
+//	  -X aegis-gateway/internal/gateway.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+type versionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+
+	// active policy set
+	PolicyFiles      int    `json:"policy_files"`
+	PoliciesLoadedAt string `json:"policies_loaded_at"`
+}
+
+func (g *Gateway) handle_version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versionResponse{
+		Version:          Version,
+		Commit:           Commit,
+		BuildTime:        BuildTime,
+		GoVersion:        runtime.Version(),
+		PolicyFiles:      len(g.policyManager.PolicyFiles()),
+		PoliciesLoadedAt: g.policyManager.LastLoaded().UTC().Format(time.RFC3339),
+	})
+}
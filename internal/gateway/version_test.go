@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+type versionClock struct{ t time.Time }
+
+func (c versionClock) Now() time.Time { return c.t }
+
+func TestVersionEndpoint(t *testing.T) {
+	// as if linked with -ldflags "-X aegis-gateway/internal/gateway.Version=..."
+	defer func(v, c, b string) { Version, Commit, BuildTime = v, c, b }(Version, Commit, BuildTime)
+	Version, Commit, BuildTime = "1.4.0", "0123abcd", "2026-10-01T12:00:00Z"
+
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	get := func() versionResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		gw.router.ServeHTTP(w, httptest.NewRequest("GET", "/version", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var resp versionResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode version: %v", err)
+		}
+		return resp
+	}
+
+	resp := get()
+	want := versionResponse{
+		Version:          "1.4.0",
+		Commit:           "0123abcd",
+		BuildTime:        "2026-10-01T12:00:00Z",
+		GoVersion:        runtime.Version(),
+		PolicyFiles:      1,
+		PoliciesLoadedAt: resp.PoliciesLoadedAt,
+	}
+	if resp != want {
+		t.Errorf("Expected %+v, got %+v", want, resp)
+	}
+	if _, err := time.Parse(time.RFC3339, resp.PoliciesLoadedAt); err != nil {
+		t.Errorf("Expected an RFC 3339 load time, got %q", resp.PoliciesLoadedAt)
+	}
+
+	// a successful reload moves the timestamp
+	reloaded := time.Date(2026, 10, 2, 8, 30, 0, 0, time.UTC)
+	gw.policyManager.SetClock(versionClock{reloaded})
+	if err := gw.policyManager.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if got := get().PoliciesLoadedAt; got != "2026-10-02T08:30:00Z" {
+		t.Errorf("Expected the reload time, got %s", got)
+	}
+}
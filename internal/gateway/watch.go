@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"fmt"
+	"time"
+
+	"aegis-gateway/internal/policy"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// quiet period after the last policy file event before reloading. editors
+// emit several events per save (truncate, write, chmod, rename)
+const policyReloadDebounce = 200 * time.Millisecond
+
+// reload once events have been quiet for the debounce window. rewatch is
+// set when the burst included a create, rename or remove: a new
+// subdirectory needs its own watch, and if a directory was replaced its
+// watch is gone
+func watch_policy_events(events <-chan fsnotify.Event, errs <-chan error, quiet time.Duration, reload func(rewatch bool)) {
+	var fire <-chan time.Time
+	rewatch := false
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+				rewatch = true
+			}
+			fmt.Printf("Policy file changed: %s\n", event.Name)
+			fire = time.After(quiet)
+		case <-fire:
+			fire = nil
+			reload(rewatch)
+			rewatch = false
+		case err, ok := <-errs:
+			if !ok {
+				return
+			}
+			fmt.Printf("ERROR: watcher error: %v\n", err)
+		}
+	}
+}
+
+func (g *Gateway) reload_watched_policies(rewatch bool) {
+	if rewatch {
+		if err := watch_policy_dirs(g.watcher, g.policyDirs); err != nil {
+			fmt.Printf("ERROR: failed to re-watch policy directory: %v\n", err)
+		}
+	}
+	fmt.Println("Reloading policies...")
+	g.reload_shadow_policies(rewatch)
+	if err := g.policyManager.Reload(); err != nil {
+		fmt.Printf("ERROR: failed to reload policies: %v\n", err)
+		return
+	}
+	fmt.Println("Policies reloaded successfully")
+}
+
+// watch each policy directory and every subdirectory policies load from.
+// fsnotify isn't recursive, and adding a watched path again is a no-op
+func watch_policy_dirs(w *fsnotify.Watcher, policyDirs []string) error {
+	for _, dir := range policyDirs {
+		dirs, err := policy.PolicyDirs(dir)
+		if err != nil {
+			return err
+		}
+		for _, d := range dirs {
+			if err := w.Add(d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
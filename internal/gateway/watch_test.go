@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestPolicyWatchDebounce(t *testing.T) {
+	events := make(chan fsnotify.Event)
+	errs := make(chan error)
+	var mu sync.Mutex
+	var reloads []bool
+	done := make(chan struct{})
+	go func() {
+		watch_policy_events(events, errs, 50*time.Millisecond, func(rewatch bool) {
+			mu.Lock()
+			reloads = append(reloads, rewatch)
+			mu.Unlock()
+		})
+		close(done)
+	}()
+	count := func() []bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]bool(nil), reloads...)
+	}
+
+	// one editor save: several writes and a chmod, closer together than the window
+	for i := 0; i < 10; i++ {
+		events <- fsnotify.Event{Name: "policy.yaml", Op: fsnotify.Write}
+		time.Sleep(5 * time.Millisecond)
+	}
+	events <- fsnotify.Event{Name: "policy.yaml", Op: fsnotify.Chmod}
+	time.Sleep(200 * time.Millisecond)
+	if got := count(); len(got) != 1 || got[0] {
+		t.Fatalf("Expected exactly one reload without rewatch, got %v", got)
+	}
+
+	// atomic save: write a temp file and rename it over the policy
+	events <- fsnotify.Event{Name: "policy.yaml.tmp", Op: fsnotify.Create}
+	events <- fsnotify.Event{Name: "policy.yaml.tmp", Op: fsnotify.Rename}
+	events <- fsnotify.Event{Name: "policy.yaml", Op: fsnotify.Create}
+	time.Sleep(200 * time.Millisecond)
+	if got := count(); len(got) != 2 || !got[1] {
+		t.Fatalf("Expected a second reload that re-adds the watch, got %v", got)
+	}
+
+	// chmod alone doesn't reload
+	events <- fsnotify.Event{Name: "policy.yaml", Op: fsnotify.Chmod}
+	time.Sleep(200 * time.Millisecond)
+	if got := count(); len(got) != 2 {
+		t.Errorf("Expected chmod to be ignored, got %v", got)
+	}
+
+	close(events)
+	<-done
+}
+
+// end to end: an atomic save is picked up through the real watcher
+func TestPolicyWatchAtomicSave(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	updated := `version: 2
+agents:
+  - id: test-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+`
+	tmp := filepath.Join(t.TempDir(), "staged.yaml")
+	if err := os.WriteFile(tmp, []byte(updated), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(gw.policyDirs[0], "test-policy.yaml")); err != nil {
+		t.Fatalf("Failed to rename policy: %v", err)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		d := gw.policyManager.Evaluate("test-agent", "payments", "create", map[string]interface{}{"amount": 100.0})
+		if d.Version == 2 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Error("Expected the renamed policy to be reloaded")
+}
+
+// a subfolder created after startup is watched and its policies loaded
+func TestPolicyWatchNewSubdirectory(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+
+	evaluate := func(agent string) bool {
+		return gw.policyManager.Evaluate(agent, "files", "read", nil).Allow
+	}
+	waitFor := func(agent string) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for time.Now().Before(deadline) {
+			if evaluate(agent) {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("Expected %s to be loaded from the new subdirectory", agent)
+	}
+
+	sub := filepath.Join(gw.policyDirs[0], "team-a")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	content := "version: 1\nagents:\n  - id: %s\n    allow:\n      - tool: files\n        actions: [read]\n"
+	if err := os.WriteFile(filepath.Join(sub, "a.yaml"), []byte(fmt.Sprintf(content, "team-a-agent")), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	waitFor("team-a-agent")
+
+	// once the reload has added its watch, later writes in the subfolder are seen
+	time.Sleep(2 * policyReloadDebounce)
+	if err := os.WriteFile(filepath.Join(sub, "b.yaml"), []byte(fmt.Sprintf(content, "team-a-second")), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	waitFor("team-a-second")
+}
+
+// every directory of a multi-directory gateway is watched
+func TestPolicyWatchMultipleDirs(t *testing.T) {
+	org, team := t.TempDir(), t.TempDir()
+	content := "version: 1\nagents:\n  - id: %s\n    allow:\n      - tool: files\n        actions: [read]\n"
+	if err := os.WriteFile(filepath.Join(org, "org.yaml"), []byte(fmt.Sprintf(content, "org-agent")), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+
+	gw, err := NewGatewayDirs([]string{org, team}, map[string]string{})
+	if err != nil {
+		t.Fatalf("Failed to create gateway: %v", err)
+	}
+	defer gw.Close()
+
+	if err := os.WriteFile(filepath.Join(team, "team.yaml"), []byte(fmt.Sprintf(content, "team-agent")), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for !gw.policyManager.Evaluate("team-agent", "files", "read", nil).Allow {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the team policy picked up from the second directory")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if d := gw.policyManager.Evaluate("org-agent", "files", "read", nil); !d.Allow {
+		t.Errorf("Expected the org policy to stay loaded: %s", d.Reason)
+	}
+}
@@ -0,0 +1,247 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"aegis-gateway/internal/policy"
+)
+
+// denial events waiting for delivery. when full, new events are dropped
+// rather than slowing down requests
+const webhookQueueSize = 1000
+
+// deliveries in parallel, so one slow target doesn't hold up the rest
+const webhookWorkers = 4
+
+const (
+	defaultWebhookTimeout     = 5 * time.Second
+	defaultWebhookMaxAttempts = 3
+	defaultWebhookBackoff     = 500 * time.Millisecond
+)
+
+// a target the gateway POSTs a DenialEvent to for matching denials. empty
+// criteria match anything, a denial must match all that are set:
+//
+//	denial_webhooks:
+//	  - url: https://alerts.example.com/aegis
+//	    agents: [finance-agent]
+//	    reason_codes: [AMOUNT_EXCEEDED, NOT_OWNER]
+type WebhookConfig struct {
+	URL         string              `yaml:"url" json:"url"`
+	Agents      []string            `yaml:"agents" json:"agents,omitempty"`
+	Tools       []string            `yaml:"tools" json:"tools,omitempty"`
+	ReasonCodes []policy.ReasonCode `yaml:"reason_codes" json:"reason_codes,omitempty"`
+
+	// added to every delivery, e.g. a shared secret
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"`
+
+	// per attempt. 0 uses the default
+	Timeout time.Duration `yaml:"timeout" json:"timeout,omitempty"`
+
+	// attempts per event, retried on transport errors and 5xx. 0 uses
+	// the default
+	MaxAttempts int `yaml:"max_attempts" json:"max_attempts,omitempty"`
+}
+
+// what a webhook receives. params are left out, only their hash is sent
+type DenialEvent struct {
+	Event         string            `json:"event"`
+	Timestamp     string            `json:"timestamp"`
+	RequestID     string            `json:"request_id,omitempty"`
+	AgentID       string            `json:"agent_id"`
+	ParentAgent   string            `json:"parent_agent,omitempty"`
+	Tool          string            `json:"tool"`
+	Action        string            `json:"action"`
+	Reason        string            `json:"reason"`
+	ReasonCode    policy.ReasonCode `json:"reason_code"`
+	PolicyVersion int               `json:"policy_version"`
+	ParamsHash    string            `json:"params_hash"`
+}
+
+const denialEventType = "policy.denied"
+
+func (wc WebhookConfig) validate() error {
+	u, err := url.Parse(wc.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q", wc.URL)
+	}
+	if wc.Timeout < 0 || wc.MaxAttempts < 0 {
+		return fmt.Errorf("timeout and max_attempts cannot be negative")
+	}
+	for _, c := range wc.ReasonCodes {
+		if !policy.ValidReasonCode(c) {
+			return fmt.Errorf("unknown reason code %q", c)
+		}
+	}
+	return nil
+}
+
+func (wc WebhookConfig) matches(e DenialEvent) bool {
+	if len(wc.Agents) > 0 && !contains_string(wc.Agents, e.AgentID) {
+		return false
+	}
+	if len(wc.Tools) > 0 && !contains_string(wc.Tools, e.Tool) {
+		return false
+	}
+	if len(wc.ReasonCodes) > 0 {
+		for _, c := range wc.ReasonCodes {
+			if c == e.ReasonCode {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func contains_string(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type webhookJob struct {
+	target WebhookConfig
+	body   []byte
+}
+
+// delivers denial events from a queue, off the request path
+type webhookNotifier struct {
+	queue   chan webhookJob
+	client  *http.Client
+	backoff time.Duration // before the first retry, doubled after each
+
+	mu      sync.Mutex
+	dropped int
+
+	// cancelled on close, also aborting deliveries in flight
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+func newWebhookNotifier() *webhookNotifier {
+	n := &webhookNotifier{
+		queue:   make(chan webhookJob, webhookQueueSize),
+		client:  &http.Client{},
+		backoff: defaultWebhookBackoff,
+	}
+	n.ctx, n.stop = context.WithCancel(context.Background())
+	n.wg.Add(webhookWorkers)
+	for i := 0; i < webhookWorkers; i++ {
+		go n.loop()
+	}
+	return n
+}
+
+// queue e for every target it matches. never blocks
+func (n *webhookNotifier) notify(targets []WebhookConfig, e DenialEvent) {
+	var body []byte
+	for _, t := range targets {
+		if !t.matches(e) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(e); err != nil {
+				return
+			}
+		}
+		select {
+		case n.queue <- webhookJob{target: t, body: body}:
+		default:
+			n.mu.Lock()
+			n.dropped++
+			if n.dropped == 1 || n.dropped%100 == 0 {
+				fmt.Printf("ERROR: denial webhook queue full, %d event(s) dropped\n", n.dropped)
+			}
+			n.mu.Unlock()
+		}
+	}
+}
+
+// stop delivering; events still queued or in flight are dropped
+func (n *webhookNotifier) close() {
+	n.stop()
+	n.wg.Wait()
+}
+
+func (n *webhookNotifier) loop() {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case job := <-n.queue:
+			if err := n.deliver(job); err != nil && n.ctx.Err() == nil {
+				fmt.Printf("ERROR: denial webhook %s: %v\n", job.target.URL, err)
+			}
+		}
+	}
+}
+
+// POST the event, retrying transport errors and 5xx with backoff
+func (n *webhookNotifier) deliver(job webhookJob) error {
+	attempts := job.target.MaxAttempts
+	if attempts == 0 {
+		attempts = defaultWebhookMaxAttempts
+	}
+	wait := n.backoff
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-n.ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+		var retry bool
+		if retry, err = n.post(job); err == nil || !retry {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
+
+// one delivery attempt, and whether a failure is worth retrying
+func (n *webhookNotifier) post(job webhookJob) (bool, error) {
+	timeout := job.target.Timeout
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(n.ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.target.URL, bytes.NewReader(job.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range job.target.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return false, nil
+}
@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aegis-gateway/internal/policy"
+)
+
+// webhook receiver handing every event it gets to the test
+func webhook_receiver(t *testing.T, status func(n int) int) (*httptest.Server, <-chan DenialEvent) {
+	t.Helper()
+	events := make(chan DenialEvent, 10)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e DenialEvent
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Errorf("Failed to decode webhook body: %v", err)
+		}
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Webhook-Secret") != "s3cret" {
+			t.Errorf("Unexpected webhook headers: %v", r.Header)
+		}
+		code := http.StatusOK
+		if status != nil {
+			code = status(int(calls.Add(1)))
+		}
+		w.WriteHeader(code)
+		events <- e
+	}))
+	t.Cleanup(srv.Close)
+	return srv, events
+}
+
+func post_tool(gw *Gateway, agent, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/tools/payments/create", strings.NewReader(body))
+	req.Header.Set("X-Agent-ID", agent)
+	req.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	gw.router.ServeHTTP(w, req)
+	return w
+}
+
+func next_event(t *testing.T, events <-chan DenialEvent) DenialEvent {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a webhook event")
+	}
+	return DenialEvent{}
+}
+
+func no_event(t *testing.T, events <-chan DenialEvent) {
+	t.Helper()
+	select {
+	case e := <-events:
+		t.Errorf("Expected no further webhook event, got %+v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDenialWebhook(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	srv, events := webhook_receiver(t, nil)
+	if err := gw.SetConfig(Config{DenialWebhooks: []WebhookConfig{{
+		URL:         srv.URL,
+		Agents:      []string{"test-agent"},
+		ReasonCodes: []policy.ReasonCode{policy.ReasonAmountExceeded},
+		Headers:     map[string]string{"X-Webhook-Secret": "s3cret"},
+	}}}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	// allowed, another agent, another reason code: none match
+	if w := post_tool(gw, "test-agent", `{"amount":100}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if w := post_tool(gw, "other-agent", `{"amount":10000}`); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403, got %d", w.Code)
+	}
+	if w := post_tool(gw, "test-agent", `{"amount":"lots"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403, got %d", w.Code)
+	}
+
+	if w := post_tool(gw, "test-agent", `{"amount":10000}`); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403, got %d", w.Code)
+	}
+	e := next_event(t, events)
+	if e.Event != "policy.denied" || e.AgentID != "test-agent" || e.Tool != "payments" || e.Action != "create" ||
+		e.ReasonCode != policy.ReasonAmountExceeded || e.Reason != "Amount 10000.00 exceeds max_amount=5000.00" ||
+		e.RequestID != "req-1" || e.PolicyVersion != 1 || e.ParamsHash == "" || e.Timestamp == "" {
+		t.Errorf("Unexpected event %+v", e)
+	}
+	no_event(t, events)
+}
+
+func TestDenialWebhookRetries(t *testing.T) {
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	gw.webhooks.backoff = time.Millisecond
+	srv, events := webhook_receiver(t, func(n int) int {
+		if n == 1 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	gw.SetConfig(Config{DenialWebhooks: []WebhookConfig{{URL: srv.URL, Headers: map[string]string{"X-Webhook-Secret": "s3cret"}}}})
+
+	post_tool(gw, "test-agent", `{"amount":10000}`)
+	first, second := next_event(t, events), next_event(t, events)
+	if first != second {
+		t.Errorf("Expected the same event retried, got %+v and %+v", first, second)
+	}
+	no_event(t, events)
+}
+
+// a hanging webhook never holds up the request
+func TestDenialWebhookDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	gw, _ := setupTestGateway(t)
+	defer gw.Close()
+	gw.SetConfig(Config{DenialWebhooks: []WebhookConfig{{URL: srv.URL, Timeout: time.Minute}}})
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		post_tool(gw, "test-agent", `{"amount":10000}`)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected denials not to wait on the webhook, took %v", elapsed)
+	}
+}
+
+func TestDenialWebhookValidation(t *testing.T) {
+	tests := map[string]WebhookConfig{
+		"bad URL":             {URL: "ftp://alerts"},
+		"unknown reason code": {URL: "http://alerts", ReasonCodes: []policy.ReasonCode{"TOO_MUCH"}},
+		"negative timeout":    {URL: "http://alerts", Timeout: -time.Second},
+	}
+	for name, wc := range tests {
+		if err := (Config{DenialWebhooks: []WebhookConfig{wc}}).validate(); err == nil {
+			t.Errorf("%s: expected the config to be rejected", name)
+		}
+	}
+}
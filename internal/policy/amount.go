@@ -0,0 +1,30 @@
+package policy
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// the amount param as a number, see ParseAmount
+func (r *Request) amount() (float64, bool) {
+	return ParseAmount(r.Params["amount"], r.StrictAmounts)
+}
+
+// a numeric param value. JSON numbers (and ints from Go callers) always
+// work; numeric strings such as "1000" or " 12.50 " are parsed unless
+// strict. NaN, infinities and anything else are rejected
+func ParseAmount(v interface{}, strict bool) (float64, bool) {
+	if n, ok := as_number(v); ok {
+		return n, !math.IsNaN(n) && !math.IsInf(n, 0)
+	}
+	s, ok := v.(string)
+	if !ok || strict {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,52 @@
+package policy
+
+import "testing"
+
+func TestAmountCoercion(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     interface{}
+		strict     bool
+		wantReason string
+	}{
+		{"float", 1000.0, false, ""},
+		{"int from a Go caller", 1000, false, ""},
+		{"float over the limit", 6000.0, false, "Amount 6000.00 exceeds max_amount=5000.00"},
+		{"numeric string", "1000", false, ""},
+		{"decimal string with spaces", " 12.50 ", false, ""},
+		{"numeric string over the limit", "6000", false, "Amount 6000.00 exceeds max_amount=5000.00"},
+		{"non-numeric string", "lots", false, "Invalid amount parameter"},
+		{"NaN string", "NaN", false, "Invalid amount parameter"},
+		{"empty string", "", false, "Invalid amount parameter"},
+		{"numeric string when strict", "1000", true, "Invalid amount parameter"},
+		{"float when strict", 1000.0, true, ""},
+	}
+
+	m := &Manager{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Request{AgentID: "a", Params: map[string]interface{}{"amount": tt.amount}, StrictAmounts: tt.strict}
+			reason, _ := m.check_conditions(req, map[string]interface{}{"max_amount": 5000})
+			if reason != tt.wantReason {
+				t.Errorf("check_conditions() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+// string amounts count towards daily_limit like numbers
+func TestAmountCoercion_DailyLimitRecordsParsedAmount(t *testing.T) {
+	m := &Manager{spends: newSpendTracker()}
+	conditions := map[string]interface{}{"daily_limit": 1500}
+	for _, amount := range []interface{}{"1000", 400.0} {
+		req := &Request{AgentID: "a", Params: map[string]interface{}{"amount": amount}}
+		if reason, _ := m.check_conditions(req, conditions); reason != "" {
+			t.Fatalf("Expected %v within the daily limit, got %s", amount, reason)
+		}
+		m.commit_state(req, conditions)
+	}
+	req := &Request{AgentID: "a", Params: map[string]interface{}{"amount": "200"}}
+	if reason, _ := m.check_conditions(req, conditions); reason == "" {
+		t.Error("Expected the string amount to count towards the daily limit")
+	}
+}
@@ -0,0 +1,224 @@
+package policy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// header carrying a second approver's signed token for require_dual_approval
+const ApproverHeader = "X-Approver"
+
+// longest an approval token may be valid for. used nonces are remembered
+// until their token expires, so this also bounds how long that is
+const MaxApprovalTTL = 24 * time.Hour
+
+// require_dual_approval: payments above threshold need a token from a
+// second, distinct approver in the X-Approver header
+//
+//	conditions:
+//	  require_dual_approval:
+//	    threshold: 10000
+//	    approvers: [cfo-agent, controller-agent]   # optional allowlist
+type dualApproval struct {
+	Threshold float64
+	Approvers []string
+}
+
+func parse_dual_approval(condVal interface{}) (dualApproval, error) {
+	raw, ok := condVal.(map[string]interface{})
+	if !ok {
+		return dualApproval{}, fmt.Errorf("require_dual_approval: expected a map, got %T", condVal)
+	}
+	var da dualApproval
+	switch v := raw["threshold"].(type) {
+	case int:
+		da.Threshold = float64(v)
+	case float64:
+		da.Threshold = v
+	default:
+		return dualApproval{}, fmt.Errorf("require_dual_approval: threshold must be a number")
+	}
+	if da.Threshold < 0 {
+		return dualApproval{}, fmt.Errorf("require_dual_approval: threshold cannot be negative")
+	}
+	if list, ok := raw["approvers"].([]interface{}); ok {
+		for _, a := range list {
+			s, ok := a.(string)
+			if !ok {
+				return dualApproval{}, fmt.Errorf("require_dual_approval: approvers must be strings")
+			}
+			da.Approvers = append(da.Approvers, s)
+		}
+	}
+	return da, nil
+}
+
+// set the HMAC keys approvers sign their tokens with, keyed by approver ID
+func (m *Manager) SetApproverKeys(keys map[string][]byte) {
+	m.update_settings(func(s *managerSettings) { s.approverKeys = keys })
+}
+
+// a random nonce for SignApproval
+func NewApprovalNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// build the X-Approver token for a request:
+// "<approver>:<expires unix>:<nonce>:<hex hmac>". the signature covers
+// agent, tool, action and params so it can't be reused for a different
+// request, and expiry and nonce so it's only good once, until expires.
+// nonce must not contain ':'
+func SignApproval(approverID string, key []byte, expires time.Time, nonce string, agentID, tool, action string, params map[string]interface{}) string {
+	tok := approvalToken{approverID: approverID, expires: expires.Unix(), nonce: nonce}
+	return fmt.Sprintf("%s:%d:%s:%s", approverID, tok.expires, nonce, hex.EncodeToString(tok.mac(key, agentID, tool, action, params)))
+}
+
+// a parsed X-Approver token
+type approvalToken struct {
+	approverID string
+	expires    int64 // unix seconds
+	nonce      string
+	sig        []byte
+}
+
+func parse_approval(token string) (approvalToken, bool) {
+	parts := strings.Split(token, ":")
+	if len(parts) != 4 || parts[0] == "" || parts[2] == "" {
+		return approvalToken{}, false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return approvalToken{}, false
+	}
+	sig, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return approvalToken{}, false
+	}
+	return approvalToken{approverID: parts[0], expires: expires, nonce: parts[2], sig: sig}, true
+}
+
+func (t approvalToken) mac(key []byte, agentID, tool, action string, params map[string]interface{}) []byte {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s|%s|%s|%s|%d|%s", agentID, tool, action, HashParams(params), t.expires, t.nonce)
+	return mac.Sum(nil)
+}
+
+// used nonces are per approver
+func (t approvalToken) key() string {
+	return t.approverID + ":" + t.nonce
+}
+
+// nonces of approval tokens already used, approver:nonce -> expiry. an
+// entry can go once its token has expired, as the token fails anyway
+type approvalNonces struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func newApprovalNonces() *approvalNonces {
+	return &approvalNonces{used: make(map[string]time.Time)}
+}
+
+func (n *approvalNonces) seen(key string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, ok := n.used[key]
+	return ok
+}
+
+// mark a nonce used, false if it already was
+func (n *approvalNonces) use(key string, expires, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for k, exp := range n.used {
+		if !now.Before(exp) {
+			delete(n.used, k)
+		}
+	}
+	if _, ok := n.used[key]; ok {
+		return false
+	}
+	n.used[key] = expires
+	return true
+}
+
+// make a nonce usable again, for a request denied after using it
+func (n *approvalNonces) release(key string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.used, key)
+}
+
+// the token of a request above the threshold, which then has to be used
+// up once the request is allowed
+func approval_to_use(req *Request, da dualApproval) (approvalToken, bool) {
+	if amt, ok := req.amount(); !ok || amt <= da.Threshold {
+		return approvalToken{}, false
+	}
+	return parse_approval(req.Headers[ApproverHeader])
+}
+
+func (m *Manager) check_dual_approval(req *Request, da dualApproval) string {
+	amt, ok := req.amount()
+	if !ok {
+		return "Invalid amount parameter"
+	}
+	if amt <= da.Threshold {
+		return ""
+	}
+
+	token := req.Headers[ApproverHeader]
+	if token == "" {
+		return fmt.Sprintf("Amount %.2f above %.2f requires dual approval via %s", amt, da.Threshold, ApproverHeader)
+	}
+
+	tok, ok := parse_approval(token)
+	if !ok {
+		return "Malformed approval token"
+	}
+	approverID := tok.approverID
+	if approverID == req.AgentID {
+		return "Approver cannot be the requesting agent"
+	}
+	if len(da.Approvers) > 0 && !contains(da.Approvers, approverID) {
+		return fmt.Sprintf("Approver %s is not allowed to approve this action", approverID)
+	}
+
+	key, known := m.settings().approverKeys[approverID]
+	if !known {
+		return fmt.Sprintf("Unknown approver %s", approverID)
+	}
+	if !hmac.Equal(tok.sig, tok.mac(key, req.AgentID, req.Tool, req.Action, req.Params)) {
+		return "Invalid approval signature"
+	}
+	now := m.now()
+	expires := time.Unix(tok.expires, 0)
+	if !now.Before(expires) {
+		return "Approval token expired"
+	}
+	if expires.Sub(now) > MaxApprovalTTL {
+		return fmt.Sprintf("Approval token valid for longer than %v", MaxApprovalTTL)
+	}
+	if m.approvals != nil && m.approvals.seen(tok.key()) {
+		return "Approval token already used"
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
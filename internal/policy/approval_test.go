@@ -0,0 +1,189 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDualApproval(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          require_dual_approval:
+            threshold: 10000
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "dual.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cfoKey := []byte("cfo-secret")
+	financeKey := []byte("finance-secret")
+	m.SetApproverKeys(map[string][]byte{
+		"cfo-agent":     cfoKey,
+		"finance-agent": financeKey,
+	})
+
+	large := map[string]interface{}{"amount": 50000.0, "currency": "USD"}
+	small := map[string]interface{}{"amount": 500.0, "currency": "USD"}
+
+	tests := []struct {
+		name      string
+		params    map[string]interface{}
+		approver  string
+		wantAllow bool
+	}{
+		{
+			name:      "below threshold needs no approval",
+			params:    small,
+			wantAllow: true,
+		},
+		{
+			name:      "large with valid distinct approver",
+			params:    large,
+			approver:  SignApproval("cfo-agent", cfoKey, time.Now().Add(time.Hour), NewApprovalNonce(), "finance-agent", "payments", "create", large),
+			wantAllow: true,
+		},
+		{
+			name:      "large with missing approval",
+			params:    large,
+			wantAllow: false,
+		},
+		{
+			name:      "large self-approved",
+			params:    large,
+			approver:  SignApproval("finance-agent", financeKey, time.Now().Add(time.Hour), NewApprovalNonce(), "finance-agent", "payments", "create", large),
+			wantAllow: false,
+		},
+		{
+			name:      "large with forged signature",
+			params:    large,
+			approver:  SignApproval("cfo-agent", []byte("wrong-key"), time.Now().Add(time.Hour), NewApprovalNonce(), "finance-agent", "payments", "create", large),
+			wantAllow: false,
+		},
+		{
+			name:      "approval for a different request",
+			params:    large,
+			approver:  SignApproval("cfo-agent", cfoKey, time.Now().Add(time.Hour), NewApprovalNonce(), "finance-agent", "payments", "create", small),
+			wantAllow: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{
+				AgentID: "finance-agent",
+				Tool:    "payments",
+				Action:  "create",
+				Params:  tt.params,
+				Headers: map[string]string{},
+			}
+			if tt.approver != "" {
+				req.Headers[ApproverHeader] = tt.approver
+			}
+			d := m.EvaluateRequest(req)
+			if d.Allow != tt.wantAllow {
+				t.Errorf("EvaluateRequest() Allow = %v, want %v. Reason: %s", d.Allow, tt.wantAllow, d.Reason)
+			}
+		})
+	}
+}
+
+func TestDualApproval_ApproverAllowlist(t *testing.T) {
+	m := &Manager{}
+	m.SetApproverKeys(map[string][]byte{"intern-agent": []byte("k")})
+	params := map[string]interface{}{"amount": 200.0}
+	req := &Request{
+		AgentID: "finance-agent",
+		Tool:    "payments",
+		Action:  "create",
+		Params:  params,
+		Headers: map[string]string{
+			ApproverHeader: SignApproval("intern-agent", []byte("k"), time.Now().Add(time.Hour), NewApprovalNonce(), "finance-agent", "payments", "create", params),
+		},
+	}
+	conditions := map[string]interface{}{
+		"require_dual_approval": map[string]interface{}{
+			"threshold": 100,
+			"approvers": []interface{}{"cfo-agent"},
+		},
+	}
+	want := "Approver intern-agent is not allowed to approve this action"
+	if reason, _ := m.check_conditions(req, conditions); reason != want {
+		t.Errorf("check_conditions() = %q, want %q", reason, want)
+	}
+}
+
+func TestDualApproval_SingleUse(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"dual.yaml": `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          require_dual_approval:
+            threshold: 10000
+`})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	m.SetClock(clock)
+	key := []byte("cfo-secret")
+	m.SetApproverKeys(map[string][]byte{"cfo-agent": key})
+
+	params := map[string]interface{}{"amount": 50000.0}
+	send := func(token string, dryRun bool) Decision {
+		return m.EvaluateRequest(Request{
+			AgentID: "finance-agent",
+			Tool:    "payments",
+			Action:  "create",
+			Params:  params,
+			Headers: map[string]string{ApproverHeader: token},
+			DryRun:  dryRun,
+		})
+	}
+
+	token := SignApproval("cfo-agent", key, clock.t.Add(time.Hour), NewApprovalNonce(), "finance-agent", "payments", "create", params)
+	// a dry run doesn't use the token up
+	if d := send(token, true); !d.Allow {
+		t.Fatalf("Expected dry run to allow: %s", d.Reason)
+	}
+	if d := send(token, false); !d.Allow {
+		t.Fatalf("Expected first use to allow: %s", d.Reason)
+	}
+	if d := send(token, false); d.Allow || d.Reason != "Approval token already used" {
+		t.Errorf("Expected the replayed token to be denied, got %+v", d)
+	}
+
+	expired := SignApproval("cfo-agent", key, clock.t.Add(-time.Second), NewApprovalNonce(), "finance-agent", "payments", "create", params)
+	if d := send(expired, false); d.Allow || d.Reason != "Approval token expired" {
+		t.Errorf("Expected an expired token to be denied, got %+v", d)
+	}
+
+	tooLong := SignApproval("cfo-agent", key, clock.t.Add(MaxApprovalTTL+time.Hour), NewApprovalNonce(), "finance-agent", "payments", "create", params)
+	if d := send(tooLong, false); d.Allow {
+		t.Error("Expected a token valid past MaxApprovalTTL to be denied")
+	}
+
+	// the expiry is signed: moving it voids the signature
+	later := strings.Replace(token, fmt.Sprint(clock.t.Add(time.Hour).Unix()), fmt.Sprint(clock.t.Add(2*time.Hour).Unix()), 1)
+	if d := send(later, false); d.Allow || d.Reason != "Invalid approval signature" {
+		t.Errorf("Expected a tampered expiry to be denied, got %+v", d)
+	}
+}
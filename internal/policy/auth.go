@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	// agent has no api_key_sha256 in any policy file
+	ErrNoAPIKey = errors.New("no API key configured for agent")
+
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// hex SHA-256 of an API key, the form stored in api_key_sha256
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func validate_api_key_hash(hash string) error {
+	if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("api_key_sha256 must be a hex SHA-256 digest")
+	}
+	return nil
+}
+
+// check key against the hash configured for agentID. returns ErrNoAPIKey
+// if the agent has none, so the caller can decide whether that's allowed
+func (m *Manager) CheckAPIKey(agentID, key string) error {
+	hash := m.snapshot().api_key_hash(agentID)
+
+	if hash == "" {
+		return ErrNoAPIKey
+	}
+	want, _ := hex.DecodeString(hash)
+	got := sha256.Sum256([]byte(key))
+	if key == "" || subtle.ConstantTimeCompare(got[:], want) != 1 {
+		return ErrInvalidAPIKey
+	}
+	return nil
+}
+
+func (s *policySnapshot) api_key_hash(agentID string) string {
+	if ai := s.index[agentID]; ai != nil {
+		return ai.apiKeyHash
+	}
+	return ""
+}
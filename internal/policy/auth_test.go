@@ -0,0 +1,38 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckAPIKey(t *testing.T) {
+	policies := map[string]Policy{
+		"p.yaml": {Version: 1, Agents: []Agent{
+			{ID: "keyed-agent", APIKeySHA256: HashAPIKey("s3cret-key")},
+			{ID: "open-agent"},
+		}},
+	}
+	m := &Manager{}
+	m.active.Store(new_policy_snapshot(policies, sorted_names(policies)))
+
+	if err := m.CheckAPIKey("keyed-agent", "s3cret-key"); err != nil {
+		t.Errorf("Expected valid key to pass, got %v", err)
+	}
+	if err := m.CheckAPIKey("keyed-agent", "wrong"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected ErrInvalidAPIKey, got %v", err)
+	}
+	if err := m.CheckAPIKey("keyed-agent", ""); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected ErrInvalidAPIKey for missing key, got %v", err)
+	}
+	if err := m.CheckAPIKey("open-agent", "anything"); !errors.Is(err, ErrNoAPIKey) {
+		t.Errorf("Expected ErrNoAPIKey, got %v", err)
+	}
+}
+
+func TestAPIKeyHash_RejectedAtLoad(t *testing.T) {
+	m := &Manager{}
+	p := Policy{Version: 1, Agents: []Agent{{ID: "a", APIKeySHA256: "plaintext-key"}}}
+	if err := m.check_policy_valid(&p); err == nil {
+		t.Error("Expected a non-digest api_key_sha256 to fail validation")
+	}
+}
@@ -0,0 +1,62 @@
+package policy
+
+import "strings"
+
+// bounded groups of denial reasons for metrics. reasons carry amounts,
+// paths and IDs, so they can't be used as labels themselves
+const (
+	CategoryNoPolicy         = "no_policy"
+	CategoryAgentDisabled    = "agent_disabled"
+	CategoryDenyRule         = "deny_rule"
+	CategoryAmountExceeded   = "amount_exceeded"
+	CategoryAmountBelowMin   = "amount_below_min"
+	CategoryCurrencyDenied   = "currency_denied"
+	CategoryPathDenied       = "path_denied"
+	CategoryLimitExceeded    = "limit_exceeded"
+	CategoryApprovalRequired = "approval_required"
+	CategoryOutsideHours     = "outside_hours"
+	CategoryInvalidParams    = "invalid_params"
+	CategoryNotOwner         = "not_owner"
+	CategoryOther            = "other"
+)
+
+// reason prefixes and substrings per category, checked in order
+var reasonCategories = []struct {
+	prefix, contains, category string
+}{
+	{prefix: "No policy found", category: CategoryNoPolicy},
+	{prefix: "Agent is disabled", category: CategoryAgentDisabled},
+	{prefix: "Denied by deny rule", category: CategoryDenyRule},
+	{contains: "exceeds max_amount", category: CategoryAmountExceeded},
+	{contains: "below min_amount", category: CategoryAmountBelowMin},
+	{contains: "requires dual approval", category: CategoryApprovalRequired},
+	{contains: "approve", category: CategoryApprovalRequired},
+	{contains: "change window", category: CategoryApprovalRequired},
+	{contains: "active window", category: CategoryApprovalRequired},
+	// daily_limit, max_distinct_vendors, max_daily_write_bytes
+	{contains: "would exceed", category: CategoryLimitExceeded},
+	{prefix: "Currency ", category: CategoryCurrencyDenied},
+	{prefix: "Path ", category: CategoryPathDenied},
+	{prefix: "Payment ", category: CategoryNotOwner},
+	{prefix: "Cannot verify owner", category: CategoryNotOwner},
+	{prefix: "Request outside allowed hours", category: CategoryOutsideHours},
+	{prefix: "Invalid ", category: CategoryInvalidParams},
+	{prefix: "Missing parameter", category: CategoryInvalidParams},
+	{prefix: "Parameter ", category: CategoryInvalidParams},
+	{prefix: "Parameters failed params_schema", category: CategoryInvalidParams},
+	{prefix: "Memo ", category: CategoryInvalidParams},
+	{contains: "must be greater than last seen", category: CategoryInvalidParams},
+}
+
+// category of a denial reason, CategoryOther when none fits
+func ReasonCategory(reason string) string {
+	for _, rc := range reasonCategories {
+		if rc.prefix != "" && strings.HasPrefix(reason, rc.prefix) {
+			return rc.category
+		}
+		if rc.contains != "" && strings.Contains(reason, rc.contains) {
+			return rc.category
+		}
+	}
+	return CategoryOther
+}
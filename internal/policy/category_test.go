@@ -0,0 +1,73 @@
+package policy
+
+import "testing"
+
+func TestReasonCategory(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   string
+	}{
+		{"No policy found for agent=ghost, tool=payments, action=create", CategoryNoPolicy},
+		{"Denied by deny rule for tool=payments, actions=[void]", CategoryDenyRule},
+		{"Amount 10000.00 exceeds max_amount=5000.00", CategoryAmountExceeded},
+		{"Amount 0.50 is below min_amount=1.00", CategoryAmountBelowMin},
+		{"Currency GBP not in allowed list", CategoryCurrencyDenied},
+		{"Currency RUB is denied by currencies_denied", CategoryCurrencyDenied},
+		{"Path /legal/contract.pdf does not match required prefix /hr-docs/", CategoryPathDenied},
+		{"Path /etc/passwd matches prefix /etc/ denied by path_denied_prefix", CategoryPathDenied},
+		{"Amount 600.00 would exceed daily_limit=1000.00 (500.00 spent in the last 24h)", CategoryLimitExceeded},
+		{"Write of 10 bytes would exceed max_daily_write_bytes=5 (0 used today)", CategoryLimitExceeded},
+		{"Amount 9000.00 above 5000.00 requires dual approval via X-Approver", CategoryApprovalRequired},
+		{"Unknown approver bob", CategoryApprovalRequired},
+		{"Action requires an active change window via X-Change-ID", CategoryApprovalRequired},
+		{"Request outside allowed hours 09:00-17:00", CategoryOutsideHours},
+		{"Payment 42 belongs to another agent", CategoryNotOwner},
+		{"Invalid currency parameter", CategoryInvalidParams},
+		{"Missing parameter transfer.kind required by required_params", CategoryInvalidParams},
+		{"Parameter tier=silver not in allowed values [gold]", CategoryInvalidParams},
+		{"Expression \"amount < 10\" not satisfied", CategoryOther},
+	}
+	for _, tt := range tests {
+		if got := ReasonCategory(tt.reason); got != tt.want {
+			t.Errorf("ReasonCategory(%q) = %s, want %s", tt.reason, got, tt.want)
+		}
+	}
+}
+
+// categories of reasons as the engine actually words them
+func TestReasonCategoryFromEvaluate(t *testing.T) {
+	policies := map[string]Policy{
+		"p.yaml": {Version: 1, Agents: []Agent{{
+			ID: "finance-agent",
+			Allow: []Permission{{
+				Tool:    "payments",
+				Actions: []string{"create"},
+				Conditions: map[string]interface{}{
+					"max_amount": 5000,
+					"currencies": []interface{}{"USD"},
+				},
+			}},
+		}}},
+	}
+	m := &Manager{}
+	m.active.Store(new_policy_snapshot(policies, sorted_names(policies)))
+
+	tests := []struct {
+		agent  string
+		params map[string]interface{}
+		want   string
+	}{
+		{"ghost-agent", nil, CategoryNoPolicy},
+		{"finance-agent", map[string]interface{}{"amount": 9000.0, "currency": "USD"}, CategoryAmountExceeded},
+		{"finance-agent", map[string]interface{}{"amount": 10.0, "currency": "GBP"}, CategoryCurrencyDenied},
+	}
+	for _, tt := range tests {
+		d := m.Evaluate(tt.agent, "payments", "create", tt.params)
+		if d.Allow {
+			t.Fatalf("Expected %s %v to be denied", tt.agent, tt.params)
+		}
+		if got := ReasonCategory(d.Reason); got != tt.want {
+			t.Errorf("ReasonCategory(%q) = %s, want %s", d.Reason, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package policy
+
+import (
+	"fmt"
+	"net/textproto"
+	"time"
+)
+
+// header carrying the change ticket for require_change_window
+const ChangeIDHeader = "X-Change-ID"
+
+// source of approved change windows, e.g. a static set or a lookup
+// against the change management system
+//
+//	conditions:
+//	  require_change_window: true
+type ChangeWindows interface {
+	// whether change id is approved and open at t
+	Active(id string, t time.Time) bool
+}
+
+// one approved change; a zero End leaves the window open-ended
+type ChangeWindow struct {
+	Start time.Time `yaml:"start" json:"start"`
+	End   time.Time `yaml:"end" json:"end,omitempty"`
+}
+
+// fixed set of change windows keyed by change ID
+type StaticChangeWindows map[string]ChangeWindow
+
+func (s StaticChangeWindows) Active(id string, t time.Time) bool {
+	w, ok := s[id]
+	if !ok || t.Before(w.Start) {
+		return false
+	}
+	return w.End.IsZero() || t.Before(w.End)
+}
+
+// set where require_change_window looks up change IDs
+func (m *Manager) SetChangeWindows(cw ChangeWindows) {
+	m.update_settings(func(s *managerSettings) { s.changeWindows = cw })
+}
+
+func (m *Manager) check_change_window(req *Request) string {
+	id := req.Headers[textproto.CanonicalMIMEHeaderKey(ChangeIDHeader)]
+	if id == "" {
+		return fmt.Sprintf("Action requires an active change window via %s", ChangeIDHeader)
+	}
+	if cw := m.settings().changeWindows; cw == nil || !cw.Active(id, m.now()) {
+		return fmt.Sprintf("Change %s is not in an active window", id)
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRequireChangeWindow(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: ops-agent
+    allow:
+      - tool: files
+        actions: [write]
+        conditions:
+          require_change_window: true
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "change.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	now := time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC)
+	m.SetClock(&fakeClock{t: now})
+	m.SetChangeWindows(StaticChangeWindows{
+		"CHG-100": {Start: now.Add(-time.Hour), End: now.Add(time.Hour)},
+		"CHG-099": {Start: now.Add(-48 * time.Hour), End: now.Add(-24 * time.Hour)},
+	})
+
+	write := func(changeID string) Decision {
+		req := Request{
+			AgentID: "ops-agent",
+			Tool:    "files",
+			Action:  "write",
+			Params:  map[string]interface{}{"path": "/etc/app.conf"},
+		}
+		if changeID != "" {
+			req.Headers = map[string]string{"X-Change-Id": changeID}
+		}
+		return m.EvaluateRequest(req)
+	}
+
+	if d := write("CHG-100"); !d.Allow {
+		t.Errorf("Expected active change to be allowed, got %s", d.Reason)
+	}
+	if d := write("CHG-099"); d.Allow || d.Reason != "Change CHG-099 is not in an active window" {
+		t.Errorf("Expected closed change to be denied, got %+v", d)
+	}
+	if d := write("CHG-404"); d.Allow {
+		t.Error("Expected unknown change to be denied")
+	}
+	if d := write(""); d.Allow || d.Reason != "Action requires an active change window via X-Change-ID" {
+		t.Errorf("Expected missing change ID to be denied, got %+v", d)
+	}
+}
+
+func TestStaticChangeWindows_OpenEnded(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	cw := StaticChangeWindows{"CHG-1": {Start: start}}
+	if cw.Active("CHG-1", start.Add(-time.Second)) {
+		t.Error("Expected change to be inactive before its start")
+	}
+	if !cw.Active("CHG-1", start.Add(365*24*time.Hour)) {
+		t.Error("Expected open-ended change to stay active")
+	}
+}
@@ -0,0 +1,25 @@
+package policy
+
+import "time"
+
+// source of the current time, swappable so time-based conditions can be tested
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// replace the clock used by time-based conditions
+func (m *Manager) SetClock(c Clock) {
+	m.update_settings(func(s *managerSettings) { s.clock = c })
+}
+
+func (m *Manager) now() time.Time {
+	c := m.settings().clock
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
@@ -0,0 +1,128 @@
+package policy
+
+import "fmt"
+
+// params_constraints: per-field comparisons against request params.
+// ordering ops need numbers on both sides, eq/neq fall back to comparing
+// strings when either side isn't numeric
+//
+//	conditions:
+//	  params_constraints:
+//	    - field: quantity
+//	      op: lte
+//	      value: 100
+type paramConstraint struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+var constraintOps = map[string]string{
+	"eq":  "==",
+	"neq": "!=",
+	"lt":  "<",
+	"lte": "<=",
+	"gt":  ">",
+	"gte": ">=",
+}
+
+func parse_params_constraints(condVal interface{}) ([]paramConstraint, error) {
+	list, ok := condVal.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("params_constraints: expected a list, got %T", condVal)
+	}
+	out := make([]paramConstraint, 0, len(list))
+	for i, item := range list {
+		raw, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("params_constraints[%d]: expected a map, got %T", i, item)
+		}
+		c := paramConstraint{Value: raw["value"]}
+		c.Field, _ = raw["field"].(string)
+		c.Op, _ = raw["op"].(string)
+		if c.Field == "" {
+			return nil, fmt.Errorf("params_constraints[%d]: field is required", i)
+		}
+		if _, ok := constraintOps[c.Op]; !ok {
+			return nil, fmt.Errorf("params_constraints[%d]: unknown op %q", i, c.Op)
+		}
+		if c.Value == nil {
+			return nil, fmt.Errorf("params_constraints[%d]: value is required", i)
+		}
+		if _, numeric := as_number(c.Value); !numeric && c.Op != "eq" && c.Op != "neq" {
+			return nil, fmt.Errorf("params_constraints[%d]: %s needs a numeric value", i, c.Op)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// "" if params satisfies c
+func (c paramConstraint) check(params map[string]interface{}) string {
+	got, ok := params[c.Field]
+	if !ok || got == nil {
+		return fmt.Sprintf("Missing parameter %s required by params_constraints", c.Field)
+	}
+
+	a, aNum := as_number(got)
+	b, bNum := as_number(c.Value)
+	var pass bool
+	switch {
+	case aNum && bNum:
+		pass = compare(a, b, c.Op)
+	case c.Op == "eq" || c.Op == "neq":
+		eq := fmt.Sprint(got) == fmt.Sprint(c.Value)
+		pass = eq == (c.Op == "eq")
+	default:
+		return fmt.Sprintf("Parameter %s must be a number for %s", c.Field, c.Op)
+	}
+	if !pass {
+		return fmt.Sprintf("Parameter %s=%v fails %s %s %v", c.Field, got, c.Field, constraintOps[c.Op], c.Value)
+	}
+	return ""
+}
+
+func compare(a, b float64, op string) bool {
+	switch op {
+	case "eq":
+		return a == b
+	case "neq":
+		return a != b
+	case "lt":
+		return a < b
+	case "lte":
+		return a <= b
+	case "gt":
+		return a > b
+	case "gte":
+		return a >= b
+	}
+	return false
+}
+
+// numeric value as float64, from JSON params or YAML policy values
+func as_number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func (m *Manager) check_params_constraints(params map[string]interface{}, condVal interface{}) string {
+	constraints, err := parse_params_constraints(condVal)
+	if err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+		return ""
+	}
+	for _, c := range constraints {
+		if reason := c.check(params); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
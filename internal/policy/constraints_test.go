@@ -0,0 +1,64 @@
+package policy
+
+import "testing"
+
+func TestParamsConstraints(t *testing.T) {
+	constraint := func(op string, value interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"params_constraints": []interface{}{
+				map[string]interface{}{"field": "quantity", "op": op, "value": value},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		op         string
+		value      interface{}
+		params     map[string]interface{}
+		wantReason string
+	}{
+		{"eq pass", "eq", 100, map[string]interface{}{"quantity": 100.0}, ""},
+		{"eq fail", "eq", 100, map[string]interface{}{"quantity": 99.0}, "Parameter quantity=99 fails quantity == 100"},
+		{"neq pass", "neq", 0, map[string]interface{}{"quantity": 1.0}, ""},
+		{"neq fail", "neq", 0, map[string]interface{}{"quantity": 0.0}, "Parameter quantity=0 fails quantity != 0"},
+		{"lt pass", "lt", 100, map[string]interface{}{"quantity": 99.5}, ""},
+		{"lt fail", "lt", 100, map[string]interface{}{"quantity": 100.0}, "Parameter quantity=100 fails quantity < 100"},
+		{"lte pass", "lte", 100, map[string]interface{}{"quantity": 100.0}, ""},
+		{"lte fail", "lte", 100, map[string]interface{}{"quantity": 101.0}, "Parameter quantity=101 fails quantity <= 100"},
+		{"gt pass", "gt", 0.5, map[string]interface{}{"quantity": 1.0}, ""},
+		{"gt fail", "gt", 0.5, map[string]interface{}{"quantity": 0.5}, "Parameter quantity=0.5 fails quantity > 0.5"},
+		{"gte pass", "gte", 1, map[string]interface{}{"quantity": 1.0}, ""},
+		{"gte fail", "gte", 1, map[string]interface{}{"quantity": 0.0}, "Parameter quantity=0 fails quantity >= 1"},
+		{"eq on strings", "eq", "standard", map[string]interface{}{"quantity": "standard"}, ""},
+		{"ordering needs a number", "lte", 100, map[string]interface{}{"quantity": "lots"}, "Parameter quantity must be a number for lte"},
+		{"missing field", "lte", 100, map[string]interface{}{"amount": 5.0}, "Missing parameter quantity required by params_constraints"},
+	}
+
+	m := &Manager{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, _ := m.check_conditions(&Request{AgentID: "a", Params: tt.params}, constraint(tt.op, tt.value))
+			if reason != tt.wantReason {
+				t.Errorf("check_conditions() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestParamsConstraints_Validation(t *testing.T) {
+	bad := []interface{}{
+		map[string]interface{}{"field": "quantity", "op": "lte", "value": 100},
+		[]interface{}{"quantity <= 100"},
+		[]interface{}{map[string]interface{}{"op": "lte", "value": 100}},
+		[]interface{}{map[string]interface{}{"field": "quantity", "op": "le", "value": 100}},
+		[]interface{}{map[string]interface{}{"field": "quantity", "op": "lte"}},
+		[]interface{}{map[string]interface{}{"field": "quantity", "op": "lte", "value": "100"}},
+	}
+	m := &Manager{}
+	for _, v := range bad {
+		if err := m.validate_conditions(map[string]interface{}{"params_constraints": v}); err == nil {
+			t.Errorf("Expected validation error for %v", v)
+		}
+	}
+}
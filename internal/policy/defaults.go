@@ -0,0 +1,72 @@
+package policy
+
+import "fmt"
+
+// what happens when none of an agent's allow rules match. deny (the
+// default) is a whitelist; allow with deny rules is a blacklist:
+//
+//	default_action: allow
+//	agents:
+//	  - id: internal-bot
+//	    deny:
+//	      - tool: payments
+//	        actions: ["*"]
+//	      - tool: files
+//	        actions: [read]
+//	        conditions:
+//	          path_prefix: /secret/
+const (
+	DefaultActionDeny  = "deny"
+	DefaultActionAllow = "allow"
+)
+
+func valid_default_action(a string) bool {
+	return a == "" || a == DefaultActionDeny || a == DefaultActionAllow
+}
+
+// the agent's own default_action, else its file's. "" when neither is set
+func effective_default(p Policy, agent Agent) string {
+	if agent.DefaultAction != "" {
+		return agent.DefaultAction
+	}
+	return p.DefaultAction
+}
+
+// fallthrough decision for an agent no allow rule matched. agents spread
+// over several files can't disagree on it, see check_policy_set. unknown
+// agents are always denied
+func (s *policySnapshot) default_decision(agentID, tool, action string) Decision {
+	if ai := s.index[agentID]; ai != nil && ai.defaultAllow {
+		return Decision{
+			Allow:      true,
+			Reason:     fmt.Sprintf("No rule for tool=%s, action=%s; allowed by default_action", tool, action),
+			Version:    ai.defaultVersion,
+			ReasonCode: ReasonOK,
+		}
+	}
+	return Decision{
+		Allow:      false,
+		Reason:     fmt.Sprintf("No policy found for agent=%s, tool=%s, action=%s", agentID, tool, action),
+		ReasonCode: ReasonNoPolicy,
+	}
+}
+
+// first deny rule that matches the request, if any. a rule with
+// conditions only applies when all of them hold. deny rules are checked
+// before any allow rule, so an explicit deny always wins
+func (m *Manager) matching_deny(snap *policySnapshot, req *Request) (Permission, int, bool) {
+	ai := snap.index[req.AgentID]
+	if ai == nil {
+		return Permission{}, 0, false
+	}
+	// conditions here select the rule rather than gate it, keep them out
+	// of the trace
+	probe := *req
+	probe.Debug = false
+	for _, c := range active_candidates(ai.deny.lookup(req.Tool, req.Action), m.now()) {
+		if reason, _ := m.check_conditions(&probe, c.perm.Conditions); reason == "" {
+			return c.perm, c.version, true
+		}
+	}
+	return Permission{}, 0, false
+}
@@ -0,0 +1,130 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDefaultActionDeny(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"finance.yaml": `version: 1
+default_action: deny
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+`})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if d := m.Evaluate("finance-agent", "payments", "create", nil); !d.Allow {
+		t.Errorf("Expected the allow rule to match, got %s", d.Reason)
+	}
+	if d := m.Evaluate("finance-agent", "files", "read", nil); d.Allow {
+		t.Error("Expected an unlisted tool to be denied under default_action: deny")
+	}
+}
+
+func TestDefaultActionAllow(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"ops.yaml": `version: 3
+default_action: allow
+agents:
+  - id: ops-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 100
+    deny:
+      - tool: payments
+        actions: [refund]
+      - tool: files
+        actions: ["*"]
+        conditions:
+          path_prefix: /secret/
+  - id: strict-agent
+    default_action: deny
+    allow:
+      - tool: files
+        actions: [read]
+`})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		agent  string
+		tool   string
+		action string
+		params map[string]interface{}
+		allow  bool
+	}{
+		{"unlisted tool falls through to allow", "ops-agent", "crm", "update", nil, true},
+		{"allow rule still applies its conditions", "ops-agent", "payments", "create", map[string]interface{}{"amount": 500.0}, false},
+		{"deny rule without conditions", "ops-agent", "payments", "refund", nil, false},
+		{"conditional deny rule matches", "ops-agent", "files", "write", map[string]interface{}{"path": "/secret/keys"}, false},
+		{"conditional deny rule doesn't match", "ops-agent", "files", "read", map[string]interface{}{"path": "/public/a.txt"}, true},
+		{"agent overrides the file default", "strict-agent", "crm", "update", nil, false},
+		{"unknown agents are never allowed", "nobody", "crm", "update", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := m.Evaluate(tt.agent, tt.tool, tt.action, tt.params)
+			if d.Allow != tt.allow {
+				t.Errorf("Evaluate() allow = %v, want %v (%s)", d.Allow, tt.allow, d.Reason)
+			}
+			if d.Allow && d.Version != 3 {
+				t.Errorf("Expected the file's version on a default allow, got %d", d.Version)
+			}
+		})
+	}
+}
+
+func TestDefaultActionValidation(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"a.yaml": reloadFinance})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	bad := map[string]Policy{
+		"unknown file default":  {Version: 1, DefaultAction: "maybe", Agents: []Agent{{ID: "a"}}},
+		"unknown agent default": {Version: 1, Agents: []Agent{{ID: "a", DefaultAction: "permit"}}},
+		"deny without actions":  {Version: 1, Agents: []Agent{{ID: "a", Deny: []Permission{{Tool: "payments"}}}}},
+		"deny with bad condition": {Version: 1, Agents: []Agent{{ID: "a", Deny: []Permission{{
+			Tool: "payments", Actions: []string{"create"}, Conditions: map[string]interface{}{"not": "lots"},
+		}}}}},
+	}
+	for name, p := range bad {
+		if err := m.check_policy_valid(&p); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+
+	// the same agent can't be allow-by-default in one file and deny in another
+	writePolicies(t, tmpDir, map[string]string{"b.yaml": `version: 1
+default_action: allow
+agents:
+  - id: finance-agent
+    allow:
+      - tool: files
+        actions: [read]
+`, "c.yaml": `version: 1
+agents:
+  - id: finance-agent
+    default_action: deny
+    allow:
+      - tool: crm
+        actions: [read]
+`})
+	var list LoadErrorList
+	if err := m.Reload(); !errors.As(err, &list) || len(list) != 1 || list[0].File != "c.yaml" {
+		t.Fatalf("Expected conflicting default_action rejected in c.yaml, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// deny lists: the inverse of currencies and path_prefix, for "allow
+// everything except ...". a missing or non-string param still fails, as
+// with the allow-list forms. paths are matched like path_prefix, cleaned
+// and on segment boundaries
+//
+//	conditions:
+//	  currencies_denied: [RUB, KPW]
+//	  path_denied_prefix: [/secret/, /etc/]
+func parse_denied_list(name string, condVal interface{}) ([]string, error) {
+	if s, ok := condVal.(string); ok {
+		condVal = []interface{}{s}
+	}
+	list, ok := condVal.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a string or list of strings, got %T", name, condVal)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%s: list cannot be empty", name)
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("%s: entries must be non-empty strings, got %v", name, item)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func check_currencies_denied(params map[string]interface{}, condVal interface{}) string {
+	denied, err := parse_denied_list("currencies_denied", condVal)
+	if err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+		return ""
+	}
+	curr, ok := params["currency"].(string)
+	if !ok {
+		return "Invalid currency parameter"
+	}
+	// the payments adapter takes currency codes in any case, so the deny
+	// list has to as well
+	for _, d := range denied {
+		if strings.EqualFold(curr, d) {
+			return fmt.Sprintf("Currency %s is denied by currencies_denied", curr)
+		}
+	}
+	return ""
+}
+
+func check_path_denied_prefix(params map[string]interface{}, condVal interface{}) string {
+	denied, err := parse_denied_list("path_denied_prefix", condVal)
+	if err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+		return ""
+	}
+	pth, ok := params["path"].(string)
+	if !ok {
+		return "Invalid path parameter"
+	}
+	for _, pfx := range denied {
+		if under_prefix(pth, pfx) {
+			return fmt.Sprintf("Path %s matches prefix %s denied by path_denied_prefix", pth, pfx)
+		}
+	}
+	return ""
+}
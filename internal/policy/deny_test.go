@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDenyConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions map[string]interface{}
+		params     map[string]interface{}
+		wantReason string
+	}{
+		{
+			name:       "currency on deny list",
+			conditions: map[string]interface{}{"currencies_denied": []interface{}{"RUB", "KPW"}},
+			params:     map[string]interface{}{"currency": "RUB"},
+			wantReason: "Currency RUB is denied by currencies_denied",
+		},
+		{
+			name:       "lowercase currency on deny list",
+			conditions: map[string]interface{}{"currencies_denied": []interface{}{"RUB", "KPW"}},
+			params:     map[string]interface{}{"currency": "rub"},
+			wantReason: "Currency rub is denied by currencies_denied",
+		},
+		{
+			name:       "currency passes through",
+			conditions: map[string]interface{}{"currencies_denied": []interface{}{"RUB", "KPW"}},
+			params:     map[string]interface{}{"currency": "USD"},
+			wantReason: "",
+		},
+		{
+			name:       "missing currency",
+			conditions: map[string]interface{}{"currencies_denied": []interface{}{"RUB"}},
+			params:     map[string]interface{}{},
+			wantReason: "Invalid currency parameter",
+		},
+		{
+			name:       "path under denied prefix",
+			conditions: map[string]interface{}{"path_denied_prefix": "/secret/"},
+			params:     map[string]interface{}{"path": "/secret/keys.txt"},
+			wantReason: "Path /secret/keys.txt matches prefix /secret/ denied by path_denied_prefix",
+		},
+		{
+			name:       "path passes through",
+			conditions: map[string]interface{}{"path_denied_prefix": []interface{}{"/secret/", "/etc/"}},
+			params:     map[string]interface{}{"path": "/data/report.csv"},
+			wantReason: "",
+		},
+		{
+			name:       "second prefix in list",
+			conditions: map[string]interface{}{"path_denied_prefix": []interface{}{"/secret/", "/etc/"}},
+			params:     map[string]interface{}{"path": "/etc/passwd"},
+			wantReason: "Path /etc/passwd matches prefix /etc/ denied by path_denied_prefix",
+		},
+	}
+
+	m := &Manager{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, _ := m.check_conditions(&Request{AgentID: "a", Params: tt.params}, tt.conditions)
+			if reason != tt.wantReason {
+				t.Errorf("check_conditions() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+// "allow all paths except /secret/" from a policy file
+func TestPathDeniedPrefix_Policy(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+schema_version: 2
+agents:
+  - id: reader-agent
+    allow:
+      - tool: files
+        actions: [read]
+        conditions:
+          path_denied_prefix: /secret/
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "deny.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	if d := m.Evaluate("reader-agent", "files", "read", map[string]interface{}{"path": "/public/a.txt"}); !d.Allow {
+		t.Errorf("Expected /public/a.txt allowed, got %s", d.Reason)
+	}
+	if d := m.Evaluate("reader-agent", "files", "read", map[string]interface{}{"path": "/secret/a.txt"}); d.Allow {
+		t.Error("Expected /secret/a.txt denied")
+	}
+}
+
+func TestDenyConditions_Validation(t *testing.T) {
+	bad := []interface{}{
+		[]interface{}{},
+		[]interface{}{""},
+		[]interface{}{1},
+		map[string]interface{}{"path": "/secret/"},
+	}
+	m := &Manager{}
+	for _, name := range []string{"currencies_denied", "path_denied_prefix"} {
+		for _, v := range bad {
+			if err := m.validate_conditions(map[string]interface{}{name: v}); err == nil {
+				t.Errorf("Expected %s validation error for %v", name, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+// a policy file found under one of the manager's directories
+type policySource struct {
+	// key in the loaded set, see policy_sources
+	name string
+	path string
+}
+
+// the same directory twice would load every file twice
+func check_policy_dirs(dirs []string) error {
+	if len(dirs) == 0 {
+		return fmt.Errorf("no policy directory given")
+	}
+	seen := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		clean := filepath.Clean(dir)
+		if seen[clean] {
+			return fmt.Errorf("policy directory %s is listed more than once", dir)
+		}
+		seen[clean] = true
+	}
+	return nil
+}
+
+// policy files of every directory in precedence order: directories in
+// the order given, then files by name. with a single directory files are
+// named by their path relative to it, as before; with several the
+// directory is put in front, e.g. policies/org/payments.yaml, so the
+// same relative name can appear in each
+func (m *Manager) policy_sources() ([]policySource, error) {
+	var out []policySource
+	for _, dir := range m.dirs {
+		files, err := policy_files(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policies directory %s: %w", dir, err)
+		}
+		for _, rel := range files {
+			name := rel
+			if len(m.dirs) > 1 {
+				name = path.Join(filepath.ToSlash(dir), rel)
+			}
+			out = append(out, policySource{
+				name: name,
+				path: filepath.Join(dir, filepath.FromSlash(rel)),
+			})
+		}
+	}
+	return out, nil
+}
+
+// directories policies are loaded from, highest precedence first
+func (m *Manager) Dirs() []string {
+	out := make([]string, len(m.dirs))
+	copy(out, m.dirs)
+	return out
+}
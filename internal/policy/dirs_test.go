@@ -0,0 +1,173 @@
+package policy
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const orgPolicy = `version: 1
+agents:
+  - id: finance-agent
+    api_key_sha256: ` + "%s" + `
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+    deny:
+      - tool: payments
+        actions: [void]
+`
+
+const teamPolicy = `version: 2
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 50000
+      - tool: payments
+        actions: [void, refund]
+      - tool: files
+        actions: [read]
+  - id: reports-agent
+    allow:
+      - tool: files
+        actions: [read]
+`
+
+func policy_dirs(t *testing.T) (string, string) {
+	t.Helper()
+	root := t.TempDir()
+	org, team := filepath.Join(root, "org"), filepath.Join(root, "team")
+	for _, d := range []string{org, team} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", d, err)
+		}
+	}
+	return org, team
+}
+
+// the team directory extends the org one: new tools and agents add up,
+// org rules win where both cover the same tool/action, and org deny
+// rules still apply
+func TestMultipleDirs(t *testing.T) {
+	org, team := policy_dirs(t)
+	writePolicies(t, org, map[string]string{"policy.yaml": strings.Replace(orgPolicy, "%s", HashAPIKey("org-key"), 1)})
+	writePolicies(t, team, map[string]string{"policy.yaml": teamPolicy})
+
+	m, err := NewManager(org, team)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	tests := []struct {
+		agent, tool, action string
+		params              map[string]interface{}
+		allow               bool
+		version             int
+	}{
+		{"finance-agent", "payments", "create", map[string]interface{}{"amount": 1000.0}, true, 1},
+		// org's max_amount wins over the team's looser one
+		{"finance-agent", "payments", "create", map[string]interface{}{"amount": 8000.0}, false, 1},
+		{"finance-agent", "payments", "refund", nil, true, 2},
+		{"finance-agent", "files", "read", nil, true, 2},
+		{"finance-agent", "payments", "void", nil, false, 1},
+		{"reports-agent", "files", "read", nil, true, 2},
+		{"reports-agent", "payments", "create", map[string]interface{}{"amount": 1.0}, false, 0},
+	}
+	for _, tt := range tests {
+		d := m.Evaluate(tt.agent, tt.tool, tt.action, tt.params)
+		if d.Allow != tt.allow || d.Version != tt.version {
+			t.Errorf("%s %s/%s %v: got allow=%v version=%d (%s), want allow=%v version=%d",
+				tt.agent, tt.tool, tt.action, tt.params, d.Allow, d.Version, d.Reason, tt.allow, tt.version)
+		}
+	}
+
+	// the org key covers the agent's rules from both directories
+	if err := m.CheckAPIKey("finance-agent", "org-key"); err != nil {
+		t.Errorf("Expected the org api key to be accepted: %v", err)
+	}
+
+	// same relative name in each directory, told apart by the prefix
+	want := []string{
+		path.Join(filepath.ToSlash(org), "policy.yaml"),
+		path.Join(filepath.ToSlash(team), "policy.yaml"),
+	}
+	if got := m.PolicyFiles(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected files %v, got %v", want, got)
+	}
+	if got := m.Dirs(); len(got) != 2 || got[0] != org || got[1] != team {
+		t.Errorf("Expected dirs [%s %s], got %v", org, team, got)
+	}
+}
+
+// order decides precedence, not directory names
+func TestMultipleDirsOrder(t *testing.T) {
+	org, team := policy_dirs(t)
+	writePolicies(t, org, map[string]string{"policy.yaml": strings.Replace(orgPolicy, "%s", HashAPIKey("org-key"), 1)})
+	writePolicies(t, team, map[string]string{"policy.yaml": teamPolicy})
+
+	m, err := NewManager(team, org)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 8000.0})
+	if !d.Allow || d.Version != 2 {
+		t.Errorf("Expected the team rule to win when listed first, got allow=%v version=%d (%s)", d.Allow, d.Version, d.Reason)
+	}
+}
+
+// agent checks span directories: a team file can't give an org agent a
+// different key, and that file is rejected as a whole
+func TestMultipleDirsConflicts(t *testing.T) {
+	org, team := policy_dirs(t)
+	writePolicies(t, org, map[string]string{"policy.yaml": strings.Replace(orgPolicy, "%s", HashAPIKey("org-key"), 1)})
+	writePolicies(t, team, map[string]string{"policy.yaml": strings.Replace(teamPolicy, "  - id: finance-agent\n",
+		"  - id: finance-agent\n    api_key_sha256: "+HashAPIKey("team-key")+"\n", 1)})
+
+	m, err := NewManager(org, team)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	errs := m.LoadErrors()
+	if len(errs) != 1 || errs[0].File != path.Join(filepath.ToSlash(team), "policy.yaml") || !strings.Contains(errs[0].Error, "different api_key_sha256") {
+		t.Fatalf("Expected the team file rejected for its api key, got %+v", errs)
+	}
+	if d := m.Evaluate("reports-agent", "files", "read", nil); d.Allow {
+		t.Error("Expected the rejected team file to grant nothing")
+	}
+
+	// Reload is all or nothing across directories too
+	writePolicies(t, org, map[string]string{"extra.yaml": "version: 1\nagents:\n  - id: audit-agent\n    allow:\n      - tool: files\n        actions: [read]\n"})
+	if err := m.Reload(); err == nil {
+		t.Error("Expected reload to fail while the team file conflicts")
+	}
+	if d := m.Evaluate("audit-agent", "files", "read", nil); d.Allow {
+		t.Error("Expected the previous set to stay active")
+	}
+	writePolicies(t, team, map[string]string{"policy.yaml": teamPolicy})
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Expected reload to succeed once the conflict is fixed: %v", err)
+	}
+	if d := m.Evaluate("reports-agent", "files", "read", nil); !d.Allow {
+		t.Errorf("Expected the team file loaded after reload: %s", d.Reason)
+	}
+}
+
+func TestNewManagerDirs(t *testing.T) {
+	org, _ := policy_dirs(t)
+	if _, err := NewManager(); err == nil {
+		t.Error("Expected an error without directories")
+	}
+	if _, err := NewManager(org, org+string(filepath.Separator)); err == nil {
+		t.Error("Expected an error for a directory listed twice")
+	}
+	if _, err := NewManager(org, filepath.Join(org, "missing")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,23 @@
+package policy
+
+// agents are enabled unless a file says otherwise
+func (a Agent) is_enabled() bool {
+	return a.Enabled == nil || *a.Enabled
+}
+
+// an agent suspended with enabled: false is denied everything, before
+// any rule is looked at, while its rules stay in place for when it's
+// enabled again. for an agent spread over several files one disabling
+// file is enough
+func (s *policySnapshot) disabled_decision(agentID string) (Decision, bool) {
+	ai := s.index[agentID]
+	if ai == nil || !ai.disabled {
+		return Decision{}, false
+	}
+	return Decision{
+		Allow:      false,
+		Reason:     "Agent is disabled",
+		Version:    ai.disabledVersion,
+		ReasonCode: ReasonAgentDisabled,
+	}, true
+}
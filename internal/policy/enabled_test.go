@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+const enabledPolicy = `version: 3
+agents:
+  - id: finance-agent
+    enabled: %s
+    allow:
+      - tool: payments
+        actions: [create]
+  - id: hr-agent
+    allow:
+      - tool: files
+        actions: [read]
+`
+
+func TestDisabledAgent(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"agents.yaml": strings.Replace(enabledPolicy, "%s", "false", 1)})
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	d := m.Evaluate("finance-agent", "payments", "create", nil)
+	if d.Allow || d.Reason != "Agent is disabled" || d.Version != 3 {
+		t.Errorf("Expected finance-agent to be denied as disabled, got %+v", d)
+	}
+	// other agents are unaffected; no enabled field means enabled
+	if d := m.Evaluate("hr-agent", "files", "read", nil); !d.Allow {
+		t.Errorf("Expected hr-agent to stay enabled: %s", d.Reason)
+	}
+
+	summaries := m.Summaries()
+	if len(summaries) != 1 || !summaries[0].Agents[0].Disabled || summaries[0].Agents[1].Disabled {
+		t.Errorf("Expected only finance-agent summarized as disabled, got %+v", summaries)
+	}
+
+	// re-enabled by a reload, with its permissions untouched
+	writePolicies(t, tmpDir, map[string]string{"agents.yaml": strings.Replace(enabledPolicy, "%s", "true", 1)})
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if d := m.Evaluate("finance-agent", "payments", "create", nil); !d.Allow {
+		t.Errorf("Expected finance-agent to be allowed once re-enabled: %s", d.Reason)
+	}
+}
+
+// disabling wins over allow-by-default and over other files still
+// granting the agent rules
+func TestDisabledAgentAcrossFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{
+		"a.yaml": "version: 1\ndefault_action: allow\nagents:\n  - id: ops-agent\n    allow:\n      - tool: files\n        actions: [read]\n",
+		"b.json": `{"version": 2, "agents": [{"id": "ops-agent", "enabled": false, "allow": []}]}`,
+	})
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	for _, ta := range []struct{ tool, action string }{{"files", "read"}, {"payments", "create"}} {
+		d := m.Evaluate("ops-agent", ta.tool, ta.action, nil)
+		if d.Allow || d.Reason != "Agent is disabled" || d.Version != 2 {
+			t.Errorf("Expected %s/%s denied as disabled, got %+v", ta.tool, ta.action, d)
+		}
+	}
+	if got := ReasonCategory("Agent is disabled"); got != CategoryAgentDisabled {
+		t.Errorf("Expected category %s, got %s", CategoryAgentDisabled, got)
+	}
+}
@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// substitute environment variables into a policy file before it's parsed,
+// so one file can carry per-environment values:
+//
+//	max_amount: ${PAYMENTS_MAX_AMOUNT}
+//	currencies: [${PAYMENTS_CURRENCY:-USD}]
+//
+// ${VAR:-default} uses default when VAR is unset or empty, and $${ writes
+// a literal ${. a variable that's unset and has no default fails the file,
+// listing every such variable
+func interpolate_env(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte("${")) {
+		return data, nil
+	}
+	var out bytes.Buffer
+	var missing []string
+	s := string(data)
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			out.WriteString(s)
+			break
+		}
+		if i > 0 && s[i-1] == '$' {
+			out.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		out.WriteString(s[:i])
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated variable reference %q", line_of(s[i:]))
+		}
+		ref := s[i+2 : i+end]
+		name, def, hasDefault := strings.Cut(ref, ":-")
+		if !env_name(name) {
+			return nil, fmt.Errorf("invalid variable reference ${%s}", ref)
+		}
+		val, ok := os.LookupEnv(name)
+		switch {
+		case hasDefault && val == "":
+			val = def
+		case !ok:
+			missing = append(missing, name)
+		}
+		out.WriteString(val)
+		s = s[i+end+1:]
+	}
+	if len(missing) == 1 {
+		return nil, fmt.Errorf("environment variable %s is not set and has no default", missing[0])
+	}
+	if len(missing) > 1 {
+		return nil, fmt.Errorf("environment variables %s are not set and have no default", strings.Join(missing, ", "))
+	}
+	return out.Bytes(), nil
+}
+
+func env_name(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// the rest of the line s starts on, for error messages
+func line_of(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+const envPolicy = `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: ${AEGIS_TEST_MAX_AMOUNT}
+          currencies: [${AEGIS_TEST_CURRENCY:-USD}]
+`
+
+func TestInterpolateEnv(t *testing.T) {
+	t.Setenv("AEGIS_TEST_MAX_AMOUNT", "250")
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"env.yaml": envPolicy})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	pay := func(amount float64, currency string) Decision {
+		return m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": amount, "currency": currency})
+	}
+	if d := pay(200, "USD"); !d.Allow {
+		t.Errorf("Expected allow, got %s", d.Reason)
+	}
+	if d := pay(300, "USD"); d.Allow || d.Reason != "Amount 300.00 exceeds max_amount=250.00" {
+		t.Errorf("Expected substituted max_amount to apply, got %+v", d)
+	}
+	// the default applies while AEGIS_TEST_CURRENCY is unset
+	if d := pay(100, "EUR"); d.Allow {
+		t.Error("Expected default currency USD to apply")
+	}
+
+	t.Setenv("AEGIS_TEST_CURRENCY", "EUR")
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if d := pay(100, "EUR"); !d.Allow {
+		t.Errorf("Expected EUR from the environment to be allowed, got %s", d.Reason)
+	}
+}
+
+func TestInterpolateEnv_MissingVariable(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"env.yaml": envPolicy})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	errs := m.LoadErrors()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error, "environment variable AEGIS_TEST_MAX_AMOUNT is not set and has no default") {
+		t.Fatalf("Expected a load error naming the missing variable, got %+v", errs)
+	}
+	if d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 1.0, "currency": "USD"}); d.Allow {
+		t.Error("Expected the file that failed to load to grant nothing")
+	}
+}
+
+func TestInterpolateEnvSyntax(t *testing.T) {
+	t.Setenv("AEGIS_TEST_SET", "value")
+	t.Setenv("AEGIS_TEST_EMPTY", "")
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "no references", want: "no references"},
+		{in: "a: ${AEGIS_TEST_SET}", want: "a: value"},
+		{in: "a: ${AEGIS_TEST_EMPTY}", want: "a: "},
+		{in: "a: ${AEGIS_TEST_EMPTY:-fallback}", want: "a: fallback"},
+		{in: "a: ${AEGIS_TEST_UNSET:-}", want: "a: "},
+		{in: "a: $${AEGIS_TEST_SET}", want: "a: ${AEGIS_TEST_SET}"},
+		{in: "a: ${AEGIS_TEST_UNSET} ${AEGIS_TEST_OTHER}", wantErr: "environment variables AEGIS_TEST_UNSET, AEGIS_TEST_OTHER are not set"},
+		{in: "a: ${1BAD}", wantErr: "invalid variable reference ${1BAD}"},
+		{in: "a: ${AEGIS_TEST_SET\nb: 1", wantErr: "unterminated variable reference"},
+	}
+	for _, tt := range tests {
+		got, err := interpolate_env([]byte(tt.in))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("interpolate_env(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("interpolate_env(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
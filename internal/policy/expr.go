@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// expr: boolean expression over the request, for rules the fixed
+// condition types can't express. see https://expr-lang.org for syntax
+//
+//	conditions:
+//	  expr: 'params.amount <= 1000 || params.currency == "USD"'
+//
+// variables: params, headers (canonical names), agent_id, tool, action, now
+func expr_env(req *Request, now time.Time) map[string]interface{} {
+	params, headers := req.Params, req.Headers
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	return map[string]interface{}{
+		"params":   params,
+		"headers":  headers,
+		"agent_id": req.AgentID,
+		"tool":     req.Tool,
+		"action":   req.Action,
+		"now":      now,
+	}
+}
+
+// compile an expression once and reuse it across evaluations and reloads
+func (m *Manager) compiled_expr(source string) (*vm.Program, error) {
+	m.exprMu.Lock()
+	defer m.exprMu.Unlock()
+
+	if prog, ok := m.exprs[source]; ok {
+		return prog, nil
+	}
+	prog, err := expr.Compile(source, expr.Env(expr_env(&Request{}, time.Time{})), expr.AsBool())
+	if err != nil {
+		return nil, err
+	}
+	if m.exprs == nil {
+		m.exprs = make(map[string]*vm.Program)
+	}
+	m.exprs[source] = prog
+	return prog, nil
+}
+
+func (m *Manager) check_expr(req *Request, source string) string {
+	prog, err := m.compiled_expr(source)
+	if err != nil {
+		return fmt.Sprintf("Invalid expr in policy: %s", source)
+	}
+	out, err := expr.Run(prog, expr_env(req, m.now()))
+	if err != nil {
+		return fmt.Sprintf("Expression %q failed: %v", source, err)
+	}
+	if ok, _ := out.(bool); !ok {
+		return fmt.Sprintf("Expression %q not satisfied", source)
+	}
+	return ""
+}
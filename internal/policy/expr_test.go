@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExprCondition(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          expr: 'params.amount <= 1000 || (params.currency == "USD" && params.amount <= 5000)'
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "expr.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]interface{}
+		wantAllow bool
+	}{
+		{
+			name:      "small amount any currency",
+			params:    map[string]interface{}{"amount": 800.0, "currency": "EUR"},
+			wantAllow: true,
+		},
+		{
+			name:      "larger USD amount",
+			params:    map[string]interface{}{"amount": 3000.0, "currency": "USD"},
+			wantAllow: true,
+		},
+		{
+			name:      "larger EUR amount",
+			params:    map[string]interface{}{"amount": 3000.0, "currency": "EUR"},
+			wantAllow: false,
+		},
+		{
+			name:      "missing amount",
+			params:    map[string]interface{}{"currency": "USD"},
+			wantAllow: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := m.Evaluate("finance-agent", "payments", "create", tt.params)
+			if d.Allow != tt.wantAllow {
+				t.Errorf("Evaluate() Allow = %v, want %v. Reason: %s", d.Allow, tt.wantAllow, d.Reason)
+			}
+		})
+	}
+}
+
+func TestExprCondition_Headers(t *testing.T) {
+	m := &Manager{}
+	cond := map[string]interface{}{"expr": `headers["X-Team"] == "treasury" && agent_id != "intern"`}
+
+	req := &Request{AgentID: "a", Headers: map[string]string{"X-Team": "treasury"}}
+	if reason, _ := m.check_conditions(req, cond); reason != "" {
+		t.Errorf("Expected expression to pass, got %q", reason)
+	}
+	req = &Request{AgentID: "a", Headers: map[string]string{"X-Team": "growth"}}
+	if reason, _ := m.check_conditions(req, cond); reason == "" {
+		t.Error("Expected expression to fail for another team")
+	}
+}
+
+func TestExprCondition_InvalidRejectedAtLoad(t *testing.T) {
+	bad := []interface{}{
+		"params.amount <=",  // syntax error
+		"amount <= 1000",    // unknown variable
+		`len(agent_id) + 1`, // not a boolean
+		42,
+	}
+	m := &Manager{}
+	for _, v := range bad {
+		p := Policy{
+			Version: 1,
+			Agents: []Agent{{
+				ID: "finance-agent",
+				Allow: []Permission{{
+					Tool:       "payments",
+					Actions:    []string{"create"},
+					Conditions: map[string]interface{}{"expr": v},
+				}},
+			}},
+		}
+		if err := m.check_policy_valid(&p); err == nil {
+			t.Errorf("Expected %v to fail validation", v)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// field_in: a string param must be one of a set of values. takes one
+// field or a list of them, all must match
+//
+//	conditions:
+//	  field_in:
+//	    field: region
+//	    values: [us, eu]
+type fieldIn struct {
+	Field  string
+	Values []string
+}
+
+func parse_field_in(condVal interface{}) ([]fieldIn, error) {
+	items, ok := condVal.([]interface{})
+	if !ok {
+		items = []interface{}{condVal}
+	}
+	out := make([]fieldIn, 0, len(items))
+	for _, item := range items {
+		raw, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field_in: expected a map with field and values, got %T", item)
+		}
+		f := fieldIn{}
+		f.Field, _ = raw["field"].(string)
+		if f.Field == "" {
+			return nil, fmt.Errorf("field_in: field is required")
+		}
+		values, ok := raw["values"].([]interface{})
+		if !ok || len(values) == 0 {
+			return nil, fmt.Errorf("field_in: %s needs a non-empty values list", f.Field)
+		}
+		for _, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("field_in: %s values must be strings, got %T", f.Field, v)
+			}
+			f.Values = append(f.Values, s)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// currencies is field_in on the currency param
+func currencies_field(condVal interface{}) (fieldIn, bool) {
+	list, ok := condVal.([]interface{})
+	if !ok {
+		return fieldIn{}, false
+	}
+	f := fieldIn{Field: "currency"}
+	for _, c := range list {
+		if s, ok := c.(string); ok {
+			f.Values = append(f.Values, s)
+		}
+	}
+	return f, true
+}
+
+// the param's value and whether it's in the set. present is false when
+// the param is missing, valid false when it isn't a string
+func (f fieldIn) match(params map[string]interface{}) (value string, present, valid, found bool) {
+	raw, present := params[f.Field]
+	if !present || raw == nil {
+		return "", false, false, false
+	}
+	value, valid = raw.(string)
+	if !valid {
+		return "", true, false, false
+	}
+	for _, v := range f.Values {
+		if v == value {
+			return value, true, true, true
+		}
+	}
+	return value, true, true, false
+}
+
+func (m *Manager) check_field_in(params map[string]interface{}, condVal interface{}) string {
+	fields, err := parse_field_in(condVal)
+	if err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+		return ""
+	}
+	for _, f := range fields {
+		value, present, valid, found := f.match(params)
+		switch {
+		case !present:
+			return fmt.Sprintf("Missing parameter %s required by field_in", f.Field)
+		case !valid:
+			return fmt.Sprintf("Invalid %s parameter", f.Field)
+		case !found:
+			return fmt.Sprintf("Parameter %s=%s not in allowed values [%s]", f.Field, value, strings.Join(f.Values, ", "))
+		}
+	}
+	return ""
+}
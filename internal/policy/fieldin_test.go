@@ -0,0 +1,54 @@
+package policy
+
+import "testing"
+
+func TestFieldIn(t *testing.T) {
+	region := map[string]interface{}{"field": "region", "values": []interface{}{"us", "eu"}}
+
+	tests := []struct {
+		name       string
+		cond       interface{}
+		params     map[string]interface{}
+		wantReason string
+	}{
+		{"present and allowed", region, map[string]interface{}{"region": "eu"}, ""},
+		{"present and denied", region, map[string]interface{}{"region": "apac"}, "Parameter region=apac not in allowed values [us, eu]"},
+		{"absent field", region, map[string]interface{}{"amount": 10.0}, "Missing parameter region required by field_in"},
+		{"not a string", region, map[string]interface{}{"region": 1.0}, "Invalid region parameter"},
+		{
+			name: "list of fields all must match",
+			cond: []interface{}{
+				region,
+				map[string]interface{}{"field": "tier", "values": []interface{}{"gold"}},
+			},
+			params:     map[string]interface{}{"region": "us", "tier": "silver"},
+			wantReason: "Parameter tier=silver not in allowed values [gold]",
+		},
+	}
+
+	m := &Manager{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, _ := m.check_conditions(&Request{AgentID: "a", Params: tt.params}, map[string]interface{}{"field_in": tt.cond})
+			if reason != tt.wantReason {
+				t.Errorf("check_conditions() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestFieldIn_Validation(t *testing.T) {
+	bad := []interface{}{
+		"region",
+		map[string]interface{}{"values": []interface{}{"us"}},
+		map[string]interface{}{"field": "region"},
+		map[string]interface{}{"field": "region", "values": []interface{}{}},
+		map[string]interface{}{"field": "region", "values": []interface{}{1}},
+	}
+	m := &Manager{}
+	for _, v := range bad {
+		if err := m.validate_conditions(map[string]interface{}{"field_in": v}); err == nil {
+			t.Errorf("Expected validation error for %v", v)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package policy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// hash the request params for logging (PII safe).
+//
+// the hash is canonical: params that are logically equal hash the same
+// however they were built. map keys are sorted at every level, numbers
+// compare by value (int 5, float64 5 and json.Number "5.0" are all 5, -0
+// is 0), and any map or slice type is treated like its JSON equivalent.
+// for params decoded from JSON the encoding matches encoding/json, so
+// hashes (and approval tokens over them) are the same as before.
+func HashParams(params map[string]interface{}) string {
+	var buf bytes.Buffer
+	write_canonical(&buf, params)
+	h := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(h[:])
+}
+
+func write_canonical(buf *bytes.Buffer, v interface{}) {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(val))
+	case string:
+		data, _ := json.Marshal(val)
+		buf.Write(data)
+	case json.Number:
+		if f, err := val.Float64(); err == nil {
+			write_number(buf, f)
+		} else {
+			write_canonical(buf, val.String())
+		}
+	case float64:
+		write_number(buf, val)
+	case float32:
+		write_number(buf, float64(val))
+	case int:
+		write_number(buf, float64(val))
+	case int64:
+		write_number(buf, float64(val))
+	case int32:
+		write_number(buf, float64(val))
+	case uint:
+		write_number(buf, float64(val))
+	case uint64:
+		write_number(buf, float64(val))
+	case uint32:
+		write_number(buf, float64(val))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			write_canonical(buf, k)
+			buf.WriteByte(':')
+			write_canonical(buf, val[k])
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			write_canonical(buf, item)
+		}
+		buf.WriteByte(']')
+	default:
+		write_canonical(buf, generic_value(v))
+	}
+}
+
+// numbers are compared as float64; integral values print without a
+// fraction, like encoding/json
+func write_number(buf *bytes.Buffer, f float64) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		// not valid JSON, but still has to hash deterministically
+		buf.WriteString(strconv.Quote(strconv.FormatFloat(f, 'g', -1, 64)))
+		return
+	}
+	if f == 0 {
+		f = 0 // -0
+	}
+	data, _ := json.Marshal(f)
+	buf.Write(data)
+}
+
+// other maps, slices and structs, reduced to the types write_canonical
+// handles
+func generic_value(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			out := make(map[string]interface{}, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				out[iter.Key().String()] = iter.Value().Interface()
+			}
+			return out
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = rv.Index(i).Interface()
+		}
+		return out
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return rv.Elem().Interface()
+	}
+
+	// anything else goes through its JSON form
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	var decoded interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return string(data)
+	}
+	return decoded
+}
@@ -0,0 +1,71 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestHashParamsCanonical(t *testing.T) {
+	// same content, built in different insertion orders and with
+	// different but equal number and container types
+	a := map[string]interface{}{}
+	a["vendor"] = "acme"
+	a["amount"] = 1000.0
+	a["meta"] = map[string]interface{}{"z": 1.0, "a": []interface{}{map[string]interface{}{"y": true, "x": nil}}}
+
+	b := map[string]interface{}{}
+	b["meta"] = map[string]interface{}{"a": []map[string]interface{}{{"x": nil, "y": true}}, "z": 1}
+	b["amount"] = json.Number("1000.0")
+	b["vendor"] = "acme"
+
+	if HashParams(a) != HashParams(b) {
+		t.Errorf("Expected logically equal params to hash the same:\n%v\n%v", a, b)
+	}
+
+	zero := HashParams(map[string]interface{}{"n": 0.0})
+	negZero := HashParams(map[string]interface{}{"n": math.Copysign(0, -1)})
+	if zero != negZero {
+		t.Error("Expected -0 and 0 to hash the same")
+	}
+}
+
+func TestHashParamsDistinct(t *testing.T) {
+	params := []map[string]interface{}{
+		{"amount": 1000.0},
+		{"amount": "1000"},
+		{"amount": 1000.5},
+		{"amount": []interface{}{1000.0}},
+		{"amount": map[string]interface{}{"value": 1000.0}},
+		{"meta": map[string]interface{}{"a": []interface{}{1.0, 2.0}}},
+		{"meta": map[string]interface{}{"a": []interface{}{2.0, 1.0}}},
+		{"a": nil},
+		{"a": false},
+		{},
+	}
+	seen := map[string]int{}
+	for i, p := range params {
+		h := HashParams(p)
+		if j, ok := seen[h]; ok {
+			t.Errorf("Params %v and %v hashed the same", params[j], p)
+		}
+		seen[h] = i
+	}
+}
+
+// hashes of JSON-decoded params must not change, approval tokens and
+// stored audit records depend on them
+func TestHashParamsMatchesJSONEncoding(t *testing.T) {
+	var params map[string]interface{}
+	body := `{"amount": 1250.75, "currency": "USD", "memo": "<Q3> & more", "items": [{"sku": "a", "qty": 2}], "big": 1e21}`
+	if err := json.Unmarshal([]byte(body), &params); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(params)
+	sum := sha256.Sum256(data)
+	if got, want := HashParams(params), hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("HashParams() = %s, want %s", got, want)
+	}
+}
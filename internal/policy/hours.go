@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// allowed_hours: requests only pass between start and end local time. a
+// window with end before start wraps past midnight (e.g. 22:00-06:00)
+//
+//	conditions:
+//	  allowed_hours:
+//	    start: "09:00"
+//	    end: "17:00"
+//	    timezone: America/New_York   # optional, defaults to UTC
+type allowedHours struct {
+	Start, End string
+	start, end int // minutes since midnight
+	Location   *time.Location
+}
+
+// loaded time zones, time.LoadLocation reads the zone database every call
+var locations sync.Map
+
+func load_location(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+func parse_allowed_hours(condVal interface{}) (allowedHours, error) {
+	raw, ok := condVal.(map[string]interface{})
+	if !ok {
+		return allowedHours{}, fmt.Errorf("allowed_hours: expected a map, got %T", condVal)
+	}
+	var ah allowedHours
+	for _, f := range []struct {
+		key string
+		str *string
+		min *int
+	}{{"start", &ah.Start, &ah.start}, {"end", &ah.End, &ah.end}} {
+		s, ok := raw[f.key].(string)
+		if !ok {
+			return allowedHours{}, fmt.Errorf("allowed_hours: %s is required (HH:MM)", f.key)
+		}
+		t, err := time.Parse("15:04", s)
+		if err != nil {
+			return allowedHours{}, fmt.Errorf("allowed_hours: invalid %s %q", f.key, s)
+		}
+		*f.str, *f.min = s, t.Hour()*60+t.Minute()
+	}
+	if ah.start == ah.end {
+		return allowedHours{}, fmt.Errorf("allowed_hours: start and end cannot be equal")
+	}
+
+	tz, _ := raw["timezone"].(string)
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := load_location(tz)
+	if err != nil {
+		return allowedHours{}, fmt.Errorf("allowed_hours: unknown timezone %q", tz)
+	}
+	ah.Location = loc
+	return ah, nil
+}
+
+// whether now falls inside the window; start inclusive, end exclusive
+func (ah allowedHours) contains(now time.Time) bool {
+	local := now.In(ah.Location)
+	m := local.Hour()*60 + local.Minute()
+	if ah.start < ah.end {
+		return m >= ah.start && m < ah.end
+	}
+	return m >= ah.start || m < ah.end
+}
@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllowedHours(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+
+	tests := []struct {
+		name       string
+		cond       map[string]interface{}
+		now        time.Time
+		wantReason string
+	}{
+		{
+			name:       "inside business hours",
+			cond:       map[string]interface{}{"start": "09:00", "end": "17:00", "timezone": "America/New_York"},
+			now:        time.Date(2024, 3, 5, 10, 30, 0, 0, ny),
+			wantReason: "",
+		},
+		{
+			name:       "after hours",
+			cond:       map[string]interface{}{"start": "09:00", "end": "17:00", "timezone": "America/New_York"},
+			now:        time.Date(2024, 3, 5, 17, 0, 0, 0, ny),
+			wantReason: "Request outside allowed hours 09:00-17:00",
+		},
+		{
+			name: "evaluated in the policy's zone, not the clock's",
+			cond: map[string]interface{}{"start": "09:00", "end": "17:00", "timezone": "America/New_York"},
+			// 15:00 UTC is 10:00 in New York
+			now:        time.Date(2024, 3, 5, 15, 0, 0, 0, time.UTC),
+			wantReason: "",
+		},
+		{
+			name:       "overnight window before midnight",
+			cond:       map[string]interface{}{"start": "22:00", "end": "06:00", "timezone": "America/New_York"},
+			now:        time.Date(2024, 3, 5, 23, 59, 0, 0, ny),
+			wantReason: "",
+		},
+		{
+			name:       "overnight window after midnight",
+			cond:       map[string]interface{}{"start": "22:00", "end": "06:00", "timezone": "America/New_York"},
+			now:        time.Date(2024, 3, 6, 0, 0, 0, 0, ny),
+			wantReason: "",
+		},
+		{
+			name:       "overnight window midday",
+			cond:       map[string]interface{}{"start": "22:00", "end": "06:00", "timezone": "America/New_York"},
+			now:        time.Date(2024, 3, 6, 12, 0, 0, 0, ny),
+			wantReason: "Request outside allowed hours 22:00-06:00",
+		},
+		{
+			name: "UTC midnight is still the previous evening in New York",
+			cond: map[string]interface{}{"start": "09:00", "end": "23:00", "timezone": "America/New_York"},
+			// 00:30 UTC on the 6th is 19:30 on the 5th in New York
+			now:        time.Date(2024, 3, 6, 0, 30, 0, 0, time.UTC),
+			wantReason: "",
+		},
+		{
+			name:       "defaults to UTC",
+			cond:       map[string]interface{}{"start": "09:00", "end": "17:00"},
+			now:        time.Date(2024, 3, 5, 8, 59, 0, 0, time.UTC),
+			wantReason: "Request outside allowed hours 09:00-17:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{}
+			m.SetClock(&fakeClock{t: tt.now})
+			reason, _ := m.check_conditions(&Request{AgentID: "a"}, map[string]interface{}{"allowed_hours": tt.cond})
+			if reason != tt.wantReason {
+				t.Errorf("check_conditions() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestAllowedHours_Validation(t *testing.T) {
+	bad := []interface{}{
+		"09:00-17:00",
+		map[string]interface{}{"start": "09:00"},
+		map[string]interface{}{"start": "9am", "end": "17:00"},
+		map[string]interface{}{"start": "09:00", "end": "09:00"},
+		map[string]interface{}{"start": "09:00", "end": "17:00", "timezone": "Mars/Olympus_Mons"},
+	}
+	m := &Manager{}
+	for _, v := range bad {
+		if err := m.validate_conditions(map[string]interface{}{"allowed_hours": v}); err == nil {
+			t.Errorf("Expected validation error for %v", v)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package policy
+
+// the loaded policies regrouped by agent, then tool, then action, so a
+// request only looks at the rules that can match it instead of scanning
+// every file. rebuilt whenever the active set changes
+type policyIndex map[string]*agentIndex
+
+type agentIndex struct {
+	// tool -> action -> rules, "*" keys hold the wildcard entries. a rule
+	// is listed under each of its actions
+	allow ruleIndex
+	deny  ruleIndex
+
+	// first api_key_sha256 set for the agent, by file name
+	apiKeyHash string
+
+	// some file makes the agent allow-by-default; version of the first
+	defaultAllow   bool
+	defaultVersion int
+
+	// some file sets enabled: false; version of the first
+	disabled        bool
+	disabledVersion int
+
+	// first quota_group set for the agent and its weight; quota is filled
+	// in by build_quotas, nil when the agent has none
+	quotaGroup  string
+	quotaWeight float64
+	quota       *agentQuota
+}
+
+type ruleIndex map[string]map[string][]indexedPerm
+
+// a rule with the version of the file it came from
+type indexedPerm struct {
+	perm    Permission
+	version int
+}
+
+// files are walked in load order (see policy_sources) and rules in file
+// order, so rules of equal specificity always come out in the same order.
+// names missing from policies are skipped
+func build_index(policies map[string]Policy, names []string) policyIndex {
+	idx := make(policyIndex)
+	for _, name := range names {
+		p, ok := policies[name]
+		if !ok {
+			continue
+		}
+		for _, agent := range p.Agents {
+			ai := idx[agent.ID]
+			if ai == nil {
+				ai = &agentIndex{
+					allow: make(ruleIndex),
+					deny:  make(ruleIndex),
+				}
+				idx[agent.ID] = ai
+			}
+			for _, perm := range agent.Allow {
+				add_indexed(ai.allow, perm, p.Version)
+			}
+			for _, perm := range agent.Deny {
+				add_indexed(ai.deny, perm, p.Version)
+			}
+			if ai.apiKeyHash == "" {
+				ai.apiKeyHash = agent.APIKeySHA256
+			}
+			if !ai.defaultAllow && effective_default(p, agent) == DefaultActionAllow {
+				ai.defaultAllow = true
+				ai.defaultVersion = p.Version
+			}
+			if !ai.disabled && !agent.is_enabled() {
+				ai.disabled = true
+				ai.disabledVersion = p.Version
+			}
+			if ai.quotaGroup == "" && agent.QuotaGroup != "" {
+				ai.quotaGroup, ai.quotaWeight = agent.QuotaGroup, agent.quota_weight()
+			}
+		}
+	}
+	build_quotas(idx, policies, names)
+	return idx
+}
+
+// tools and actions the rules name, across agents
+func (idx policyIndex) named() map[string]map[string]bool {
+	named := make(map[string]map[string]bool)
+	for _, ai := range idx {
+		for _, rules := range []ruleIndex{ai.allow, ai.deny} {
+			for tool, actions := range rules {
+				if named[tool] == nil {
+					named[tool] = make(map[string]bool)
+				}
+				for action := range actions {
+					named[tool][action] = true
+				}
+			}
+		}
+	}
+	return named
+}
+
+// whether some loaded rule names action for tool, or for "*". wildcard
+// actions don't count: they match anything
+func (m *Manager) Names(tool, action string) bool {
+	named := m.snapshot().named
+	return action != Wildcard && (named[tool][action] || named[Wildcard][action])
+}
+
+func add_indexed(rules ruleIndex, perm Permission, version int) {
+	actions := rules[perm.Tool]
+	if actions == nil {
+		actions = make(map[string][]indexedPerm)
+		rules[perm.Tool] = actions
+	}
+	seen := make(map[string]bool, len(perm.Actions))
+	for _, a := range perm.Actions {
+		if !seen[a] {
+			seen[a] = true
+			actions[a] = append(actions[a], indexedPerm{perm, version})
+		}
+	}
+}
+
+// rules covering tool/action, most specific first: exact tool and action,
+// exact tool with "*" action, "*" tool with exact action, then "*" for
+// both. a rule listing both the action and "*" is only returned once
+func (rules ruleIndex) lookup(tool, action string) []candidate {
+	var out []candidate
+	add := func(t string, spec int) {
+		actions := rules[t]
+		if actions == nil {
+			return
+		}
+		for _, ip := range actions[action] {
+			out = append(out, candidate{perm: ip.perm, version: ip.version, specificity: spec + 1})
+		}
+		if action == Wildcard {
+			return
+		}
+		for _, ip := range actions[Wildcard] {
+			if !contains(ip.perm.Actions, action) {
+				out = append(out, candidate{perm: ip.perm, version: ip.version, specificity: spec})
+			}
+		}
+	}
+	add(tool, 2)
+	if tool != Wildcard {
+		add(Wildcard, 0)
+	}
+	return out
+}
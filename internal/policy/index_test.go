@@ -0,0 +1,256 @@
+package policy
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// the scan the index replaced, kept as the reference it must agree with.
+// names fixes the file order the old map walk left random
+func linear_candidates(policies map[string]Policy, names []string, agentID, tool, action string) []candidate {
+	var out []candidate
+	for _, name := range names {
+		for _, agent := range policies[name].Agents {
+			if agent.ID != agentID {
+				continue
+			}
+			for _, perm := range agent.Allow {
+				if spec, ok := linear_match(perm, tool, action); ok {
+					out = append(out, candidate{perm: perm, version: policies[name].Version, specificity: spec})
+				}
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].specificity > out[j].specificity
+	})
+	return out
+}
+
+func sorted_names(policies map[string]Policy) []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func linear_match(perm Permission, tool, action string) (int, bool) {
+	spec := 0
+	switch perm.Tool {
+	case tool:
+		spec += 2
+	case Wildcard:
+	default:
+		return 0, false
+	}
+	switch {
+	case contains(perm.Actions, action):
+		spec++
+	case contains(perm.Actions, Wildcard):
+	default:
+		return 0, false
+	}
+	return spec, true
+}
+
+var (
+	benchTools   = []string{"payments", "files", "crm", "email", Wildcard}
+	benchActions = []string{"create", "refund", "read", "write", "delete", Wildcard}
+)
+
+// n agents over files of 50, each with a handful of rules mixing exact
+// and wildcard tools and actions
+func generate_policies(rng *rand.Rand, n int) map[string]Policy {
+	policies := make(map[string]Policy)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("team-%03d.yaml", i/50)
+		p := policies[name]
+		p.Version = i/50 + 1
+		agent := Agent{ID: fmt.Sprintf("agent-%04d", i)}
+		for j := 0; j < 2+rng.Intn(5); j++ {
+			perm := Permission{
+				Tool:       benchTools[rng.Intn(len(benchTools))],
+				Conditions: map[string]interface{}{"max_amount": float64(rng.Intn(10000))},
+			}
+			for k := 0; k < 1+rng.Intn(3); k++ {
+				perm.Actions = append(perm.Actions, benchActions[rng.Intn(len(benchActions))])
+			}
+			agent.Allow = append(agent.Allow, perm)
+		}
+		p.Agents = append(p.Agents, agent)
+		policies[name] = p
+	}
+	return policies
+}
+
+func TestIndexMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	policies := generate_policies(rng, 300)
+	m := &Manager{}
+	m.active.Store(new_policy_snapshot(policies, sorted_names(policies)))
+	names := sorted_names(policies)
+
+	for i := 0; i < 5000; i++ {
+		agent := fmt.Sprintf("agent-%04d", rng.Intn(320))
+		tool := benchTools[rng.Intn(len(benchTools))]
+		action := benchActions[rng.Intn(len(benchActions))]
+
+		want := linear_candidates(policies, names, agent, tool, action)
+		got := m.candidates(m.snapshot(), agent, tool, action)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("candidates(%s, %s, %s) = %v, want %v", agent, tool, action, got, want)
+		}
+
+		params := map[string]interface{}{"amount": float64(rng.Intn(10000))}
+		d := m.EvaluateRequest(Request{AgentID: agent, Tool: tool, Action: action, Params: params, DryRun: true})
+		wantAllow := false
+		if len(want) > 0 {
+			reason, _ := m.check_conditions(&Request{AgentID: agent, Params: params}, want[0].perm.Conditions)
+			wantAllow = reason == ""
+		}
+		if d.Allow != wantAllow {
+			t.Fatalf("Evaluate(%s, %s, %s, %v) allow = %v, want %v (%s)", agent, tool, action, params, d.Allow, wantAllow, d.Reason)
+		}
+	}
+}
+
+// loaded from disk, the index gives the same decisions as the scan,
+// including wildcards and deny rules
+func TestIndexFromFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"a.yaml": `version: 1
+agents:
+  - id: ops-agent
+    allow:
+      - tool: "*"
+        actions: [read]
+      - tool: payments
+        actions: ["*", create]
+        conditions:
+          max_amount: 100
+    deny:
+      - tool: files
+        actions: [read]
+        conditions:
+          path_prefix: /secret/
+`, "b.yaml": `version: 2
+agents:
+  - id: ops-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+`})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	// both files have an exact payments/create rule; file order decides
+	if d := m.Evaluate("ops-agent", "payments", "create", map[string]interface{}{"amount": 1000.0}); d.Allow {
+		t.Errorf("Expected a.yaml's exact rule to decide first, got %+v", d)
+	}
+	if d := m.Evaluate("ops-agent", "payments", "refund", map[string]interface{}{"amount": 50.0}); !d.Allow || d.Version != 1 {
+		t.Errorf("Expected the payments \"*\" rule to allow refund, got %+v", d)
+	}
+	if d := m.Evaluate("ops-agent", "crm", "read", nil); !d.Allow {
+		t.Errorf("Expected the \"*\" tool rule to allow crm read, got %s", d.Reason)
+	}
+
+	// names rules spell out, not what their wildcards would match
+	for _, tt := range []struct {
+		tool, action string
+		want         bool
+	}{
+		{"payments", "create", true},
+		{"crm", "read", true},
+		{"files", "read", true},
+		{"payments", "refund", false},
+		{"payments", "*", false},
+		{"files", "write", false},
+	} {
+		if got := m.Names(tt.tool, tt.action); got != tt.want {
+			t.Errorf("Names(%s, %s) = %v, want %v", tt.tool, tt.action, got, tt.want)
+		}
+	}
+	if d := m.Evaluate("ops-agent", "files", "read", map[string]interface{}{"path": "/secret/k"}); d.Allow {
+		t.Error("Expected the deny rule to apply through the index")
+	}
+	if got := m.candidates(m.snapshot(), "ops-agent", "payments", "create"); len(got) != 2 {
+		t.Errorf("Expected a rule listing both create and \"*\" only once, got %d candidates", len(got))
+	}
+}
+
+func write_bench_policies(b *testing.B, policies map[string]Policy) string {
+	dir := b.TempDir()
+	for name, p := range policies {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "version: %d\nagents:\n", p.Version)
+		for _, agent := range p.Agents {
+			fmt.Fprintf(&sb, "  - id: %s\n    allow:\n", agent.ID)
+			for _, perm := range agent.Allow {
+				fmt.Fprintf(&sb, "      - tool: %q\n        actions: [", perm.Tool)
+				for i, a := range perm.Actions {
+					if i > 0 {
+						sb.WriteString(", ")
+					}
+					fmt.Fprintf(&sb, "%q", a)
+				}
+				fmt.Fprintf(&sb, "]\n        conditions:\n          max_amount: %v\n", perm.Conditions["max_amount"])
+			}
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(sb.String()), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// 1,000 agents over 20 files. linear_scan is the per-request cost before
+// the index, for comparison
+func BenchmarkEvaluate(b *testing.B) {
+	policies := generate_policies(rand.New(rand.NewSource(1)), 1000)
+	m, err := NewManager(write_bench_policies(b, policies))
+	if err != nil {
+		b.Fatal(err)
+	}
+	params := map[string]interface{}{"amount": 500.0}
+	policies = m.snapshot().policies
+	names := sorted_names(policies)
+
+	b.Run("index", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				i++
+				m.EvaluateRequest(Request{
+					AgentID: fmt.Sprintf("agent-%04d", i%1000),
+					Tool:    "payments",
+					Action:  "create",
+					Params:  params,
+					DryRun:  true,
+				})
+			}
+		})
+	})
+	b.Run("linear_scan", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				i++
+				linear_candidates(policies, names, fmt.Sprintf("agent-%04d", i%1000), "payments", "create")
+			}
+		})
+	})
+}
@@ -0,0 +1,35 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// a policy file that couldn't be read, parsed or validated
+type PolicyLoadError struct {
+	File      string `json:"file"`
+	Error     string `json:"error"`
+	Timestamp string `json:"timestamp"`
+}
+
+// returned by Reload when any file failed; the previous set stays active
+type LoadErrorList []PolicyLoadError
+
+func (l LoadErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error
+	}
+	return fmt.Sprintf("%d policy file(s) failed to load: %s", len(l), strings.Join(msgs, "; "))
+}
+
+// files that failed the most recent load or reload
+func (m *Manager) LoadErrors() []PolicyLoadError {
+	return append([]PolicyLoadError(nil), m.snapshot().loadErrors...)
+}
+
+// when the active policies were loaded; failed reloads don't count
+func (m *Manager) LastLoaded() time.Time {
+	return m.snapshot().loadedAt
+}
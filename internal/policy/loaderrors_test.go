@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadErrorsReported(t *testing.T) {
+	tmpDir := t.TempDir()
+	valid := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+`
+	files := map[string]string{
+		"valid.yaml":  valid,
+		"broken.yaml": "version: 1\nagents: [\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Expected manager to start despite a bad file, got %v", err)
+	}
+	if d := m.Evaluate("finance-agent", "payments", "create", nil); !d.Allow {
+		t.Errorf("Expected valid file to be loaded: %s", d.Reason)
+	}
+
+	errs := m.LoadErrors()
+	if len(errs) != 1 || errs[0].File != "broken.yaml" || errs[0].Timestamp == "" {
+		t.Fatalf("Expected one load error for broken.yaml, got %+v", errs)
+	}
+	if !strings.Contains(errs[0].Error, "failed to parse policy file") {
+		t.Errorf("Unexpected error text: %s", errs[0].Error)
+	}
+
+	loaded := m.LastLoaded()
+	if loaded.IsZero() {
+		t.Error("Expected the startup load time to be recorded")
+	}
+	clock := &fakeClock{t: loaded.Add(time.Hour)}
+	m.SetClock(clock)
+
+	var list LoadErrorList
+	if err := m.Reload(); !errors.As(err, &list) || len(list) != 1 {
+		t.Errorf("Expected Reload to return the load errors, got %v", err)
+	}
+	if !m.LastLoaded().Equal(loaded) {
+		t.Errorf("Expected a failed reload to keep the load time %v, got %v", loaded, m.LastLoaded())
+	}
+
+	// fixing the file clears the errors
+	if err := os.WriteFile(filepath.Join(tmpDir, "broken.yaml"), []byte(valid), 0644); err != nil {
+		t.Fatalf("Failed to fix policy: %v", err)
+	}
+	if err := m.Reload(); err != nil {
+		t.Errorf("Expected clean reload, got %v", err)
+	}
+	if errs := m.LoadErrors(); len(errs) != 0 {
+		t.Errorf("Expected no load errors, got %+v", errs)
+	}
+	if !m.LastLoaded().Equal(clock.t) {
+		t.Errorf("Expected the reload time %v, got %v", clock.t, m.LastLoaded())
+	}
+}
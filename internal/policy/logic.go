@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// boolean combinators for conditions. a flat conditions map is an implicit
+// AND; "and"/"or" take a list of condition maps and "not" takes a single
+// map, nesting freely:
+//
+//	conditions:
+//	  or:
+//	    - currencies: [USD]
+//	    - max_amount: 100
+
+// every branch must pass, the first failing one gives the code
+func (m *Manager) check_and(req *Request, condVal interface{}) (string, ReasonCode) {
+	branches, _ := as_condition_list(condVal)
+	for _, b := range branches {
+		if reason, code := m.check_conditions(req, b); reason != "" {
+			return reason, code
+		}
+	}
+	return "", ""
+}
+
+// at least one branch must pass
+func (m *Manager) check_or(req *Request, condVal interface{}) string {
+	branches, _ := as_condition_list(condVal)
+	var reasons []string
+	for _, b := range branches {
+		reason, _ := m.check_conditions(req, b)
+		if reason == "" {
+			return ""
+		}
+		reasons = append(reasons, reason)
+	}
+	return fmt.Sprintf("No alternative matched: %s", strings.Join(reasons, "; "))
+}
+
+// the first branch of an "or" that passes, nil if none does. checked
+// without adding to the trace, which already has the or's result
+func (m *Manager) passing_branch(req *Request, condVal interface{}) map[string]interface{} {
+	probe := *req
+	probe.Debug = false
+	branches, _ := as_condition_list(condVal)
+	for _, b := range branches {
+		if reason, _ := m.check_conditions(&probe, b); reason == "" {
+			return b
+		}
+	}
+	return nil
+}
+
+// the nested conditions must fail
+func (m *Manager) check_not(req *Request, condVal interface{}) string {
+	inner, _ := condVal.(map[string]interface{})
+	if reason, _ := m.check_conditions(req, inner); reason == "" {
+		return fmt.Sprintf("Request matches negated condition %s", describe_conditions(inner))
+	}
+	return ""
+}
+
+func as_condition_list(condVal interface{}) ([]map[string]interface{}, error) {
+	items, ok := condVal.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list of conditions, got %T", condVal)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("condition list cannot be empty")
+	}
+	out := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		c, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a condition map, got %T", item)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// short human form of a condition map for deny reasons
+func describe_conditions(conditions map[string]interface{}) string {
+	parts := make([]string, 0, len(conditions))
+	for name, val := range conditions {
+		parts = append(parts, fmt.Sprintf("%s=%v", name, val))
+	}
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, ", ") + "}"
+}
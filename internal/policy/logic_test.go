@@ -0,0 +1,164 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBooleanConditions(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          or:
+            - currencies: [USD]
+            - max_amount: 100
+  - id: files-agent
+    allow:
+      - tool: files
+        actions: [read]
+        conditions:
+          folder_prefix: "/shared/"
+          not:
+            folder_prefix: "/shared/secret/"
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "logic.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		agentID   string
+		tool      string
+		action    string
+		params    map[string]interface{}
+		wantAllow bool
+	}{
+		{
+			name:      "or: first branch matches",
+			agentID:   "finance-agent",
+			tool:      "payments",
+			action:    "create",
+			params:    map[string]interface{}{"amount": 5000.0, "currency": "USD"},
+			wantAllow: true,
+		},
+		{
+			name:      "or: second branch matches",
+			agentID:   "finance-agent",
+			tool:      "payments",
+			action:    "create",
+			params:    map[string]interface{}{"amount": 50.0, "currency": "EUR"},
+			wantAllow: true,
+		},
+		{
+			name:      "or: no branch matches",
+			agentID:   "finance-agent",
+			tool:      "payments",
+			action:    "create",
+			params:    map[string]interface{}{"amount": 5000.0, "currency": "EUR"},
+			wantAllow: false,
+		},
+		{
+			name:      "not: outside negated prefix",
+			agentID:   "files-agent",
+			tool:      "files",
+			action:    "read",
+			params:    map[string]interface{}{"path": "/shared/report.txt"},
+			wantAllow: true,
+		},
+		{
+			name:      "not: inside negated prefix",
+			agentID:   "files-agent",
+			tool:      "files",
+			action:    "read",
+			params:    map[string]interface{}{"path": "/shared/secret/keys.txt"},
+			wantAllow: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := m.Evaluate(tt.agentID, tt.tool, tt.action, tt.params)
+			if d.Allow != tt.wantAllow {
+				t.Errorf("Evaluate() Allow = %v, want %v. Reason: %s", d.Allow, tt.wantAllow, d.Reason)
+			}
+		})
+	}
+}
+
+func TestBooleanConditions_NestedAnd(t *testing.T) {
+	m := &Manager{}
+	conditions := map[string]interface{}{
+		"or": []interface{}{
+			map[string]interface{}{
+				"and": []interface{}{
+					map[string]interface{}{"currencies": []interface{}{"EUR"}},
+					map[string]interface{}{"max_amount": 500},
+				},
+			},
+			map[string]interface{}{"currencies": []interface{}{"USD"}},
+		},
+	}
+
+	if reason, _ := m.check_conditions(&Request{AgentID: "a", Params: map[string]interface{}{"amount": 400.0, "currency": "EUR"}}, conditions); reason != "" {
+		t.Errorf("Expected nested and to pass, got %q", reason)
+	}
+	if reason, _ := m.check_conditions(&Request{AgentID: "a", Params: map[string]interface{}{"amount": 600.0, "currency": "EUR"}}, conditions); reason == "" {
+		t.Error("Expected nested and to fail on amount")
+	}
+}
+
+func TestBooleanConditions_Validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions map[string]interface{}
+		wantError  bool
+	}{
+		{
+			name:       "valid or",
+			conditions: map[string]interface{}{"or": []interface{}{map[string]interface{}{"max_amount": 1}}},
+		},
+		{
+			name:       "or not a list",
+			conditions: map[string]interface{}{"or": map[string]interface{}{"max_amount": 1}},
+			wantError:  true,
+		},
+		{
+			name:       "empty and",
+			conditions: map[string]interface{}{"and": []interface{}{}},
+			wantError:  true,
+		},
+		{
+			name:       "not with list",
+			conditions: map[string]interface{}{"not": []interface{}{}},
+			wantError:  true,
+		},
+		{
+			name: "invalid nested branch",
+			conditions: map[string]interface{}{"or": []interface{}{
+				map[string]interface{}{"not": "oops"},
+			}},
+			wantError: true,
+		},
+	}
+
+	m := &Manager{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.validate_conditions(tt.conditions)
+			if (err != nil) != tt.wantError {
+				t.Errorf("validate_conditions() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// returned by PaymentOwners when the payment doesn't exist
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// who created a payment, e.g. a lookup against the payments adapter. it's
+// consulted while evaluating, so it should answer quickly
+//
+//	conditions:
+//	  owns_payment: true
+type PaymentOwners interface {
+	// agent that created payment id. ctx ends with the request being
+	// evaluated
+	Owner(ctx context.Context, id string) (string, error)
+}
+
+type ownerLookup struct {
+	owner string
+	err   error
+}
+
+// po's answer for id, asked at most once per request: the shadow policy
+// reuses what the enforced one looked up
+func (r *Request) payment_owner(po PaymentOwners, id string) (string, error) {
+	if l, ok := r.owners[id]; ok {
+		return l.owner, l.err
+	}
+	owner, err := po.Owner(r.context(), id)
+	if r.owners != nil {
+		r.owners[id] = ownerLookup{owner, err}
+	}
+	return owner, err
+}
+
+// set where owns_payment looks up payment owners
+func (m *Manager) SetPaymentOwners(po PaymentOwners) {
+	m.update_settings(func(s *managerSettings) { s.paymentOwners = po })
+}
+
+// fails closed: a payment whose owner can't
+// be established is treated as someone else's
+func (m *Manager) check_owns_payment(req *Request) string {
+	id, ok := req.Params["payment_id"].(string)
+	if !ok || id == "" {
+		return "Invalid payment_id parameter"
+	}
+	po := m.settings().paymentOwners
+	if po == nil {
+		return fmt.Sprintf("Cannot verify owner of payment %s", id)
+	}
+	owner, err := req.payment_owner(po, id)
+	if errors.Is(err, ErrPaymentNotFound) {
+		return fmt.Sprintf("Payment %s not found", id)
+	}
+	if err != nil {
+		fmt.Printf("WARNING: owns_payment lookup for %s: %v\n", id, err)
+		return fmt.Sprintf("Cannot verify owner of payment %s", id)
+	}
+	if owner != req.AgentID {
+		return fmt.Sprintf("Payment %s belongs to another agent", id)
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type staticPaymentOwners map[string]string
+
+func (s staticPaymentOwners) Owner(_ context.Context, id string) (string, error) {
+	if id == "broken" {
+		return "", errors.New("adapter unreachable")
+	}
+	owner, ok := s[id]
+	if !ok {
+		return "", ErrPaymentNotFound
+	}
+	return owner, nil
+}
+
+func TestOwnsPayment(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [refund]
+        conditions:
+          owns_payment: true
+  - id: other-agent
+    allow:
+      - tool: payments
+        actions: [refund]
+        conditions:
+          owns_payment: true
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "refunds.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	refund := func(agentID string, params map[string]interface{}) Decision {
+		return m.EvaluateRequest(Request{AgentID: agentID, Tool: "payments", Action: "refund", Params: params})
+	}
+
+	// no lookup configured fails closed
+	if d := refund("finance-agent", map[string]interface{}{"payment_id": "p1"}); d.Allow || d.Reason != "Cannot verify owner of payment p1" {
+		t.Errorf("Expected denial without a lookup, got %+v", d)
+	}
+
+	m.SetPaymentOwners(staticPaymentOwners{"p1": "finance-agent"})
+	tests := []struct {
+		name   string
+		agent  string
+		params map[string]interface{}
+		reason string
+	}{
+		{"same agent", "finance-agent", map[string]interface{}{"payment_id": "p1"}, ""},
+		{"other agent", "other-agent", map[string]interface{}{"payment_id": "p1"}, "Payment p1 belongs to another agent"},
+		{"unknown payment", "finance-agent", map[string]interface{}{"payment_id": "p2"}, "Payment p2 not found"},
+		{"lookup error", "finance-agent", map[string]interface{}{"payment_id": "broken"}, "Cannot verify owner of payment broken"},
+		{"missing payment_id", "finance-agent", map[string]interface{}{}, "Invalid payment_id parameter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := refund(tt.agent, tt.params)
+			if tt.reason == "" && !d.Allow {
+				t.Errorf("Expected allow, got %s", d.Reason)
+			}
+			if tt.reason != "" && (d.Allow || d.Reason != tt.reason) {
+				t.Errorf("Expected denial %q, got %+v", tt.reason, d)
+			}
+		})
+	}
+}
+
+func TestOwnsPayment_Invalid(t *testing.T) {
+	m := &Manager{}
+	if err := m.validate_conditions(map[string]interface{}{"owns_payment": "yes"}); err == nil {
+		t.Error("Expected non-bool owns_payment to fail validation")
+	}
+}
+
+// records the lookups made and the context each one got
+type countingPaymentOwners struct {
+	owners staticPaymentOwners
+	calls  int
+	ctx    context.Context
+}
+
+func (c *countingPaymentOwners) Owner(ctx context.Context, id string) (string, error) {
+	c.calls++
+	c.ctx = ctx
+	return c.owners.Owner(ctx, id)
+}
+
+func TestOwnsPayment_LookupOncePerRequest(t *testing.T) {
+	policyContent := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [refund]
+        conditions:
+          owns_payment: true
+`
+	liveDir, shadowDir := t.TempDir(), t.TempDir()
+	writePolicies(t, liveDir, map[string]string{"refunds.yaml": policyContent})
+	writePolicies(t, shadowDir, map[string]string{"refunds.yaml": policyContent})
+	live, err := NewManager(liveDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	shadow, err := NewManager(shadowDir)
+	if err != nil {
+		t.Fatalf("Failed to create shadow manager: %v", err)
+	}
+	live.SetShadow(shadow)
+	owners := &countingPaymentOwners{owners: staticPaymentOwners{"p1": "finance-agent"}}
+	live.SetPaymentOwners(owners)
+	shadow.SetPaymentOwners(owners)
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	d := live.EvaluateRequest(Request{
+		AgentID: "finance-agent",
+		Tool:    "payments",
+		Action:  "refund",
+		Params:  map[string]interface{}{"payment_id": "p1"},
+		Context: ctx,
+	})
+	if !d.Allow || d.Shadow == nil || !d.Shadow.Allow {
+		t.Fatalf("Expected both policies to allow, got %+v", d)
+	}
+	if owners.calls != 1 {
+		t.Errorf("Expected one lookup shared with the shadow policy, got %d", owners.calls)
+	}
+	if owners.ctx != ctx {
+		t.Errorf("Expected the lookup to get the request's context")
+	}
+}
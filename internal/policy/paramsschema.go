@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// params_schema: the whole params object must validate against a JSON
+// Schema (draft 2020-12 unless the schema's $schema says otherwise),
+// written inline in YAML or JSON:
+//
+//	conditions:
+//	  params_schema:
+//	    type: object
+//	    required: [amount, vendor_id]
+//	    properties:
+//	      amount: {type: number, exclusiveMinimum: 0}
+//	      vendor_id: {type: string, pattern: "^V[0-9]+$"}
+//	    additionalProperties: false
+//
+// schemas are compiled when the policy loads, so a bad one fails the load.
+// remote $refs are not fetched
+const paramsSchemaURL = "urn:aegis:params_schema"
+
+// canonical JSON of a schema, its cache key
+func params_schema_source(condVal interface{}) (string, error) {
+	switch condVal.(type) {
+	case map[string]interface{}, bool:
+	default:
+		return "", fmt.Errorf("params_schema: expected a schema object, got %T", condVal)
+	}
+	b, err := json.Marshal(condVal)
+	if err != nil {
+		return "", fmt.Errorf("params_schema: %w", err)
+	}
+	return string(b), nil
+}
+
+// compile a schema once and reuse it across evaluations and reloads
+func (m *Manager) compiled_params_schema(condVal interface{}) (*jsonschema.Schema, error) {
+	source, err := params_schema_source(condVal)
+	if err != nil {
+		return nil, err
+	}
+
+	m.schemaMu.Lock()
+	defer m.schemaMu.Unlock()
+	if sch, ok := m.schemas[source]; ok {
+		return sch, nil
+	}
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(source))
+	if err != nil {
+		return nil, fmt.Errorf("params_schema: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.UseLoader(noSchemaLoader{})
+	if err := c.AddResource(paramsSchemaURL, doc); err != nil {
+		return nil, fmt.Errorf("params_schema: %w", err)
+	}
+	sch, err := c.Compile(paramsSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("params_schema: %w", err)
+	}
+	if m.schemas == nil {
+		m.schemas = make(map[string]*jsonschema.Schema)
+	}
+	m.schemas[source] = sch
+	return sch, nil
+}
+
+// refuses every remote $ref
+type noSchemaLoader struct{}
+
+func (noSchemaLoader) Load(url string) (any, error) {
+	return nil, fmt.Errorf("loading %s is not allowed", url)
+}
+
+// "" when params conform, else the first schema violation
+func (m *Manager) check_params_schema(params map[string]interface{}, condVal interface{}) string {
+	sch, err := m.compiled_params_schema(condVal)
+	if err != nil {
+		return "Invalid params_schema in policy"
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	// round-trip so numbers are what the validator expects
+	b, err := json.Marshal(params)
+	if err != nil {
+		return "Invalid parameters"
+	}
+	inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(b))
+	if err != nil {
+		return "Invalid parameters"
+	}
+	err = sch.Validate(inst)
+	if err == nil {
+		return ""
+	}
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return fmt.Sprintf("Parameters failed params_schema: %v", err)
+	}
+	return "Parameters failed params_schema: " + schema_violation(ve)
+}
+
+// the first leaf error, e.g. "at '/amount': maximum: got 6,000, want 5,000"
+func schema_violation(ve *jsonschema.ValidationError) string {
+	for _, u := range ve.BasicOutput().Errors {
+		if u.Error == nil || u.Error.Kind == nil {
+			continue
+		}
+		loc := u.InstanceLocation
+		if loc == "" {
+			loc = "/"
+		}
+		return fmt.Sprintf("at '%s': %s", loc, u.Error.String())
+	}
+	return ve.Error()
+}
@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+const paramsSchemaPolicy = `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          params_schema:
+            type: object
+            required: [amount, vendor_id]
+            properties:
+              amount: {type: number, exclusiveMinimum: 0, maximum: 5000}
+              currency: {enum: [USD, EUR]}
+              vendor_id: {type: string, pattern: "^V[0-9]+$"}
+            additionalProperties: false
+`
+
+func TestParamsSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"schema.yaml": paramsSchemaPolicy})
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if errs := m.LoadErrors(); len(errs) != 0 {
+		t.Fatalf("Expected the schema to compile, got %+v", errs)
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		reason string
+	}{
+		{"conforming", map[string]interface{}{"amount": 100.0, "currency": "USD", "vendor_id": "V42"}, ""},
+		{"too large", map[string]interface{}{"amount": 6000.0, "vendor_id": "V42"}, "Parameters failed params_schema: at '/amount': maximum: got 6,000, want 5,000"},
+		{"missing field", map[string]interface{}{"amount": 100.0}, "Parameters failed params_schema: at '/': missing property 'vendor_id'"},
+		{"bad pattern", map[string]interface{}{"amount": 100.0, "vendor_id": "X1"}, "at '/vendor_id'"},
+		{"wrong type", map[string]interface{}{"amount": "100", "vendor_id": "V1"}, "at '/amount'"},
+		{"extra field", map[string]interface{}{"amount": 100.0, "vendor_id": "V1", "memo": "hi"}, "additional properties 'memo' not allowed"},
+		{"no params", nil, "missing properties"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := m.Evaluate("finance-agent", "payments", "create", tt.params)
+			if tt.reason == "" {
+				if !d.Allow {
+					t.Errorf("Expected allow, got %s", d.Reason)
+				}
+				return
+			}
+			if d.Allow || !strings.Contains(d.Reason, tt.reason) {
+				t.Errorf("Expected denial containing %q, got %+v", tt.reason, d)
+			}
+			if strings.Contains(d.Reason, "\n") {
+				t.Errorf("Expected a single line reason, got %q", d.Reason)
+			}
+			if got := ReasonCategory(d.Reason); got != CategoryInvalidParams {
+				t.Errorf("Expected category %s, got %s", CategoryInvalidParams, got)
+			}
+		})
+	}
+}
+
+func TestParamsSchemaInvalidRejectedAtLoad(t *testing.T) {
+	for name, schema := range map[string]string{
+		"unknown type":   "{type: money}",
+		"bad pattern":    `{type: string, pattern: "("}`,
+		"not an object":  `"string"`,
+		"remote ref":     `{$ref: "https://example.com/schema.json"}`,
+		"bad min length": "{minLength: -1}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writePolicies(t, tmpDir, map[string]string{"schema.yaml": "version: 1\nagents:\n  - id: a\n    allow:\n      - tool: payments\n        actions: [create]\n        conditions:\n          params_schema: " + schema + "\n"})
+			m, err := NewManager(tmpDir)
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			errs := m.LoadErrors()
+			if len(errs) != 1 || !strings.Contains(errs[0].Error, "params_schema") {
+				t.Errorf("Expected the file to be rejected for its params_schema, got %+v", errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package policy
+
+import (
+	"path"
+	"strings"
+)
+
+// a request path as the files adapter resolves it: rooted, with "." and
+// ".." segments and duplicate slashes removed, so /hr-docs/../legal/x
+// is checked as /legal/x
+func clean_path(p string) string {
+	return path.Clean("/" + p)
+}
+
+// whether p is prefix or lies beneath it. both sides are cleaned and the
+// match has to end on a segment boundary, so /hr-docs covers
+// /hr-docs/a.pdf but not /hr-docs-archive/a.pdf
+func under_prefix(p, prefix string) bool {
+	p, prefix = clean_path(p), clean_path(prefix)
+	if prefix == "/" {
+		return true
+	}
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
@@ -0,0 +1,65 @@
+package policy
+
+import "testing"
+
+func TestPathPrefixCanonicalization(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions map[string]interface{}
+		path       string
+		wantReason string
+	}{
+		{
+			name:       "traversal out of prefix",
+			conditions: map[string]interface{}{"path_prefix": "/hr-docs/"},
+			path:       "/hr-docs/../legal/secret.pdf",
+			wantReason: "Path /hr-docs/../legal/secret.pdf does not match required prefix /hr-docs/",
+		},
+		{
+			name:       "prefix without a segment boundary",
+			conditions: map[string]interface{}{"path_prefix": "/hr-docs"},
+			path:       "/hr-docs-archive/salaries.pdf",
+			wantReason: "Path /hr-docs-archive/salaries.pdf does not match required prefix /hr-docs",
+		},
+		{
+			name:       "legitimate nested file",
+			conditions: map[string]interface{}{"path_prefix": "/hr-docs/"},
+			path:       "/hr-docs/policies/2024/handbook.pdf",
+			wantReason: "",
+		},
+		{
+			name:       "redundant segments inside the prefix",
+			conditions: map[string]interface{}{"path_prefix": "/hr-docs"},
+			path:       "/hr-docs//./policies/../handbook.pdf",
+			wantReason: "",
+		},
+		{
+			name:       "traversal into a denied prefix",
+			conditions: map[string]interface{}{"path_denied_prefix": "/secret/"},
+			path:       "/public/../secret/keys.txt",
+			wantReason: "Path /public/../secret/keys.txt matches prefix /secret/ denied by path_denied_prefix",
+		},
+		{
+			name:       "denied prefix needs a segment boundary",
+			conditions: map[string]interface{}{"path_denied_prefix": "/secret"},
+			path:       "/secretary/notes.txt",
+			wantReason: "",
+		},
+		{
+			name:       "regex sees the cleaned path",
+			conditions: map[string]interface{}{"path_regex": `^/hr-docs/.*\.pdf$`},
+			path:       "/hr-docs/../legal/contract.pdf",
+			wantReason: "Path /hr-docs/../legal/contract.pdf does not match required pattern ^/hr-docs/.*\\.pdf$",
+		},
+	}
+
+	m := &Manager{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, _ := m.check_conditions(&Request{AgentID: "a", Params: map[string]interface{}{"path": tt.path}}, tt.conditions)
+			if reason != tt.wantReason {
+				t.Errorf("check_conditions() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
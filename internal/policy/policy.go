@@ -1,52 +1,160 @@
 package policy
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
+	"regexp"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/expr-lang/expr/vm"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
-// Policy stuff - main structure for YAML files
+// Policy stuff - main structure for YAML and JSON files
 type Policy struct {
-	Version int      `yaml:"version"`
-	Agents  []Agent  `yaml:"agents"`
+	// revision of the file, reported with every decision
+	Version int `yaml:"version" json:"version"`
+
+	// format of the file, see CurrentSchemaVersion. 0 means 1
+	SchemaVersion int `yaml:"schema_version" json:"schema_version,omitempty"`
+
+	// fallthrough for this file's agents, see DefaultActionAllow
+	DefaultAction string `yaml:"default_action" json:"default_action,omitempty"`
+
+	// reusable permissions, referenced with `use`
+	Templates map[string]Permission `yaml:"templates" json:"templates,omitempty"`
+
+	// reusable permission sets, joined with Agent.Roles
+	Roles map[string]Role `yaml:"roles" json:"roles,omitempty"`
+
+	// shared daily spend caps, joined with Agent.QuotaGroup
+	QuotaGroups map[string]QuotaGroup `yaml:"quota_groups" json:"quota_groups,omitempty"`
+
+	Agents []Agent `yaml:"agents" json:"agents"`
 }
 
 type Agent struct {
-	ID    string       `yaml:"id"`
-	Allow []Permission `yaml:"allow"`
+	ID    string       `yaml:"id" json:"id"`
+	Allow []Permission `yaml:"allow" json:"allow"`
+
+	// checked before Allow; a match denies even if an allow rule matches
+	Deny []Permission `yaml:"deny" json:"deny,omitempty"`
+
+	// roles from Policy.Roles whose rules are added to Allow and Deny,
+	// see apply_roles
+	Roles []string `yaml:"roles" json:"roles,omitempty"`
+
+	// overrides the file's default_action for this agent
+	DefaultAction string `yaml:"default_action" json:"default_action,omitempty"`
+
+	// hex SHA-256 of the agent's API key, see HashAPIKey
+	APIKeySHA256 string `yaml:"api_key_sha256" json:"api_key_sha256,omitempty"`
+
+	// false suspends the agent, see disabled_decision. nil means enabled
+	Enabled *bool `yaml:"enabled" json:"enabled,omitempty"`
+
+	// quota group the agent spends from and its weight there, see
+	// QuotaGroup. 0 weight means 1
+	QuotaGroup  string  `yaml:"quota_group" json:"quota_group,omitempty"`
+	QuotaWeight float64 `yaml:"quota_weight" json:"quota_weight,omitempty"`
 }
 
 type Permission struct {
-	Tool       string                 `yaml:"tool"`
-	Actions    []string               `yaml:"actions"`
-	Conditions map[string]interface{} `yaml:"conditions"`
+	Tool       string                 `yaml:"tool" json:"tool"`
+	Actions    []string               `yaml:"actions" json:"actions"`
+	Conditions map[string]interface{} `yaml:"conditions" json:"conditions,omitempty"`
+
+	// template from Policy.Templates to start from, see apply_templates
+	Use string `yaml:"use" json:"use,omitempty"`
+
+	// when the permission applies, see in_window. nil is unbounded
+	ValidFrom  *time.Time `yaml:"valid_from" json:"valid_from,omitempty"`
+	ValidUntil *time.Time `yaml:"valid_until" json:"valid_until,omitempty"`
 }
 
 // result of policy check
 type Decision struct {
-	Allow   bool
-	Reason  string
-	Version int
+	Allow   bool   `json:"allow"`
+	Reason  string `json:"reason"`
+	Version int    `json:"version"`
+
+	// stable code for Reason, see ReasonCode
+	ReasonCode ReasonCode `json:"reason_code"`
+
+	// conditions evaluated and their outcome, only for Debug requests
+	Trace []ConditionResult `json:"trace,omitempty"`
+
+	// what the shadow policies would have decided, see SetShadow
+	Shadow *Decision `json:"shadow,omitempty"`
 }
 
 type Manager struct {
-	mu       sync.RWMutex
-	policies map[string]Policy
-	dir      string
+	// serializes writers (loads and the setters). evaluations never take
+	// it, they read active and conf
+	mu sync.Mutex
+
+	// the loaded policies, see policySnapshot
+	active atomic.Pointer[policySnapshot]
+
+	// clock and lookups, see managerSettings
+	conf atomic.Pointer[managerSettings]
+
+	// policy directories, highest precedence first
+	dirs []string
+
+	// compiled regex conditions keyed by pattern
+	regexMu sync.Mutex
+	regexes map[string]*regexp.Regexp
+
+	// compiled expr conditions keyed by source
+	exprMu sync.Mutex
+	exprs  map[string]*vm.Program
+
+	// compiled params_schema conditions keyed by canonical JSON
+	schemaMu sync.Mutex
+	schemas  map[string]*jsonschema.Schema
+
+	// policies evaluated alongside but never enforced, see SetShadow
+	shadow atomic.Pointer[Manager]
+
+	// last-seen values for monotonic_field conditions
+	sequences *sequenceTracker
+
+	// vendors paid per agent for max_distinct_vendors conditions
+	vendors *vendorTracker
+
+	// bytes written per agent per day for max_daily_write_bytes conditions
+	writes *writeBudgetTracker
+
+	// payments per agent within the last 24h for daily_limit conditions
+	spends *spendTracker
+
+	// spending per agent and quota group for quota_groups
+	quotas *quotaTracker
+
+	// approval tokens already used for require_dual_approval
+	approvals *approvalNonces
 }
 
-func NewManager(dir string) (*Manager, error) {
+// load policies from one or more directories. with several, all of them
+// form one set: agents may be spread across directories, and for rules
+// of equal specificity the earlier directory wins, see policy_sources
+func NewManager(dirs ...string) (*Manager, error) {
+	if err := check_policy_dirs(dirs); err != nil {
+		return nil, err
+	}
 	m := &Manager{
-		policies: make(map[string]Policy),
-		dir:      dir,
+		dirs:      dirs,
+		sequences: newSequenceTracker(),
+		vendors:   newVendorTracker(),
+		writes:    newWriteBudgetTracker(),
+		spends:    newSpendTracker(),
+		quotas:    newQuotaTracker(),
+		approvals: newApprovalNonces(),
 	}
 	err := m.load_policies()
 	if err != nil {
@@ -55,46 +163,125 @@ func NewManager(dir string) (*Manager, error) {
 	return m, nil
 }
 
+// startup load: bad files are skipped so one broken file doesn't keep the
+// gateway down. Reload is stricter, see there
 func (m *Manager) load_policies() error {
-	entries, err := os.ReadDir(m.dir)
+	set, err := m.read_policies()
 	if err != nil {
-		return fmt.Errorf("failed to read policies directory: %w", err)
+		return err
+	}
+	for _, e := range m.check_policy_set(set.policies, set.order) {
+		fmt.Printf("ERROR: %s\n", e.Error)
+		delete(set.policies, e.File)
+		set.errors = append(set.errors, e)
 	}
 
+	snap := new_policy_snapshot(set.policies, set.order)
+	snap.loadErrors, snap.loadNotes, snap.loadedAt = set.errors, set.notes, m.now()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.active.Store(snap)
+	return nil
+}
 
-	// clear old policies and load fresh ones
-	newPolicies := make(map[string]Policy)
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
-			continue
-		}
+// everything read from the policy directories in one pass
+type policySet struct {
+	policies map[string]Policy
+
+	// file names in precedence order
+	order []string
+	errors   []PolicyLoadError
+	notes    []PolicyLoadNote
+}
+
+// read, migrate and validate every policy file under the directories
+// without touching the active set. files are keyed by their slash
+// separated path relative to the directory, e.g. team-a/payments.yaml
+func (m *Manager) read_policies() (policySet, error) {
+	files, err := m.policy_sources()
+	if err != nil {
+		return policySet{}, err
+	}
 
-		policyPath := filepath.Join(m.dir, entry.Name())
+	set := policySet{policies: make(map[string]Policy)}
+	fail := func(name string, err error) {
+		fmt.Printf("ERROR: %v\n", err)
+		set.errors = append(set.errors, PolicyLoadError{
+			File:      name,
+			Error:     err.Error(),
+			Timestamp: m.now().UTC().Format(time.RFC3339),
+		})
+	}
+	for _, src := range files {
+		name, policyPath := src.name, src.path
 		fileData, err := os.ReadFile(policyPath)
 		if err != nil {
-			fmt.Printf("ERROR: failed to read policy file %s: %v\n", policyPath, err)
+			fail(name, fmt.Errorf("failed to read policy file %s: %w", policyPath, err))
 			continue
 		}
 
-		var pol Policy
-		if err := yaml.Unmarshal(fileData, &pol); err != nil {
-			fmt.Printf("ERROR: failed to parse policy file %s: %v\n", policyPath, err)
+		pol, notes, stage, err := m.prepare_policy(name, fileData)
+		if stage == StageParse {
+			fail(name, fmt.Errorf("failed to parse policy file %s: %w", policyPath, err))
 			continue
 		}
-
-		// validate before adding
-		if err := m.check_policy_valid(&pol); err != nil {
-			fmt.Printf("ERROR: invalid policy file %s: %v\n", policyPath, err)
+		if err != nil {
+			fail(name, fmt.Errorf("invalid policy file %s: %w", policyPath, err))
 			continue
 		}
 
-		newPolicies[entry.Name()] = pol
+		set.policies[name] = pol
+		set.order = append(set.order, name)
+		for _, note := range notes {
+			fmt.Printf("NOTE: policy file %s: %s\n", policyPath, note)
+			set.notes = append(set.notes, PolicyLoadNote{
+				File:      name,
+				Note:      note,
+				Timestamp: m.now().UTC().Format(time.RFC3339),
+			})
+		}
 	}
+	return set, nil
+}
 
-	m.policies = newPolicies
-	return nil
+// stages a policy file goes through on load, in order
+const (
+	StageEnv       = "env"
+	StageParse     = "parse"
+	StageRoles     = "roles"
+	StageTemplates = "templates"
+	StageSchema    = "schema"
+	StageValidate  = "validate"
+	// checked against the other files, see check_policy_set
+	StageSet = "set"
+)
+
+// turn one file's contents into a validated policy, with the migration
+// notes. on failure the stage it failed at is returned with the error
+func (m *Manager) prepare_policy(name string, data []byte) (Policy, []string, string, error) {
+	data, err := interpolate_env(data)
+	if err != nil {
+		return Policy{}, nil, StageEnv, err
+	}
+	pol, err := parse_policy(name, data)
+	if err != nil {
+		return Policy{}, nil, StageParse, err
+	}
+	if err := apply_roles(&pol); err != nil {
+		return Policy{}, nil, StageRoles, err
+	}
+	if err := apply_templates(&pol); err != nil {
+		return Policy{}, nil, StageTemplates, err
+	}
+	notes, err := migrate_policy(&pol)
+	if err != nil {
+		return Policy{}, nil, StageSchema, err
+	}
+	if err := m.check_policy_valid(&pol); err != nil {
+		return Policy{}, nil, StageValidate, err
+	}
+	return pol, notes, "", nil
 }
 
 func (m *Manager) check_policy_valid(p *Policy) error {
@@ -105,149 +292,776 @@ func (m *Manager) check_policy_valid(p *Policy) error {
 	if len(p.Agents) == 0 {
 		return fmt.Errorf("policy must have at least one agent")
 	}
+	if !valid_default_action(p.DefaultAction) {
+		return fmt.Errorf("default_action must be %s or %s", DefaultActionAllow, DefaultActionDeny)
+	}
+	if err := validate_quota_groups(p); err != nil {
+		return err
+	}
 	for _, agent := range p.Agents {
 		if agent.ID == "" {
 			return fmt.Errorf("agent ID cannot be empty")
 		}
+		if !valid_default_action(agent.DefaultAction) {
+			return fmt.Errorf("agent %s: default_action must be %s or %s", agent.ID, DefaultActionAllow, DefaultActionDeny)
+		}
+		if agent.APIKeySHA256 != "" {
+			if err := validate_api_key_hash(agent.APIKeySHA256); err != nil {
+				return fmt.Errorf("agent %s: %w", agent.ID, err)
+			}
+		}
+		for _, perm := range agent.Allow {
+			if err := validate_window(perm); err != nil {
+				return fmt.Errorf("agent %s, tool %s: %w", agent.ID, perm.Tool, err)
+			}
+			if err := m.validate_conditions(perm.Conditions); err != nil {
+				return fmt.Errorf("agent %s, tool %s: %w", agent.ID, perm.Tool, err)
+			}
+		}
+		for _, perm := range agent.Deny {
+			if perm.Tool == "" || len(perm.Actions) == 0 {
+				return fmt.Errorf("agent %s: deny rules need a tool and at least one action", agent.ID)
+			}
+			if err := validate_window(perm); err != nil {
+				return fmt.Errorf("agent %s, deny tool %s: %w", agent.ID, perm.Tool, err)
+			}
+			if err := m.validate_conditions(perm.Conditions); err != nil {
+				return fmt.Errorf("agent %s, deny tool %s: %w", agent.ID, perm.Tool, err)
+			}
+		}
+	}
+	return nil
+}
+
+// check condition values at load time so a bad policy never passes traffic
+func (m *Manager) validate_conditions(conditions map[string]interface{}) error {
+	for name, val := range conditions {
+		switch name {
+		case "and", "or":
+			branches, err := as_condition_list(val)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			for _, b := range branches {
+				if err := m.validate_conditions(b); err != nil {
+					return err
+				}
+			}
+		case "not":
+			inner, ok := val.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("not: expected a condition map, got %T", val)
+			}
+			if len(inner) == 0 {
+				return fmt.Errorf("not: condition map cannot be empty")
+			}
+			if err := m.validate_conditions(inner); err != nil {
+				return err
+			}
+		case "max_distinct_vendors":
+			if _, err := parse_vendor_limit(val); err != nil {
+				return err
+			}
+		case "require_dual_approval":
+			if _, err := parse_dual_approval(val); err != nil {
+				return err
+			}
+		case "max_daily_write_bytes":
+			if _, err := parse_write_budget(val); err != nil {
+				return err
+			}
+		case "daily_limit":
+			if _, err := parse_daily_limit(val); err != nil {
+				return err
+			}
+		case "params_constraints":
+			if _, err := parse_params_constraints(val); err != nil {
+				return err
+			}
+		case "params_schema":
+			if _, err := m.compiled_params_schema(val); err != nil {
+				return err
+			}
+		case "field_in":
+			if _, err := parse_field_in(val); err != nil {
+				return err
+			}
+		case "required_params":
+			if _, err := parse_required_params(val); err != nil {
+				return err
+			}
+		case "currencies_denied", "path_denied_prefix":
+			if _, err := parse_denied_list(name, val); err != nil {
+				return err
+			}
+		case "allowed_hours":
+			if _, err := parse_allowed_hours(val); err != nil {
+				return err
+			}
+		case "require_change_window":
+			if _, ok := val.(bool); !ok {
+				return fmt.Errorf("require_change_window: expected a bool, got %T", val)
+			}
+		case "owns_payment":
+			if _, ok := val.(bool); !ok {
+				return fmt.Errorf("owns_payment: expected a bool, got %T", val)
+			}
+		case "expr":
+			source, ok := val.(string)
+			if !ok {
+				return fmt.Errorf("expr: expected a string, got %T", val)
+			}
+			if _, err := m.compiled_expr(source); err != nil {
+				return fmt.Errorf("expr: %w", err)
+			}
+		case "folder_prefix":
+			return fmt.Errorf("folder_prefix was renamed to path_prefix in schema_version 2")
+		case "memo_regex", "path_regex":
+			pattern, ok := val.(string)
+			if !ok {
+				return fmt.Errorf("%s: expected a string, got %T", name, val)
+			}
+			if _, err := m.compiled_regex(pattern); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
 	}
 	return nil
 }
 
-// reload all policies from disk
+// names of the policy files currently loaded, sorted
+func (m *Manager) PolicyFiles() []string {
+	policies := m.snapshot().policies
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// reload all policies from disk. the new set only replaces the active one
+// if every file loads and the files agree with each other; otherwise the
+// previous set stays fully active and the failures are returned as one
+// error and kept in LoadErrors. the new set is built completely before
+// it's swapped in, so evaluations running meanwhile aren't held up and
+// finish against the set they started with
 func (m *Manager) Reload() error {
-	return m.load_policies()
+	set, err := m.read_policies()
+	if err != nil {
+		return err
+	}
+	set.errors = append(set.errors, m.check_policy_set(set.policies, set.order)...)
+
+	if len(set.errors) > 0 {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		// same set, only the errors change
+		snap := *m.snapshot()
+		snap.loadErrors = set.errors
+		m.active.Store(&snap)
+		return LoadErrorList(set.errors)
+	}
+
+	snap := new_policy_snapshot(set.policies, set.order)
+	snap.loadNotes, snap.loadedAt = set.notes, m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active.Store(snap)
+	return nil
+}
+
+// a tool request as seen by the policy engine
+type Request struct {
+	AgentID string
+	Tool    string
+	Action  string
+	Params  map[string]interface{}
+
+	// request headers (canonical name -> first value) for conditions that
+	// look beyond the body, e.g. X-Approver
+	Headers map[string]string
+
+	// collect a per-condition trace into Decision.Trace
+	Debug bool
+
+	// evaluate without recording state for stateful conditions
+	// (daily_limit, max_distinct_vendors, ...)
+	DryRun bool
+
+	// only accept JSON numbers for amount; by default numeric strings
+	// like "1000" are parsed
+	StrictAmounts bool
+
+	// the request's own context: lookups made while evaluating
+	// (owns_payment) give up with it. nil means context.Background()
+	Context context.Context
+
+	trace []ConditionResult
+	// owns_payment answers already looked up for this request, shared
+	// with its shadow evaluation
+	owners map[string]ownerLookup
+}
+
+func (r *Request) context() context.Context {
+	if r.Context == nil {
+		return context.Background()
+	}
+	return r.Context
 }
 
 // check if agent can do this action
 func (m *Manager) Evaluate(agentID, tool, action string, params map[string]interface{}) Decision {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return m.EvaluateRequest(Request{
+		AgentID: agentID,
+		Tool:    tool,
+		Action:  action,
+		Params:  params,
+	})
+}
 
-	// loop through all loaded policies
-	for _, policy := range m.policies {
-		for _, agent := range policy.Agents {
-			if agent.ID != agentID {
-				continue
-			}
+// like Evaluate, with access to the request headers
+func (m *Manager) EvaluateRequest(req Request) Decision {
+	req.owners = make(map[string]ownerLookup)
+	d := m.evaluate(req)
+	if s := m.shadow.Load(); s != nil {
+		sd := s.evaluate(shadow_request(req, d))
+		d.Shadow = &sd
+	}
+	return d
+}
 
-			// found the agent, check permissions
-			for _, perm := range agent.Allow {
-				if perm.Tool != tool {
-					continue
-				}
+// the enforced decision, without the shadow one
+func (m *Manager) evaluate(req Request) Decision {
+	agentID, tool, action := req.AgentID, req.Tool, req.Action
 
-				// check if action is in allowed list
-				actionAllowed := false
-				for _, a := range perm.Actions {
-					if a == action {
-						actionAllowed = true
-						break
-					}
-				}
-				if !actionAllowed {
-					continue
-				}
+	// one snapshot for the whole evaluation, a concurrent reload can't
+	// change the rules halfway through
+	snap := m.snapshot()
 
-				// check conditions (amount, currency, path, etc)
-				if reason := m.check_conditions(perm.Conditions, params); reason != "" {
-					return Decision{
-						Allow:   false,
-						Reason:  reason,
-						Version: policy.Version,
-					}
-				}
+	if d, ok := snap.disabled_decision(agentID); ok {
+		return d
+	}
 
-				// all checks passed!
-				return Decision{
-					Allow:   true,
-					Reason:  "Policy allows this action",
-					Version: policy.Version,
-				}
+	if perm, version, ok := m.matching_deny(snap, &req); ok {
+		return Decision{
+			Allow:      false,
+			Reason:     fmt.Sprintf("Denied by deny rule for tool=%s, actions=%v", perm.Tool, perm.Actions),
+			Version:    version,
+			ReasonCode: ReasonActionDenied,
+		}
+	}
+
+	// most specific matching permission decides, so an exact tool/action
+	// entry overrides a "*" one
+	candidates := m.candidates(snap, agentID, tool, action)
+	if len(candidates) == 0 {
+		d := snap.default_decision(agentID, tool, action)
+		if d.Allow {
+			if reason, _ := m.charge_quota(snap, &req); reason != "" {
+				d.Allow, d.Reason, d.ReasonCode = false, reason, condition_code("daily_limit", reason)
 			}
 		}
+		return d
 	}
+	perm, version := candidates[0].perm, candidates[0].version
 
-	// no matching policy found
+	// check conditions (amount, currency, path, etc)
+	if reason, code := m.check_conditions(&req, perm.Conditions); reason != "" {
+		return Decision{
+			Allow:      false,
+			Reason:     reason,
+			Version:    version,
+			Trace:      req.trace,
+			ReasonCode: code,
+		}
+	}
+
+	// quota groups span all of the agent's rules, so they're charged
+	// apart from the conditions
+	reason, refund := m.charge_quota(snap, &req)
+	if reason != "" {
+		return Decision{
+			Allow:      false,
+			Reason:     reason,
+			Version:    version,
+			Trace:      req.trace,
+			ReasonCode: condition_code("daily_limit", reason),
+		}
+	}
+
+	// record stateful values only once the request is allowed. a request
+	// denied here mustn't count against its quota group either
+	if !req.DryRun {
+		if reason, code := m.commit_state(&req, perm.Conditions); reason != "" {
+			refund()
+			return Decision{
+				Allow:      false,
+				Reason:     reason,
+				Version:    version,
+				Trace:      req.trace,
+				ReasonCode: code,
+			}
+		}
+	}
+
+	// all checks passed!
 	return Decision{
-		Allow:  false,
-		Reason: fmt.Sprintf("No policy found for agent=%s, tool=%s, action=%s", agentID, tool, action),
+		Allow:      true,
+		Reason:     "Policy allows this action",
+		Version:    version,
+		Trace:      req.trace,
+		ReasonCode: ReasonOK,
+	}
+}
+
+// matches any tool, or any action in Permission.Actions
+const Wildcard = "*"
+
+// a permission matching a request, with the version of its policy file
+type candidate struct {
+	perm        Permission
+	version     int
+	specificity int
+}
+
+// permissions of agentID covering tool/action, most specific first:
+// exact tool beats "*" tool, then exact action beats "*" action.
+// permissions outside their valid_from/valid_until window are left out
+func (m *Manager) candidates(snap *policySnapshot, agentID, tool, action string) []candidate {
+	ai := snap.index[agentID]
+	if ai == nil {
+		return nil
 	}
+	return active_candidates(ai.allow.lookup(tool, action), m.now())
 }
 
-func (m *Manager) check_conditions(conditions map[string]interface{}, params map[string]interface{}) string {
+// first failing condition's reason and code, "" if all of them pass
+func (m *Manager) check_conditions(req *Request, conditions map[string]interface{}) (string, ReasonCode) {
 	// iterate through each condition and validate
 	for condName, condVal := range conditions {
-		switch condName {
-		case "max_amount":
-			var maxAmt float64
-			// handle different number types from yaml
-			switch v := condVal.(type) {
-			case float64:
-				maxAmt = v
-			case int:
-				maxAmt = float64(v)
-			default:
-				fmt.Printf("WARNING: invalid max_amount type in policy: %T\n", condVal)
-				continue
-			}
-			
-			amt, ok := params["amount"].(float64)
-			if !ok {
-				return "Invalid amount parameter"
-			}
-			if amt > maxAmt {
-				return fmt.Sprintf("Amount %.2f exceeds max_amount=%.2f", amt, maxAmt)
-			}
+		reason, code := m.check_condition(req, condName, condVal)
+		req.record(condName, reason)
+		if reason != "" {
+			return reason, code
+		}
+	}
+	return "", ""
+}
 
-		case "currencies":
-			allowedCurrs, ok := condVal.([]interface{})
-			if !ok {
-				fmt.Printf("WARNING: invalid currencies type in policy: %T\n", condVal)
-				continue
-			}
-			curr, ok := params["currency"].(string)
-			if !ok {
-				return "Invalid currency parameter"
-			}
-			
-			// check if currency is in the allowed list
-			currencyFound := false
-			for _, c := range allowedCurrs {
-				cStr, ok := c.(string)
-				if !ok {
-					continue
-				}
-				if cStr == curr {
-					currencyFound = true
-					break
-				}
-			}
-			if !currencyFound {
-				return fmt.Sprintf("Currency %s not in allowed list", curr)
-			}
+// evaluate one condition, "" if it passes. an "and" keeps the code of the
+// branch that failed
+func (m *Manager) check_condition(req *Request, condName string, condVal interface{}) (string, ReasonCode) {
+	if condName == "and" {
+		return m.check_and(req, condVal)
+	}
+	if reason := m.condition_reason(req, condName, condVal); reason != "" {
+		return reason, condition_code(condName, reason)
+	}
+	return "", ""
+}
 
-		case "folder_prefix":
-			pfx, ok := condVal.(string)
-			if !ok {
-				fmt.Printf("WARNING: invalid folder_prefix type in policy: %T\n", condVal)
-				continue
+func (m *Manager) condition_reason(req *Request, condName string, condVal interface{}) string {
+	agentID, params := req.AgentID, req.Params
+
+	switch condName {
+	case "or":
+		if reason := m.check_or(req, condVal); reason != "" {
+			return reason
+		}
+
+	case "not":
+		if reason := m.check_not(req, condVal); reason != "" {
+			return reason
+		}
+
+	case "max_amount":
+		var maxAmt float64
+		// handle different number types from yaml
+		switch v := condVal.(type) {
+		case float64:
+			maxAmt = v
+		case int:
+			maxAmt = float64(v)
+		default:
+			fmt.Printf("WARNING: invalid max_amount type in policy: %T\n", condVal)
+			return ""
+		}
+		
+		amt, ok := req.amount()
+		if !ok {
+			return "Invalid amount parameter"
+		}
+		if amt > maxAmt {
+			return fmt.Sprintf("Amount %.2f exceeds max_amount=%.2f", amt, maxAmt)
+		}
+
+	case "min_amount":
+		var minAmt float64
+		switch v := condVal.(type) {
+		case float64:
+			minAmt = v
+		case int:
+			minAmt = float64(v)
+		default:
+			fmt.Printf("WARNING: invalid min_amount type in policy: %T\n", condVal)
+			return ""
+		}
+
+		amt, ok := req.amount()
+		if !ok {
+			return "Invalid amount parameter"
+		}
+		if amt < minAmt {
+			return fmt.Sprintf("Amount %.2f is below min_amount=%.2f", amt, minAmt)
+		}
+
+	case "params_constraints":
+		if reason := m.check_params_constraints(params, condVal); reason != "" {
+			return reason
+		}
+
+	case "currencies":
+		f, ok := currencies_field(condVal)
+		if !ok {
+			fmt.Printf("WARNING: invalid currencies type in policy: %T\n", condVal)
+			return ""
+		}
+		curr, _, valid, found := f.match(params)
+		if !valid {
+			return "Invalid currency parameter"
+		}
+		if !found {
+			return fmt.Sprintf("Currency %s not in allowed list", curr)
+		}
+
+	case "currencies_denied":
+		if reason := check_currencies_denied(params, condVal); reason != "" {
+			return reason
+		}
+
+	case "field_in":
+		if reason := m.check_field_in(params, condVal); reason != "" {
+			return reason
+		}
+
+	case "required_params":
+		if reason := m.check_required_params(params, condVal); reason != "" {
+			return reason
+		}
+
+	case "path_prefix":
+		pfx, ok := condVal.(string)
+		if !ok {
+			fmt.Printf("WARNING: invalid path_prefix type in policy: %T\n", condVal)
+			return ""
+		}
+		pth, ok := params["path"].(string)
+		if !ok {
+			return "Invalid path parameter"
+		}
+		// check if the cleaned path is inside the required prefix
+		if !under_prefix(pth, pfx) {
+			return fmt.Sprintf("Path %s does not match required prefix %s", pth, pfx)
+		}
+
+	case "path_denied_prefix":
+		if reason := check_path_denied_prefix(params, condVal); reason != "" {
+			return reason
+		}
+
+	case "path_regex":
+		pattern, ok := condVal.(string)
+		if !ok {
+			fmt.Printf("WARNING: invalid path_regex type in policy: %T\n", condVal)
+			return ""
+		}
+		re, err := m.compiled_regex(pattern)
+		if err != nil {
+			return fmt.Sprintf("Invalid path_regex in policy: %s", pattern)
+		}
+		pth, ok := params["path"].(string)
+		if !ok {
+			return "Invalid path parameter"
+		}
+		if !re.MatchString(clean_path(pth)) {
+			return fmt.Sprintf("Path %s does not match required pattern %s", pth, pattern)
+		}
+
+	case "max_distinct_vendors":
+		vl, err := parse_vendor_limit(condVal)
+		if err != nil {
+			fmt.Printf("WARNING: %v\n", err)
+			return ""
+		}
+		vendor, ok := params["vendor_id"].(string)
+		if !ok || vendor == "" {
+			return "Invalid vendor_id parameter"
+		}
+		if reason := m.vendors.check(agentID, vendor, m.now(), vl); reason != "" {
+			return reason
+		}
+
+	case "allowed_hours":
+		ah, err := parse_allowed_hours(condVal)
+		if err != nil {
+			fmt.Printf("WARNING: %v\n", err)
+			return ""
+		}
+		if !ah.contains(m.now()) {
+			return fmt.Sprintf("Request outside allowed hours %s-%s", ah.Start, ah.End)
+		}
+
+	case "daily_limit":
+		limit, err := parse_daily_limit(condVal)
+		if err != nil {
+			fmt.Printf("WARNING: %v\n", err)
+			return ""
+		}
+		amt, ok := req.amount()
+		if !ok {
+			return "Invalid amount parameter"
+		}
+		if reason := m.spends.check(agentID, amt, m.now(), limit); reason != "" {
+			return reason
+		}
+
+	case "max_daily_write_bytes":
+		budget, err := parse_write_budget(condVal)
+		if err != nil {
+			fmt.Printf("WARNING: %v\n", err)
+			return ""
+		}
+		size, ok := write_size(params)
+		if !ok {
+			return "Invalid content parameter"
+		}
+		if reason := m.writes.check(agentID, size, m.now(), budget); reason != "" {
+			return reason
+		}
+
+	case "require_dual_approval":
+		da, err := parse_dual_approval(condVal)
+		if err != nil {
+			fmt.Printf("WARNING: %v\n", err)
+			return ""
+		}
+		if reason := m.check_dual_approval(req, da); reason != "" {
+			return reason
+		}
+
+	case "require_change_window":
+		if required, _ := condVal.(bool); !required {
+			return ""
+		}
+		if reason := m.check_change_window(req); reason != "" {
+			return reason
+		}
+
+	case "owns_payment":
+		if required, _ := condVal.(bool); !required {
+			return ""
+		}
+		if reason := m.check_owns_payment(req); reason != "" {
+			return reason
+		}
+
+	case "params_schema":
+		if reason := m.check_params_schema(params, condVal); reason != "" {
+			return reason
+		}
+
+	case "expr":
+		source, ok := condVal.(string)
+		if !ok {
+			fmt.Printf("WARNING: invalid expr type in policy: %T\n", condVal)
+			return ""
+		}
+		if reason := m.check_expr(req, source); reason != "" {
+			return reason
+		}
+
+	case "memo_regex":
+		pattern, ok := condVal.(string)
+		if !ok {
+			fmt.Printf("WARNING: invalid memo_regex type in policy: %T\n", condVal)
+			return ""
+		}
+		re, err := m.compiled_regex(pattern)
+		if err != nil {
+			return fmt.Sprintf("Invalid memo_regex in policy: %s", pattern)
+		}
+		memo, present := params["memo"]
+		if !present || memo == "" {
+			return fmt.Sprintf("Memo is required and must match %s", pattern)
+		}
+		memoStr, ok := memo.(string)
+		if !ok {
+			return "Invalid memo parameter"
+		}
+		if !re.MatchString(memoStr) {
+			return fmt.Sprintf("Memo %q does not match required format %s", memoStr, pattern)
+		}
+
+	case "monotonic_field":
+		field, ok := condVal.(string)
+		if !ok {
+			fmt.Printf("WARNING: invalid monotonic_field type in policy: %T\n", condVal)
+			return ""
+		}
+		val, ok := params[field].(float64)
+		if !ok {
+			return fmt.Sprintf("Invalid %s parameter", field)
+		}
+		if last, seen := m.sequences.last(agentID, field); seen && val <= last {
+			return fmt.Sprintf("%s %v must be greater than last seen value %v", field, val, last)
+		}
+	}
+	return ""
+}
+
+// record state for stateful conditions after the request passed all checks.
+// re-checks under the tracker lock so concurrent requests can't both win.
+// stateful conditions nested under and/or are recorded too. the whole tree
+// is gathered first and recorded together, and if one of them denies, the
+// ones already recorded are taken back
+func (m *Manager) commit_state(req *Request, conditions map[string]interface{}) (string, ReasonCode) {
+	sc := stateCommit{seen: make(map[string]bool)}
+	m.collect_state(req, conditions, &sc)
+
+	agentID, now := req.AgentID, m.now()
+	var undo []func()
+	deny := func(reason string, code ReasonCode) (string, ReasonCode) {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		return reason, code
+	}
+
+	if len(sc.vendorLimits) > 0 && m.vendors != nil {
+		vendor, _ := req.Params["vendor_id"].(string)
+		reason, u := m.vendors.record(agentID, vendor, now, sc.vendorLimits...)
+		if reason != "" {
+			return deny(reason, ReasonLimitExceeded)
+		}
+		undo = append(undo, u)
+	}
+
+	if len(sc.dailyLimits) > 0 && m.spends != nil {
+		amt, _ := req.amount()
+		reason, u := m.spends.record(agentID, amt, now, sc.dailyLimits...)
+		if reason != "" {
+			return deny(reason, condition_code("daily_limit", reason))
+		}
+		undo = append(undo, u)
+	}
+
+	if len(sc.writeBudgets) > 0 && m.writes != nil {
+		size, _ := write_size(req.Params)
+		reason, u := m.writes.record(agentID, size, now, sc.writeBudgets...)
+		if reason != "" {
+			return deny(reason, ReasonLimitExceeded)
+		}
+		undo = append(undo, u)
+	}
+
+	if m.approvals != nil {
+		for _, tok := range sc.approvals {
+			key := tok.key()
+			if !m.approvals.use(key, time.Unix(tok.expires, 0), now) {
+				return deny("Approval token already used", ReasonApprovalRequired)
 			}
-			pth, ok := params["path"].(string)
+			undo = append(undo, func() { m.approvals.release(key) })
+		}
+	}
+
+	if m.sequences != nil {
+		for _, field := range sc.sequences {
+			val, _ := req.Params[field].(float64)
+			last, ok, u := m.sequences.advance(agentID, field, val)
 			if !ok {
-				return "Invalid path parameter"
-			}
-			// check if path starts with required prefix
-			if !strings.HasPrefix(pth, pfx) {
-				return fmt.Sprintf("Path %s does not match required prefix %s", pth, pfx)
+				return deny(fmt.Sprintf("%s %v must be greater than last seen value %v", field, val, last), ReasonConditionFailed)
 			}
+			undo = append(undo, u)
 		}
 	}
-	return ""
+	return "", ""
+}
+
+// stateful conditions of one request, each recorded once against every
+// limit it turned up with
+type stateCommit struct {
+	seen         map[string]bool
+	vendorLimits []vendorLimit
+	dailyLimits  []float64
+	writeBudgets []int64
+	approvals    []approvalToken
+	sequences    []string
 }
 
-// hash the request params for logging (PII safe)
-func HashParams(params map[string]interface{}) string {
-	data, _ := json.Marshal(params)
-	h := sha256.Sum256(data)
-	return hex.EncodeToString(h[:])
+// gather the stateful conditions of the tree. a repeat of the same
+// condition and value, e.g. a nested daily_limit under an equal top-level
+// one, is only counted once; a different value adds its limit
+func (m *Manager) collect_state(req *Request, conditions map[string]interface{}, sc *stateCommit) {
+	first := func(name string) bool {
+		condVal, ok := conditions[name]
+		if !ok {
+			return false
+		}
+		key := fmt.Sprintf("%s=%v", name, condVal)
+		if sc.seen[key] {
+			return false
+		}
+		sc.seen[key] = true
+		return true
+	}
+
+	if first("max_distinct_vendors") {
+		if vl, err := parse_vendor_limit(conditions["max_distinct_vendors"]); err == nil {
+			sc.vendorLimits = append(sc.vendorLimits, vl)
+		}
+	}
+
+	if first("daily_limit") {
+		if limit, err := parse_daily_limit(conditions["daily_limit"]); err == nil {
+			sc.dailyLimits = append(sc.dailyLimits, limit)
+		}
+	}
+
+	if first("max_daily_write_bytes") {
+		if budget, err := parse_write_budget(conditions["max_daily_write_bytes"]); err == nil {
+			sc.writeBudgets = append(sc.writeBudgets, budget)
+		}
+	}
+
+	// the token is used up once, however many thresholds it cleared
+	if first("require_dual_approval") {
+		if da, err := parse_dual_approval(conditions["require_dual_approval"]); err == nil {
+			if tok, ok := approval_to_use(req, da); ok && !sc.seen["approval:"+tok.key()] {
+				sc.seen["approval:"+tok.key()] = true
+				sc.approvals = append(sc.approvals, tok)
+			}
+		}
+	}
+
+	// keyed by field, so monotonic_field on two different fields both count
+	if field, ok := conditions["monotonic_field"].(string); ok && first("monotonic_field") {
+		sc.sequences = append(sc.sequences, field)
+	}
+
+	// every "and" branch passed; of an "or" only the branch that let the
+	// request through counts. a "not" passed because its conditions
+	// failed, so there's nothing in it to record
+	if condVal, ok := conditions["and"]; ok {
+		branches, _ := as_condition_list(condVal)
+		for _, b := range branches {
+			m.collect_state(req, b, sc)
+		}
+	}
+	if condVal, ok := conditions["or"]; ok {
+		if b := m.passing_branch(req, condVal); b != nil {
+			m.collect_state(req, b, sc)
+		}
+	}
 }
@@ -317,6 +317,39 @@ func TestConditionChecks(t *testing.T) {
 			},
 			wantReason: "Amount 10000.00 exceeds max_amount=5000.00",
 		},
+		{
+			name: "amount range below floor",
+			conditions: map[string]interface{}{
+				"min_amount": 1,
+				"max_amount": 5000,
+			},
+			params: map[string]interface{}{
+				"amount": 0.5,
+			},
+			wantReason: "Amount 0.50 is below min_amount=1.00",
+		},
+		{
+			name: "amount range in range",
+			conditions: map[string]interface{}{
+				"min_amount": 1.0,
+				"max_amount": 5000,
+			},
+			params: map[string]interface{}{
+				"amount": 1.0,
+			},
+			wantReason: "",
+		},
+		{
+			name: "amount range above ceiling",
+			conditions: map[string]interface{}{
+				"min_amount": 1,
+				"max_amount": 5000,
+			},
+			params: map[string]interface{}{
+				"amount": 5000.01,
+			},
+			wantReason: "Amount 5000.01 exceeds max_amount=5000.00",
+		},
 		{
 			name: "currencies pass",
 			conditions: map[string]interface{}{
@@ -338,9 +371,9 @@ func TestConditionChecks(t *testing.T) {
 			wantReason: "Currency GBP not in allowed list",
 		},
 		{
-			name: "folder_prefix pass",
+			name: "path_prefix pass",
 			conditions: map[string]interface{}{
-				"folder_prefix": "/hr-docs/",
+				"path_prefix": "/hr-docs/",
 			},
 			params: map[string]interface{}{
 				"path": "/hr-docs/handbook.pdf",
@@ -348,23 +381,117 @@ func TestConditionChecks(t *testing.T) {
 			wantReason: "",
 		},
 		{
-			name: "folder_prefix fail",
+			name: "path_prefix fail",
 			conditions: map[string]interface{}{
-				"folder_prefix": "/hr-docs/",
+				"path_prefix": "/hr-docs/",
 			},
 			params: map[string]interface{}{
 				"path": "/legal/contract.pdf",
 			},
 			wantReason: "Path /legal/contract.pdf does not match required prefix /hr-docs/",
 		},
+		{
+			name: "path_regex pass",
+			conditions: map[string]interface{}{
+				"path_regex": `^/hr-docs/[^/]+\.pdf$`,
+			},
+			params: map[string]interface{}{
+				"path": "/hr-docs/handbook.pdf",
+			},
+			wantReason: "",
+		},
+		{
+			name: "path_regex fail on nested folder",
+			conditions: map[string]interface{}{
+				"path_regex": `^/hr-docs/[^/]+\.pdf$`,
+			},
+			params: map[string]interface{}{
+				"path": "/hr-docs/private/salaries.pdf",
+			},
+			wantReason: `Path /hr-docs/private/salaries.pdf does not match required pattern ^/hr-docs/[^/]+\.pdf$`,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			reason := m.check_conditions(tt.conditions, tt.params)
+			reason, _ := m.check_conditions(&Request{AgentID: "test-agent", Params: tt.params}, tt.conditions)
 			if reason != tt.wantReason {
 				t.Errorf("check_conditions() = %q, want %q", reason, tt.wantReason)
 			}
 		})
 	}
 }
+
+func TestMemoRegex(t *testing.T) {
+	m := &Manager{}
+	conditions := map[string]interface{}{
+		"memo_regex": `JIRA-\d+`,
+	}
+
+	tests := []struct {
+		name       string
+		params     map[string]interface{}
+		wantReason string
+	}{
+		{
+			name:       "conforming memo",
+			params:     map[string]interface{}{"memo": "Office supplies JIRA-1234"},
+			wantReason: "",
+		},
+		{
+			name:       "non-conforming memo",
+			params:     map[string]interface{}{"memo": "Office supplies"},
+			wantReason: `Memo "Office supplies" does not match required format JIRA-\d+`,
+		},
+		{
+			name:       "missing memo",
+			params:     map[string]interface{}{},
+			wantReason: `Memo is required and must match JIRA-\d+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, _ := m.check_conditions(&Request{AgentID: "test-agent", Params: tt.params}, conditions)
+			if reason != tt.wantReason {
+				t.Errorf("check_conditions() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestMemoRegex_InvalidPatternRejectedAtLoad(t *testing.T) {
+	m := &Manager{}
+	p := Policy{
+		Version: 1,
+		Agents: []Agent{{
+			ID: "finance-agent",
+			Allow: []Permission{{
+				Tool:       "payments",
+				Actions:    []string{"create"},
+				Conditions: map[string]interface{}{"memo_regex": "JIRA-(\\d+"},
+			}},
+		}},
+	}
+	if err := m.check_policy_valid(&p); err == nil {
+		t.Error("Expected invalid memo_regex to fail validation")
+	}
+}
+
+func TestPathRegex_InvalidPatternRejectedAtLoad(t *testing.T) {
+	m := &Manager{}
+	p := Policy{
+		Version: 1,
+		Agents: []Agent{{
+			ID: "hr-agent",
+			Allow: []Permission{{
+				Tool:       "files",
+				Actions:    []string{"read"},
+				Conditions: map[string]interface{}{"path_regex": "^/hr-docs/[^/+\\.pdf$"},
+			}},
+		}},
+	}
+	if err := m.check_policy_valid(&p); err == nil {
+		t.Error("Expected invalid path_regex to fail validation")
+	}
+}
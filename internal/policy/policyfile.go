@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// policy files are picked up by extension
+func is_policy_file(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".json":
+		return true
+	}
+	return false
+}
+
+// directories starting with "." are skipped: editor state, and the
+// ..data style directories Kubernetes mounts hold the same files again
+func skip_policy_dir(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// policy files under dir, recursively, as sorted slash separated paths
+// relative to dir. the walk starts from dir's real path so a symlinked
+// policy directory still works
+func policy_files(dir string) ([]string, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skip_policy_dir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !is_policy_file(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		out = append(out, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(out)
+	return out, err
+}
+
+// directories load_policies reads from, dir first, for watching
+func PolicyDirs(dir string) ([]string, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := []string{dir}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		if skip_policy_dir(d.Name()) {
+			return filepath.SkipDir
+		}
+		out = append(out, path)
+		return nil
+	})
+	return out, err
+}
+
+// decode a policy file, choosing the decoder by extension
+func parse_policy(name string, data []byte) (Policy, error) {
+	var pol Policy
+	if filepath.Ext(name) != ".json" {
+		err := yaml.Unmarshal(data, &pol)
+		return pol, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&pol); err != nil {
+		return pol, err
+	}
+	for _, agent := range pol.Agents {
+		for _, perm := range append(agent.Allow, agent.Deny...) {
+			for k, v := range perm.Conditions {
+				perm.Conditions[k] = yaml_numbers(v)
+			}
+		}
+	}
+	for _, tmpl := range pol.Templates {
+		for k, v := range tmpl.Conditions {
+			tmpl.Conditions[k] = yaml_numbers(v)
+		}
+	}
+	for _, role := range pol.Roles {
+		for _, perm := range append(role.Allow, role.Deny...) {
+			for k, v := range perm.Conditions {
+				perm.Conditions[k] = yaml_numbers(v)
+			}
+		}
+	}
+	return pol, nil
+}
+
+// convert JSON numbers to what the YAML decoder produces (int for whole
+// numbers, float64 otherwise) so conditions behave the same in both formats
+func yaml_numbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return int(i)
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		for k, inner := range val {
+			val[k] = yaml_numbers(inner)
+		}
+	case []interface{}:
+		for i, inner := range val {
+			val[i] = yaml_numbers(inner)
+		}
+	}
+	return v
+}
@@ -0,0 +1,149 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONPolicyMatchesYAML(t *testing.T) {
+	yamlPolicy := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+          currencies: [USD, EUR]
+          max_distinct_vendors:
+            limit: 2
+            window: 24h
+`
+	jsonPolicy := `{
+  "version": 1,
+  "agents": [{
+    "id": "finance-agent",
+    "allow": [{
+      "tool": "payments",
+      "actions": ["create"],
+      "conditions": {
+        "max_amount": 5000,
+        "currencies": ["USD", "EUR"],
+        "max_distinct_vendors": {"limit": 2, "window": "24h"}
+      }
+    }]
+  }]
+}`
+
+	load := func(name, content string) *Manager {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		m, err := NewManager(dir)
+		if err != nil {
+			t.Fatalf("Failed to create manager: %v", err)
+		}
+		if errs := m.LoadErrors(); len(errs) != 0 {
+			t.Fatalf("Unexpected load errors for %s: %+v", name, errs)
+		}
+		return m
+	}
+	fromYAML := load("finance.yaml", yamlPolicy)
+	fromJSON := load("finance.json", jsonPolicy)
+
+	requests := []map[string]interface{}{
+		{"amount": 1000.0, "currency": "USD", "vendor_id": "V1"},
+		{"amount": 9000.0, "currency": "USD", "vendor_id": "V1"},
+		{"amount": 1000.0, "currency": "GBP", "vendor_id": "V1"},
+		{"amount": 1000.0, "currency": "EUR", "vendor_id": "V2"},
+		{"amount": 1000.0, "currency": "EUR", "vendor_id": "V3"},
+	}
+	for _, params := range requests {
+		y := fromYAML.Evaluate("finance-agent", "payments", "create", params)
+		j := fromJSON.Evaluate("finance-agent", "payments", "create", params)
+		if y.Allow != j.Allow || y.Reason != j.Reason {
+			t.Errorf("params %v: yaml=%+v json=%+v", params, y, j)
+		}
+	}
+}
+
+func TestJSONAndYAMLPoliciesMerge(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"payments.yaml": "version: 1\nagents:\n  - id: ops-agent\n    allow:\n      - tool: payments\n        actions: [create]\n",
+		"files.json":    `{"version": 1, "agents": [{"id": "ops-agent", "allow": [{"tool": "files", "actions": ["read"]}]}]}`,
+		"notes.txt":     "not a policy",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	m, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	if d := m.Evaluate("ops-agent", "payments", "create", nil); !d.Allow {
+		t.Errorf("Expected YAML grant: %s", d.Reason)
+	}
+	if d := m.Evaluate("ops-agent", "files", "read", nil); !d.Allow {
+		t.Errorf("Expected JSON grant: %s", d.Reason)
+	}
+	if got := m.PolicyFiles(); len(got) != 2 {
+		t.Errorf("Expected 2 policy files, got %v", got)
+	}
+}
+
+func TestNestedPolicyDirectories(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"root.yaml":               "version: 1\nagents:\n  - id: ops-agent\n    allow:\n      - tool: payments\n        actions: [create]\n",
+		"team-a/files.json":       `{"version": 1, "agents": [{"id": "ops-agent", "allow": [{"tool": "files", "actions": ["read"]}]}]}`,
+		"team-a/deep/writes.yaml": "version: 1\nagents:\n  - id: ops-agent\n    allow:\n      - tool: files\n        actions: [write]\n",
+		"team-b/broken.yaml":      "version: 1\nagents: [\n",
+		".hidden/ignored.yaml":    "version: 1\nagents:\n  - id: ghost-agent\n    allow:\n      - tool: files\n        actions: [read]\n",
+		"team-a/deep/notes.txt":   "not a policy",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	m, err := NewManager(dir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	for _, action := range []struct{ tool, action string }{{"payments", "create"}, {"files", "read"}, {"files", "write"}} {
+		if d := m.Evaluate("ops-agent", action.tool, action.action, nil); !d.Allow {
+			t.Errorf("Expected %s/%s allowed from a nested file: %s", action.tool, action.action, d.Reason)
+		}
+	}
+	if d := m.Evaluate("ghost-agent", "files", "read", nil); d.Allow {
+		t.Error("Expected files under a dot directory to be ignored")
+	}
+
+	want := []string{"root.yaml", "team-a/deep/writes.yaml", "team-a/files.json"}
+	if got := m.PolicyFiles(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected files %v, got %v", want, got)
+	}
+	if errs := m.LoadErrors(); len(errs) != 1 || errs[0].File != "team-b/broken.yaml" {
+		t.Errorf("Expected the broken nested file reported by relative path, got %+v", errs)
+	}
+
+	dirs, err := PolicyDirs(dir)
+	if err != nil {
+		t.Fatalf("PolicyDirs: %v", err)
+	}
+	if len(dirs) != 4 || dirs[0] != dir {
+		t.Errorf("Expected the root and 3 subdirectories, got %v", dirs)
+	}
+}
@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+)
+
+// checks across the whole set of files, beyond what check_policy_valid
+// sees in one file. an agent may be spread over several files, but it
+// can't appear twice in one file or carry different API keys or
+// default_action values, since which definition wins would depend on
+// load order. files are checked in the given order, so problems are
+// reported against the later file by name
+func (m *Manager) check_policy_set(policies map[string]Policy, names []string) []PolicyLoadError {
+	var errs []PolicyLoadError
+	fail := func(file string, err error) {
+		fmt.Printf("ERROR: %v\n", err)
+		errs = append(errs, PolicyLoadError{
+			File:      file,
+			Error:     err.Error(),
+			Timestamp: m.now().UTC().Format(time.RFC3339),
+		})
+	}
+
+	keys := make(map[string]keyOwner)
+	defaults := make(map[string]keyOwner)
+	groups := make(map[string]quotaOwner)
+	members := make(map[string]quotaOwner)
+	defined := defined_quota_groups(policies)
+	for _, name := range names {
+		if err := check_file_agents(name, policies[name], keys, defaults); err != nil {
+			fail(name, err)
+			continue
+		}
+		if err := check_file_quotas(name, policies[name], defined, groups, members); err != nil {
+			fail(name, err)
+		}
+	}
+	return errs
+}
+
+// file and value of the first API key hash (or default_action) seen for
+// an agent
+type keyOwner struct{ file, hash string }
+
+// keys are only recorded once the whole file checks out, so a rejected
+// file can't cause conflicts for the ones after it
+func check_file_agents(name string, p Policy, keys, defaults map[string]keyOwner) error {
+	seen := make(map[string]bool)
+	for _, agent := range p.Agents {
+		if seen[agent.ID] {
+			return fmt.Errorf("policy file %s: agent %s is defined more than once", name, agent.ID)
+		}
+		seen[agent.ID] = true
+
+		if prev, ok := keys[agent.ID]; ok && agent.APIKeySHA256 != "" && prev.hash != agent.APIKeySHA256 {
+			return fmt.Errorf("policy file %s: agent %s has a different api_key_sha256 than in %s", name, agent.ID, prev.file)
+		}
+		if prev, ok := defaults[agent.ID]; ok {
+			if def := effective_default(p, agent); def != "" && prev.hash != def {
+				return fmt.Errorf("policy file %s: agent %s has default_action %s but %s in %s", name, agent.ID, def, prev.hash, prev.file)
+			}
+		}
+	}
+	for _, agent := range p.Agents {
+		if _, ok := keys[agent.ID]; !ok && agent.APIKeySHA256 != "" {
+			keys[agent.ID] = keyOwner{name, agent.APIKeySHA256}
+		}
+		if _, ok := defaults[agent.ID]; !ok {
+			if def := effective_default(p, agent); def != "" {
+				defaults[agent.ID] = keyOwner{name, def}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePolicies(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+const (
+	reloadFinance = `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+`
+	reloadHR = `version: 1
+agents:
+  - id: hr-agent
+    allow:
+      - tool: files
+        actions: [read]
+        conditions:
+          path_prefix: /hr-docs/
+`
+)
+
+func TestReloadKeepsPreviousSetOnBrokenFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"finance.yaml": reloadFinance, "hr.yaml": reloadHR})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	// a valid edit to one file alongside a mid-edit save of the other
+	writePolicies(t, tmpDir, map[string]string{
+		"finance.yaml": strings.Replace(reloadFinance, "max_amount: 5000", "max_amount: 100", 1),
+		"hr.yaml":      "version: 1\nagents:\n  - id: hr-agent\n    allow: [\n",
+	})
+	var list LoadErrorList
+	if err := m.Reload(); !errors.As(err, &list) || len(list) != 1 || list[0].File != "hr.yaml" {
+		t.Fatalf("Expected Reload to fail on hr.yaml, got %v", err)
+	}
+
+	// neither file changed: the finance edit wasn't applied on its own
+	if d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 3000.0}); !d.Allow {
+		t.Errorf("Expected previous max_amount to stay active, got %s", d.Reason)
+	}
+	if d := m.Evaluate("hr-agent", "files", "read", map[string]interface{}{"path": "/hr-docs/a.pdf"}); !d.Allow {
+		t.Errorf("Expected previous hr policy to stay active, got %s", d.Reason)
+	}
+	if files := m.PolicyFiles(); len(files) != 2 {
+		t.Errorf("Expected both files still active, got %v", files)
+	}
+	if errs := m.LoadErrors(); len(errs) != 1 || errs[0].File != "hr.yaml" {
+		t.Errorf("Expected the failed reload in LoadErrors, got %+v", errs)
+	}
+
+	// fixing the file applies both edits together
+	writePolicies(t, tmpDir, map[string]string{"hr.yaml": reloadHR})
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Expected clean reload, got %v", err)
+	}
+	if d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 3000.0}); d.Allow {
+		t.Error("Expected the new max_amount once the set is valid")
+	}
+	if errs := m.LoadErrors(); len(errs) != 0 {
+		t.Errorf("Expected no load errors, got %+v", errs)
+	}
+}
+
+func TestReloadRejectsConflictingAgents(t *testing.T) {
+	keyA, keyB := HashAPIKey("key-a"), HashAPIKey("key-b")
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{
+			name:    "agent twice in one file",
+			file:    "version: 1\nagents:\n  - id: hr-agent\n    allow: [{tool: files, actions: [read]}]\n  - id: hr-agent\n    allow: [{tool: files, actions: [write]}]\n",
+			wantErr: "agent hr-agent is defined more than once",
+		},
+		{
+			name:    "different API keys",
+			file:    "version: 1\nagents:\n  - id: finance-agent\n    api_key_sha256: " + keyB + "\n    allow: [{tool: files, actions: [read]}]\n",
+			wantErr: "agent finance-agent has a different api_key_sha256 than in a.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writePolicies(t, tmpDir, map[string]string{
+				"a.yaml": strings.Replace(reloadFinance, "  - id: finance-agent\n", "  - id: finance-agent\n    api_key_sha256: "+keyA+"\n", 1),
+			})
+			m, err := NewManager(tmpDir)
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			writePolicies(t, tmpDir, map[string]string{"b.yaml": tt.file})
+			var list LoadErrorList
+			if err := m.Reload(); !errors.As(err, &list) || len(list) != 1 || !strings.Contains(list[0].Error, tt.wantErr) {
+				t.Fatalf("Expected Reload to fail with %q, got %v", tt.wantErr, err)
+			}
+			if files := m.PolicyFiles(); len(files) != 1 || files[0] != "a.yaml" {
+				t.Errorf("Expected only a.yaml active, got %v", files)
+			}
+
+			// at startup the conflicting file is skipped like any bad file
+			m, err = NewManager(tmpDir)
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			if files := m.PolicyFiles(); len(files) != 1 || files[0] != "a.yaml" {
+				t.Errorf("Expected b.yaml skipped at startup, got %v", files)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package policy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// a daily spend cap shared by several agents. each member may spend its
+// weighted share of the cap within a rolling 24h, and the group as a
+// whole never more than the cap:
+//
+//	quota_groups:
+//	  finance:
+//	    daily_limit: 10000
+//	agents:
+//	  - id: ap-agent
+//	    quota_group: finance
+//	    quota_weight: 3     # 7500 of the 10000
+//	  - id: ar-agent
+//	    quota_group: finance # weight 1 by default, 2500
+//
+// shares are recomputed whenever the policies load, while spending is
+// kept, so the group cap still holds when members or weights change
+// during the day. only requests carrying an amount count
+type QuotaGroup struct {
+	DailyLimit float64 `yaml:"daily_limit" json:"daily_limit"`
+}
+
+// the agent's weight in its quota group, 1 unless set
+func (a Agent) quota_weight() float64 {
+	if a.QuotaWeight == 0 {
+		return 1
+	}
+	return a.QuotaWeight
+}
+
+func validate_quota_groups(p *Policy) error {
+	for name, g := range p.QuotaGroups {
+		if name == "" {
+			return fmt.Errorf("quota group name cannot be empty")
+		}
+		if g.DailyLimit <= 0 {
+			return fmt.Errorf("quota group %s: daily_limit must be positive", name)
+		}
+	}
+	for _, agent := range p.Agents {
+		if agent.QuotaWeight < 0 {
+			return fmt.Errorf("agent %s: quota_weight cannot be negative", agent.ID)
+		}
+		if agent.QuotaWeight != 0 && agent.QuotaGroup == "" {
+			return fmt.Errorf("agent %s: quota_weight needs a quota_group", agent.ID)
+		}
+	}
+	return nil
+}
+
+// who set a quota group's limit or an agent's membership first
+type quotaOwner struct {
+	file   string
+	group  string
+	amount float64
+}
+
+// names of the quota groups any of the files defines
+func defined_quota_groups(policies map[string]Policy) map[string]bool {
+	defined := make(map[string]bool)
+	for _, p := range policies {
+		for group := range p.QuotaGroups {
+			defined[group] = true
+		}
+	}
+	return defined
+}
+
+// quota groups may be defined and joined from any file, but a group can't
+// have two limits and an agent can't be in two groups or carry two
+// weights. like check_file_agents, a file's entries are only recorded once
+// the whole file checks out
+func check_file_quotas(name string, p Policy, defined map[string]bool, groups, members map[string]quotaOwner) error {
+	for group, g := range p.QuotaGroups {
+		if prev, ok := groups[group]; ok && prev.amount != g.DailyLimit {
+			return fmt.Errorf("policy file %s: quota group %s has daily_limit %.2f but %.2f in %s", name, group, g.DailyLimit, prev.amount, prev.file)
+		}
+	}
+	for _, agent := range p.Agents {
+		if agent.QuotaGroup == "" {
+			continue
+		}
+		if !defined[agent.QuotaGroup] {
+			return fmt.Errorf("policy file %s: agent %s is in undefined quota group %s", name, agent.ID, agent.QuotaGroup)
+		}
+		if prev, ok := members[agent.ID]; ok && (prev.group != agent.QuotaGroup || prev.amount != agent.quota_weight()) {
+			return fmt.Errorf("policy file %s: agent %s has quota_group %s with weight %g but %s with weight %g in %s",
+				name, agent.ID, agent.QuotaGroup, agent.quota_weight(), prev.group, prev.amount, prev.file)
+		}
+	}
+	for group, g := range p.QuotaGroups {
+		if _, ok := groups[group]; !ok {
+			groups[group] = quotaOwner{file: name, amount: g.DailyLimit}
+		}
+	}
+	for _, agent := range p.Agents {
+		if _, ok := members[agent.ID]; !ok && agent.QuotaGroup != "" {
+			members[agent.ID] = quotaOwner{file: name, group: agent.QuotaGroup, amount: agent.quota_weight()}
+		}
+	}
+	return nil
+}
+
+// an agent's place in its quota group, see build_quotas
+type agentQuota struct {
+	group string
+	limit float64 // the whole group's
+	share float64 // the agent's part of limit
+}
+
+// fill in agentIndex.quota once every agent is indexed. members of a
+// group no loaded file defines get no quota
+func build_quotas(idx policyIndex, policies map[string]Policy, names []string) {
+	limits := make(map[string]float64)
+	for _, name := range names {
+		for group, g := range policies[name].QuotaGroups {
+			if _, ok := limits[group]; !ok {
+				limits[group] = g.DailyLimit
+			}
+		}
+	}
+
+	weights := make(map[string]float64)
+	for _, ai := range idx {
+		if _, ok := limits[ai.quotaGroup]; ok {
+			weights[ai.quotaGroup] += ai.quotaWeight
+		}
+	}
+	for _, ai := range idx {
+		limit, ok := limits[ai.quotaGroup]
+		if !ok {
+			continue
+		}
+		ai.quota = &agentQuota{
+			group: ai.quotaGroup,
+			limit: limit,
+			share: limit * ai.quotaWeight / weights[ai.quotaGroup],
+		}
+	}
+}
+
+// spending per agent and per quota group within spendWindow
+type quotaTracker struct {
+	mu     sync.Mutex
+	agents *spendTracker
+	groups *spendTracker
+}
+
+func newQuotaTracker() *quotaTracker {
+	return &quotaTracker{agents: newSpendTracker(), groups: newSpendTracker()}
+}
+
+// whether amount fits both the group cap and the agent's share, recorded
+// against both when record is set
+func (t *quotaTracker) charge(agentID string, q *agentQuota, amount float64, now time.Time, record bool) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.groups.mu.Lock()
+	groupTotal := t.groups.prune(q.group, now)
+	t.groups.mu.Unlock()
+	if groupTotal+amount > q.limit {
+		return fmt.Sprintf("Amount %.2f would exceed quota group %s daily_limit=%.2f (%.2f spent in the last 24h)", amount, q.group, q.limit, groupTotal)
+	}
+
+	t.agents.mu.Lock()
+	agentTotal := t.agents.prune(agentID, now)
+	t.agents.mu.Unlock()
+	if agentTotal+amount > q.share {
+		return fmt.Sprintf("Amount %.2f would exceed the agent's share %.2f of quota group %s (%.2f spent in the last 24h)", amount, q.share, q.group, agentTotal)
+	}
+
+	if record {
+		t.groups.record(q.group, amount, now, q.limit)
+		t.agents.record(agentID, amount, now, q.share)
+	}
+	return ""
+}
+
+// take back a charge whose request ended up denied by a later check
+func (t *quotaTracker) refund(agentID string, q *agentQuota, amount float64, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.groups.remove(q.group, amount, at)
+	t.agents.remove(agentID, amount, at)
+}
+
+// charge an allowed request to the agent's quota group, if it has one and
+// the request carries an amount. DryRun requests are only checked. the
+// returned func refunds the charge, for when commit_state then denies
+func (m *Manager) charge_quota(snap *policySnapshot, req *Request) (string, func()) {
+	noRefund := func() {}
+	ai := snap.index[req.AgentID]
+	if ai == nil || ai.quota == nil || m.quotas == nil {
+		return "", noRefund
+	}
+	if _, ok := req.Params["amount"]; !ok {
+		return "", noRefund
+	}
+	amt, ok := req.amount()
+	if !ok || amt <= 0 {
+		return "Invalid amount parameter", noRefund
+	}
+	now := m.now()
+	if reason := m.quotas.charge(req.AgentID, ai.quota, amt, now, !req.DryRun); reason != "" || req.DryRun {
+		return reason, noRefund
+	}
+	return "", func() { m.quotas.refund(req.AgentID, ai.quota, amt, now) }
+}
@@ -0,0 +1,289 @@
+package policy
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const quotaPolicy = `version: 4
+quota_groups:
+  finance:
+    daily_limit: 1000
+agents:
+  - id: ap-agent
+    quota_group: finance
+    quota_weight: 3
+    allow:
+      - tool: payments
+        actions: [create]
+  - id: ar-agent
+    quota_group: finance
+    allow:
+      - tool: payments
+        actions: [create]
+  - id: hr-agent
+    allow:
+      - tool: payments
+        actions: [create]
+`
+
+func TestQuotaGroup(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"quota.yaml": quotaPolicy})
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	m.SetClock(clock)
+
+	pay := func(agent string, amount float64) Decision {
+		return m.Evaluate(agent, "payments", "create", map[string]interface{}{"amount": amount})
+	}
+
+	// ap-agent's share is 750, ar-agent's 250
+	if d := pay("ap-agent", 700); !d.Allow {
+		t.Fatalf("Expected 700 within ap-agent's share: %s", d.Reason)
+	}
+	d := pay("ap-agent", 100)
+	if d.Allow || d.Version != 4 || !strings.Contains(d.Reason, "share 750.00 of quota group finance") {
+		t.Errorf("Expected ap-agent's share to be exhausted, got %+v", d)
+	}
+	if got := ReasonCategory(d.Reason); got != CategoryLimitExceeded {
+		t.Errorf("Expected category %s, got %s", CategoryLimitExceeded, got)
+	}
+	if d.ReasonCode != ReasonLimitExceeded {
+		t.Errorf("Expected code %s, got %s", ReasonLimitExceeded, d.ReasonCode)
+	}
+	if d := pay("ar-agent", 300); d.Allow || !strings.Contains(d.Reason, "share 250.00") {
+		t.Errorf("Expected 300 beyond ar-agent's share, got %+v", d)
+	}
+	if d := pay("ar-agent", 250); !d.Allow {
+		t.Fatalf("Expected 250 within ar-agent's share: %s", d.Reason)
+	}
+	if d := pay("ap-agent", 50); !d.Allow {
+		t.Fatalf("Expected the rest of ap-agent's share: %s", d.Reason)
+	}
+
+	// the group cap is reached
+	d = pay("ar-agent", 1)
+	if d.Allow || d.Reason != "Amount 1.00 would exceed quota group finance daily_limit=1000.00 (1000.00 spent in the last 24h)" {
+		t.Errorf("Expected the group cap to deny, got %+v", d)
+	}
+
+	// agents outside the group and requests without an amount don't count
+	if d := pay("hr-agent", 5000); !d.Allow {
+		t.Errorf("Expected hr-agent to be unaffected: %s", d.Reason)
+	}
+	if d := m.Evaluate("ap-agent", "payments", "create", nil); !d.Allow {
+		t.Errorf("Expected a request without an amount to be unaffected: %s", d.Reason)
+	}
+
+	// a rolling 24h window, like daily_limit
+	clock.t = clock.t.Add(24 * time.Hour)
+	if d := pay("ap-agent", 750); !d.Allow {
+		t.Errorf("Expected the shares to refill after 24h: %s", d.Reason)
+	}
+}
+
+// the group cap holds when weights change during the day
+func TestQuotaGroupReloadKeepsSpending(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"quota.yaml": quotaPolicy})
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	pay := func(agent string, amount float64) Decision {
+		return m.Evaluate(agent, "payments", "create", map[string]interface{}{"amount": amount})
+	}
+	if d := pay("ap-agent", 750); !d.Allow {
+		t.Fatalf("Expected ap-agent's full share: %s", d.Reason)
+	}
+
+	// ar-agent now gets three quarters, but only 250 of the cap is left
+	writePolicies(t, tmpDir, map[string]string{"quota.yaml": strings.Replace(quotaPolicy, "quota_group: finance\n    allow", "quota_group: finance\n    quota_weight: 9\n    allow", 1)})
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if d := pay("ar-agent", 300); d.Allow || !strings.Contains(d.Reason, "quota group finance daily_limit") {
+		t.Errorf("Expected the group cap to deny, got %+v", d)
+	}
+	if d := pay("ar-agent", 250); !d.Allow {
+		t.Errorf("Expected the rest of the cap to be allowed: %s", d.Reason)
+	}
+}
+
+// dry runs check the quota without spending it
+func TestQuotaGroupDryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"quota.yaml": quotaPolicy})
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	req := Request{AgentID: "ar-agent", Tool: "payments", Action: "create", Params: map[string]interface{}{"amount": 250.0}, DryRun: true}
+	for i := 0; i < 2; i++ {
+		if d := m.EvaluateRequest(req); !d.Allow {
+			t.Fatalf("Expected dry run %d to be allowed: %s", i, d.Reason)
+		}
+	}
+	req.Params["amount"] = 251.0
+	if d := m.EvaluateRequest(req); d.Allow {
+		t.Error("Expected a dry run beyond the share to be denied")
+	}
+}
+
+func TestQuotaGroupValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "non-positive limit",
+			files: map[string]string{"a.yaml": "version: 1\nquota_groups:\n  finance:\n    daily_limit: 0\nagents:\n  - id: a\n    allow: []\n"},
+			want:  "quota group finance: daily_limit must be positive",
+		},
+		{
+			name:  "negative weight",
+			files: map[string]string{"a.yaml": "version: 1\nquota_groups:\n  finance:\n    daily_limit: 10\nagents:\n  - id: a\n    quota_group: finance\n    quota_weight: -1\n    allow: []\n"},
+			want:  "agent a: quota_weight cannot be negative",
+		},
+		{
+			name:  "weight without group",
+			files: map[string]string{"a.yaml": "version: 1\nagents:\n  - id: a\n    quota_weight: 2\n    allow: []\n"},
+			want:  "agent a: quota_weight needs a quota_group",
+		},
+		{
+			name:  "undefined group",
+			files: map[string]string{"a.yaml": "version: 1\nagents:\n  - id: a\n    quota_group: finance\n    allow: []\n"},
+			want:  "agent a is in undefined quota group finance",
+		},
+		{
+			name: "conflicting limits",
+			files: map[string]string{
+				"a.yaml": "version: 1\nquota_groups:\n  finance:\n    daily_limit: 10\nagents:\n  - id: a\n    allow: []\n",
+				"b.yaml": "version: 1\nquota_groups:\n  finance:\n    daily_limit: 20\nagents:\n  - id: b\n    allow: []\n",
+			},
+			want: "policy file b.yaml: quota group finance has daily_limit 20.00 but 10.00 in a.yaml",
+		},
+		{
+			name: "conflicting weights",
+			files: map[string]string{
+				"a.yaml": "version: 1\nquota_groups:\n  finance:\n    daily_limit: 10\nagents:\n  - id: a\n    quota_group: finance\n    allow: []\n",
+				"b.yaml": "version: 1\nagents:\n  - id: a\n    quota_group: finance\n    quota_weight: 2\n    allow: []\n",
+			},
+			want: "policy file b.yaml: agent a has quota_group finance with weight 2 but finance with weight 1 in a.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writePolicies(t, tmpDir, tt.files)
+			m, err := NewManager(tmpDir)
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			errs := m.LoadErrors()
+			if len(errs) != 1 || !strings.Contains(errs[0].Error, tt.want) {
+				t.Errorf("Expected one load error containing %q, got %+v", tt.want, errs)
+			}
+		})
+	}
+}
+
+// members and the group definition may live in different files
+func TestQuotaGroupAcrossFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{
+		"a-agents.yaml": "version: 1\nagents:\n  - id: a\n    quota_group: finance\n    allow:\n      - tool: payments\n        actions: [create]\n",
+		"b-quotas.yaml": "version: 2\nquota_groups:\n  finance:\n    daily_limit: 100\nagents:\n  - id: b\n    quota_group: finance\n    allow:\n      - tool: payments\n        actions: [create]\n",
+	})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if errs := m.LoadErrors(); len(errs) != 0 {
+		t.Fatalf("Expected no load errors, got %+v", errs)
+	}
+	if d := m.Evaluate("a", "payments", "create", map[string]interface{}{"amount": 51.0}); d.Allow {
+		t.Error("Expected a's share of 50 to be enforced")
+	}
+}
+
+// a charge is taken back when commit_state denies the request after it
+func TestQuotaGroupRefund(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"quota.yaml": quotaPolicy})
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	req := Request{AgentID: "ar-agent", Tool: "payments", Action: "create", Params: map[string]interface{}{"amount": 250.0}}
+	reason, refund := m.charge_quota(m.snapshot(), &req)
+	if reason != "" {
+		t.Fatalf("Expected 250 within ar-agent's share: %s", reason)
+	}
+	if d := m.EvaluateRequest(req); d.Allow {
+		t.Fatal("Expected the share to be used up by the charge")
+	}
+	refund()
+	if d := m.EvaluateRequest(req); !d.Allow {
+		t.Errorf("Expected the refunded share to be available again: %s", d.Reason)
+	}
+
+	// a negative amount can't hand share back either
+	if d := m.Evaluate("ap-agent", "payments", "create", map[string]interface{}{"amount": -500.0}); d.Allow || d.ReasonCode != ReasonInvalidParams {
+		t.Errorf("Expected a negative amount to be rejected as invalid, got %+v", d)
+	}
+}
+
+// requests denied by a daily_limit condition never use up the quota
+// group, however they race
+func TestQuotaGroupWithDailyLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"quota.yaml": `version: 1
+quota_groups:
+  finance:
+    daily_limit: 250
+agents:
+  - id: ar-agent
+    quota_group: finance
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          daily_limit: 100
+      - tool: payments
+        actions: [refund]
+`})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Evaluate("ar-agent", "payments", "create", map[string]interface{}{"amount": 10.0})
+		}()
+	}
+	wg.Wait()
+
+	// 100 of the 250 went to create
+	if d := m.Evaluate("ar-agent", "payments", "refund", map[string]interface{}{"amount": 150.0}); !d.Allow {
+		t.Fatalf("Expected the rest of the quota to be left: %s", d.Reason)
+	}
+	if d := m.Evaluate("ar-agent", "payments", "refund", map[string]interface{}{"amount": 1.0}); d.Allow {
+		t.Error("Expected the quota to be used up")
+	}
+}
@@ -0,0 +1,75 @@
+package policy
+
+import "strings"
+
+// stable, machine-readable form of a Decision's reason. Reason is for
+// humans and carries amounts, paths and IDs; callers switch on this
+type ReasonCode string
+
+const (
+	ReasonOK               ReasonCode = "OK"
+	ReasonNoPolicy         ReasonCode = "NO_POLICY"
+	ReasonAgentDisabled    ReasonCode = "AGENT_DISABLED"
+	ReasonActionDenied     ReasonCode = "ACTION_DENIED"
+	ReasonAmountExceeded   ReasonCode = "AMOUNT_EXCEEDED"
+	ReasonAmountBelowMin   ReasonCode = "AMOUNT_BELOW_MIN"
+	ReasonCurrencyDenied   ReasonCode = "CURRENCY_DENIED"
+	ReasonPathDenied       ReasonCode = "PATH_DENIED"
+	ReasonLimitExceeded    ReasonCode = "LIMIT_EXCEEDED"
+	ReasonApprovalRequired ReasonCode = "APPROVAL_REQUIRED"
+	ReasonOutsideHours     ReasonCode = "OUTSIDE_HOURS"
+	ReasonNotOwner         ReasonCode = "NOT_OWNER"
+	ReasonInvalidParams    ReasonCode = "INVALID_PARAMS"
+	ReasonConditionFailed  ReasonCode = "CONDITION_FAILED"
+)
+
+// every code a Decision can carry
+var reasonCodes = []ReasonCode{
+	ReasonOK, ReasonNoPolicy, ReasonAgentDisabled, ReasonActionDenied,
+	ReasonAmountExceeded, ReasonAmountBelowMin, ReasonCurrencyDenied,
+	ReasonPathDenied, ReasonLimitExceeded, ReasonApprovalRequired,
+	ReasonOutsideHours, ReasonNotOwner, ReasonInvalidParams, ReasonConditionFailed,
+}
+
+// whether c is one of the codes above, for settings that name codes
+func ValidReasonCode(c ReasonCode) bool {
+	for _, rc := range reasonCodes {
+		if rc == c {
+			return true
+		}
+	}
+	return false
+}
+
+// code per condition name. conditions not listed, and the or/not
+// combinators, fail with ReasonConditionFailed
+var conditionCodes = map[string]ReasonCode{
+	"max_amount":            ReasonAmountExceeded,
+	"min_amount":            ReasonAmountBelowMin,
+	"currencies":            ReasonCurrencyDenied,
+	"currencies_denied":     ReasonCurrencyDenied,
+	"path_prefix":           ReasonPathDenied,
+	"path_denied_prefix":    ReasonPathDenied,
+	"path_regex":            ReasonPathDenied,
+	"max_distinct_vendors":  ReasonLimitExceeded,
+	"daily_limit":           ReasonLimitExceeded,
+	"max_daily_write_bytes": ReasonLimitExceeded,
+	"require_dual_approval": ReasonApprovalRequired,
+	"require_change_window": ReasonApprovalRequired,
+	"allowed_hours":         ReasonOutsideHours,
+	"owns_payment":          ReasonNotOwner,
+	"required_params":       ReasonInvalidParams,
+	"params_schema":         ReasonInvalidParams,
+}
+
+// code for a failed condition. a missing or mistyped param is
+// INVALID_PARAMS whichever condition needed it
+func condition_code(condName, reason string) ReasonCode {
+	if strings.HasPrefix(reason, "Invalid ") && strings.HasSuffix(reason, " parameter") {
+		return ReasonInvalidParams
+	}
+	if code, ok := conditionCodes[condName]; ok {
+		return code
+	}
+	return ReasonConditionFailed
+}
@@ -0,0 +1,174 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckConditionsReasonCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions map[string]interface{}
+		params     map[string]interface{}
+		want       ReasonCode
+	}{
+		{
+			name:       "max_amount",
+			conditions: map[string]interface{}{"max_amount": 100},
+			params:     map[string]interface{}{"amount": 500.0},
+			want:       ReasonAmountExceeded,
+		},
+		{
+			name:       "min_amount",
+			conditions: map[string]interface{}{"min_amount": 10},
+			params:     map[string]interface{}{"amount": 1.0},
+			want:       ReasonAmountBelowMin,
+		},
+		{
+			name:       "currencies",
+			conditions: map[string]interface{}{"currencies": []interface{}{"USD"}},
+			params:     map[string]interface{}{"currency": "GBP"},
+			want:       ReasonCurrencyDenied,
+		},
+		{
+			name:       "currencies_denied",
+			conditions: map[string]interface{}{"currencies_denied": []interface{}{"RUB"}},
+			params:     map[string]interface{}{"currency": "RUB"},
+			want:       ReasonCurrencyDenied,
+		},
+		{
+			name:       "path_prefix",
+			conditions: map[string]interface{}{"path_prefix": "/hr-docs/"},
+			params:     map[string]interface{}{"path": "/legal/contract.pdf"},
+			want:       ReasonPathDenied,
+		},
+		{
+			name:       "path_denied_prefix",
+			conditions: map[string]interface{}{"path_denied_prefix": []interface{}{"/etc/"}},
+			params:     map[string]interface{}{"path": "/etc/passwd"},
+			want:       ReasonPathDenied,
+		},
+		{
+			name:       "required_params",
+			conditions: map[string]interface{}{"required_params": []interface{}{"vendor_id"}},
+			params:     map[string]interface{}{},
+			want:       ReasonInvalidParams,
+		},
+		{
+			name:       "missing param of another condition",
+			conditions: map[string]interface{}{"max_amount": 100},
+			params:     map[string]interface{}{"amount": "lots"},
+			want:       ReasonInvalidParams,
+		},
+		{
+			name:       "dual approval",
+			conditions: map[string]interface{}{"require_dual_approval": map[string]interface{}{"threshold": 100}},
+			params:     map[string]interface{}{"amount": 500.0},
+			want:       ReasonApprovalRequired,
+		},
+		{
+			name:       "allowed_hours",
+			conditions: map[string]interface{}{"allowed_hours": map[string]interface{}{"start": "09:00", "end": "17:00"}},
+			want:       ReasonOutsideHours,
+		},
+		{
+			name:       "expr",
+			conditions: map[string]interface{}{"expr": "amount < 10"},
+			params:     map[string]interface{}{"amount": 50.0},
+			want:       ReasonConditionFailed,
+		},
+		{
+			name: "and keeps the failing branch's code",
+			conditions: map[string]interface{}{"and": []interface{}{
+				map[string]interface{}{"currencies": []interface{}{"USD"}},
+				map[string]interface{}{"max_amount": 100},
+			}},
+			params: map[string]interface{}{"amount": 500.0, "currency": "USD"},
+			want:   ReasonAmountExceeded,
+		},
+		{
+			name: "or",
+			conditions: map[string]interface{}{"or": []interface{}{
+				map[string]interface{}{"currencies": []interface{}{"USD"}},
+				map[string]interface{}{"max_amount": 100},
+			}},
+			params: map[string]interface{}{"amount": 500.0, "currency": "EUR"},
+			want:   ReasonConditionFailed,
+		},
+		{
+			name:       "not",
+			conditions: map[string]interface{}{"not": map[string]interface{}{"currencies": []interface{}{"USD"}}},
+			params:     map[string]interface{}{"currency": "USD"},
+			want:       ReasonConditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{}
+			m.SetClock(&fakeClock{t: time.Date(2024, 3, 5, 20, 0, 0, 0, time.UTC)})
+			reason, code := m.check_conditions(&Request{AgentID: "a", Params: tt.params}, tt.conditions)
+			if reason == "" {
+				t.Fatal("Expected conditions to fail")
+			}
+			if code != tt.want {
+				t.Errorf("check_conditions() code = %s, want %s (%s)", code, tt.want, reason)
+			}
+		})
+	}
+}
+
+func TestCheckConditionsPassHasNoCode(t *testing.T) {
+	m := &Manager{}
+	reason, code := m.check_conditions(&Request{AgentID: "a", Params: map[string]interface{}{"amount": 5.0}}, map[string]interface{}{"max_amount": 100})
+	if reason != "" || code != "" {
+		t.Errorf("check_conditions() = %q, %q, want both empty", reason, code)
+	}
+}
+
+func TestDecisionReasonCode(t *testing.T) {
+	disabled := false
+	policies := map[string]Policy{
+		"p.yaml": {Version: 1, Agents: []Agent{
+			{
+				ID: "finance-agent",
+				Allow: []Permission{{
+					Tool:       "payments",
+					Actions:    []string{"create", "void"},
+					Conditions: map[string]interface{}{"max_amount": 5000},
+				}},
+				Deny: []Permission{{Tool: "payments", Actions: []string{"void"}}},
+			},
+			{
+				ID:      "suspended-agent",
+				Enabled: &disabled,
+				Allow:   []Permission{{Tool: "payments", Actions: []string{"create"}}},
+			},
+		}},
+	}
+	m := &Manager{}
+	m.active.Store(new_policy_snapshot(policies, sorted_names(policies)))
+
+	tests := []struct {
+		name   string
+		agent  string
+		action string
+		params map[string]interface{}
+		want   ReasonCode
+	}{
+		{"allowed", "finance-agent", "create", map[string]interface{}{"amount": 10.0}, ReasonOK},
+		{"unknown agent", "ghost-agent", "create", nil, ReasonNoPolicy},
+		{"no matching rule", "finance-agent", "refund", nil, ReasonNoPolicy},
+		{"disabled agent", "suspended-agent", "create", nil, ReasonAgentDisabled},
+		{"deny rule", "finance-agent", "void", nil, ReasonActionDenied},
+		{"failed condition", "finance-agent", "create", map[string]interface{}{"amount": 9000.0}, ReasonAmountExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := m.Evaluate(tt.agent, "payments", tt.action, tt.params)
+			if d.ReasonCode != tt.want {
+				t.Errorf("ReasonCode = %s, want %s (%s)", d.ReasonCode, tt.want, d.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package policy
+
+import "regexp"
+
+// compile a pattern once and reuse it across evaluations and reloads
+func (m *Manager) compiled_regex(pattern string) (*regexp.Regexp, error) {
+	m.regexMu.Lock()
+	defer m.regexMu.Unlock()
+
+	if re, ok := m.regexes[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if m.regexes == nil {
+		m.regexes = make(map[string]*regexp.Regexp)
+	}
+	m.regexes[pattern] = re
+	return re, nil
+}
@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// required_params: params that must be present, optionally with a given
+// value. dotted names reach into nested objects. a list only checks
+// presence; in the map form a null value means presence only
+//
+//	conditions:
+//	  required_params: [memo, vendor_id]
+//
+//	conditions:
+//	  required_params:
+//	    op: transfer
+//	    transfer.kind: wire
+//	    memo: null
+type requiredParam struct {
+	Field string
+	Value interface{} // nil = any value
+}
+
+func parse_required_params(condVal interface{}) ([]requiredParam, error) {
+	var out []requiredParam
+	switch v := condVal.(type) {
+	case []interface{}:
+		for _, item := range v {
+			field, ok := item.(string)
+			if !ok || field == "" {
+				return nil, fmt.Errorf("required_params: expected param names, got %v", item)
+			}
+			out = append(out, requiredParam{Field: field})
+		}
+	case map[string]interface{}:
+		for field, val := range v {
+			if field == "" {
+				return nil, fmt.Errorf("required_params: empty param name")
+			}
+			switch val.(type) {
+			case nil, string, bool, float64, int, int64:
+			default:
+				return nil, fmt.Errorf("required_params: %s must equal a scalar, got %T", field, val)
+			}
+			out = append(out, requiredParam{Field: field, Value: val})
+		}
+		// deterministic reasons when several are missing
+		sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	default:
+		return nil, fmt.Errorf("required_params: expected a list or map, got %T", condVal)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("required_params: no params listed")
+	}
+	return out, nil
+}
+
+// value at a dotted path through nested objects
+func lookup_param(params map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = params
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, cur != nil
+}
+
+// "" if params satisfies r
+func (r requiredParam) check(params map[string]interface{}) string {
+	got, ok := lookup_param(params, r.Field)
+	if !ok {
+		return fmt.Sprintf("Missing parameter %s required by required_params", r.Field)
+	}
+	if r.Value == nil {
+		return ""
+	}
+	a, aNum := as_number(got)
+	b, bNum := as_number(r.Value)
+	if (aNum && bNum && a == b) || (!aNum && !bNum && fmt.Sprint(got) == fmt.Sprint(r.Value)) {
+		return ""
+	}
+	return fmt.Sprintf("Parameter %s=%v must equal %v", r.Field, got, r.Value)
+}
+
+func (m *Manager) check_required_params(params map[string]interface{}, condVal interface{}) string {
+	required, err := parse_required_params(condVal)
+	if err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+		return ""
+	}
+	for _, r := range required {
+		if reason := r.check(params); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRequiredParams(t *testing.T) {
+	transfer := map[string]interface{}{"op": "transfer", "transfer.kind": "wire", "memo": nil}
+
+	tests := []struct {
+		name       string
+		cond       interface{}
+		params     map[string]interface{}
+		wantReason string
+	}{
+		{"list present", []interface{}{"memo", "vendor_id"}, map[string]interface{}{"memo": "x", "vendor_id": "V1"}, ""},
+		{"list missing", []interface{}{"memo", "vendor_id"}, map[string]interface{}{"memo": "x"}, "Missing parameter vendor_id required by required_params"},
+		{"null counts as missing", []interface{}{"memo"}, map[string]interface{}{"memo": nil}, "Missing parameter memo required by required_params"},
+		{
+			name:       "nested values match",
+			cond:       transfer,
+			params:     map[string]interface{}{"op": "transfer", "memo": "rent", "transfer": map[string]interface{}{"kind": "wire"}},
+			wantReason: "",
+		},
+		{
+			name:       "nested value mismatch",
+			cond:       transfer,
+			params:     map[string]interface{}{"op": "transfer", "memo": "rent", "transfer": map[string]interface{}{"kind": "ach"}},
+			wantReason: "Parameter transfer.kind=ach must equal wire",
+		},
+		{
+			name:       "nested missing",
+			cond:       transfer,
+			params:     map[string]interface{}{"op": "transfer", "memo": "rent"},
+			wantReason: "Missing parameter transfer.kind required by required_params",
+		},
+		{
+			name:       "top-level mismatch",
+			cond:       map[string]interface{}{"op": "transfer"},
+			params:     map[string]interface{}{"op": "withdraw"},
+			wantReason: "Parameter op=withdraw must equal transfer",
+		},
+		{"numeric equality", map[string]interface{}{"tier": 2}, map[string]interface{}{"tier": 2.0}, ""},
+	}
+
+	m := &Manager{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, _ := m.check_conditions(&Request{AgentID: "a", Params: tt.params}, map[string]interface{}{"required_params": tt.cond})
+			if reason != tt.wantReason {
+				t.Errorf("check_conditions() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+// memo only required above a threshold, via or
+func TestRequiredParams_AboveThreshold(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          or:
+            - max_amount: 1000
+            - required_params: [memo]
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "required.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	if d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 500.0}); !d.Allow {
+		t.Errorf("Expected small payment without memo allowed, got %s", d.Reason)
+	}
+	if d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 5000.0}); d.Allow {
+		t.Error("Expected large payment without memo denied")
+	}
+	if d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 5000.0, "memo": "Q3 invoice"}); !d.Allow {
+		t.Errorf("Expected large payment with memo allowed, got %s", d.Reason)
+	}
+}
+
+func TestRequiredParams_Validation(t *testing.T) {
+	bad := []interface{}{
+		"memo",
+		[]interface{}{},
+		[]interface{}{1},
+		map[string]interface{}{},
+		map[string]interface{}{"op": []interface{}{"a"}},
+	}
+	m := &Manager{}
+	for _, v := range bad {
+		if err := m.validate_conditions(map[string]interface{}{"required_params": v}); err == nil {
+			t.Errorf("Expected validation error for %v", v)
+		}
+	}
+}
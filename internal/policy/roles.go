@@ -0,0 +1,64 @@
+package policy
+
+import "fmt"
+
+// named permission sets agents take on with `roles`. an agent's rules
+// are its own allow/deny followed by those of each role it lists
+//
+//	roles:
+//	  finance:
+//	    allow:
+//	      - tool: payments
+//	        actions: [create, refund]
+//	        conditions:
+//	          max_amount: 5000
+//	  reader:
+//	    allow:
+//	      - tool: files
+//	        actions: [read]
+//	agents:
+//	  - id: finance-agent
+//	    roles: [finance, reader]
+//	    allow:
+//	      - tool: files
+//	        actions: [write]
+//
+// roles are resolved when the file loads and only within that file, into
+// the plain allow/deny lists Evaluate uses. role rules may `use` templates
+type Role struct {
+	Allow []Permission `yaml:"allow" json:"allow"`
+	Deny  []Permission `yaml:"deny" json:"deny,omitempty"`
+}
+
+func apply_roles(p *Policy) error {
+	for i := range p.Agents {
+		agent := &p.Agents[i]
+		seen := make(map[string]bool, len(agent.Roles))
+		for _, name := range agent.Roles {
+			role, ok := p.Roles[name]
+			if !ok {
+				return fmt.Errorf("agent %s: unknown role %q", agent.ID, name)
+			}
+			if seen[name] {
+				return fmt.Errorf("agent %s: role %q listed twice", agent.ID, name)
+			}
+			seen[name] = true
+			agent.Allow = append(agent.Allow, copy_permissions(role.Allow)...)
+			agent.Deny = append(agent.Deny, copy_permissions(role.Deny)...)
+		}
+	}
+	return nil
+}
+
+// copied like templates, so agents sharing a role never share maps
+func copy_permissions(perms []Permission) []Permission {
+	out := make([]Permission, len(perms))
+	for i, perm := range perms {
+		perm.Actions = append([]string(nil), perm.Actions...)
+		if perm.Conditions != nil {
+			perm.Conditions = copy_condition(perm.Conditions).(map[string]interface{})
+		}
+		out[i] = perm
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const rolesPolicy = `version: 1
+roles:
+  finance:
+    allow:
+      - tool: payments
+        actions: [create, refund]
+        conditions:
+          max_amount: 5000
+    deny:
+      - tool: payments
+        actions: [void]
+  reader:
+    allow:
+      - tool: files
+        actions: [read]
+agents:
+  - id: finance-agent
+    roles: [finance, reader]
+  - id: ops-agent
+    roles: [reader]
+    allow:
+      - tool: files
+        actions: [write]
+        conditions:
+          path_prefix: /ops/
+  - id: hr-agent
+    allow:
+      - tool: files
+        actions: [read]
+`
+
+func TestPolicyRoles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"roles.yaml": rolesPolicy})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	tests := []struct {
+		name                string
+		agent, tool, action string
+		params              map[string]interface{}
+		allow               bool
+	}{
+		// two roles
+		{"first role", "finance-agent", "payments", "create", map[string]interface{}{"amount": 100.0}, true},
+		{"first role's conditions", "finance-agent", "payments", "refund", map[string]interface{}{"amount": 9000.0}, false},
+		{"first role's deny rule", "finance-agent", "payments", "void", nil, false},
+		{"second role", "finance-agent", "files", "read", map[string]interface{}{"path": "/any"}, true},
+		{"neither role", "finance-agent", "files", "write", map[string]interface{}{"path": "/any"}, false},
+		// role plus inline rules
+		{"role rule", "ops-agent", "files", "read", map[string]interface{}{"path": "/any"}, true},
+		{"inline rule", "ops-agent", "files", "write", map[string]interface{}{"path": "/ops/run.log"}, true},
+		{"inline conditions", "ops-agent", "files", "write", map[string]interface{}{"path": "/hr/x"}, false},
+		{"other role not granted", "ops-agent", "payments", "create", map[string]interface{}{"amount": 1.0}, false},
+		// agents without roles are unaffected
+		{"no roles", "hr-agent", "payments", "create", map[string]interface{}{"amount": 1.0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := m.Evaluate(tt.agent, tt.tool, tt.action, tt.params)
+			if d.Allow != tt.allow {
+				t.Errorf("%s %s/%s %v: allow = %v, want %v (%s)", tt.agent, tt.tool, tt.action, tt.params, d.Allow, tt.allow, d.Reason)
+			}
+		})
+	}
+
+	// the summary lists the inherited rules with the roles they came from
+	for _, as := range m.Summaries()[0].Agents {
+		if as.ID == "finance-agent" && (len(as.Roles) != 2 || len(as.Permissions) != 2 || len(as.Deny) != 1) {
+			t.Errorf("Expected finance-agent's roles flattened into its summary, got %+v", as)
+		}
+	}
+}
+
+func TestPolicyRoleErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"roles.yaml": rolesPolicy})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	tests := []struct {
+		name, content, want string
+	}{
+		{"missing role", strings.Replace(rolesPolicy, "roles: [finance, reader]", "roles: [finance, auditor]", 1), `agent finance-agent: unknown role "auditor"`},
+		{"role listed twice", strings.Replace(rolesPolicy, "roles: [finance, reader]", "roles: [reader, reader]", 1), `agent finance-agent: role "reader" listed twice`},
+	}
+	for _, tt := range tests {
+		writePolicies(t, tmpDir, map[string]string{"roles.yaml": tt.content})
+		var list LoadErrorList
+		if err := m.Reload(); !errors.As(err, &list) || len(list) != 1 {
+			t.Errorf("%s: expected the file to be rejected, got %v", tt.name, err)
+			continue
+		}
+		if !strings.Contains(list[0].Error, tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, list[0].Error)
+		}
+	}
+
+	res := m.ValidatePolicy("roles.yaml", []byte(tests[0].content))
+	if res.Valid || len(res.Errors) != 1 || res.Errors[0].Stage != StageRoles {
+		t.Errorf("Expected a %s stage error, got %+v", StageRoles, res)
+	}
+}
+
+// role rules can use templates, and each agent gets its own copy
+func TestPolicyRolesUseTemplates(t *testing.T) {
+	p := Policy{
+		Templates: map[string]Permission{"small": {
+			Tool:       "payments",
+			Actions:    []string{"create"},
+			Conditions: map[string]interface{}{"max_amount": 100},
+		}},
+		Roles: map[string]Role{"payer": {Allow: []Permission{{
+			Use:        "small",
+			Conditions: map[string]interface{}{"currencies": []interface{}{"USD"}},
+		}}}},
+		Agents: []Agent{
+			{ID: "a", Roles: []string{"payer"}},
+			{ID: "b", Roles: []string{"payer"}},
+		},
+	}
+	if err := apply_roles(&p); err != nil {
+		t.Fatalf("apply_roles() error = %v", err)
+	}
+	if err := apply_templates(&p); err != nil {
+		t.Fatalf("apply_templates() error = %v", err)
+	}
+	a, b := p.Agents[0].Allow[0], p.Agents[1].Allow[0]
+	if a.Tool != "payments" || a.Conditions["max_amount"] != 100 || a.Conditions["currencies"] == nil {
+		t.Errorf("Expected the template applied to the role rule, got %+v", a)
+	}
+	a.Conditions["max_amount"] = 5
+	if b.Conditions["max_amount"] != 100 {
+		t.Error("Expected agents sharing a role not to share conditions")
+	}
+	if len(p.Roles["payer"].Allow[0].Conditions) != 1 {
+		t.Errorf("Expected the role itself untouched, got %v", p.Roles["payer"].Allow[0].Conditions)
+	}
+}
@@ -0,0 +1,82 @@
+package policy
+
+import "fmt"
+
+// policy file schema versions. files without schema_version are version
+// 1 and are migrated in memory when loaded; anything newer than
+// CurrentSchemaVersion is rejected rather than half understood.
+//
+//	1: original format
+//	2: folder_prefix renamed to path_prefix
+const (
+	SchemaVersion1       = 1
+	CurrentSchemaVersion = 2
+)
+
+// something the loader changed or noticed in a file that still loaded
+type PolicyLoadNote struct {
+	File      string `json:"file"`
+	Note      string `json:"note"`
+	Timestamp string `json:"timestamp"`
+}
+
+// bring a parsed file up to CurrentSchemaVersion. returns a note for
+// each migration that changed something
+func migrate_policy(p *Policy) ([]string, error) {
+	if p.SchemaVersion == 0 {
+		p.SchemaVersion = SchemaVersion1
+	}
+	if p.SchemaVersion < SchemaVersion1 || p.SchemaVersion > CurrentSchemaVersion {
+		return nil, fmt.Errorf("unsupported schema_version %d, this gateway understands %d to %d",
+			p.SchemaVersion, SchemaVersion1, CurrentSchemaVersion)
+	}
+
+	var notes []string
+	if p.SchemaVersion == SchemaVersion1 {
+		renamed := 0
+		for _, agent := range p.Agents {
+			for _, perm := range append(agent.Allow, agent.Deny...) {
+				renamed += rename_condition(perm.Conditions, "folder_prefix", "path_prefix")
+			}
+		}
+		p.SchemaVersion = 2
+		if renamed > 0 {
+			notes = append(notes, fmt.Sprintf("migrated from schema_version 1 to 2: renamed folder_prefix to path_prefix in %d condition(s)", renamed))
+		}
+	}
+	return notes, nil
+}
+
+// rename a condition, including inside and/or/not. returns how many were
+// renamed
+func rename_condition(conditions map[string]interface{}, from, to string) int {
+	n := 0
+	if v, ok := conditions[from]; ok {
+		if _, clash := conditions[to]; !clash {
+			delete(conditions, from)
+			conditions[to] = v
+			n++
+		}
+	}
+	for name, val := range conditions {
+		switch name {
+		case "and", "or":
+			list, _ := val.([]interface{})
+			for _, item := range list {
+				if inner, ok := item.(map[string]interface{}); ok {
+					n += rename_condition(inner, from, to)
+				}
+			}
+		case "not":
+			if inner, ok := val.(map[string]interface{}); ok {
+				n += rename_condition(inner, from, to)
+			}
+		}
+	}
+	return n
+}
+
+// files changed by migration on the most recent load
+func (m *Manager) LoadNotes() []PolicyLoadNote {
+	return append([]PolicyLoadNote(nil), m.snapshot().loadNotes...)
+}
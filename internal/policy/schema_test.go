@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPolicySchemaVersions(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		// no schema_version: version 1, migrated on load
+		"v1.yaml": `version: 3
+agents:
+  - id: hr-agent
+    allow:
+      - tool: files
+        actions: [read]
+        conditions:
+          folder_prefix: "/hr-docs/"
+          not:
+            folder_prefix: "/hr-docs/private/"
+`,
+		"current.yaml": `version: 1
+schema_version: 2
+agents:
+  - id: legal-agent
+    allow:
+      - tool: files
+        actions: [read]
+        conditions:
+          path_prefix: "/legal/"
+`,
+		"future.yaml": `version: 1
+schema_version: 99
+agents:
+  - id: future-agent
+    allow:
+      - tool: files
+        actions: [read]
+`,
+		"stale.yaml": `version: 1
+schema_version: 2
+agents:
+  - id: stale-agent
+    allow:
+      - tool: files
+        actions: [read]
+        conditions:
+          folder_prefix: "/legal/"
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write policy: %v", err)
+		}
+	}
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	// v1 file works as before, with the rename recorded
+	if d := m.Evaluate("hr-agent", "files", "read", map[string]interface{}{"path": "/hr-docs/handbook.pdf"}); !d.Allow || d.Version != 3 {
+		t.Errorf("Expected migrated v1 policy to allow at version 3, got %+v", d)
+	}
+	if d := m.Evaluate("hr-agent", "files", "read", map[string]interface{}{"path": "/hr-docs/private/pay.pdf"}); d.Allow {
+		t.Error("Expected the nested folder_prefix to be migrated too")
+	}
+	notes := m.LoadNotes()
+	if len(notes) != 1 || notes[0].File != "v1.yaml" || !strings.Contains(notes[0].Note, "renamed folder_prefix to path_prefix in 2 condition(s)") {
+		t.Errorf("Expected one migration note for v1.yaml, got %+v", notes)
+	}
+
+	if d := m.Evaluate("legal-agent", "files", "read", map[string]interface{}{"path": "/legal/nda.pdf"}); !d.Allow {
+		t.Errorf("Expected current-version policy to allow, got %s", d.Reason)
+	}
+
+	errs := map[string]string{}
+	for _, e := range m.LoadErrors() {
+		errs[e.File] = e.Error
+	}
+	if !strings.Contains(errs["future.yaml"], "unsupported schema_version 99") {
+		t.Errorf("Expected future.yaml rejected for its schema version, got %q", errs["future.yaml"])
+	}
+	if !strings.Contains(errs["stale.yaml"], "folder_prefix was renamed to path_prefix") {
+		t.Errorf("Expected stale.yaml rejected for the old condition name, got %q", errs["stale.yaml"])
+	}
+	if len(errs) != 2 {
+		t.Errorf("Expected exactly 2 rejected files, got %v", errs)
+	}
+}
@@ -0,0 +1,139 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// how long monotonic_field changes are batched before the state file is
+// rewritten. a crash loses at most this much
+const sequenceSaveDelay = time.Second
+
+// tracks the last accepted value per agent+field for monotonic_field
+// conditions, optionally persisted so restarts can't be used to roll back
+type sequenceTracker struct {
+	mu        sync.Mutex
+	values    map[string]float64
+	path      string // empty = in-memory only
+	saveDelay time.Duration
+	pending   *time.Timer // scheduled save
+}
+
+func newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{values: make(map[string]float64), saveDelay: sequenceSaveDelay}
+}
+
+func sequenceKey(agentID, field string) string {
+	return agentID + "|" + field
+}
+
+func (s *sequenceTracker) last(agentID, field string) (float64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[sequenceKey(agentID, field)]
+	return v, ok
+}
+
+// store val if it is greater than the last value, returns the previous
+// value and false when it isn't. the returned func puts the previous
+// value back, unless val has been passed since
+func (s *sequenceTracker) advance(agentID, field string, val float64) (float64, bool, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := sequenceKey(agentID, field)
+	last, had := s.values[key]
+	if had && val <= last {
+		return last, false, nil
+	}
+	s.values[key] = val
+	s.persist()
+	return val, true, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.values[key] != val {
+			return
+		}
+		if had {
+			s.values[key] = last
+		} else {
+			delete(s.values, key)
+		}
+		s.persist()
+	}
+}
+
+// schedule a save, so a burst of requests rewrites the file once. caller
+// must hold s.mu
+func (s *sequenceTracker) persist() {
+	if s.path == "" || s.pending != nil {
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(s.saveDelay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// flushed and stopped too late: the save's been done
+		if s.pending != t {
+			return
+		}
+		s.pending = nil
+		if err := s.save(); err != nil {
+			fmt.Printf("ERROR: failed to persist sequence state: %v\n", err)
+		}
+	})
+	s.pending = t
+}
+
+// caller must hold s.mu
+func (s *sequenceTracker) save() error {
+	data, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// persist monotonic_field state to path, loading any previously saved values
+func (m *Manager) SetSequenceStateFile(path string) error {
+	s := m.sequences
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read sequence state: %w", err)
+	}
+	if len(data) > 0 {
+		values := make(map[string]float64)
+		if err := json.Unmarshal(data, &values); err != nil {
+			return fmt.Errorf("failed to parse sequence state: %w", err)
+		}
+		s.values = values
+	}
+	s.path = path
+	return nil
+}
+
+// write pending monotonic_field state now, e.g. on shutdown
+func (m *Manager) FlushSequenceState() error {
+	s := m.sequences
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		return nil
+	}
+	s.pending.Stop()
+	s.pending = nil
+	return s.save()
+}
@@ -0,0 +1,136 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeSequencePolicy(t *testing.T) string {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: counter-agent
+    allow:
+      - tool: ledger
+        actions: [append]
+        conditions:
+          monotonic_field: sequence
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "seq.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+	return tmpDir
+}
+
+func TestMonotonicField(t *testing.T) {
+	m, err := NewManager(writeSequencePolicy(t))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	steps := []struct {
+		seq       float64
+		wantAllow bool
+	}{
+		{1, true},
+		{2, true},
+		{5, true},
+		{5, false}, // repeated
+		{3, false}, // rollback
+		{6, true},
+	}
+
+	for _, s := range steps {
+		d := m.Evaluate("counter-agent", "ledger", "append", map[string]interface{}{"sequence": s.seq})
+		if d.Allow != s.wantAllow {
+			t.Errorf("sequence %v: Allow = %v, want %v. Reason: %s", s.seq, d.Allow, s.wantAllow, d.Reason)
+		}
+	}
+}
+
+func TestMonotonicField_MissingParam(t *testing.T) {
+	m, err := NewManager(writeSequencePolicy(t))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	d := m.Evaluate("counter-agent", "ledger", "append", map[string]interface{}{})
+	if d.Allow {
+		t.Error("Expected request without sequence to be denied")
+	}
+	if d.Reason != "Invalid sequence parameter" {
+		t.Errorf("Unexpected reason: %s", d.Reason)
+	}
+}
+
+func TestMonotonicField_PersistsAcrossRestart(t *testing.T) {
+	dir := writeSequencePolicy(t)
+	statePath := filepath.Join(t.TempDir(), "sequences.json")
+
+	m, _ := NewManager(dir)
+	if err := m.SetSequenceStateFile(statePath); err != nil {
+		t.Fatalf("SetSequenceStateFile() error = %v", err)
+	}
+	if d := m.Evaluate("counter-agent", "ledger", "append", map[string]interface{}{"sequence": 10.0}); !d.Allow {
+		t.Fatalf("Expected first value allowed: %s", d.Reason)
+	}
+	if err := m.FlushSequenceState(); err != nil {
+		t.Fatalf("FlushSequenceState() error = %v", err)
+	}
+
+	// new manager simulating a restart
+	m2, _ := NewManager(dir)
+	if err := m2.SetSequenceStateFile(statePath); err != nil {
+		t.Fatalf("SetSequenceStateFile() error = %v", err)
+	}
+	defer m2.FlushSequenceState()
+	if d := m2.Evaluate("counter-agent", "ledger", "append", map[string]interface{}{"sequence": 9.0}); d.Allow {
+		t.Error("Expected rollback after restart to be denied")
+	}
+	if d := m2.Evaluate("counter-agent", "ledger", "append", map[string]interface{}{"sequence": 11.0}); !d.Allow {
+		t.Errorf("Expected increasing value after restart allowed: %s", d.Reason)
+	}
+}
+
+func TestMonotonicField_PerAgent(t *testing.T) {
+	tr := newSequenceTracker()
+	tr.advance("a", "sequence", 5)
+	if _, ok, _ := tr.advance("b", "sequence", 1); !ok {
+		t.Error("Expected other agent's sequence to be tracked independently")
+	}
+}
+
+// a burst of requests is saved once, after the delay
+func TestMonotonicField_BatchesSaves(t *testing.T) {
+	m, _ := NewManager(writeSequencePolicy(t))
+	statePath := filepath.Join(t.TempDir(), "sequences.json")
+	if err := m.SetSequenceStateFile(statePath); err != nil {
+		t.Fatalf("SetSequenceStateFile() error = %v", err)
+	}
+	m.sequences.mu.Lock()
+	m.sequences.saveDelay = 50 * time.Millisecond
+	m.sequences.mu.Unlock()
+
+	for i := 1; i <= 20; i++ {
+		if d := m.Evaluate("counter-agent", "ledger", "append", map[string]interface{}{"sequence": float64(i)}); !d.Allow {
+			t.Fatalf("Expected sequence %d allowed: %s", i, d.Reason)
+		}
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Fatalf("Expected no save before the delay, got %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, _ := os.ReadFile(statePath)
+		if string(data) == `{"counter-agent|sequence":20}` {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the last value saved, got %s", data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
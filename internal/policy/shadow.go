@@ -0,0 +1,29 @@
+package policy
+
+// run candidate policies in shadow mode: every request is also evaluated
+// against s and its decision attached as Decision.Shadow, while only m's
+// decision is enforced. s keeps its own state for stateful conditions
+// (daily_limit, ...), which only moves for requests the enforced policy
+// let through, so it tracks what would actually have run. nil turns
+// shadow mode off
+func (m *Manager) SetShadow(s *Manager) {
+	m.shadow.Store(s)
+}
+
+// the shadow manager set with SetShadow, nil when off
+func (m *Manager) Shadow() *Manager {
+	return m.shadow.Load()
+}
+
+// the request as the shadow policy sees it
+func shadow_request(req Request, enforced Decision) Request {
+	req.Debug = false
+	req.DryRun = req.DryRun || !enforced.Allow
+	req.trace = nil
+	return req
+}
+
+// the shadow policy decided differently than the enforced one
+func (d Decision) Diverged() bool {
+	return d.Shadow != nil && d.Shadow.Allow != d.Allow
+}
@@ -0,0 +1,69 @@
+package policy
+
+import "testing"
+
+func TestShadowEvaluation(t *testing.T) {
+	liveDir, shadowDir := t.TempDir(), t.TempDir()
+	writePolicies(t, liveDir, map[string]string{"live.yaml": `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+`})
+	writePolicies(t, shadowDir, map[string]string{"next.yaml": `version: 2
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+          daily_limit: 1000
+`})
+
+	live, err := NewManager(liveDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	shadow, err := NewManager(shadowDir)
+	if err != nil {
+		t.Fatalf("Failed to create shadow manager: %v", err)
+	}
+	if d := live.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 800.0}); d.Shadow != nil {
+		t.Errorf("Expected no shadow decision without a shadow manager, got %+v", d.Shadow)
+	}
+	live.SetShadow(shadow)
+
+	// denied by the live policy, so it never ran and mustn't count towards
+	// the shadow daily_limit
+	d := live.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 6000.0})
+	if d.Allow || d.Shadow == nil || d.Shadow.Allow || d.Diverged() {
+		t.Errorf("Expected both to deny 6000, got %+v", d)
+	}
+
+	d = live.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 800.0})
+	if !d.Allow || d.Shadow == nil || !d.Shadow.Allow || d.Diverged() {
+		t.Errorf("Expected both to allow the first 800, got %+v", d)
+	}
+
+	// the shadow daily_limit would have stopped the second one; the live
+	// policy still decides
+	d = live.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 800.0})
+	if !d.Allow {
+		t.Errorf("Expected the live policy to allow the second 800: %s", d.Reason)
+	}
+	if !d.Diverged() || d.Shadow.Version != 2 || ReasonCategory(d.Shadow.Reason) != CategoryLimitExceeded {
+		t.Errorf("Expected the shadow daily_limit to deny, got %+v", d.Shadow)
+	}
+
+	live.SetShadow(nil)
+	if live.Shadow() != nil {
+		t.Error("Expected shadow mode to be off")
+	}
+	if d := live.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 800.0}); d.Shadow != nil {
+		t.Errorf("Expected no shadow decision once turned off, got %+v", d.Shadow)
+	}
+}
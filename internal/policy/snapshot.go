@@ -0,0 +1,70 @@
+package policy
+
+import "time"
+
+// one loaded policy set and everything derived from it. a snapshot is
+// never modified once published: load_policies and Reload build a new one
+// and swap the pointer, so evaluations read it without locking and see
+// either the old set or the new one, never a mix
+type policySnapshot struct {
+	policies map[string]Policy
+
+	// policies regrouped for lookup, always built from policies
+	index policyIndex
+
+	// tool -> actions some rule names, "*" included, from index
+	named map[string]map[string]bool
+
+	// files that failed the last load or reload
+	loadErrors []PolicyLoadError
+
+	// migrations applied by the last successful load
+	loadNotes []PolicyLoadNote
+
+	// when the set was loaded, by NewManager or a successful Reload
+	loadedAt time.Time
+}
+
+func new_policy_snapshot(policies map[string]Policy, names []string) *policySnapshot {
+	idx := build_index(policies, names)
+	return &policySnapshot{policies: policies, index: idx, named: idx.named()}
+}
+
+// the active snapshot, empty before the first load
+func (m *Manager) snapshot() *policySnapshot {
+	if s := m.active.Load(); s != nil {
+		return s
+	}
+	return &policySnapshot{}
+}
+
+// the clock and lookups conditions consult. swapped whole like the
+// snapshot, so setting them doesn't block evaluations either
+type managerSettings struct {
+	clock Clock
+
+	// approver ID -> HMAC key for require_dual_approval tokens
+	approverKeys map[string][]byte
+
+	// approved changes for require_change_window
+	changeWindows ChangeWindows
+
+	// payment owners for owns_payment
+	paymentOwners PaymentOwners
+}
+
+func (m *Manager) settings() *managerSettings {
+	if s := m.conf.Load(); s != nil {
+		return s
+	}
+	return &managerSettings{}
+}
+
+// publish a copy of the settings with fn applied
+func (m *Manager) update_settings(fn func(*managerSettings)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := *m.settings()
+	fn(&s)
+	m.conf.Store(&s)
+}
@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// version and limits move together, so a decision mixing two sets shows
+// up as an allow under the wrong version
+func snapshot_policy(version int) string {
+	return fmt.Sprintf(`version: %d
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: %d
+    deny:
+      - tool: payments
+        actions: [void]
+`, version, version*100)
+}
+
+func TestReloadDuringEvaluate(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "p.yaml")
+	if err := os.WriteFile(path, []byte(snapshot_policy(1)), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	var stop atomic.Bool
+	var evaluations atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				d := m.EvaluateRequest(Request{
+					AgentID: "finance-agent",
+					Tool:    "payments",
+					Action:  "create",
+					Params:  map[string]interface{}{"amount": 150.0},
+					DryRun:  true,
+				})
+				if d.Version != 1 && d.Version != 2 {
+					t.Errorf("Unexpected version %d", d.Version)
+					return
+				}
+				// 150 is over version 1's limit and under version 2's
+				if d.Allow != (d.Version == 2) {
+					t.Errorf("Torn read: allow=%v under version %d (%s)", d.Allow, d.Version, d.Reason)
+					return
+				}
+				if d := m.Evaluate("finance-agent", "payments", "void", nil); d.Allow {
+					t.Error("Expected the deny rule to hold in every version")
+					return
+				}
+				m.Summaries()
+				m.LoadErrors()
+				evaluations.Add(1)
+			}
+		}()
+	}
+
+	deadline := time.Now().Add(300 * time.Millisecond)
+	for reloads := 0; time.Now().Before(deadline) || reloads < 20; reloads++ {
+		if err := os.WriteFile(path, []byte(snapshot_policy(reloads%2+1)), 0644); err != nil {
+			t.Fatalf("Failed to write test policy: %v", err)
+		}
+		if err := m.Reload(); err != nil {
+			t.Fatalf("Reload() error = %v", err)
+		}
+		// a rejected reload publishes its errors alongside the same set
+		if reloads%5 == 0 {
+			os.WriteFile(path, []byte("version: [broken"), 0644)
+			if err := m.Reload(); err == nil {
+				t.Fatal("Expected a broken file to fail the reload")
+			}
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	if evaluations.Load() == 0 {
+		t.Fatal("Expected evaluations to run during the reloads")
+	}
+}
@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// window daily_limit totals are summed over
+const spendWindow = 24 * time.Hour
+
+// daily_limit: caps the total amount an agent may pay within a rolling 24h
+//
+//	conditions:
+//	  daily_limit: 10000
+func parse_daily_limit(condVal interface{}) (float64, error) {
+	var limit float64
+	switch v := condVal.(type) {
+	case int:
+		limit = float64(v)
+	case float64:
+		limit = v
+	default:
+		return 0, fmt.Errorf("daily_limit: expected a number, got %T", condVal)
+	}
+	if limit <= 0 {
+		return 0, fmt.Errorf("daily_limit: must be positive")
+	}
+	return limit, nil
+}
+
+type spend struct {
+	at     time.Time
+	amount float64
+}
+
+// per-agent payments within the window
+type spendTracker struct {
+	mu     sync.Mutex
+	agents map[string][]spend
+}
+
+func newSpendTracker() *spendTracker {
+	return &spendTracker{agents: make(map[string][]spend)}
+}
+
+// drop payments older than the window and return the remaining total,
+// caller must hold t.mu
+func (t *spendTracker) prune(agentID string, now time.Time) float64 {
+	kept := t.agents[agentID][:0]
+	total := 0.0
+	for _, s := range t.agents[agentID] {
+		if now.Sub(s.at) < spendWindow {
+			kept = append(kept, s)
+			total += s.amount
+		}
+	}
+	if len(kept) == 0 {
+		delete(t.agents, agentID)
+	} else {
+		t.agents[agentID] = kept
+	}
+	return total
+}
+
+// check whether paying amount would exceed the limit, without recording it
+func (t *spendTracker) check(agentID string, amount float64, now time.Time, limit float64) string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return spend_reason(t.prune(agentID, now), amount, limit)
+}
+
+// record a payment, denying if it would exceed any of the limits. the
+// returned func takes the payment back
+func (t *spendTracker) record(agentID string, amount float64, now time.Time, limits ...float64) (string, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total := t.prune(agentID, now)
+	for _, limit := range limits {
+		if reason := spend_reason(total, amount, limit); reason != "" {
+			return reason, nil
+		}
+	}
+	t.agents[agentID] = append(t.agents[agentID], spend{at: now, amount: amount})
+	return "", func() { t.remove(agentID, amount, now) }
+}
+
+// take back a payment recorded at at, e.g. when its request was denied
+// after all
+func (t *spendTracker) remove(key string, amount float64, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spends := t.agents[key]
+	for i := len(spends) - 1; i >= 0; i-- {
+		if spends[i].at.Equal(at) && spends[i].amount == amount {
+			spends = append(spends[:i], spends[i+1:]...)
+			break
+		}
+	}
+	if len(spends) == 0 {
+		delete(t.agents, key)
+	} else {
+		t.agents[key] = spends
+	}
+}
+
+// amounts must be positive: a negative one would free up budget
+func spend_reason(total, amount, limit float64) string {
+	if amount <= 0 {
+		return "Invalid amount parameter"
+	}
+	if total+amount > limit {
+		return fmt.Sprintf("Amount %.2f would exceed daily_limit=%.2f (%.2f spent in the last 24h)", amount, limit, total)
+	}
+	return ""
+}
@@ -0,0 +1,218 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newSpendManager(t *testing.T) *Manager {
+	return newSpendManagerWith(t, "daily_limit: 10000")
+}
+
+// conditions are indented under the rule's conditions key
+func newSpendManagerWith(t *testing.T, conditions string) *Manager {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          ` + conditions + "\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "spend.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	return m
+}
+
+func TestDailyLimit(t *testing.T) {
+	m := newSpendManager(t)
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	m.SetClock(clock)
+
+	pay := func(amount float64) Decision {
+		return m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": amount})
+	}
+
+	if d := pay(6000); !d.Allow {
+		t.Fatalf("First payment should be allowed: %s", d.Reason)
+	}
+	clock.t = clock.t.Add(6 * time.Hour)
+	if d := pay(4000); !d.Allow {
+		t.Fatalf("Payment up to the limit should be allowed: %s", d.Reason)
+	}
+	d := pay(0.01)
+	if d.Allow {
+		t.Fatal("Payment past the limit should be denied")
+	}
+	if d.Reason != "Amount 0.01 would exceed daily_limit=10000.00 (10000.00 spent in the last 24h)" {
+		t.Errorf("Unexpected reason: %s", d.Reason)
+	}
+
+	// rolling window: the first payment ages out, the second doesn't
+	clock.t = time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	if d := pay(6000); !d.Allow {
+		t.Errorf("Payment should be allowed once the first ages out: %s", d.Reason)
+	}
+	if d := pay(1); d.Allow {
+		t.Error("Expected 4000 + 6000 within the window to block further spend")
+	}
+}
+
+func TestDailyLimit_DryRun(t *testing.T) {
+	m := newSpendManager(t)
+
+	req := Request{AgentID: "finance-agent", Tool: "payments", Action: "create", Params: map[string]interface{}{"amount": 10000.0}, DryRun: true}
+	for i := 0; i < 2; i++ {
+		if d := m.EvaluateRequest(req); !d.Allow {
+			t.Fatalf("Dry run %d denied: %s", i, d.Reason)
+		}
+	}
+
+	// dry runs didn't count against the limit
+	req.DryRun = false
+	if d := m.EvaluateRequest(req); !d.Allow {
+		t.Errorf("Expected real request to be allowed after dry runs, got %s", d.Reason)
+	}
+}
+
+func TestDailyLimit_Validation(t *testing.T) {
+	m := &Manager{}
+	for _, v := range []interface{}{0, -1, "10000"} {
+		if err := m.validate_conditions(map[string]interface{}{"daily_limit": v}); err == nil {
+			t.Errorf("Expected validation error for %v", v)
+		}
+	}
+}
+
+// a negative payment must not free up budget for the ones after it
+func TestDailyLimit_NonPositiveAmounts(t *testing.T) {
+	m := newSpendManagerWith(t, "daily_limit: 100")
+	pay := func(amount interface{}) Decision {
+		return m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": amount})
+	}
+
+	for _, amount := range []interface{}{-1000.0, 0.0, "-5"} {
+		d := pay(amount)
+		if d.Allow || d.ReasonCode != ReasonInvalidParams {
+			t.Errorf("Expected amount %v to be rejected as invalid, got allow=%v %s (%s)", amount, d.Allow, d.ReasonCode, d.Reason)
+		}
+	}
+	if d := pay(90.0); !d.Allow {
+		t.Fatalf("Expected 90 to be allowed: %s", d.Reason)
+	}
+	if d := pay(15.0); d.Allow {
+		t.Error("Expected 90 + 15 to exceed the limit")
+	}
+}
+
+// stateful conditions under and/or are recorded like top-level ones
+func TestDailyLimit_Nested(t *testing.T) {
+	tests := map[string]string{
+		"and": "and: [{daily_limit: 100}]",
+		"or":  "or: [{currencies: [EUR]}, {daily_limit: 100}]",
+	}
+	for name, conditions := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := newSpendManagerWith(t, conditions)
+			allowed := 0
+			for i := 0; i < 5; i++ {
+				d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 60.0, "currency": "USD"})
+				if d.Allow {
+					allowed++
+				}
+			}
+			if allowed != 1 {
+				t.Errorf("Expected only the first payment of 60 within daily_limit=100, %d allowed", allowed)
+			}
+		})
+	}
+
+	// the or branch that let the request through is the one recorded
+	m := newSpendManagerWith(t, "or: [{currencies: [EUR]}, {daily_limit: 100}]")
+	for i := 0; i < 3; i++ {
+		if d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 60.0, "currency": "EUR"}); !d.Allow {
+			t.Fatalf("Expected EUR payment %d to pass the currencies branch: %s", i, d.Reason)
+		}
+	}
+	if d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 60.0, "currency": "USD"}); !d.Allow {
+		t.Errorf("Expected EUR payments not to count against daily_limit: %s", d.Reason)
+	}
+}
+
+func TestCommitState_NestedMonotonic(t *testing.T) {
+	m := newSpendManagerWith(t, "and: [{monotonic_field: seq}]")
+	seq := func(n float64) Decision {
+		return m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"seq": n})
+	}
+	if d := seq(5); !d.Allow {
+		t.Fatalf("Expected seq 5 to be allowed: %s", d.Reason)
+	}
+	if d := seq(5); d.Allow {
+		t.Error("Expected a repeated seq under and to be denied")
+	}
+}
+
+// a daily_limit both top-level and nested counts the payment once
+func TestDailyLimit_Repeated(t *testing.T) {
+	m := newSpendManagerWith(t, "daily_limit: 100\n          and: [{daily_limit: 150}, {monotonic_field: seq}]")
+	for i, amount := range []float64{60, 40} {
+		d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": amount, "seq": float64(i + 1)})
+		if !d.Allow {
+			t.Fatalf("Expected payment %d of %.0f to be allowed: %s", i, amount, d.Reason)
+		}
+	}
+	if d := m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"amount": 1.0, "seq": 3.0}); d.Allow {
+		t.Error("Expected the top-level daily_limit to be used up")
+	}
+}
+
+// a condition denying at commit takes back what the ones before it recorded
+func TestCommitState_RollsBack(t *testing.T) {
+	m := &Manager{spends: newSpendTracker(), writes: newWriteBudgetTracker(), sequences: newSequenceTracker()}
+	conditions := map[string]interface{}{"daily_limit": 100, "monotonic_field": "seq"}
+	commit := func(amount, seq float64) string {
+		reason, _ := m.commit_state(&Request{AgentID: "a", Params: map[string]interface{}{"amount": amount, "seq": seq}}, conditions)
+		return reason
+	}
+	if reason := commit(10, 5); reason != "" {
+		t.Fatalf("Expected the first payment recorded, got %s", reason)
+	}
+	// seq 5 again: recorded the spend before monotonic_field denied
+	if reason := commit(60, 5); reason == "" {
+		t.Fatal("Expected the repeated seq to be denied")
+	}
+	if reason := commit(90, 6); reason != "" {
+		t.Errorf("Expected the denied payment taken back, got %s", reason)
+	}
+}
+
+// a nested daily_limit lower than the top-level one still applies
+func TestDailyLimit_RepeatedDifferentLimits(t *testing.T) {
+	m := &Manager{spends: newSpendTracker()}
+	conditions := map[string]interface{}{
+		"daily_limit": 1000,
+		"and":         []interface{}{map[string]interface{}{"daily_limit": 100}},
+	}
+	commit := func(amount float64) string {
+		reason, _ := m.commit_state(&Request{AgentID: "a", Params: map[string]interface{}{"amount": amount}}, conditions)
+		return reason
+	}
+	if reason := commit(60); reason != "" {
+		t.Fatalf("Expected 60 within both limits, got %s", reason)
+	}
+	if reason := commit(60); reason == "" {
+		t.Error("Expected the nested daily_limit=100 to deny a second 60")
+	}
+	if reason := commit(40); reason != "" {
+		t.Errorf("Expected the payment counted once, got %s", reason)
+	}
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"sort"
+	"time"
+)
+
+// what a loaded policy file grants, without secrets or condition values
+type PolicySummary struct {
+	File          string         `json:"file"`
+	Version       int            `json:"version"`
+	SchemaVersion int            `json:"schema_version"`
+	Agents        []AgentSummary `json:"agents"`
+}
+
+type AgentSummary struct {
+	ID          string              `json:"id"`
+	HasAPIKey   bool                `json:"has_api_key"`
+	Permissions []PermissionSummary `json:"permissions"`
+
+	// roles the agent takes on, their rules are included in Permissions
+	// and Deny
+	Roles []string `json:"roles,omitempty"`
+
+	// effective for this file, from the agent or the file
+	DefaultAction string              `json:"default_action,omitempty"`
+	Deny          []PermissionSummary `json:"deny,omitempty"`
+
+	// enabled: false in this file
+	Disabled bool `json:"disabled,omitempty"`
+
+	// quota group joined in this file and the agent's weight there
+	QuotaGroup  string  `json:"quota_group,omitempty"`
+	QuotaWeight float64 `json:"quota_weight,omitempty"`
+}
+
+type PermissionSummary struct {
+	Tool    string   `json:"tool"`
+	Actions []string `json:"actions"`
+
+	// names of the conditions attached, sorted
+	Conditions []string `json:"conditions,omitempty"`
+
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
+
+// summaries of the currently loaded policies, sorted by file name
+func (m *Manager) Summaries() []PolicySummary {
+	policies := m.snapshot().policies
+	out := make([]PolicySummary, 0, len(policies))
+	for file, p := range policies {
+		ps := PolicySummary{File: file, Version: p.Version, SchemaVersion: p.SchemaVersion, Agents: make([]AgentSummary, 0, len(p.Agents))}
+		for _, agent := range p.Agents {
+			as := AgentSummary{
+				ID:          agent.ID,
+				HasAPIKey:   agent.APIKeySHA256 != "",
+				Permissions: make([]PermissionSummary, 0, len(agent.Allow)),
+				Roles:       agent.Roles,
+
+				DefaultAction: effective_default(p, agent),
+				Disabled:      !agent.is_enabled(),
+			}
+			if agent.QuotaGroup != "" {
+				as.QuotaGroup, as.QuotaWeight = agent.QuotaGroup, agent.quota_weight()
+			}
+			for _, perm := range agent.Allow {
+				as.Permissions = append(as.Permissions, summarize_permission(perm))
+			}
+			for _, perm := range agent.Deny {
+				as.Deny = append(as.Deny, summarize_permission(perm))
+			}
+			ps.Agents = append(ps.Agents, as)
+		}
+		out = append(out, ps)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].File < out[j].File })
+	return out
+}
+
+func summarize_permission(perm Permission) PermissionSummary {
+	var conds []string
+	for name := range perm.Conditions {
+		conds = append(conds, name)
+	}
+	sort.Strings(conds)
+	return PermissionSummary{
+		Tool:       perm.Tool,
+		Actions:    append([]string(nil), perm.Actions...),
+		Conditions: conds,
+		ValidFrom:  perm.ValidFrom,
+		ValidUntil: perm.ValidUntil,
+	}
+}
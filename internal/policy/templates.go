@@ -0,0 +1,106 @@
+package policy
+
+import "fmt"
+
+// shared permission blocks. a permission with `use` starts from the named
+// template: its own tool and validity window win, actions are combined,
+// and its own conditions override the template's of the same name
+//
+//	templates:
+//	  small-usd-payments:
+//	    tool: payments
+//	    actions: [create]
+//	    conditions:
+//	      max_amount: 1000
+//	      currencies: [USD]
+//	agents:
+//	  - id: finance-agent
+//	    allow:
+//	      - use: small-usd-payments
+//	  - id: refunds-agent
+//	    allow:
+//	      - use: small-usd-payments
+//	        actions: [refund]
+//	        conditions:
+//	          max_amount: 200
+//
+// templates are resolved when the file loads and only within that file
+func apply_templates(p *Policy) error {
+	for name, tmpl := range p.Templates {
+		if tmpl.Use != "" {
+			return fmt.Errorf("template %s: templates cannot use other templates", name)
+		}
+	}
+	for i := range p.Agents {
+		agent := &p.Agents[i]
+		for j := range agent.Allow {
+			if err := use_template(p.Templates, &agent.Allow[j]); err != nil {
+				return fmt.Errorf("agent %s: %w", agent.ID, err)
+			}
+		}
+		for j := range agent.Deny {
+			if err := use_template(p.Templates, &agent.Deny[j]); err != nil {
+				return fmt.Errorf("agent %s: %w", agent.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func use_template(templates map[string]Permission, perm *Permission) error {
+	if perm.Use == "" {
+		return nil
+	}
+	tmpl, ok := templates[perm.Use]
+	if !ok {
+		return fmt.Errorf("unknown template %q", perm.Use)
+	}
+	if perm.Tool == "" {
+		perm.Tool = tmpl.Tool
+	}
+	actions := append([]string(nil), tmpl.Actions...)
+	for _, a := range perm.Actions {
+		if !contains(actions, a) {
+			actions = append(actions, a)
+		}
+	}
+	perm.Actions = actions
+	if perm.ValidFrom == nil {
+		perm.ValidFrom = tmpl.ValidFrom
+	}
+	if perm.ValidUntil == nil {
+		perm.ValidUntil = tmpl.ValidUntil
+	}
+
+	// copied, so migrations and state never share maps between uses
+	conds := copy_condition(tmpl.Conditions).(map[string]interface{})
+	if conds == nil && len(perm.Conditions) > 0 {
+		conds = make(map[string]interface{}, len(perm.Conditions))
+	}
+	for k, v := range perm.Conditions {
+		conds[k] = v
+	}
+	perm.Conditions = conds
+	return nil
+}
+
+func copy_condition(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if val == nil {
+			return val
+		}
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = copy_condition(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = copy_condition(item)
+		}
+		return out
+	}
+	return v
+}
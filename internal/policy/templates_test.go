@@ -0,0 +1,113 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const templatePolicy = `version: 1
+templates:
+  small-usd-payments:
+    tool: payments
+    actions: [create]
+    conditions:
+      max_amount: 1000
+      currencies: [USD]
+agents:
+  - id: finance-agent
+    allow:
+      - use: small-usd-payments
+  - id: refunds-agent
+    allow:
+      - use: small-usd-payments
+        actions: [refund]
+        conditions:
+          max_amount: 200
+`
+
+func TestPolicyTemplates(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"payments.yaml": templatePolicy})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	tests := []struct {
+		agent, action string
+		params        map[string]interface{}
+		allow         bool
+	}{
+		{"finance-agent", "create", map[string]interface{}{"amount": 900.0, "currency": "USD"}, true},
+		{"finance-agent", "create", map[string]interface{}{"amount": 900.0, "currency": "EUR"}, false},
+		{"finance-agent", "create", map[string]interface{}{"amount": 1500.0, "currency": "USD"}, false},
+		{"finance-agent", "refund", map[string]interface{}{"amount": 10.0, "currency": "USD"}, false},
+		// actions combined, own max_amount overrides, currencies inherited
+		{"refunds-agent", "refund", map[string]interface{}{"amount": 150.0, "currency": "USD"}, true},
+		{"refunds-agent", "create", map[string]interface{}{"amount": 500.0, "currency": "USD"}, false},
+		{"refunds-agent", "refund", map[string]interface{}{"amount": 150.0, "currency": "EUR"}, false},
+	}
+	for _, tt := range tests {
+		d := m.Evaluate(tt.agent, "payments", tt.action, tt.params)
+		if d.Allow != tt.allow {
+			t.Errorf("%s %s %v: allow = %v, want %v (%s)", tt.agent, tt.action, tt.params, d.Allow, tt.allow, d.Reason)
+		}
+	}
+}
+
+func TestPolicyTemplateErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"payments.yaml": templatePolicy})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	bad := map[string]string{
+		"dangling reference": strings.Replace(templatePolicy, "- use: small-usd-payments\n  - id: refunds", "- use: large-payments\n  - id: refunds", 1),
+		"nested template":    strings.Replace(templatePolicy, "    tool: payments\n", "    tool: payments\n    use: other\n", 1),
+	}
+	for name, content := range bad {
+		writePolicies(t, tmpDir, map[string]string{"payments.yaml": content})
+		var list LoadErrorList
+		if err := m.Reload(); !errors.As(err, &list) || len(list) != 1 {
+			t.Errorf("%s: expected the file to be rejected, got %v", name, err)
+			continue
+		}
+		if name == "dangling reference" && !strings.Contains(list[0].Error, `unknown template "large-payments"`) {
+			t.Errorf("Expected the missing template named, got %q", list[0].Error)
+		}
+	}
+}
+
+// each use gets its own conditions, so a schema 1 rename or an override
+// in one agent doesn't leak into another
+func TestPolicyTemplatesAreCopied(t *testing.T) {
+	p := Policy{
+		Templates: map[string]Permission{"hr": {
+			Tool:       "files",
+			Actions:    []string{"read"},
+			Conditions: map[string]interface{}{"and": []interface{}{map[string]interface{}{"folder_prefix": "/hr/"}}},
+		}},
+		Agents: []Agent{
+			{ID: "a", Allow: []Permission{{Use: "hr"}}},
+			{ID: "b", Allow: []Permission{{Use: "hr", Conditions: map[string]interface{}{"max_amount": 5}}}},
+		},
+	}
+	if err := apply_templates(&p); err != nil {
+		t.Fatalf("apply_templates() error = %v", err)
+	}
+	if _, err := migrate_policy(&p); err != nil {
+		t.Fatalf("migrate_policy() error = %v", err)
+	}
+	if _, ok := p.Agents[0].Allow[0].Conditions["max_amount"]; ok {
+		t.Error("Expected b's override to stay out of a's conditions")
+	}
+	for _, agent := range p.Agents {
+		inner := agent.Allow[0].Conditions["and"].([]interface{})[0].(map[string]interface{})
+		if _, ok := inner["path_prefix"]; !ok {
+			t.Errorf("Expected %s's copy migrated, got %v", agent.ID, inner)
+		}
+	}
+}
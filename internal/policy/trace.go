@@ -0,0 +1,21 @@
+package policy
+
+// outcome of one condition check, collected when Request.Debug is set
+type ConditionResult struct {
+	Condition string `json:"condition"`
+	Passed    bool   `json:"passed"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// note a condition outcome in the request's trace. nested and/or/not
+// branches are recorded before the combinator that contains them.
+func (r *Request) record(condition, reason string) {
+	if !r.Debug {
+		return
+	}
+	r.trace = append(r.trace, ConditionResult{
+		Condition: condition,
+		Passed:    reason == "",
+		Reason:    reason,
+	})
+}
@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConditionTrace(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_amount: 5000
+          currencies: [USD, EUR]
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "trace.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	// allowed: both conditions pass
+	d := m.EvaluateRequest(Request{
+		AgentID: "finance-agent",
+		Tool:    "payments",
+		Action:  "create",
+		Params:  map[string]interface{}{"amount": 100.0, "currency": "USD"},
+		Debug:   true,
+	})
+	if !d.Allow {
+		t.Fatalf("Expected allow, got %s", d.Reason)
+	}
+	if len(d.Trace) != 2 {
+		t.Fatalf("Expected 2 trace entries, got %+v", d.Trace)
+	}
+	for _, r := range d.Trace {
+		if !r.Passed || r.Reason != "" {
+			t.Errorf("Expected %s to pass, got %+v", r.Condition, r)
+		}
+	}
+
+	// denied on currency: the failing condition is in the trace with its reason
+	d = m.EvaluateRequest(Request{
+		AgentID: "finance-agent",
+		Tool:    "payments",
+		Action:  "create",
+		Params:  map[string]interface{}{"amount": 100.0, "currency": "GBP"},
+		Debug:   true,
+	})
+	if d.Allow {
+		t.Fatal("Expected deny")
+	}
+	last := d.Trace[len(d.Trace)-1]
+	if last.Condition != "currencies" || last.Passed || last.Reason != "Currency GBP not in allowed list" {
+		t.Errorf("Expected failing currencies entry last, got %+v", last)
+	}
+	for _, r := range d.Trace[:len(d.Trace)-1] {
+		if r.Condition != "max_amount" || !r.Passed {
+			t.Errorf("Expected only a passing max_amount before the failure, got %+v", r)
+		}
+	}
+}
+
+func TestConditionTrace_OffByDefault(t *testing.T) {
+	m := &Manager{}
+	req := &Request{Params: map[string]interface{}{"amount": 1.0}}
+	m.check_conditions(req, map[string]interface{}{"max_amount": 5})
+	if req.trace != nil {
+		t.Errorf("Expected no trace without Debug, got %+v", req.trace)
+	}
+}
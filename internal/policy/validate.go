@@ -0,0 +1,59 @@
+package policy
+
+import "sort"
+
+// one problem with a candidate policy file
+type ValidationIssue struct {
+	// step that rejected the file, one of the Stage constants
+	Stage string `json:"stage"`
+	Error string `json:"error"`
+}
+
+// outcome of ValidatePolicy
+type ValidationResult struct {
+	File    string            `json:"file"`
+	Valid   bool              `json:"valid"`
+	Version int               `json:"version,omitempty"`
+	Agents  []string          `json:"agents,omitempty"`
+	Errors  []ValidationIssue `json:"errors,omitempty"`
+	Notes   []string          `json:"notes,omitempty"`
+}
+
+// check a candidate policy file the way a reload would, without loading
+// it. it goes through the same steps as a file read from disk, then is
+// checked against the active files as if it had been added under name,
+// or had replaced the file of that name. the extension of name picks the
+// decoder
+func (m *Manager) ValidatePolicy(name string, data []byte) ValidationResult {
+	res := ValidationResult{File: name}
+	pol, notes, stage, err := m.prepare_policy(name, data)
+	if err != nil {
+		res.Errors = []ValidationIssue{{Stage: stage, Error: err.Error()}}
+		return res
+	}
+	res.Version, res.Notes = pol.Version, notes
+	for _, agent := range pol.Agents {
+		res.Agents = append(res.Agents, agent.ID)
+	}
+
+	// the candidate goes last, so conflicts are reported against it
+	live := m.snapshot().policies
+	policies := make(map[string]Policy, len(live)+1)
+	names := make([]string, 0, len(live)+1)
+	for file, p := range live {
+		if file != name {
+			policies[file] = p
+			names = append(names, file)
+		}
+	}
+	sort.Strings(names)
+	policies[name] = pol
+	names = append(names, name)
+	for _, e := range m.check_policy_set(policies, names) {
+		if e.File == name {
+			res.Errors = append(res.Errors, ValidationIssue{Stage: StageSet, Error: e.Error})
+		}
+	}
+	res.Valid = len(res.Errors) == 0
+	return res
+}
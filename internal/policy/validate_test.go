@@ -0,0 +1,79 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"finance.yaml": `version: 1
+agents:
+  - id: finance-agent
+    api_key_sha256: ` + HashAPIKey("finance-key") + `
+    allow:
+      - tool: payments
+        actions: [create]
+`})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	agent := func(conditions string) string {
+		return `version: 1
+agents:
+  - id: ops-agent
+    allow:
+      - tool: files
+        actions: [read]
+        conditions:
+` + conditions
+	}
+	tests := []struct {
+		name      string
+		file      string
+		content   string
+		wantStage string
+		wantError string
+	}{
+		{name: "parse error", file: "c.yaml", content: "version: [1\n", wantStage: StageParse},
+		{name: "invalid json", file: "c.json", content: `{"version": 1,`, wantStage: StageParse},
+		{name: "version", file: "c.yaml", content: "version: 0\nagents:\n  - id: a\n", wantStage: StageValidate, wantError: "policy version must be >= 1"},
+		{name: "schema version", file: "c.yaml", content: "version: 1\nschema_version: 99\nagents:\n  - id: a\n", wantStage: StageSchema, wantError: "unsupported schema_version 99"},
+		{name: "bad regex", file: "c.yaml", content: agent("          path_regex: \"([\"\n"), wantStage: StageValidate, wantError: "path_regex"},
+		{name: "bad timezone", file: "c.yaml", content: agent("          allowed_hours: {start: \"09:00\", end: \"17:00\", timezone: Mars/Olympus}\n"), wantStage: StageValidate, wantError: "Mars/Olympus"},
+		{name: "duplicate agent", file: "c.yaml", content: "version: 1\nagents:\n  - id: a\n  - id: a\n", wantStage: StageSet, wantError: "agent a is defined more than once"},
+		{name: "conflicting key", file: "c.yaml", content: "version: 1\nagents:\n  - id: finance-agent\n    api_key_sha256: " + HashAPIKey("other-key") + "\n", wantStage: StageSet, wantError: "api_key_sha256"},
+		{name: "missing env", file: "c.yaml", content: "version: ${AEGIS_TEST_UNSET_VERSION}\n", wantStage: StageEnv, wantError: "AEGIS_TEST_UNSET_VERSION"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := m.ValidatePolicy(tt.file, []byte(tt.content))
+			if res.Valid || len(res.Errors) != 1 {
+				t.Fatalf("Expected one error, got %+v", res)
+			}
+			if res.Errors[0].Stage != tt.wantStage || !strings.Contains(res.Errors[0].Error, tt.wantError) {
+				t.Errorf("Expected %s error containing %q, got %+v", tt.wantStage, tt.wantError, res.Errors[0])
+			}
+		})
+	}
+
+	res := m.ValidatePolicy("ops.yaml", []byte(agent("          path_prefix: /tmp/\n")))
+	if !res.Valid || res.Version != 1 || len(res.Agents) != 1 || res.Agents[0] != "ops-agent" {
+		t.Errorf("Expected a valid file, got %+v", res)
+	}
+	// replacing a file may change what it conflicted with
+	res = m.ValidatePolicy("finance.yaml", []byte("version: 2\nagents:\n  - id: finance-agent\n    api_key_sha256: "+HashAPIKey("other-key")+"\n"))
+	if !res.Valid {
+		t.Errorf("Expected a replacement for finance.yaml to be valid, got %+v", res)
+	}
+
+	// nothing validated is loaded
+	if files := m.PolicyFiles(); len(files) != 1 || files[0] != "finance.yaml" {
+		t.Errorf("Expected only finance.yaml loaded, got %v", files)
+	}
+	if d := m.Evaluate("ops-agent", "files", "read", map[string]interface{}{"path": "/tmp/a"}); d.Allow {
+		t.Error("Expected a validated file not to grant anything")
+	}
+}
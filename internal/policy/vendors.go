@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// max_distinct_vendors: caps how many different vendor_id values an agent
+// may pay within a sliding window
+//
+//	conditions:
+//	  max_distinct_vendors:
+//	    limit: 5
+//	    window: 24h
+type vendorLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+func parse_vendor_limit(condVal interface{}) (vendorLimit, error) {
+	raw, ok := condVal.(map[string]interface{})
+	if !ok {
+		return vendorLimit{}, fmt.Errorf("max_distinct_vendors: expected a map, got %T", condVal)
+	}
+	limit, ok := raw["limit"].(int)
+	if !ok || limit < 1 {
+		return vendorLimit{}, fmt.Errorf("max_distinct_vendors: limit must be a positive integer")
+	}
+	windowStr, ok := raw["window"].(string)
+	if !ok {
+		return vendorLimit{}, fmt.Errorf("max_distinct_vendors: window is required (e.g. 24h)")
+	}
+	window, err := time.ParseDuration(windowStr)
+	if err != nil || window <= 0 {
+		return vendorLimit{}, fmt.Errorf("max_distinct_vendors: invalid window %q", windowStr)
+	}
+	return vendorLimit{Limit: limit, Window: window}, nil
+}
+
+// per-agent vendor -> last payment time
+type vendorTracker struct {
+	mu     sync.Mutex
+	agents map[string]map[string]time.Time
+}
+
+func newVendorTracker() *vendorTracker {
+	return &vendorTracker{agents: make(map[string]map[string]time.Time)}
+}
+
+// drop vendors not seen within the window, caller must hold t.mu
+func (t *vendorTracker) prune(agentID string, now time.Time, window time.Duration) map[string]time.Time {
+	seen := t.agents[agentID]
+	for vendor, last := range seen {
+		if now.Sub(last) >= window {
+			delete(seen, vendor)
+		}
+	}
+	return seen
+}
+
+// check whether paying vendor would exceed the limit, without recording it
+func (t *vendorTracker) check(agentID, vendor string, now time.Time, vl vendorLimit) string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// no pruning here: another limit on the agent may have a longer window
+	return vendor_reason(t.agents[agentID], vendor, now, vl)
+}
+
+// record a payment to vendor, denying if it would exceed any of the
+// limits. the returned func takes the payment back
+func (t *vendorTracker) record(agentID, vendor string, now time.Time, limits ...vendorLimit) (string, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// keep what the longest window still needs
+	var window time.Duration
+	for _, vl := range limits {
+		window = max(window, vl.Window)
+	}
+	seen := t.prune(agentID, now, window)
+	for _, vl := range limits {
+		if reason := vendor_reason(seen, vendor, now, vl); reason != "" {
+			return reason, nil
+		}
+	}
+	if seen == nil {
+		seen = make(map[string]time.Time)
+		t.agents[agentID] = seen
+	}
+	prev, known := seen[vendor]
+	seen[vendor] = now
+	return "", func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		seen := t.agents[agentID]
+		if last, ok := seen[vendor]; !ok || !last.Equal(now) {
+			return
+		}
+		if known {
+			seen[vendor] = prev
+		} else {
+			delete(seen, vendor)
+		}
+	}
+}
+
+// counts the vendors paid within vl's window
+func vendor_reason(seen map[string]time.Time, vendor string, now time.Time, vl vendorLimit) string {
+	if last, known := seen[vendor]; known && now.Sub(last) < vl.Window {
+		return ""
+	}
+	n := 0
+	for _, last := range seen {
+		if now.Sub(last) < vl.Window {
+			n++
+		}
+	}
+	if n >= vl.Limit {
+		return fmt.Sprintf("Vendor %s would exceed max_distinct_vendors=%d within %s", vendor, vl.Limit, vl.Window)
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func TestMaxDistinctVendors(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: finance-agent
+    allow:
+      - tool: payments
+        actions: [create]
+        conditions:
+          max_distinct_vendors:
+            limit: 2
+            window: 24h
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "vendors.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	m.SetClock(clock)
+
+	pay := func(vendor string) Decision {
+		return m.Evaluate("finance-agent", "payments", "create", map[string]interface{}{"vendor_id": vendor})
+	}
+
+	if d := pay("V1"); !d.Allow {
+		t.Fatalf("V1 should be allowed: %s", d.Reason)
+	}
+	if d := pay("V2"); !d.Allow {
+		t.Fatalf("V2 should be allowed: %s", d.Reason)
+	}
+	// repeat vendor doesn't count twice
+	if d := pay("V1"); !d.Allow {
+		t.Errorf("Repeat V1 should be allowed: %s", d.Reason)
+	}
+	d := pay("V3")
+	if d.Allow {
+		t.Fatal("V3 should exceed the distinct vendor cap")
+	}
+	if d.Reason != "Vendor V3 would exceed max_distinct_vendors=2 within 24h0m0s" {
+		t.Errorf("Unexpected reason: %s", d.Reason)
+	}
+
+	// window rolls over, set is cleared
+	clock.t = clock.t.Add(25 * time.Hour)
+	if d := pay("V3"); !d.Allow {
+		t.Errorf("V3 should be allowed after window rollover: %s", d.Reason)
+	}
+}
+
+func TestMaxDistinctVendors_MissingVendor(t *testing.T) {
+	m := &Manager{vendors: newVendorTracker()}
+	cond := map[string]interface{}{
+		"max_distinct_vendors": map[string]interface{}{"limit": 1, "window": "1h"},
+	}
+	if reason, _ := m.check_conditions(&Request{AgentID: "a", Params: map[string]interface{}{}}, cond); reason != "Invalid vendor_id parameter" {
+		t.Errorf("Unexpected reason: %q", reason)
+	}
+}
+
+func TestMaxDistinctVendors_Validation(t *testing.T) {
+	bad := []interface{}{
+		"5",
+		map[string]interface{}{"limit": 0, "window": "1h"},
+		map[string]interface{}{"limit": 2},
+		map[string]interface{}{"limit": 2, "window": "soon"},
+	}
+	m := &Manager{}
+	for _, v := range bad {
+		if err := m.validate_conditions(map[string]interface{}{"max_distinct_vendors": v}); err == nil {
+			t.Errorf("Expected validation error for %v", v)
+		}
+	}
+}
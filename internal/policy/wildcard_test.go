@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWildcardMatching(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: ops-agent
+    allow:
+      - tool: "*"
+        actions: ["*"]
+      - tool: payments
+        actions: ["*"]
+        conditions:
+          max_amount: 100
+      - tool: payments
+        actions: [refund]
+        conditions:
+          max_amount: 10
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "wildcard.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		tool      string
+		action    string
+		params    map[string]interface{}
+		wantAllow bool
+	}{
+		{
+			name:      "wildcard grants a tool the policy never mentions",
+			tool:      "calendar",
+			action:    "schedule",
+			params:    map[string]interface{}{},
+			wantAllow: true,
+		},
+		{
+			name:      "exact tool overrides wildcard tool",
+			tool:      "payments",
+			action:    "create",
+			params:    map[string]interface{}{"amount": 500.0},
+			wantAllow: false,
+		},
+		{
+			name:      "exact tool within its limit",
+			tool:      "payments",
+			action:    "create",
+			params:    map[string]interface{}{"amount": 50.0},
+			wantAllow: true,
+		},
+		{
+			name:      "exact action overrides wildcard action",
+			tool:      "payments",
+			action:    "refund",
+			params:    map[string]interface{}{"amount": 50.0},
+			wantAllow: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := m.Evaluate("ops-agent", tt.tool, tt.action, tt.params)
+			if d.Allow != tt.wantAllow {
+				t.Errorf("Evaluate() Allow = %v, want %v. Reason: %s", d.Allow, tt.wantAllow, d.Reason)
+			}
+		})
+	}
+
+	if d := m.Evaluate("other-agent", "calendar", "schedule", nil); d.Allow {
+		t.Error("Wildcard must not extend to other agents")
+	}
+}
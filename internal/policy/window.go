@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"fmt"
+	"time"
+)
+
+// a permission with valid_from/valid_until only applies inside that
+// window, checked against the manager's clock. outside it the rule is
+// skipped as if it wasn't there, so temporary access lapses on its own:
+//
+//	allow:
+//	  - tool: payments
+//	    actions: [refund]
+//	    valid_until: 2026-11-01T00:00:00Z
+//
+// valid_from is inclusive, valid_until exclusive
+func in_window(perm Permission, now time.Time) bool {
+	if perm.ValidFrom != nil && now.Before(*perm.ValidFrom) {
+		return false
+	}
+	if perm.ValidUntil != nil && !now.Before(*perm.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+func validate_window(perm Permission) error {
+	if perm.ValidFrom != nil && perm.ValidUntil != nil && perm.ValidUntil.Before(*perm.ValidFrom) {
+		return fmt.Errorf("valid_until %s is before valid_from %s",
+			perm.ValidUntil.Format(time.RFC3339), perm.ValidFrom.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// candidates whose window contains now, order kept. filters in place,
+// lookup hands out a fresh slice each call
+func active_candidates(cands []candidate, now time.Time) []candidate {
+	out := cands[:0]
+	for _, c := range cands {
+		if in_window(c.perm, now) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const windowPolicy = `version: 1
+agents:
+  - id: oncall-agent
+    allow:
+      - tool: files
+        actions: [read]
+      - tool: payments
+        actions: [refund]
+        valid_from: 2026-10-01T00:00:00Z
+        valid_until: 2026-10-08T00:00:00Z
+    deny:
+      - tool: files
+        actions: [read]
+        valid_from: 2026-10-05T00:00:00Z
+`
+
+func TestPermissionWindow(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{"oncall.yaml": windowPolicy})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if errs := m.LoadErrors(); len(errs) > 0 {
+		t.Fatalf("Unexpected load errors: %+v", errs)
+	}
+
+	tests := []struct {
+		name          string
+		now           string
+		refund, files bool
+	}{
+		{"not yet valid", "2026-09-30T23:59:59Z", false, true},
+		{"starts at valid_from", "2026-10-01T00:00:00Z", true, true},
+		{"active", "2026-10-03T12:00:00Z", true, true},
+		{"deny window started", "2026-10-06T00:00:00Z", true, false},
+		{"expired at valid_until", "2026-10-08T00:00:00Z", false, false},
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.now)
+		m.SetClock(&fakeClock{t: now})
+		if d := m.Evaluate("oncall-agent", "payments", "refund", nil); d.Allow != tt.refund {
+			t.Errorf("%s: expected refund allow=%v, got %+v", tt.name, tt.refund, d)
+		}
+		if d := m.Evaluate("oncall-agent", "files", "read", nil); d.Allow != tt.files {
+			t.Errorf("%s: expected files read allow=%v, got %+v", tt.name, tt.files, d)
+		}
+	}
+
+	// an expired grant falls through as if it wasn't there
+	expired, _ := time.Parse(time.RFC3339, "2026-12-01T00:00:00Z")
+	m.SetClock(&fakeClock{t: expired})
+	if d := m.Evaluate("oncall-agent", "payments", "refund", nil); !strings.HasPrefix(d.Reason, "No policy found") {
+		t.Errorf("Expected the default decision for an expired grant, got %+v", d)
+	}
+}
+
+func TestPermissionWindowValidation(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePolicies(t, tmpDir, map[string]string{
+		"backwards.yaml": "version: 1\nagents:\n  - id: a\n    allow:\n      - tool: files\n        actions: [read]\n        valid_from: 2026-10-08T00:00:00Z\n        valid_until: 2026-10-01T00:00:00Z\n",
+		"json.json":      `{"version": 1, "agents": [{"id": "b", "allow": [{"tool": "files", "actions": ["read"], "valid_until": "2026-10-01T00:00:00Z"}]}]}`,
+		"template.yaml": `version: 1
+templates:
+  temp-read:
+    tool: files
+    actions: [read]
+    valid_until: 2026-10-01T00:00:00Z
+agents:
+  - id: c
+    allow:
+      - use: temp-read
+`,
+	})
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	errs := m.LoadErrors()
+	if len(errs) != 1 || errs[0].File != "backwards.yaml" || !strings.Contains(errs[0].Error, "valid_until 2026-10-01T00:00:00Z is before valid_from 2026-10-08T00:00:00Z") {
+		t.Fatalf("Expected backwards.yaml rejected for its window, got %+v", errs)
+	}
+
+	before, _ := time.Parse(time.RFC3339, "2026-09-01T00:00:00Z")
+	m.SetClock(&fakeClock{t: before})
+	for _, agent := range []string{"b", "c"} {
+		if d := m.Evaluate(agent, "files", "read", nil); !d.Allow {
+			t.Errorf("Expected %s allowed before valid_until: %s", agent, d.Reason)
+		}
+	}
+	m.SetClock(&fakeClock{t: before.AddDate(0, 1, 0)})
+	for _, agent := range []string{"b", "c"} {
+		if d := m.Evaluate(agent, "files", "read", nil); d.Allow {
+			t.Errorf("Expected %s denied after valid_until", agent)
+		}
+	}
+}
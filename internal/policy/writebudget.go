@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// max_daily_write_bytes: caps the total bytes of file content an agent may
+// write per UTC day, across all files
+//
+//	conditions:
+//	  max_daily_write_bytes: 10485760
+func parse_write_budget(condVal interface{}) (int64, error) {
+	budget, ok := condVal.(int)
+	if !ok || budget < 1 {
+		return 0, fmt.Errorf("max_daily_write_bytes: expected a positive integer, got %v", condVal)
+	}
+	return int64(budget), nil
+}
+
+// size of the content a files write request carries
+func write_size(params map[string]interface{}) (int64, bool) {
+	content, ok := params["content"].(string)
+	return int64(len(content)), ok
+}
+
+type writeUsage struct {
+	day   string
+	bytes int64
+}
+
+// per-agent bytes written on the current day
+type writeBudgetTracker struct {
+	mu     sync.Mutex
+	agents map[string]writeUsage
+}
+
+func newWriteBudgetTracker() *writeBudgetTracker {
+	return &writeBudgetTracker{agents: make(map[string]writeUsage)}
+}
+
+// bytes already written today, caller must hold t.mu
+func (t *writeBudgetTracker) used(agentID string, now time.Time) int64 {
+	u := t.agents[agentID]
+	if u.day != now.UTC().Format(time.DateOnly) {
+		return 0
+	}
+	return u.bytes
+}
+
+// check whether writing size bytes would exceed the budget, without recording it
+func (t *writeBudgetTracker) check(agentID string, size int64, now time.Time, budget int64) string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return write_budget_reason(t.used(agentID, now), size, budget)
+}
+
+// record a write of size bytes, denying if it would exceed any of the
+// budgets. the returned func takes the write back
+func (t *writeBudgetTracker) record(agentID string, size int64, now time.Time, budgets ...int64) (string, func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	used := t.used(agentID, now)
+	for _, budget := range budgets {
+		if reason := write_budget_reason(used, size, budget); reason != "" {
+			return reason, nil
+		}
+	}
+	day := now.UTC().Format(time.DateOnly)
+	t.agents[agentID] = writeUsage{day: day, bytes: used + size}
+	return "", func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if u := t.agents[agentID]; u.day == day {
+			u.bytes -= size
+			t.agents[agentID] = u
+		}
+	}
+}
+
+func write_budget_reason(used, size, budget int64) string {
+	if used+size > budget {
+		return fmt.Sprintf("Write of %d bytes would exceed max_daily_write_bytes=%d (%d used today)", size, budget, used)
+	}
+	return ""
+}
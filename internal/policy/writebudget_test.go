@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxDailyWriteBytes(t *testing.T) {
+	tmpDir := t.TempDir()
+	policyContent := `version: 1
+agents:
+  - id: writer-agent
+    allow:
+      - tool: files
+        actions: [write]
+        conditions:
+          max_daily_write_bytes: 100
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "writes.yaml"), []byte(policyContent), 0644); err != nil {
+		t.Fatalf("Failed to write test policy: %v", err)
+	}
+
+	m, err := NewManager(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	m.SetClock(clock)
+
+	write := func(path string, n int) Decision {
+		return m.Evaluate("writer-agent", "files", "write", map[string]interface{}{
+			"path":    path,
+			"content": strings.Repeat("x", n),
+		})
+	}
+
+	if d := write("/tmp/a.txt", 60); !d.Allow {
+		t.Fatalf("First write should be allowed: %s", d.Reason)
+	}
+	// budget spans files
+	if d := write("/tmp/b.txt", 40); !d.Allow {
+		t.Fatalf("Write up to the budget should be allowed: %s", d.Reason)
+	}
+	d := write("/tmp/c.txt", 1)
+	if d.Allow {
+		t.Fatal("Write past the budget should be denied")
+	}
+	if d.Reason != "Write of 1 bytes would exceed max_daily_write_bytes=100 (100 used today)" {
+		t.Errorf("Unexpected reason: %s", d.Reason)
+	}
+
+	// next day starts from zero
+	clock.t = time.Date(2024, 1, 2, 0, 0, 1, 0, time.UTC)
+	if d := write("/tmp/c.txt", 100); !d.Allow {
+		t.Errorf("Write should be allowed after day rollover: %s", d.Reason)
+	}
+}
+
+func TestMaxDailyWriteBytes_DeniedWriteNotCounted(t *testing.T) {
+	m := &Manager{writes: newWriteBudgetTracker()}
+	m.SetClock(&fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)})
+	cond := map[string]interface{}{"max_daily_write_bytes": 10}
+
+	req := &Request{AgentID: "a", Params: map[string]interface{}{"content": "0123456789AB"}}
+	if reason, _ := m.check_conditions(req, cond); reason == "" {
+		t.Fatal("Expected oversized write to be denied")
+	}
+	req = &Request{AgentID: "a", Params: map[string]interface{}{"content": "0123456789"}}
+	if reason, _ := m.check_conditions(req, cond); reason != "" {
+		t.Errorf("Expected denied write not to consume budget, got %q", reason)
+	}
+	req = &Request{AgentID: "a", Params: map[string]interface{}{}}
+	if reason, _ := m.check_conditions(req, cond); reason != "Invalid content parameter" {
+		t.Errorf("Unexpected reason: %q", reason)
+	}
+}
+
+func TestMaxDailyWriteBytes_Validation(t *testing.T) {
+	m := &Manager{}
+	for _, v := range []interface{}{0, -5, "1MB", 1.5} {
+		if err := m.validate_conditions(map[string]interface{}{"max_daily_write_bytes": v}); err == nil {
+			t.Errorf("Expected validation error for %v", v)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package apierror is the error envelope shared by the gateway and its
+// adapters. every error response looks like
+//
+//	{"error": "PolicyViolation", "code": "AEGIS-403-POLICY", "reason": "..."}
+//
+// error is a readable name, code is stable and safe to branch on, reason
+// is for humans and may change between releases
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// a documented error: the HTTP status it's sent with, its name and code
+type Kind struct {
+	Status int
+	Name   string
+	Code   string
+}
+
+var (
+	MissingHeader        = Kind{http.StatusBadRequest, "MissingHeader", "AEGIS-400-HEADER"}
+	InvalidRequest       = Kind{http.StatusBadRequest, "InvalidRequest", "AEGIS-400-REQUEST"}
+	ConfigReloadFailed   = Kind{http.StatusBadRequest, "ConfigReloadFailed", "AEGIS-400-CONFIG"}
+	DeadLetterInvalid    = Kind{http.StatusBadRequest, "DeadLetterInvalid", "AEGIS-400-DEAD-LETTER"}
+	Unauthorized         = Kind{http.StatusUnauthorized, "Unauthorized", "AEGIS-401-AUTH"}
+	PolicyViolation      = Kind{http.StatusForbidden, "PolicyViolation", "AEGIS-403-POLICY"}
+	NotFound             = Kind{http.StatusNotFound, "NotFound", "AEGIS-404-NOT-FOUND"}
+	AdapterNotFound      = Kind{http.StatusNotFound, "AdapterNotFound", "AEGIS-404-ADAPTER"}
+	MethodNotAllowed     = Kind{http.StatusMethodNotAllowed, "MethodNotAllowed", "AEGIS-405-METHOD"}
+	Conflict             = Kind{http.StatusConflict, "Conflict", "AEGIS-409-CONFLICT"}
+	RequestTooLarge      = Kind{http.StatusRequestEntityTooLarge, "RequestTooLarge", "AEGIS-413-REQUEST-SIZE"}
+	UnsupportedMediaType = Kind{http.StatusUnsupportedMediaType, "UnsupportedMediaType", "AEGIS-415-MEDIA-TYPE"}
+	PolicyReloadRejected = Kind{http.StatusUnprocessableEntity, "PolicyReloadRejected", "AEGIS-422-POLICY-RELOAD"}
+	RateLimited          = Kind{http.StatusTooManyRequests, "RateLimited", "AEGIS-429-RATE-LIMIT"}
+	TransformError       = Kind{http.StatusInternalServerError, "TransformError", "AEGIS-500-TRANSFORM"}
+	ReloadFailed         = Kind{http.StatusInternalServerError, "ReloadFailed", "AEGIS-500-RELOAD"}
+	DeadLetterError      = Kind{http.StatusInternalServerError, "DeadLetterError", "AEGIS-500-DEAD-LETTER"}
+	StorageError         = Kind{http.StatusInternalServerError, "StorageError", "AEGIS-500-STORAGE"}
+	AdapterError         = Kind{http.StatusBadGateway, "AdapterError", "AEGIS-502-ADAPTER"}
+	ResponseTooLarge     = Kind{http.StatusBadGateway, "ResponseTooLarge", "AEGIS-502-RESPONSE-SIZE"}
+	AdapterUnavailable   = Kind{http.StatusServiceUnavailable, "AdapterUnavailable", "AEGIS-503-CIRCUIT-OPEN"}
+	AuditUnavailable     = Kind{http.StatusServiceUnavailable, "AuditUnavailable", "AEGIS-503-AUDIT"}
+	ConcurrencyLimit     = Kind{http.StatusServiceUnavailable, "ConcurrencyLimit", "AEGIS-503-CONCURRENCY"}
+	Maintenance          = Kind{http.StatusServiceUnavailable, "Maintenance", "AEGIS-503-MAINTENANCE"}
+	GatewayTimeout       = Kind{http.StatusGatewayTimeout, "GatewayTimeout", "AEGIS-504-TIMEOUT"}
+)
+
+// every kind above, for documentation and tests
+var Kinds = []Kind{
+	MissingHeader, InvalidRequest, ConfigReloadFailed, DeadLetterInvalid, Unauthorized,
+	PolicyViolation, NotFound, AdapterNotFound, MethodNotAllowed, Conflict, RequestTooLarge,
+	UnsupportedMediaType, PolicyReloadRejected, RateLimited, TransformError, ReloadFailed,
+	DeadLetterError, StorageError, AdapterError, ResponseTooLarge, AdapterUnavailable,
+	AuditUnavailable, ConcurrencyLimit, Maintenance, GatewayTimeout,
+}
+
+type Response struct {
+	Error  string `json:"error"`
+	Code   string `json:"code"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// the envelope for this kind
+func (k Kind) Response(reason string) Response {
+	return Response{Error: k.Name, Code: k.Code, Reason: reason}
+}
+
+// write a plain error response
+func Write(w http.ResponseWriter, k Kind, reason string) {
+	WriteBody(w, k.Status, k.Response(reason))
+}
+
+// write a response whose body embeds Response with extra fields, e.g. the
+// gateway's condition trace
+func WriteBody(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
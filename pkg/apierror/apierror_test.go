@@ -0,0 +1,46 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestKindsAreDocumented(t *testing.T) {
+	pattern := regexp.MustCompile(`^AEGIS-(\d{3})-[A-Z]+(-[A-Z]+)*$`)
+	seen := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, k := range Kinds {
+		if names[k.Name] {
+			t.Errorf("name %s used twice", k.Name)
+		}
+		names[k.Name] = true
+		m := pattern.FindStringSubmatch(k.Code)
+		if m == nil {
+			t.Errorf("%s: malformed code %q", k.Name, k.Code)
+			continue
+		}
+		if m[1] != strconv.Itoa(k.Status) {
+			t.Errorf("%s: code %s doesn't carry status %d", k.Name, k.Code, k.Status)
+		}
+		if seen[k.Code] {
+			t.Errorf("code %s used twice", k.Code)
+		}
+		seen[k.Code] = true
+	}
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	Write(w, RateLimited, "slow down")
+	if w.Code != 429 || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected response: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp != (Response{Error: "RateLimited", Code: "AEGIS-429-RATE-LIMIT", Reason: "slow down"}) {
+		t.Errorf("Unexpected body: %+v", resp)
+	}
+}
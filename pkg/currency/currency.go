@@ -0,0 +1,31 @@
+// Package currency holds the ISO 4217 minor units shared by the gateway,
+// which converts minor-unit amounts, and the payments adapter, which
+// checks amounts against them
+package currency
+
+import (
+	"math"
+	"strings"
+)
+
+// minor units for currencies that don't use two decimal places.
+// everything else, including codes not listed, allows cents
+var exponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// decimal places amounts in code may have, in any case
+func Exponent(code string) int {
+	if exp, ok := exponents[strings.ToUpper(code)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// minor units per major unit of code: 100 for USD, 1 for JPY, 1000 for KWD
+func Scale(code string) float64 {
+	return math.Pow10(Exponent(code))
+}
@@ -0,0 +1,15 @@
+package currency
+
+import "testing"
+
+func TestExponent(t *testing.T) {
+	tests := map[string]int{"USD": 2, "jpy": 0, "KWD": 3, "XYZ": 2, "": 2}
+	for code, want := range tests {
+		if got := Exponent(code); got != want {
+			t.Errorf("Exponent(%q) = %d, want %d", code, got, want)
+		}
+	}
+	if got := Scale("KWD"); got != 1000 {
+		t.Errorf("Scale(KWD) = %v, want 1000", got)
+	}
+}
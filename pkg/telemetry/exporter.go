@@ -0,0 +1,138 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// telemetry settings for InitTelemetryWithConfig
+type Config struct {
+	ServiceName string `yaml:"service_name"`
+	LogPath     string `yaml:"log_path"`
+
+	// audit log rotation, off by default
+	Rotation RotationConfig `yaml:"rotation"`
+
+	// where audit records go: "both" (default), "stdout", "file" or "none".
+	// containers that already ship stdout usually want "file" or "stdout"
+	AuditOutput string `yaml:"audit_output"`
+
+	// span and metric exporter, "stdout" (default) or "otlp"
+	Exporter string     `yaml:"exporter"`
+	OTLP     OTLPConfig `yaml:"otlp"`
+}
+
+// where to send spans and metrics when Exporter is "otlp"
+type OTLPConfig struct {
+	// collector host:port, e.g. "otel-collector:4317"
+	Endpoint string `yaml:"endpoint"`
+
+	// "grpc" (default, port 4317) or "http" (port 4318)
+	Protocol string `yaml:"protocol"`
+
+	// sent with every export, e.g. auth tokens for a hosted collector
+	Headers map[string]string `yaml:"headers"`
+
+	// plaintext instead of TLS
+	Insecure bool `yaml:"insecure"`
+}
+
+func (c Config) Validate() error {
+	switch c.AuditOutput {
+	case "", "both", "stdout", "file", "none":
+	default:
+		return fmt.Errorf("unknown audit_output %q, expected both, stdout, file or none", c.AuditOutput)
+	}
+
+	switch c.Exporter {
+	case "", "stdout":
+	case "otlp":
+		if c.OTLP.Endpoint == "" {
+			return fmt.Errorf("otlp exporter needs an endpoint")
+		}
+		if p := c.OTLP.Protocol; p != "" && p != "grpc" && p != "http" {
+			return fmt.Errorf("unknown otlp protocol %q, expected grpc or http", p)
+		}
+	default:
+		return fmt.Errorf("unknown exporter %q, expected stdout or otlp", c.Exporter)
+	}
+	return nil
+}
+
+// which audit destinations are enabled
+func (c Config) audit_outputs() (toStdout, toFile bool) {
+	switch c.AuditOutput {
+	case "stdout":
+		return true, false
+	case "file":
+		return false, true
+	case "none":
+		return false, false
+	default:
+		return true, true
+	}
+}
+
+func new_exporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	if cfg.Exporter != "otlp" {
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	}
+
+	o := cfg.OTLP
+	if o.Protocol == "http" {
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(o.Endpoint)}
+		if len(o.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(o.Headers))
+		}
+		if o.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(o.Endpoint)}
+	if len(o.Headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(o.Headers))
+	}
+	if o.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return otlptracegrpc.New(ctx, opts...)
+}
+
+// metrics go wherever spans go, to the same collector for otlp
+func new_metric_exporter(ctx context.Context, cfg Config) (sdkmetric.Exporter, error) {
+	if cfg.Exporter != "otlp" {
+		return stdoutmetric.New()
+	}
+
+	o := cfg.OTLP
+	if o.Protocol == "http" {
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(o.Endpoint)}
+		if len(o.Headers) > 0 {
+			opts = append(opts, otlpmetrichttp.WithHeaders(o.Headers))
+		}
+		if o.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	}
+
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(o.Endpoint)}
+	if len(o.Headers) > 0 {
+		opts = append(opts, otlpmetricgrpc.WithHeaders(o.Headers))
+	}
+	if o.Insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	return otlpmetricgrpc.New(ctx, opts...)
+}
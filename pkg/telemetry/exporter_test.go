@@ -0,0 +1,81 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitTelemetryWithConfig_OTLPHTTP(t *testing.T) {
+	type export struct {
+		path, auth string
+	}
+	exports := make(chan export, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exports <- export{r.URL.Path, r.Header.Get("Authorization")}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer collector.Close()
+
+	err := InitTelemetryWithConfig(Config{
+		ServiceName: "aegis-test",
+		LogPath:     filepath.Join(t.TempDir(), "audit.log"),
+		Exporter:    "otlp",
+		OTLP: OTLPConfig{
+			Endpoint: strings.TrimPrefix(collector.URL, "http://"),
+			Protocol: "http",
+			Headers:  map[string]string{"Authorization": "Bearer collector-token"},
+			Insecure: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("InitTelemetryWithConfig() error = %v", err)
+	}
+	defer Close()
+
+	_, span := StartSpan(context.Background(), "test")
+	span.End()
+	if err := Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	select {
+	case e := <-exports:
+		if e.path != "/v1/traces" || e.auth != "Bearer collector-token" {
+			t.Errorf("Unexpected export %+v", e)
+		}
+	default:
+		t.Fatal("Expected spans to be exported to the collector")
+	}
+}
+
+func TestInitTelemetry_DefaultsToStdout(t *testing.T) {
+	if err := InitTelemetry("aegis-test", filepath.Join(t.TempDir(), "audit.log")); err != nil {
+		t.Fatalf("InitTelemetry() error = %v", err)
+	}
+	defer Close()
+
+	if _, span := StartSpan(context.Background(), "test"); !span.SpanContext().IsValid() {
+		t.Error("Expected a recording tracer after InitTelemetry")
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	bad := []Config{
+		{Exporter: "jaeger"},
+		{Exporter: "otlp"},
+		{Exporter: "otlp", OTLP: OTLPConfig{Endpoint: "collector:4317", Protocol: "udp"}},
+		{AuditOutput: "syslog"},
+	}
+	for _, c := range bad {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Expected validation error for %+v", c)
+		}
+	}
+	if err := (Config{Exporter: "otlp", OTLP: OTLPConfig{Endpoint: "collector:4317"}}).Validate(); err != nil {
+		t.Errorf("Expected grpc default to be valid, got %v", err)
+	}
+}
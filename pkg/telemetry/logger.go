@@ -0,0 +1,251 @@
+package telemetry
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errLoggerClosed = errors.New("audit log is closed")
+
+// layout of the timestamp in rotated file names, sorts chronologically
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// when and how the audit log is rolled. zero values disable each part
+type RotationConfig struct {
+	// roll once the file would grow past this many megabytes
+	MaxSizeMB int `yaml:"max_size_mb" json:"max_size_mb,omitempty"`
+
+	// rotated files kept, oldest removed first
+	MaxBackups int `yaml:"max_backups" json:"max_backups,omitempty"`
+
+	// rotated files older than this are removed
+	MaxAgeDays int `yaml:"max_age_days" json:"max_age_days,omitempty"`
+
+	// gzip rotated files
+	Compress bool `yaml:"compress" json:"compress,omitempty"`
+}
+
+// append-only audit log file. when it passes the size limit it's renamed
+// to <name>-<timestamp><ext> and a fresh file started. writes come from
+// concurrent requests so everything is under mu
+type Logger struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	size     int64
+	maxSize  int64 // bytes, 0 never rotates
+	rotation RotationConfig
+	now      func() time.Time
+	failures int // consecutive failed writes
+}
+
+func open_logger(path string, rotation RotationConfig) (*Logger, error) {
+	l := &Logger{
+		path:     path,
+		maxSize:  int64(rotation.MaxSizeMB) << 20,
+		rotation: rotation,
+		now:      time.Now,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Logger) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// append one record and a newline, rolling the file first if it would
+// pass the size limit
+func (l *Logger) write(record []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		l.track(errLoggerClosed)
+		return errLoggerClosed
+	}
+	n := int64(len(record) + 1)
+	if l.maxSize > 0 && l.size > 0 && l.size+n > l.maxSize {
+		if err := l.rotate(); err != nil {
+			fmt.Printf("ERROR: audit log rotation failed: %v\n", err)
+		}
+	}
+	written, err := l.file.Write(append(record, '\n'))
+	l.size += int64(written)
+	l.track(err)
+	return err
+}
+
+// report the first failure, then every 100th, and the recovery. caller
+// must hold l.mu
+func (l *Logger) track(err error) {
+	if err == nil {
+		if l.failures > 0 {
+			fmt.Printf("Audit log writable again after %d failed writes\n", l.failures)
+		}
+		l.failures = 0
+		return
+	}
+	l.failures++
+	if l.failures == 1 || l.failures%100 == 0 {
+		fmt.Printf("ERROR: audit log write failed (%d in a row): %v\n", l.failures, err)
+	}
+}
+
+// caller must hold l.mu
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	backup := l.backup_name(l.now())
+	if err := os.Rename(l.path, backup); err != nil {
+		// keep writing to the current file rather than losing records
+		l.open()
+		return err
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+
+	if l.rotation.Compress {
+		if err := compress_file(backup); err != nil {
+			fmt.Printf("ERROR: failed to compress %s: %v\n", backup, err)
+		}
+	}
+	return l.remove_old()
+}
+
+func (l *Logger) backup_name(t time.Time) string {
+	ext := filepath.Ext(l.path)
+	base := strings.TrimSuffix(l.path, ext)
+	name := fmt.Sprintf("%s-%s%s", base, t.UTC().Format(backupTimeFormat), ext)
+	// two rolls in the same millisecond mustn't overwrite each other
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s-%s.%d%s", base, t.UTC().Format(backupTimeFormat), i, ext)
+	}
+	return name
+}
+
+// rotated files, newest first
+func (l *Logger) backups() ([]string, error) {
+	ext := filepath.Ext(l.path)
+	prefix := filepath.Base(strings.TrimSuffix(l.path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(l.path))
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if !strings.HasSuffix(name, ext) && !strings.HasSuffix(name, ext+".gz") {
+			continue
+		}
+		out = append(out, filepath.Join(filepath.Dir(l.path), name))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(out)))
+	return out, nil
+}
+
+// drop backups past MaxBackups or MaxAgeDays
+func (l *Logger) remove_old() error {
+	if l.rotation.MaxBackups == 0 && l.rotation.MaxAgeDays == 0 {
+		return nil
+	}
+	backups, err := l.backups()
+	if err != nil {
+		return err
+	}
+	cutoff := l.now().AddDate(0, 0, -l.rotation.MaxAgeDays)
+	for i, name := range backups {
+		tooMany := l.rotation.MaxBackups > 0 && i >= l.rotation.MaxBackups
+		tooOld := false
+		if l.rotation.MaxAgeDays > 0 {
+			if info, err := os.Stat(name); err == nil && info.ModTime().Before(cutoff) {
+				tooOld = true
+			}
+		}
+		if tooMany || tooOld {
+			os.Remove(name)
+		}
+	}
+	return nil
+}
+
+func (l *Logger) sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Sync()
+}
+
+func (l *Logger) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// gzip name to name.gz and remove the original
+func compress_file(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -0,0 +1,215 @@
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logger that rolls every 1KB, with a clock the test moves forward
+func openTestLogger(t *testing.T, rotation RotationConfig) (*Logger, *time.Time) {
+	t.Helper()
+	l, err := open_logger(filepath.Join(t.TempDir(), "audit.log"), rotation)
+	if err != nil {
+		t.Fatalf("open_logger() error = %v", err)
+	}
+	t.Cleanup(func() { l.close() })
+	l.maxSize = 1024
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return l, &now
+}
+
+func TestLoggerRotatesBySize(t *testing.T) {
+	l, _ := openTestLogger(t, RotationConfig{})
+
+	record := bytes.Repeat([]byte("x"), 99) // 100 bytes with the newline
+	for i := 0; i < 15; i++ {
+		if err := l.write(record); err != nil {
+			t.Fatalf("write() error = %v", err)
+		}
+	}
+
+	backups, _ := l.backups()
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 rotated file, got %v", backups)
+	}
+	if want := filepath.Join(filepath.Dir(l.path), "audit-2024-01-01T00-00-01.000.log"); backups[0] != want {
+		t.Errorf("Expected backup %s, got %s", want, backups[0])
+	}
+
+	// old file keeps the first 10 records, the new one the rest
+	old, _ := os.ReadFile(backups[0])
+	current, _ := os.ReadFile(l.path)
+	if len(old) != 1000 || len(current) != 500 {
+		t.Errorf("Expected 1000 bytes rotated and 500 current, got %d and %d", len(old), len(current))
+	}
+}
+
+func TestLoggerCompressesAndPrunesBackups(t *testing.T) {
+	l, _ := openTestLogger(t, RotationConfig{MaxBackups: 2, Compress: true})
+
+	record := bytes.Repeat([]byte("y"), 511)
+	for i := 0; i < 10; i++ {
+		l.write(record)
+	}
+
+	backups, _ := l.backups()
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups kept, got %v", backups)
+	}
+	for _, name := range backups {
+		if !strings.HasSuffix(name, ".log.gz") {
+			t.Errorf("Expected compressed backup, got %s", name)
+			continue
+		}
+		f, _ := os.Open(name)
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("Backup %s isn't gzip: %v", name, err)
+		}
+		data, _ := io.ReadAll(gz)
+		f.Close()
+		if len(data) != 1024 {
+			t.Errorf("Expected 1024 bytes in %s, got %d", name, len(data))
+		}
+	}
+}
+
+func TestLoggerPrunesByAge(t *testing.T) {
+	l, now := openTestLogger(t, RotationConfig{MaxAgeDays: 7})
+
+	record := bytes.Repeat([]byte("z"), 1023)
+	l.write(record)
+	l.write(record)
+	backups, _ := l.backups()
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %v", backups)
+	}
+	stale := time.Now().AddDate(0, 0, -8)
+	os.Chtimes(backups[0], stale, stale)
+
+	*now = time.Now()
+	l.write(record)
+	backups, _ = l.backups()
+	if len(backups) != 1 || strings.Contains(backups[0], "2024-01-01") {
+		t.Errorf("Expected only the fresh backup to remain, got %v", backups)
+	}
+}
+
+func TestLoggerConcurrentWrites(t *testing.T) {
+	l, _ := openTestLogger(t, RotationConfig{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				l.write([]byte(`{"agent_id":"a"}`))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// every line intact across all files
+	files, _ := l.backups()
+	files = append(files, l.path)
+	lines := 0
+	for _, name := range files {
+		data, _ := os.ReadFile(name)
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if line != `{"agent_id":"a"}` {
+				t.Fatalf("Corrupt line %q in %s", line, name)
+			}
+			lines++
+		}
+	}
+	if lines != 400 {
+		t.Errorf("Expected 400 records, got %d", lines)
+	}
+}
+
+func TestLogDecisionConcurrentLinesAreValidJSON(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	if err := InitTelemetry("aegis-test", logPath); err != nil {
+		t.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+	defer Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				LogDecision(context.Background(), fmt.Sprintf("agent-%d", i), "files", "read", strings.Repeat("r", 500), "hash", "", true, 1, 0.1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	f, _ := os.Open(logPath)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	lines := 0
+	for scanner.Scan() {
+		var entry AuditLog
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Line %d isn't valid JSON: %v", lines+1, err)
+		}
+		lines++
+	}
+	if lines != 500 {
+		t.Errorf("Expected 500 records, got %d", lines)
+	}
+}
+
+func TestLoggerWriteFailuresSurface(t *testing.T) {
+	l, _ := openTestLogger(t, RotationConfig{})
+	l.file.Close() // writes to a closed fd fail
+
+	for i := 0; i < 3; i++ {
+		if err := l.write([]byte("{}")); err == nil {
+			t.Fatal("Expected write to a closed file to fail")
+		}
+	}
+	if l.failures != 3 {
+		t.Errorf("Expected 3 consecutive failures counted, got %d", l.failures)
+	}
+
+	if err := l.open(); err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	if err := l.write([]byte("{}")); err != nil || l.failures != 0 {
+		t.Errorf("Expected recovery to reset failures, got err=%v failures=%d", err, l.failures)
+	}
+}
+
+// JSON uses the same names as the YAML config
+func TestRotationConfig_JSON(t *testing.T) {
+	data, err := json.Marshal(RotationConfig{MaxSizeMB: 100, MaxBackups: 5, MaxAgeDays: 30, Compress: true})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `{"max_size_mb":100,"max_backups":5,"max_age_days":30,"compress":true}`; string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+	var rc RotationConfig
+	if err := json.Unmarshal([]byte(`{"max_size_mb":10,"compress":true}`), &rc); err != nil || rc != (RotationConfig{MaxSizeMB: 10, Compress: true}) {
+		t.Errorf("Unexpected decode: %+v (err=%v)", rc, err)
+	}
+}
@@ -0,0 +1,53 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// name of the denial counter, for dashboards and alerts
+const DenialsMetric = "aegis.policy.denials"
+
+var (
+	meterProvider *sdkmetric.MeterProvider
+
+	// no-op until telemetry is initialised
+	denials metric.Int64Counter = noop.Int64Counter{}
+)
+
+// install mp as the global meter provider and create the instruments
+// from it. split out so tests can use a manual reader
+func set_meter_provider(mp *sdkmetric.MeterProvider, serviceName string) error {
+	counter, err := mp.Meter(serviceName).Int64Counter(DenialsMetric,
+		metric.WithDescription("Tool requests denied by policy"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return err
+	}
+	otel.SetMeterProvider(mp)
+	meterProvider = mp
+	denials = counter
+	return nil
+}
+
+// count a policy decision. only denials are counted, by agent, tool,
+// action and category. category has to come from a small fixed set
+// (see policy.ReasonCategory), never the reason text, or every distinct
+// amount would make a new series
+func RecordDecision(ctx context.Context, agentID, tool, action, category string, allowed bool) {
+	if allowed {
+		return
+	}
+	denials.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("agent", agentID),
+		attribute.String("tool", tool),
+		attribute.String("action", action),
+		attribute.String("reason_category", category),
+	))
+}
@@ -0,0 +1,71 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// counter values by attribute set, from one collection of reader
+func collect_denials(t *testing.T, reader sdkmetric.Reader) map[attribute.Distinct]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+	out := make(map[attribute.Distinct]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != DenialsMetric {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok || !sum.IsMonotonic {
+				t.Fatalf("Expected %s to be a monotonic int64 sum, got %T", DenialsMetric, m.Data)
+			}
+			for _, dp := range sum.DataPoints {
+				out[dp.Attributes.Equivalent()] = dp.Value
+			}
+		}
+	}
+	return out
+}
+
+func TestRecordDecisionCountsDenials(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	if err := set_meter_provider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "aegis-test"); err != nil {
+		t.Fatalf("Failed to set meter provider: %v", err)
+	}
+
+	ctx := context.Background()
+	RecordDecision(ctx, "finance-agent", "payments", "create", "amount_exceeded", false)
+	RecordDecision(ctx, "finance-agent", "payments", "create", "amount_exceeded", false)
+	RecordDecision(ctx, "hr-agent", "files", "read", "no_policy", false)
+	RecordDecision(ctx, "finance-agent", "payments", "create", "", true)
+
+	attrs := func(agent, tool, action, category string) attribute.Distinct {
+		set := attribute.NewSet(
+			attribute.String("agent", agent),
+			attribute.String("tool", tool),
+			attribute.String("action", action),
+			attribute.String("reason_category", category),
+		)
+		return set.Equivalent()
+	}
+	got := collect_denials(t, reader)
+	want := map[attribute.Distinct]int64{
+		attrs("finance-agent", "payments", "create", "amount_exceeded"): 2,
+		attrs("hr-agent", "files", "read", "no_policy"):                 1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d series, got %d: %v", len(want), len(got), got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Expected %v for %v, got %d", v, k, got[k])
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// W3C traceparent/tracestate plus baggage, installed globally by InitTelemetry
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// add the trace context of ctx to outbound request headers
+func InjectHeaders(ctx context.Context, h http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// continue a trace from inbound request headers
+func ExtractHeaders(ctx context.Context, h http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// wrap an adapter's handler so each request gets a span continuing the
+// caller's trace. a no-op span is used before InitTelemetry
+func TraceHandler(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ExtractHeaders(r.Context(), r.Header)
+		ctx, span := otel.Tracer(name).Start(ctx, name+" "+r.URL.Path)
+		defer span.End()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
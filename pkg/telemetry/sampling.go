@@ -0,0 +1,50 @@
+package telemetry
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// controls which allow decisions make it into the audit log. denials and
+// AlwaysLog tools/actions are always written; other allows are kept with
+// probability AllowRate.
+type AuditSampling struct {
+	// fraction of low-value allows to keep, 0..1
+	AllowRate float64 `yaml:"allow_rate" json:"allow_rate"`
+
+	// "tool" or "tool/action" entries that are never sampled out
+	AlwaysLog []string `yaml:"always_log" json:"always_log,omitempty"`
+}
+
+var (
+	samplingMu sync.RWMutex
+	sampling   *AuditSampling // nil = log everything
+)
+
+// enable audit sampling, nil restores logging of every decision
+func SetAuditSampling(s *AuditSampling) {
+	samplingMu.Lock()
+	defer samplingMu.Unlock()
+	sampling = s
+}
+
+// whether a decision should be written to the audit log
+func should_log(tool, action string, allowed bool) bool {
+	if !allowed {
+		return true
+	}
+
+	samplingMu.RLock()
+	s := sampling
+	samplingMu.RUnlock()
+	if s == nil {
+		return true
+	}
+
+	for _, entry := range s.AlwaysLog {
+		if entry == tool || entry == tool+"/"+action {
+			return true
+		}
+	}
+	return rand.Float64() < s.AllowRate
+}
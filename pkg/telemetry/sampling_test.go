@@ -0,0 +1,80 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSampling_DenialsNeverSampledOut(t *testing.T) {
+	SetAuditSampling(&AuditSampling{AllowRate: 0})
+	defer SetAuditSampling(nil)
+
+	for i := 0; i < 1000; i++ {
+		if !should_log("files", "read", false) {
+			t.Fatal("Denial was sampled out")
+		}
+	}
+	if should_log("files", "read", true) {
+		t.Error("Expected allow to be sampled out at rate 0")
+	}
+}
+
+func TestSampling_AlwaysLogTools(t *testing.T) {
+	SetAuditSampling(&AuditSampling{AllowRate: 0, AlwaysLog: []string{"payments", "files/write"}})
+	defer SetAuditSampling(nil)
+
+	if !should_log("payments", "create", true) {
+		t.Error("Expected payments allows to always be logged")
+	}
+	if !should_log("files", "write", true) {
+		t.Error("Expected files/write allows to always be logged")
+	}
+	if should_log("files", "read", true) {
+		t.Error("Expected files/read allow to be sampled out")
+	}
+}
+
+func TestSampling_ApproximateRate(t *testing.T) {
+	SetAuditSampling(&AuditSampling{AllowRate: 0.1})
+	defer SetAuditSampling(nil)
+
+	const n = 20000
+	kept := 0
+	for i := 0; i < n; i++ {
+		if should_log("files", "read", true) {
+			kept++
+		}
+	}
+	// 10% of 20000 = 2000, allow generous slack for randomness
+	if kept < 1700 || kept > 2300 {
+		t.Errorf("Expected roughly 2000 of %d allows kept, got %d", n, kept)
+	}
+}
+
+func TestSampling_LogDecision(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	if err := InitTelemetry("aegis-test", logPath); err != nil {
+		t.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+	defer Close()
+
+	SetAuditSampling(&AuditSampling{AllowRate: 0})
+	defer SetAuditSampling(nil)
+
+	LogDecision(context.Background(), "a", "files", "read", "ok", "h", "", true, 1, 0.1)
+	LogDecision(context.Background(), "a", "files", "read", "denied", "h", "", false, 1, 0.1)
+
+	f, _ := os.Open(logPath)
+	defer f.Close()
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+	}
+	if lines != 1 {
+		t.Errorf("Expected only the denial to be logged, got %d records", lines)
+	}
+}
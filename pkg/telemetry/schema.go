@@ -0,0 +1,67 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// description of one field in an audit record
+type SchemaField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional"`
+}
+
+// the audit record format consumers can code against
+type Schema struct {
+	Version int           `json:"schema_version"`
+	Fields  []SchemaField `json:"fields"`
+}
+
+// describe the current AuditLog format, derived from the struct itself so
+// it can't drift from what LogDecision actually writes
+func AuditSchema() Schema {
+	t := reflect.TypeOf(AuditLog{})
+	fields := make([]SchemaField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fields = append(fields, SchemaField{
+			Name:     name,
+			Type:     json_type(t.Field(i).Type),
+			Optional: strings.Contains(opts, "omitempty"),
+		})
+	}
+	return Schema{Version: AuditSchemaVersion, Fields: fields}
+}
+
+// write the current schema as indented JSON
+func WriteAuditSchema(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(AuditSchema())
+}
+
+func json_type(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return json_type(t.Elem())
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return "integer"
+	case reflect.Float64, reflect.Float32:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
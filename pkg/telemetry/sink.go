@@ -0,0 +1,46 @@
+package telemetry
+
+import "sync"
+
+// secondary destination for audit records, alongside the log file
+type AuditSink interface {
+	// record one decision. an error means the sink can't currently
+	// persist records; callers decide whether to fail open or closed
+	Write(log AuditLog) error
+	Close() error
+}
+
+var (
+	sinkMu sync.RWMutex
+	sink   AuditSink
+)
+
+// install an audit sink, nil removes it. the previous sink is returned
+// so the caller can close it once it's no longer in use
+func SetAuditSink(s AuditSink) AuditSink {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	prev := sink
+	sink = s
+	return prev
+}
+
+// sinks that queue records can also write one through right away
+type SyncAuditSink interface {
+	AuditSink
+	// like Write, but only returns once log is persisted
+	WriteSync(log AuditLog) error
+}
+
+func write_sink(log AuditLog, durable bool) error {
+	sinkMu.RLock()
+	s := sink
+	sinkMu.RUnlock()
+	if s == nil {
+		return nil
+	}
+	if ss, ok := s.(SyncAuditSink); ok && durable {
+		return ss.WriteSync(log)
+	}
+	return s.Write(log)
+}
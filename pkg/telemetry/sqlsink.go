@@ -0,0 +1,285 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSQLBatchSize     = 100
+	defaultSQLFlushInterval = time.Second
+	defaultSQLMaxOpenConns  = 4
+
+	// records held while the database is unreachable, per batch slot
+	sqlPendingBatches = 100
+)
+
+var sqlTablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// settings for writing audit records to a relational table. columns are
+// named after the AuditLog JSON fields (agent_id, decision_allow, ...)
+type SQLSinkConfig struct {
+	// database/sql driver name, e.g. "postgres"
+	Driver string `yaml:"driver" json:"driver"`
+	DSN    string `yaml:"dsn" json:"-"`
+	Table  string `yaml:"table" json:"table"`
+
+	// rows per INSERT, flushed early once this many are pending
+	BatchSize int `yaml:"batch_size" json:"batch_size,omitempty"`
+
+	// max time a record waits before being flushed
+	FlushInterval time.Duration `yaml:"flush_interval" json:"flush_interval,omitempty"`
+
+	// connection pool size
+	MaxOpenConns int `yaml:"max_open_conns" json:"max_open_conns,omitempty"`
+}
+
+func (c SQLSinkConfig) Validate() error {
+	if c.Driver == "" {
+		return fmt.Errorf("driver is required")
+	}
+	if !sqlTablePattern.MatchString(c.Table) {
+		return fmt.Errorf("invalid table name %q", c.Table)
+	}
+	if c.BatchSize < 0 || c.FlushInterval < 0 || c.MaxOpenConns < 0 {
+		return fmt.Errorf("batch_size, flush_interval and max_open_conns cannot be negative")
+	}
+	return nil
+}
+
+// batches audit records into multi-row INSERTs on a background loop.
+// while the database is unavailable records are kept (up to a bound,
+// oldest dropped first) and Write reports the last flush error.
+type SQLAuditSink struct {
+	db       *sql.DB
+	insert   string // "INSERT INTO t (cols) VALUES "
+	dollar   bool   // $1 placeholders instead of ?
+	batch    int
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []AuditLog
+	err     error // last flush error, nil once a flush succeeds
+	dropped int
+
+	flushCh chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// open a connection pool for cfg and start the sink
+func OpenSQLAuditSink(cfg SQLSinkConfig) (*SQLAuditSink, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit database: %w", err)
+	}
+	return NewSQLAuditSink(db, cfg)
+}
+
+// start a sink on an existing pool; the sink owns db and closes it
+func NewSQLAuditSink(db *sql.DB, cfg SQLSinkConfig) (*SQLAuditSink, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = defaultSQLBatchSize
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = defaultSQLFlushInterval
+	}
+	if cfg.MaxOpenConns == 0 {
+		cfg.MaxOpenConns = defaultSQLMaxOpenConns
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxOpenConns)
+
+	s := &SQLAuditSink{
+		db:       db,
+		insert:   fmt.Sprintf("INSERT INTO %s (%s) VALUES ", cfg.Table, strings.Join(audit_column_names(), ", ")),
+		dollar:   cfg.Driver == "postgres" || cfg.Driver == "pgx",
+		batch:    cfg.BatchSize,
+		interval: cfg.FlushInterval,
+		flushCh:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.loop()
+	return s, nil
+}
+
+func (s *SQLAuditSink) Write(log AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if max := s.batch * sqlPendingBatches; len(s.pending) >= max {
+		s.pending = s.pending[1:]
+		s.dropped++
+	}
+	s.pending = append(s.pending, log)
+	if len(s.pending) >= s.batch {
+		select {
+		case s.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return s.err
+}
+
+// insert log on its own instead of batching it. a record that can't be
+// inserted is dropped, not queued: the caller refuses the request, and
+// writing the record later would log an action that never ran
+func (s *SQLAuditSink) WriteSync(log AuditLog) error {
+	err := s.insert_batch([]AuditLog{log})
+	if err == nil {
+		return nil
+	}
+	s.mu.Lock()
+	if s.err == nil {
+		fmt.Printf("ERROR: audit database unavailable: %v\n", err)
+	}
+	s.err = err
+	s.mu.Unlock()
+	return err
+}
+
+// write out everything pending
+func (s *SQLAuditSink) Flush() error {
+	for {
+		s.mu.Lock()
+		n := min(len(s.pending), s.batch)
+		batch := s.pending[:n:n]
+		s.pending = s.pending[n:]
+		s.mu.Unlock()
+
+		if n == 0 {
+			return nil
+		}
+		if err := s.insert_batch(batch); err != nil {
+			s.mu.Lock()
+			if s.err == nil {
+				fmt.Printf("ERROR: audit database unavailable: %v\n", err)
+			}
+			s.err = err
+			s.pending = append(batch, s.pending...)
+			s.mu.Unlock()
+			return err
+		}
+
+		s.mu.Lock()
+		if s.err != nil {
+			fmt.Println("Audit database available again")
+		}
+		s.err = nil
+		if s.dropped > 0 {
+			fmt.Printf("ERROR: dropped %d audit records while the database was unavailable\n", s.dropped)
+			s.dropped = 0
+		}
+		s.mu.Unlock()
+	}
+}
+
+// stop the background loop, flush what's left and close the pool
+func (s *SQLAuditSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+	err := s.Flush()
+	if cerr := s.db.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *SQLAuditSink) loop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.flushCh:
+		}
+		s.Flush()
+	}
+}
+
+func (s *SQLAuditSink) insert_batch(batch []AuditLog) error {
+	cols := len(auditColumns)
+	var query strings.Builder
+	query.WriteString(s.insert)
+	args := make([]interface{}, 0, len(batch)*cols)
+	for i, log := range batch {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for j := 0; j < cols; j++ {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			if s.dollar {
+				fmt.Fprintf(&query, "$%d", len(args)+j+1)
+			} else {
+				query.WriteString("?")
+			}
+		}
+		query.WriteString(")")
+		args = append(args, audit_values(log)...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, query.String(), args...)
+	return err
+}
+
+// audit table columns and the AuditLog field each one holds. adding a
+// field to AuditLog means adding its column here and to the table
+var auditColumns = []struct {
+	name  string
+	value func(AuditLog) interface{}
+}{
+	{"schema_version", func(l AuditLog) interface{} { return l.SchemaVersion }},
+	{"timestamp", func(l AuditLog) interface{} { return l.Timestamp }},
+	{"trace_id", func(l AuditLog) interface{} { return l.TraceID }},
+	{"agent_id", func(l AuditLog) interface{} { return l.AgentID }},
+	{"tool", func(l AuditLog) interface{} { return l.Tool }},
+	{"action", func(l AuditLog) interface{} { return l.Action }},
+	{"decision_allow", func(l AuditLog) interface{} { return l.Decision }},
+	{"reason", func(l AuditLog) interface{} { return l.Reason }},
+	{"policy_version", func(l AuditLog) interface{} { return l.Version }},
+	{"params_hash", func(l AuditLog) interface{} { return l.ParamsHash }},
+	{"latency_ms", func(l AuditLog) interface{} { return l.LatencyMs }},
+	{"parent_agent", func(l AuditLog) interface{} { return l.ParentAgent }},
+	{"request_bytes", func(l AuditLog) interface{} { return l.RequestBytes }},
+	{"response_bytes", func(l AuditLog) interface{} { return l.ResponseBytes }},
+	{"request_id", func(l AuditLog) interface{} { return l.RequestID }},
+	{"shadow_decision_allow", func(l AuditLog) interface{} { return l.ShadowDecision }},
+	{"shadow_reason", func(l AuditLog) interface{} { return l.ShadowReason }},
+}
+
+func audit_column_names() []string {
+	names := make([]string, len(auditColumns))
+	for i, c := range auditColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// column values, in auditColumns order
+func audit_values(log AuditLog) []interface{} {
+	values := make([]interface{}, len(auditColumns))
+	for i, c := range auditColumns {
+		values[i] = c.value(log)
+	}
+	return values
+}
@@ -0,0 +1,225 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// in-memory database/sql driver that records INSERTs as rows of column
+// name -> value, and can be switched off to simulate an outage
+type memDB struct {
+	mu    sync.Mutex
+	down  bool
+	execs int
+	rows  []map[string]driver.Value
+}
+
+var memDBs sync.Map // DSN -> *memDB
+
+type memDriver struct{}
+
+func (memDriver) Open(name string) (driver.Conn, error) {
+	db, _ := memDBs.LoadOrStore(name, &memDB{})
+	return &memConn{db: db.(*memDB)}, nil
+}
+
+type memConn struct{ db *memDB }
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c *memConn) Close() error              { return nil }
+func (c *memConn) Begin() (driver.Tx, error) { return nil, errors.New("transactions not supported") }
+
+func (c *memConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	if c.db.down {
+		return nil, errors.New("connection refused")
+	}
+
+	// INSERT INTO t (a, b) VALUES (?, ?), (?, ?)
+	open := strings.Index(query, "(")
+	cols := strings.Split(query[open+1:strings.Index(query, ")")], ", ")
+	for i := 0; i+len(cols) <= len(args); i += len(cols) {
+		row := make(map[string]driver.Value, len(cols))
+		for j, col := range cols {
+			row[col] = args[i+j].Value
+		}
+		c.db.rows = append(c.db.rows, row)
+	}
+	c.db.execs++
+	return driver.RowsAffected(len(args) / len(cols)), nil
+}
+
+func init() {
+	sql.Register("auditmem", memDriver{})
+}
+
+func openMemSink(t *testing.T, batchSize int) (*SQLAuditSink, *memDB) {
+	t.Helper()
+	s, err := OpenSQLAuditSink(SQLSinkConfig{
+		Driver:        "auditmem",
+		DSN:           t.Name(),
+		Table:         "audit_log",
+		BatchSize:     batchSize,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open sink: %v", err)
+	}
+	db, _ := memDBs.LoadOrStore(t.Name(), &memDB{})
+	return s, db.(*memDB)
+}
+
+func TestSQLAuditSink_InsertsRows(t *testing.T) {
+	s, db := openMemSink(t, 10)
+
+	s.Write(AuditLog{
+		SchemaVersion: AuditSchemaVersion,
+		Timestamp:     "2024-01-01T00:00:00Z",
+		AgentID:       "finance-agent",
+		Tool:          "payments",
+		Action:        "create",
+		Decision:      true,
+		Reason:        "ok",
+		Version:       3,
+		ParamsHash:    "abc",
+		LatencyMs:     1.5,
+	})
+	s.Write(AuditLog{AgentID: "files-agent", Tool: "files", Action: "write", ParentAgent: "orchestrator"})
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(db.rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(db.rows))
+	}
+	if db.execs != 1 {
+		t.Errorf("Expected both rows in a single batched insert, got %d execs", db.execs)
+	}
+	row := db.rows[0]
+	want := map[string]driver.Value{
+		"schema_version": int64(AuditSchemaVersion),
+		"agent_id":       "finance-agent",
+		"tool":           "payments",
+		"action":         "create",
+		"decision_allow": true,
+		"reason":         "ok",
+		"policy_version": int64(3),
+		"params_hash":    "abc",
+		"latency_ms":     1.5,
+	}
+	for col, v := range want {
+		if row[col] != v {
+			t.Errorf("Column %s = %v, want %v", col, row[col], v)
+		}
+	}
+	if db.rows[1]["parent_agent"] != "orchestrator" {
+		t.Errorf("Expected parent_agent on second row, got %v", db.rows[1]["parent_agent"])
+	}
+}
+
+func TestSQLAuditSink_DatabaseUnavailable(t *testing.T) {
+	s, db := openMemSink(t, 10)
+	defer s.Close()
+
+	db.mu.Lock()
+	db.down = true
+	db.mu.Unlock()
+
+	s.Write(AuditLog{AgentID: "a"})
+	if err := s.Flush(); err == nil {
+		t.Fatal("Expected flush to fail while the database is down")
+	}
+	if err := s.Write(AuditLog{AgentID: "b"}); err == nil {
+		t.Error("Expected Write to report the outage")
+	}
+
+	// records are kept and written once the database is back
+	db.mu.Lock()
+	db.down = false
+	db.mu.Unlock()
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(db.rows) != 2 || db.rows[0]["agent_id"] != "a" || db.rows[1]["agent_id"] != "b" {
+		t.Errorf("Expected buffered rows in order, got %v", db.rows)
+	}
+	if err := s.Write(AuditLog{AgentID: "c"}); err != nil {
+		t.Errorf("Expected Write to succeed after recovery, got %v", err)
+	}
+}
+
+func TestSQLSinkConfig_Validate(t *testing.T) {
+	bad := []SQLSinkConfig{
+		{Table: "audit_log"},
+		{Driver: "postgres", Table: "audit; DROP TABLE x"},
+		{Driver: "postgres", Table: "audit_log", BatchSize: -1},
+	}
+	for _, c := range bad {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Expected validation error for %+v", c)
+		}
+	}
+	if err := (SQLSinkConfig{Driver: "postgres", Table: "compliance.audit_log"}).Validate(); err != nil {
+		t.Errorf("Expected schema-qualified table to be valid, got %v", err)
+	}
+}
+
+func TestSQLAuditSink_WriteSync(t *testing.T) {
+	s, db := openMemSink(t, 10)
+	defer s.Close()
+
+	// batch not full and the interval an hour away: only WriteSync inserts
+	s.Write(AuditLog{AgentID: "queued"})
+	if err := s.WriteSync(AuditLog{AgentID: "sync"}); err != nil {
+		t.Fatalf("WriteSync() error = %v", err)
+	}
+	db.mu.Lock()
+	if len(db.rows) != 1 || db.rows[0]["agent_id"] != "sync" {
+		t.Errorf("Expected only the synchronous row inserted, got %v", db.rows)
+	}
+	db.down = true
+	db.mu.Unlock()
+
+	if err := s.WriteSync(AuditLog{AgentID: "down"}); err == nil {
+		t.Fatal("Expected WriteSync to fail while the database is down")
+	}
+
+	// the failed record belongs to a refused request, so it's dropped
+	// rather than written once the database is back
+	db.mu.Lock()
+	db.down = false
+	db.mu.Unlock()
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(db.rows) != 2 || db.rows[1]["agent_id"] != "queued" {
+		t.Errorf("Expected only the queued record written on recovery, got %v", db.rows)
+	}
+}
+
+func TestAuditColumns_CoverSchema(t *testing.T) {
+	cols := make(map[string]bool, len(auditColumns))
+	for _, c := range auditColumns {
+		if cols[c.name] {
+			t.Errorf("Duplicate audit column %q", c.name)
+		}
+		cols[c.name] = true
+	}
+	for _, f := range AuditSchema().Fields {
+		if !cols[f.Name] {
+			t.Errorf("AuditLog field %q has no audit column", f.Name)
+		}
+	}
+	if len(cols) != len(AuditSchema().Fields) {
+		t.Errorf("Expected %d audit columns, got %d", len(AuditSchema().Fields), len(cols))
+	}
+}
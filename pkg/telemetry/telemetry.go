@@ -4,52 +4,76 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
-type Logger struct {
-	file *os.File
-}
+// version of the AuditLog record format. bump it whenever a JSON field is
+// added, removed or renamed so downstream parsers can tell records apart.
+const AuditSchemaVersion = 4
 
 type AuditLog struct {
-	Timestamp   string  `json:"timestamp"`
-	TraceID     string  `json:"trace_id"`
-	AgentID     string  `json:"agent_id"`
-	Tool        string  `json:"tool"`
-	Action      string  `json:"action"`
-	Decision    bool    `json:"decision_allow"`
-	Reason      string  `json:"reason"`
-	Version     int     `json:"policy_version"`
-	ParamsHash  string  `json:"params_hash"`
-	LatencyMs   float64 `json:"latency_ms"`
-	ParentAgent string  `json:"parent_agent,omitempty"`
+	SchemaVersion int     `json:"schema_version"`
+	Timestamp     string  `json:"timestamp"`
+	TraceID       string  `json:"trace_id"`
+	AgentID       string  `json:"agent_id"`
+	Tool          string  `json:"tool"`
+	Action        string  `json:"action"`
+	Decision      bool    `json:"decision_allow"`
+	Reason        string  `json:"reason"`
+	Version       int     `json:"policy_version"`
+	ParamsHash    string  `json:"params_hash"`
+	LatencyMs     float64 `json:"latency_ms"`
+	ParentAgent   string  `json:"parent_agent,omitempty"`
+
+	// inbound request body and adapter response body, 0 when there was
+	// no response
+	RequestBytes  int64 `json:"request_bytes"`
+	ResponseBytes int64 `json:"response_bytes"`
+
+	// X-Request-ID correlating the gateway record with adapter logs
+	RequestID string `json:"request_id,omitempty"`
+
+	// decision of the shadow policies, only set when it differs from the
+	// enforced one
+	ShadowDecision *bool  `json:"shadow_decision_allow,omitempty"`
+	ShadowReason   string `json:"shadow_reason,omitempty"`
 }
 
 var (
-	tracer trace.Tracer
-	logger *Logger
+	tracer   trace.Tracer
+	provider *sdktrace.TracerProvider
+	logger   *Logger
+
+	// echo audit records to stdout as well as (or instead of) the file
+	auditStdout = true
 )
 
+// set up tracing with the stdout exporter and the audit log at logPath
 func InitTelemetry(serviceName string, logPath string) error {
-	exporter, err := stdouttrace.New(
-		stdouttrace.WithPrettyPrint(),
-	)
+	return InitTelemetryWithConfig(Config{ServiceName: serviceName, LogPath: logPath})
+}
+
+func InitTelemetryWithConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	exporter, err := new_exporter(context.Background(), cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create stdout exporter: %w", err)
+		return fmt.Errorf("failed to create span exporter: %w", err)
 	}
 
 	res, err := resource.New(context.Background(),
 		resource.WithAttributes(
-			semconv.ServiceName(serviceName),
+			semconv.ServiceName(cfg.ServiceName),
 			semconv.ServiceVersion("1.0.0"),
 		),
 	)
@@ -62,15 +86,33 @@ func InitTelemetry(serviceName string, logPath string) error {
 		sdktrace.WithResource(res),
 	)
 	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
 
-	tracer = tp.Tracer(serviceName)
+	provider = tp
+	tracer = tp.Tracer(cfg.ServiceName)
 
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	metricExporter, err := new_metric_exporter(context.Background(), cfg)
 	if err != nil {
-		return fmt.Errorf("failed to open log file: %w", err)
+		return fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		sdkmetric.WithResource(res),
+	)
+	if err := set_meter_provider(mp, cfg.ServiceName); err != nil {
+		return fmt.Errorf("failed to create metrics: %w", err)
 	}
 
-	logger = &Logger{file: logFile}
+	toStdout, toFile := cfg.audit_outputs()
+	auditStdout = toStdout
+	logger = nil
+	if toFile {
+		l, err := open_logger(cfg.LogPath, cfg.Rotation)
+		if err != nil {
+			return err
+		}
+		logger = l
+	}
 
 	return nil
 }
@@ -79,15 +121,10 @@ func StartSpan(ctx context.Context, name string) (context.Context, trace.Span) {
 	return tracer.Start(ctx, name)
 }
 
-func LogDecision(ctx context.Context, agentID, tool, action, reason, paramsHash, parentAgent string, allowed bool, version int, latencyMs float64) {
-	traceID := ""
-	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
-		traceID = span.SpanContext().TraceID().String()
-	}
-
-	log := AuditLog{
-		Timestamp:   time.Now().UTC().Format(time.RFC3339),
-		TraceID:     traceID,
+// write an audit record. an error means the record may not have reached
+// the log file or the audit sink, if one is set.
+func LogDecision(ctx context.Context, agentID, tool, action, reason, paramsHash, parentAgent string, allowed bool, version int, latencyMs float64) error {
+	return LogAudit(ctx, AuditLog{
 		AgentID:     agentID,
 		Tool:        tool,
 		Action:      action,
@@ -97,15 +134,48 @@ func LogDecision(ctx context.Context, agentID, tool, action, reason, paramsHash,
 		ParamsHash:  paramsHash,
 		LatencyMs:   latencyMs,
 		ParentAgent: parentAgent,
+	})
+}
+
+// like LogDecision for a prepared record. schema version, timestamp and
+// trace ID are filled in here
+func LogAudit(ctx context.Context, log AuditLog) error {
+	return log_audit(ctx, log, false)
+}
+
+// like LogAudit, but a sink that batches (SQLAuditSink) persists the record
+// before returning. for audit_fail_closed, where the record has to exist
+// before the action runs
+func LogAuditSync(ctx context.Context, log AuditLog) error {
+	return log_audit(ctx, log, true)
+}
+
+func log_audit(ctx context.Context, log AuditLog, durable bool) error {
+	if !should_log(log.Tool, log.Action, log.Decision) {
+		return nil
+	}
+
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		log.TraceID = span.SpanContext().TraceID().String()
 	}
+	log.SchemaVersion = AuditSchemaVersion
+	log.Timestamp = time.Now().UTC().Format(time.RFC3339)
 
 	data, _ := json.Marshal(log)
-	fmt.Println(string(data))
+	if auditStdout {
+		fmt.Println(string(data))
+	}
+
+	var fileErr error
+	if logger != nil {
+		fileErr = logger.write(data)
+	}
 
-	if logger != nil && logger.file != nil {
-		logger.file.Write(data)
-		logger.file.WriteString("\n")
+	sinkErr := write_sink(log, durable)
+	if fileErr != nil {
+		return fmt.Errorf("audit log: %w", fileErr)
 	}
+	return sinkErr
 }
 
 func AddSpanAttributes(span trace.Span, attrs map[string]interface{}) {
@@ -127,8 +197,29 @@ func AddSpanAttributes(span trace.Span, attrs map[string]interface{}) {
 	span.SetAttributes(spanAttrs...)
 }
 
+// export buffered spans and metrics and sync the audit log to disk
+func Flush(ctx context.Context) error {
+	if provider != nil {
+		if err := provider.ForceFlush(ctx); err != nil {
+			return fmt.Errorf("failed to flush spans: %w", err)
+		}
+	}
+	if meterProvider != nil {
+		if err := meterProvider.ForceFlush(ctx); err != nil {
+			return fmt.Errorf("failed to flush metrics: %w", err)
+		}
+	}
+	if logger != nil {
+		return logger.sync()
+	}
+	return nil
+}
+
 func Close() {
-	if logger != nil && logger.file != nil {
-		logger.file.Close()
+	if s := SetAuditSink(nil); s != nil {
+		s.Close()
+	}
+	if logger != nil {
+		logger.close()
 	}
 }
@@ -0,0 +1,178 @@
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pinned field lists per schema version. if this test fails you changed
+// AuditLog: bump AuditSchemaVersion and add the new field list here.
+var schemaFields = map[int][]string{
+	1: {"schema_version", "timestamp", "trace_id", "agent_id", "tool", "action", "decision_allow", "reason", "policy_version", "params_hash", "latency_ms", "parent_agent"},
+	2: {"schema_version", "timestamp", "trace_id", "agent_id", "tool", "action", "decision_allow", "reason", "policy_version", "params_hash", "latency_ms", "parent_agent", "request_bytes", "response_bytes"},
+	3: {"schema_version", "timestamp", "trace_id", "agent_id", "tool", "action", "decision_allow", "reason", "policy_version", "params_hash", "latency_ms", "parent_agent", "request_bytes", "response_bytes", "request_id"},
+	4: {"schema_version", "timestamp", "trace_id", "agent_id", "tool", "action", "decision_allow", "reason", "policy_version", "params_hash", "latency_ms", "parent_agent", "request_bytes", "response_bytes", "request_id", "shadow_decision_allow", "shadow_reason"},
+}
+
+func TestAuditSchemaVersionMatchesFields(t *testing.T) {
+	want, ok := schemaFields[AuditSchemaVersion]
+	if !ok {
+		t.Fatalf("No pinned field list for schema version %d", AuditSchemaVersion)
+	}
+
+	var got []string
+	for _, f := range AuditSchema().Fields {
+		got = append(got, f.Name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AuditLog fields changed without a schema version bump.\n got: %v\nwant: %v", got, want)
+	}
+}
+
+func TestLogDecisionCarriesSchemaVersion(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	if err := InitTelemetry("aegis-test", logPath); err != nil {
+		t.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+	defer Close()
+
+	LogDecision(context.Background(), "agent-1", "payments", "create", "ok", "hash", "", true, 1, 0.5)
+	LogDecision(context.Background(), "agent-2", "files", "read", "denied", "hash", "parent", false, 2, 0.7)
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("Invalid audit record %q: %v", scanner.Text(), err)
+		}
+		if rec["schema_version"] != float64(AuditSchemaVersion) {
+			t.Errorf("Expected schema_version %d, got %v", AuditSchemaVersion, rec["schema_version"])
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 audit records, got %d", count)
+	}
+}
+
+func TestWriteAuditSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteAuditSchema(&buf); err != nil {
+		t.Fatalf("WriteAuditSchema() error = %v", err)
+	}
+
+	var schema Schema
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+	if schema.Version != AuditSchemaVersion {
+		t.Errorf("Expected version %d, got %d", AuditSchemaVersion, schema.Version)
+	}
+	for _, f := range schema.Fields {
+		if f.Name == "parent_agent" && !f.Optional {
+			t.Error("Expected parent_agent to be marked optional")
+		}
+		if f.Name == "latency_ms" && f.Type != "number" {
+			t.Errorf("Expected latency_ms type number, got %s", f.Type)
+		}
+	}
+}
+
+func TestTraceHandlerContinuesTrace(t *testing.T) {
+	if err := InitTelemetry("aegis-test", filepath.Join(t.TempDir(), "audit.log")); err != nil {
+		t.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+	defer Close()
+
+	ctx, span := StartSpan(context.Background(), "caller")
+	defer span.End()
+	header := http.Header{}
+	InjectHeaders(ctx, header)
+
+	var got trace.SpanContext
+	handler := TraceHandler("test-adapter", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = trace.SpanContextFromContext(r.Context())
+	}))
+	req := httptest.NewRequest("POST", "/read", nil)
+	req.Header = header
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	want := span.SpanContext()
+	if got.TraceID() != want.TraceID() {
+		t.Errorf("Expected adapter span in trace %s, got %s", want.TraceID(), got.TraceID())
+	}
+	if got.SpanID() == want.SpanID() {
+		t.Error("Expected the adapter to start its own span")
+	}
+}
+
+// run fn with os.Stdout redirected and return what it printed
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error = %v", err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	fn()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestLogDecisionAuditOutput(t *testing.T) {
+	tests := []struct {
+		output               string
+		wantStdout, wantFile bool
+	}{
+		{"", true, true},
+		{"both", true, true},
+		{"file", false, true},
+		{"stdout", true, false},
+		{"none", false, false},
+	}
+	for _, tt := range tests {
+		t.Run("output="+tt.output, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "audit.log")
+			err := InitTelemetryWithConfig(Config{ServiceName: "aegis-test", LogPath: logPath, AuditOutput: tt.output})
+			if err != nil {
+				t.Fatalf("InitTelemetryWithConfig() error = %v", err)
+			}
+			defer Close()
+
+			out := captureStdout(t, func() {
+				LogDecision(context.Background(), "agent-1", "payments", "create", "ok", "hash", "", true, 1, 0.5)
+			})
+			if got := strings.Contains(out, `"agent_id":"agent-1"`); got != tt.wantStdout {
+				t.Errorf("Record on stdout = %v, want %v (stdout %q)", got, tt.wantStdout, out)
+			}
+
+			data, _ := os.ReadFile(logPath)
+			if got := strings.Contains(string(data), `"agent_id":"agent-1"`); got != tt.wantFile {
+				t.Errorf("Record in file = %v, want %v", got, tt.wantFile)
+			}
+		})
+	}
+}